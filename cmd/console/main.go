@@ -2,10 +2,18 @@ package main
 
 import (
     "log"
+    "os"
+    "path/filepath"
+    "strconv"
+    "time"
 
+    "megabaseGo/internal/app/services"
     "megabaseGo/internal/config"
     "megabaseGo/internal/models"
+    "megabaseGo/internal/utils"
     dbpkg "megabaseGo/internal/database"
+    "megabaseGo/internal/database/encryption"
+    dbmigrations "megabaseGo/internal/database/migrations"
     dbseed "megabaseGo/internal/database/seeders"
 
     "github.com/spf13/cobra"
@@ -37,9 +45,14 @@ func main() {
             if err := db.AutoMigrate(models.AllModels...); err != nil {
                 log.Fatalf("Error en AutoMigrate: %v", err)
             }
+
+            // 4) Migraciones SQL que AutoMigrate no cubre (índices funcionales, datos...)
+            if err := dbmigrations.Run(db); err != nil {
+                log.Fatalf("Error en migraciones SQL: %v", err)
+            }
             log.Println("✔ Migraciones completadas")
 
-            // 4) Si se pasa --seed, ejecuta todos los seeders
+            // 5) Si se pasa --seed, ejecuta todos los seeders
             if withSeed {
                 seeder := &dbseed.DatabaseSeeder{}
                 if err := seeder.Run(db); err != nil {
@@ -52,6 +65,390 @@ func main() {
     migrateCmd.Flags().BoolVarP(&withSeed, "seed", "s", false, "Ejecutar seeders tras migrar")
     rootCmd.AddCommand(migrateCmd)
 
+    identifiersCmd := &cobra.Command{
+        Use:   "identifiers",
+        Short: "Herramientas para usernames y emails de usuarios",
+    }
+
+    identifiersCheckCmd := &cobra.Command{
+        Use:   "check",
+        Short: "Reporta usernames/emails que colisionan sin distinguir mayúsculas",
+        Run: func(cmd *cobra.Command, args []string) {
+            cfg := config.LoadConfig()
+            db, err := dbpkg.InitDB(cfg)
+            if err != nil {
+                log.Fatalf("Error iniciando BD: %v", err)
+            }
+            defer dbpkg.CloseDB()
+
+            collisions, err := dbmigrations.FindIdentifierCollisions(db)
+            if err != nil {
+                log.Fatalf("Error buscando colisiones: %v", err)
+            }
+            if len(collisions) == 0 {
+                log.Println("✔ No hay colisiones de identificadores")
+                return
+            }
+            dbmigrations.ReportIdentifierCollisions(collisions)
+            log.Fatalf("Se encontraron %d colisiones", len(collisions))
+        },
+    }
+    identifiersCmd.AddCommand(identifiersCheckCmd)
+    rootCmd.AddCommand(identifiersCmd)
+
+    scimClientCmd := &cobra.Command{
+        Use:   "scim-client",
+        Short: "Gestiona los clientes de aprovisionamiento SCIM",
+    }
+
+    scimClientCreateCmd := &cobra.Command{
+        Use:   "create <nombre>",
+        Short: "Crea un cliente SCIM y muestra su token (solo una vez)",
+        Args:  cobra.ExactArgs(1),
+        Run: func(cmd *cobra.Command, args []string) {
+            cfg := config.LoadConfig()
+            if _, err := dbpkg.InitDB(cfg); err != nil {
+                log.Fatalf("Error iniciando BD: %v", err)
+            }
+            defer dbpkg.CloseDB()
+
+            token, err := services.NewScimService().CreateClient(args[0])
+            if err != nil {
+                log.Fatalf("Error creando cliente SCIM: %v", err)
+            }
+            log.Printf("✔ Cliente SCIM %q creado", args[0])
+            log.Printf("Token (guárdalo ahora, no se volverá a mostrar): %s", token)
+        },
+    }
+
+    scimClientListCmd := &cobra.Command{
+        Use:   "list",
+        Short: "Lista los clientes SCIM",
+        Run: func(cmd *cobra.Command, args []string) {
+            cfg := config.LoadConfig()
+            if _, err := dbpkg.InitDB(cfg); err != nil {
+                log.Fatalf("Error iniciando BD: %v", err)
+            }
+            defer dbpkg.CloseDB()
+
+            clients, err := services.NewScimService().ListClients()
+            if err != nil {
+                log.Fatalf("Error listando clientes SCIM: %v", err)
+            }
+            for _, client := range clients {
+                lastUsed := "nunca"
+                if client.LastUsedAt != nil {
+                    lastUsed = client.LastUsedAt.Format("2006-01-02 15:04:05")
+                }
+                log.Printf("%-30s activo=%-5t último uso=%s", client.Name, client.IsActive, lastUsed)
+            }
+        },
+    }
+
+    scimClientRevokeCmd := &cobra.Command{
+        Use:   "revoke <nombre>",
+        Short: "Revoca el token de un cliente SCIM",
+        Args:  cobra.ExactArgs(1),
+        Run: func(cmd *cobra.Command, args []string) {
+            cfg := config.LoadConfig()
+            if _, err := dbpkg.InitDB(cfg); err != nil {
+                log.Fatalf("Error iniciando BD: %v", err)
+            }
+            defer dbpkg.CloseDB()
+
+            if err := services.NewScimService().RevokeClient(args[0]); err != nil {
+                log.Fatalf("Error revocando cliente SCIM: %v", err)
+            }
+            log.Printf("✔ Cliente SCIM %q revocado", args[0])
+        },
+    }
+    scimClientCmd.AddCommand(scimClientCreateCmd, scimClientListCmd, scimClientRevokeCmd)
+    rootCmd.AddCommand(scimClientCmd)
+
+    samlCmd := &cobra.Command{
+        Use:   "saml",
+        Short: "Herramientas para SSO SAML",
+    }
+
+    var keygenCommonName, keygenOutDir string
+    var keygenDays int
+    samlKeygenCmd := &cobra.Command{
+        Use:   "keygen",
+        Short: "Genera una clave RSA y un certificado autofirmado (par del SP o IdP de pruebas local)",
+        Run: func(cmd *cobra.Command, args []string) {
+            keyPEM, certPEM, err := utils.GenerateSelfSignedCertificate(keygenCommonName, time.Duration(keygenDays)*24*time.Hour)
+            if err != nil {
+                log.Fatalf("Error generando certificado: %v", err)
+            }
+            if err := os.MkdirAll(keygenOutDir, 0o700); err != nil {
+                log.Fatalf("Error creando directorio: %v", err)
+            }
+
+            keyFile := filepath.Join(keygenOutDir, "saml.key")
+            certFile := filepath.Join(keygenOutDir, "saml.crt")
+            if err := os.WriteFile(keyFile, keyPEM, 0o600); err != nil {
+                log.Fatalf("Error escribiendo clave: %v", err)
+            }
+            if err := os.WriteFile(certFile, certPEM, 0o644); err != nil {
+                log.Fatalf("Error escribiendo certificado: %v", err)
+            }
+            log.Printf("✔ Clave: %s", keyFile)
+            log.Printf("✔ Certificado: %s", certFile)
+        },
+    }
+    samlKeygenCmd.Flags().StringVar(&keygenCommonName, "cn", "megabase-saml", "Common Name del certificado")
+    samlKeygenCmd.Flags().StringVar(&keygenOutDir, "out", ".", "Directorio de salida")
+    samlKeygenCmd.Flags().IntVar(&keygenDays, "days", 365, "Días de validez")
+    samlCmd.AddCommand(samlKeygenCmd)
+    rootCmd.AddCommand(samlCmd)
+
+    auditCmd := &cobra.Command{
+        Use:   "audit",
+        Short: "Herramientas para la cadena de hashes del log de auditoría",
+    }
+
+    auditVerifyCmd := &cobra.Command{
+        Use:   "verify",
+        Short: "Verifica la cadena completa y los anclajes; indica el primer eslabón roto",
+        Run: func(cmd *cobra.Command, args []string) {
+            cfg := config.LoadConfig()
+            if _, err := dbpkg.InitDB(cfg); err != nil {
+                log.Fatalf("Error iniciando BD: %v", err)
+            }
+            defer dbpkg.CloseDB()
+
+            report, err := services.NewAuditService().VerifyChain()
+            if err != nil {
+                log.Fatalf("Error verificando la cadena: %v", err)
+            }
+            if report.Legacy > 0 {
+                log.Printf("%d entradas anteriores a la cadena (sin hash) no se pueden verificar", report.Legacy)
+            }
+            if !report.Valid() {
+                log.Printf("Entradas verificadas hasta el fallo: %d (última válida: #%d)", report.Checked, report.HeadID)
+                log.Fatalf("✘ Cadena rota en la entrada #%d: %s", report.BrokenAt, report.Reason)
+            }
+            log.Printf("✔ Cadena íntegra: %d entradas, %d anclajes contrastados", report.Checked, report.AnchorsChecked)
+            log.Printf("Cabeza: #%d %s", report.HeadID, report.HeadHash)
+        },
+    }
+
+    auditAnchorCmd := &cobra.Command{
+        Use:   "anchor",
+        Short: "Añade la cabeza actual de la cadena al fichero de anclaje (AUDIT_ANCHOR_FILE)",
+        Run: func(cmd *cobra.Command, args []string) {
+            cfg := config.LoadConfig()
+            if _, err := dbpkg.InitDB(cfg); err != nil {
+                log.Fatalf("Error iniciando BD: %v", err)
+            }
+            defer dbpkg.CloseDB()
+
+            anchor, err := services.NewAuditService().AnchorHead()
+            if err != nil {
+                log.Fatalf("Error anclando la cadena: %v", err)
+            }
+            if anchor == nil {
+                log.Println("La cadena de auditoría está vacía")
+                return
+            }
+            log.Printf("✔ Anclada la entrada #%d %s", anchor.EntryID, anchor.Hash)
+        },
+    }
+    auditCmd.AddCommand(auditVerifyCmd, auditAnchorCmd)
+    rootCmd.AddCommand(auditCmd)
+
+    trashCmd := &cobra.Command{
+        Use:   "trash",
+        Short: "Gestión de la papelera de usuarios y roles",
+    }
+
+    trashPurgeCmd := &cobra.Command{
+        Use:   "purge",
+        Short: "Elimina definitivamente los registros con más de TRASH_RETENTION_DAYS días en la papelera",
+        Run: func(cmd *cobra.Command, args []string) {
+            cfg := config.LoadConfig()
+            if _, err := dbpkg.InitDB(cfg); err != nil {
+                log.Fatalf("Error iniciando BD: %v", err)
+            }
+            defer dbpkg.CloseDB()
+
+            users, roles, err := services.NewTrashService().PurgeExpired()
+            if err != nil {
+                log.Fatalf("Error purgando la papelera (%d usuarios y %d roles ya purgados): %v", users, roles, err)
+            }
+            log.Printf("✔ Papelera purgada: %d usuarios, %d roles", users, roles)
+        },
+    }
+    trashCmd.AddCommand(trashPurgeCmd)
+    rootCmd.AddCommand(trashCmd)
+
+    privacyCmd := &cobra.Command{
+        Use:   "privacy",
+        Short: "Supresión de cuentas y exportaciones de datos (RGPD)",
+    }
+
+    privacyProcessCmd := &cobra.Command{
+        Use:   "process",
+        Short: "Ejecuta las supresiones con el plazo de gracia vencido y borra las exportaciones caducadas",
+        Run: func(cmd *cobra.Command, args []string) {
+            cfg := config.LoadConfig()
+            if _, err := dbpkg.InitDB(cfg); err != nil {
+                log.Fatalf("Error iniciando BD: %v", err)
+            }
+            defer dbpkg.CloseDB()
+
+            erased, err := services.NewPrivacyService().ProcessDue()
+            if err != nil {
+                log.Fatalf("Error procesando (%d usuarios ya suprimidos): %v", erased, err)
+            }
+            log.Printf("✔ Usuarios suprimidos: %d", erased)
+        },
+    }
+
+    privacyEraseCmd := &cobra.Command{
+        Use:   "erase <user_id>",
+        Short: "Suprime ya los datos personales de un usuario",
+        Args:  cobra.ExactArgs(1),
+        Run: func(cmd *cobra.Command, args []string) {
+            userID, err := strconv.ParseUint(args[0], 10, 32)
+            if err != nil {
+                log.Fatalf("ID de usuario no válido: %s", args[0])
+            }
+
+            cfg := config.LoadConfig()
+            if _, err := dbpkg.InitDB(cfg); err != nil {
+                log.Fatalf("Error iniciando BD: %v", err)
+            }
+            defer dbpkg.CloseDB()
+
+            if _, err := services.NewPrivacyService().EraseUser(uint(userID), &services.Actor{UserName: "console"}); err != nil {
+                log.Fatalf("Error suprimiendo el usuario: %v", err)
+            }
+            log.Printf("✔ Datos del usuario #%d suprimidos", userID)
+        },
+    }
+    privacyCmd.AddCommand(privacyProcessCmd, privacyEraseCmd)
+    rootCmd.AddCommand(privacyCmd)
+
+    accountsCmd := &cobra.Command{
+        Use:   "accounts",
+        Short: "Gestión de las bajas de cuenta pedidas por los usuarios",
+    }
+
+    accountsProcessCmd := &cobra.Command{
+        Use:   "process-deletions",
+        Short: "Envía a la papelera las cuentas cuyo plazo de reactivación ha vencido",
+        Run: func(cmd *cobra.Command, args []string) {
+            cfg := config.LoadConfig()
+            if _, err := dbpkg.InitDB(cfg); err != nil {
+                log.Fatalf("Error iniciando BD: %v", err)
+            }
+            defer dbpkg.CloseDB()
+
+            completed, err := services.NewProfileService().ProcessDueDeletions()
+            if err != nil {
+                log.Fatalf("Error procesando bajas de cuenta (%d ya completadas): %v", completed, err)
+            }
+            log.Printf("✔ %d bajas de cuenta completadas", completed)
+        },
+    }
+    accountsCmd.AddCommand(accountsProcessCmd)
+    rootCmd.AddCommand(accountsCmd)
+
+    encryptionCmd := &cobra.Command{
+        Use:   "encryption",
+        Short: "Gestión del cifrado de campos (FIELD_ENCRYPTION_KEKS)",
+    }
+
+    // initEncryptedDB abre la BD y comprueba que el cifrado esté configurado
+    initEncryptedDB := func() {
+        cfg := config.LoadConfig()
+        if _, err := dbpkg.InitDB(cfg); err != nil {
+            log.Fatalf("Error iniciando BD: %v", err)
+        }
+        if !encryption.Enabled() {
+            dbpkg.CloseDB()
+            log.Fatalf("El cifrado de campos no está configurado (FIELD_ENCRYPTION_KEKS)")
+        }
+    }
+
+    encryptionStatusCmd := &cobra.Command{
+        Use:   "status",
+        Short: "Lista las claves de datos y la KEK que envuelve cada una",
+        Run: func(cmd *cobra.Command, args []string) {
+            initEncryptedDB()
+            defer dbpkg.CloseDB()
+
+            keys, err := encryption.Keys()
+            if err != nil {
+                log.Fatalf("Error leyendo las claves de datos: %v", err)
+            }
+            if len(keys) == 0 {
+                log.Println("No hay claves de datos; se creará una al cifrar el primer valor")
+            }
+            for _, key := range keys {
+                state := "retirada"
+                if key.Active {
+                    state = "activa"
+                }
+                log.Printf("DEK #%d  KEK %s  %s  creada %s", key.ID, key.KEKID, state, key.CreatedAt.Format(time.RFC3339))
+            }
+        },
+    }
+
+    encryptionRotateCmd := &cobra.Command{
+        Use:   "rotate-dek",
+        Short: "Crea una clave de datos nueva para los valores que se cifren a partir de ahora",
+        Run: func(cmd *cobra.Command, args []string) {
+            initEncryptedDB()
+            defer dbpkg.CloseDB()
+
+            key, err := encryption.RotateDataKey()
+            if err != nil {
+                log.Fatalf("Error rotando la clave de datos: %v", err)
+            }
+            log.Printf("✔ Clave de datos #%d activa (KEK %s). Ejecuta 'encryption reencrypt' para re-cifrar los valores existentes", key.ID, key.KEKID)
+        },
+    }
+
+    encryptionRewrapCmd := &cobra.Command{
+        Use:   "rewrap",
+        Short: "Envuelve las claves de datos con FIELD_ENCRYPTION_ACTIVE_KEK",
+        Run: func(cmd *cobra.Command, args []string) {
+            initEncryptedDB()
+            defer dbpkg.CloseDB()
+
+            count, err := encryption.RewrapDataKeys()
+            if err != nil {
+                log.Fatalf("Error re-envolviendo las claves de datos: %v", err)
+            }
+            log.Printf("✔ %d claves de datos re-envueltas", count)
+        },
+    }
+
+    encryptionReencryptCmd := &cobra.Command{
+        Use:   "reencrypt",
+        Short: "Cifra con la clave de datos activa los valores en claro o cifrados con claves retiradas",
+        Run: func(cmd *cobra.Command, args []string) {
+            initEncryptedDB()
+            defer dbpkg.CloseDB()
+
+            count, err := services.NewEncryptionService().ReencryptUsers()
+            if err != nil {
+                log.Fatalf("Error re-cifrando usuarios (%d ya re-cifrados): %v", count, err)
+            }
+            log.Printf("✔ %d usuarios re-cifrados", count)
+
+            count, err = services.NewEncryptionService().ReencryptRevisions()
+            if err != nil {
+                log.Fatalf("Error re-cifrando revisiones (%d ya re-cifradas): %v", count, err)
+            }
+            log.Printf("✔ %d revisiones re-cifradas", count)
+        },
+    }
+    encryptionCmd.AddCommand(encryptionStatusCmd, encryptionRotateCmd, encryptionRewrapCmd, encryptionReencryptCmd)
+    rootCmd.AddCommand(encryptionCmd)
+
     if err := rootCmd.Execute(); err != nil {
         log.Fatal(err)
     }
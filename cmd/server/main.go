@@ -8,9 +8,11 @@ import (
 	"syscall"
 	"time"
 
+	"megabaseGo/internal/app/services"
 	"megabaseGo/internal/config"
 	"megabaseGo/internal/database"
 	"megabaseGo/internal/routes"
+	"megabaseGo/internal/utils"
 
 	"github.com/gin-gonic/gin"
 )
@@ -39,6 +41,30 @@ func main() {
 		log.Println("🔧 Modo de desarrollo activado")
 	}
 
+	// 3b. Anclaje periódico de la cadena de auditoría para archivarla fuera de la BD
+	if minutes := utils.GetEnvInt("AUDIT_ANCHOR_INTERVAL_MINUTES", 0); minutes > 0 {
+		go services.NewAuditService().RunAnchoring(time.Duration(minutes) * time.Minute)
+		log.Printf("⚓ Anclaje de auditoría cada %d minutos", minutes)
+	}
+
+	// 3c. Purga periódica de la papelera (TRASH_RETENTION_DAYS)
+	if minutes := utils.GetEnvInt("TRASH_PURGE_INTERVAL_MINUTES", 0); minutes > 0 {
+		go services.NewTrashService().RunPurging(time.Duration(minutes) * time.Minute)
+		log.Printf("🗑️  Purga de la papelera cada %d minutos", minutes)
+	}
+
+	// 3d. Supresiones de cuenta vencidas y limpieza de exportaciones de datos caducadas
+	if minutes := utils.GetEnvInt("PRIVACY_JOB_INTERVAL_MINUTES", 0); minutes > 0 {
+		go services.NewPrivacyService().RunProcessing(time.Duration(minutes) * time.Minute)
+		log.Printf("🔒 Tareas de privacidad cada %d minutos", minutes)
+	}
+
+	// 3e. Bajas de cuenta cuyo plazo de reactivación ha vencido pasan a la papelera
+	if minutes := utils.GetEnvInt("ACCOUNT_DELETION_JOB_INTERVAL_MINUTES", 0); minutes > 0 {
+		go services.NewProfileService().RunProcessing(time.Duration(minutes) * time.Minute)
+		log.Printf("🗑️  Bajas de cuenta cada %d minutos", minutes)
+	}
+
 	// 4. Inicializar rutas
 	router := routes.Setup()
 	log.Println("🛣️  Rutas configuradas")
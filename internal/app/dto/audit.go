@@ -0,0 +1,39 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditFilter filtros de búsqueda del log de auditoría; los campos vacíos no filtran
+type AuditFilter struct {
+	ActorID        *uint
+	Action         string
+	TargetType     string
+	TargetID       string
+	OrganizationID *uint
+	RequestID      string
+	IPAddress      string
+	From           *time.Time
+	To             *time.Time
+}
+
+// AuditLogResponse estructura para respuestas del log de auditoría
+type AuditLogResponse struct {
+	ID             uint            `json:"id"`
+	ActorID        *uint           `json:"actor_id"`
+	ActorName      string          `json:"actor_name,omitempty"`
+	Action         string          `json:"action"`
+	TargetType     string          `json:"target_type,omitempty"`
+	TargetID       string          `json:"target_id,omitempty"`
+	OrganizationID *uint           `json:"organization_id,omitempty"`
+	Changes        json.RawMessage `json:"changes,omitempty"`
+	Metadata       json.RawMessage `json:"metadata,omitempty"`
+	IPAddress      string          `json:"ip_address,omitempty"`
+	UserAgent      string          `json:"user_agent,omitempty"`
+	RequestID      string          `json:"request_id,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	PrevHash       string          `json:"prev_hash"`
+	Hash           string          `json:"hash"`
+	Signature      string          `json:"signature,omitempty"`
+}
@@ -1,9 +1,30 @@
 package dto
 
-// LoginRequest estructura para login
+// LoginRequest estructura para login.
+// El identificador puede enviarse como login, user_name o email; se acepta username o email en cualquiera.
 type LoginRequest struct {
-	UserName string `json:"user_name" binding:"required"`
+	Login    string `json:"login" binding:"required_without_all=UserName Email"`
+	UserName string `json:"user_name"`
+	Email    string `json:"email"`
 	Password string `json:"password" binding:"required"`
+	// RememberMe elige el perfil de sesión de larga duración
+	RememberMe bool `json:"remember_me"`
+	// ClientID identifica la aplicación cliente para aplicar su perfil de sesión (opcional)
+	ClientID string `json:"client_id"`
+	// Organization slug o ID de la organización con la que se inicia sesión (opcional)
+	Organization string `json:"organization"`
+}
+
+// Identifier devuelve el identificador de login enviado por el cliente
+func (r *LoginRequest) Identifier() string {
+	switch {
+	case r.Login != "":
+		return r.Login
+	case r.UserName != "":
+		return r.UserName
+	default:
+		return r.Email
+	}
 }
 
 // RegisterRequest estructura para registro
@@ -18,10 +39,14 @@ type RegisterRequest struct {
 // AuthResponse estructura para respuestas de autenticación
 type AuthResponse struct {
 	User         UserResponse `json:"user"`
-	AccessToken  string       `json:"access_token"`
-	RefreshToken string       `json:"refresh_token"`
+	AccessToken  string       `json:"access_token,omitempty"`
+	RefreshToken string       `json:"refresh_token,omitempty"`
 	TokenType    string       `json:"token_type"`
 	ExpiresIn    int64        `json:"expires_in"`
+	// RefreshExpiresIn segundos de validez del refresh token (también el max-age de su cookie)
+	RefreshExpiresIn int64 `json:"refresh_expires_in"`
+	// SessionExpiresAt fin absoluto de la sesión; ninguna renovación lo extiende
+	SessionExpiresAt interface{} `json:"session_expires_at,omitempty"`
 }
 
 // RefreshTokenRequest estructura para refresh token
@@ -33,4 +58,63 @@ type RefreshTokenRequest struct {
 type ChangePasswordRequest struct {
 	CurrentPassword string `json:"current_password" binding:"required"`
 	NewPassword     string `json:"new_password" binding:"required,min=6"`
+}
+
+// MagicLinkRequest estructura para solicitar un enlace mágico
+type MagicLinkRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// MagicLinkVerifyRequest estructura para canjear un enlace mágico por tokens
+type MagicLinkVerifyRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// ReauthenticateRequest estructura para re-autenticación (step-up)
+type ReauthenticateRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// ReauthenticateResponse estructura con el token elevado de vida corta
+type ReauthenticateResponse struct {
+	AccessToken string `json:"access_token,omitempty"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// DeviceCodeRequest estructura para iniciar el flujo de autorización de dispositivo (RFC 8628)
+type DeviceCodeRequest struct {
+	ClientID string `form:"client_id" json:"client_id" binding:"required"`
+	Scope    string `form:"scope" json:"scope"`
+}
+
+// DeviceCodeResponse estructura con los códigos del flujo de dispositivo
+type DeviceCodeResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int64  `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+// DeviceTokenRequest estructura para el polling del token de dispositivo
+type DeviceTokenRequest struct {
+	GrantType  string `form:"grant_type" json:"grant_type" binding:"required"`
+	DeviceCode string `form:"device_code" json:"device_code" binding:"required"`
+	ClientID   string `form:"client_id" json:"client_id" binding:"required"`
+}
+
+// DeviceVerifyRequest estructura para aprobar o rechazar un código de usuario
+type DeviceVerifyRequest struct {
+	UserCode string `json:"user_code" binding:"required"`
+}
+
+// DeviceAuthorizationResponse estructura con la solicitud pendiente que el usuario debe confirmar
+type DeviceAuthorizationResponse struct {
+	UserCode  string      `json:"user_code"`
+	ClientID  string      `json:"client_id"`
+	Scope     string      `json:"scope"`
+	Status    string      `json:"status"`
+	ExpiresAt interface{} `json:"expires_at"`
 }
\ No newline at end of file
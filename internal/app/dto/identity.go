@@ -0,0 +1,42 @@
+package dto
+
+// IdentityResponse estructura para respuestas de identidades externas vinculadas
+type IdentityResponse struct {
+	ID            uint        `json:"id"`
+	Provider      string      `json:"provider"`
+	ProviderRef   string      `json:"provider_ref"`
+	Subject       string      `json:"subject"`
+	Email         string      `json:"email"`
+	EmailVerified bool        `json:"email_verified"`
+	LastUsedAt    interface{} `json:"last_used_at"`
+	CreatedAt     interface{} `json:"created_at"`
+}
+
+// IdentitiesResponse métodos de login de la cuenta: contraseña e identidades externas
+type IdentitiesResponse struct {
+	HasPassword bool               `json:"has_password"`
+	Identities  []IdentityResponse `json:"identities"`
+}
+
+// LinkIdentityRequest estructura para iniciar la vinculación de una identidad externa
+type LinkIdentityRequest struct {
+	RedirectTo string `json:"redirect_to"`
+}
+
+// SamlReauthenticateRequest estructura para iniciar una re-autenticación (step-up) con el IdP
+type SamlReauthenticateRequest struct {
+	RedirectTo string `json:"redirect_to"`
+}
+
+// DuplicateAccountResponse cuentas que comparten un email verificado
+type DuplicateAccountResponse struct {
+	Email            string `json:"email"`
+	TargetUserID     uint   `json:"target_user_id"`
+	DuplicateUserIDs []uint `json:"duplicate_user_ids"`
+}
+
+// MergeAccountsRequest estructura para fusionar una cuenta duplicada en otra
+type MergeAccountsRequest struct {
+	SourceUserID uint `json:"source_user_id" binding:"required"`
+	TargetUserID uint `json:"target_user_id" binding:"required"`
+}
@@ -0,0 +1,26 @@
+package dto
+
+// ListQuery parámetros de paginación, orden y filtros de un listado.
+// Con Cursor se pagina por keyset (a continuación del último elemento de la página anterior);
+// sin él, por desplazamiento con Page.
+type ListQuery struct {
+	Page    int
+	PerPage int
+	Cursor  string
+	// Sort campos separados por comas; el prefijo "-" ordena de forma descendente (p.ej. "-created_at,user_name")
+	Sort string
+	// Filters resto de parámetros de la query string; cada listado aplica solo los que admite
+	Filters map[string]string
+}
+
+// PageMeta bloque meta de las respuestas paginadas
+type PageMeta struct {
+	Total      int64  `json:"total"`
+	Count      int    `json:"count"`
+	PerPage    int    `json:"per_page"`
+	Page       int    `json:"page,omitempty"`
+	TotalPages int    `json:"total_pages"`
+	Sort       string `json:"sort"`
+	// NextCursor cursor de la página siguiente; vacío si no hay más resultados
+	NextCursor string `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,14 @@
+package dto
+
+// LoginAttemptResponse estructura para respuestas del historial de login
+type LoginAttemptResponse struct {
+	ID            uint        `json:"id"`
+	UserID        *uint       `json:"user_id"`
+	Success       bool        `json:"success"`
+	FailureReason string      `json:"failure_reason,omitempty"`
+	Method        string      `json:"method"`
+	IPAddress     string      `json:"ip_address"`
+	UserAgent     string      `json:"user_agent"`
+	NewDevice     bool        `json:"new_device"`
+	CreatedAt     interface{} `json:"created_at"`
+}
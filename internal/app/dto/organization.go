@@ -0,0 +1,42 @@
+package dto
+
+// OrganizationRequest estructura para crear o actualizar una organización
+type OrganizationRequest struct {
+	Slug     string `json:"slug" binding:"required"`
+	Name     string `json:"name" binding:"required"`
+	IsActive *bool  `json:"is_active"`
+}
+
+// OrganizationResponse estructura para respuestas de organizaciones
+type OrganizationResponse struct {
+	ID        uint        `json:"id"`
+	Slug      string      `json:"slug"`
+	Name      string      `json:"name"`
+	IsActive  bool        `json:"is_active"`
+	CreatedAt interface{} `json:"created_at"`
+	UpdatedAt interface{} `json:"updated_at"`
+}
+
+// MembershipRequest estructura para añadir un usuario a una organización
+type MembershipRequest struct {
+	UserID uint `json:"user_id" binding:"required"`
+	RoleID uint `json:"role_id" binding:"required"`
+}
+
+// UpdateMembershipRequest estructura para cambiar el rol de un miembro
+type UpdateMembershipRequest struct {
+	RoleID uint `json:"role_id" binding:"required"`
+}
+
+// MembershipResponse estructura con la pertenencia de un usuario a una organización
+type MembershipResponse struct {
+	OrganizationID   uint        `json:"organization_id"`
+	OrganizationSlug string      `json:"organization_slug"`
+	OrganizationName string      `json:"organization_name"`
+	UserID           uint        `json:"user_id"`
+	UserName         string      `json:"user_name,omitempty"`
+	Email            string      `json:"email,omitempty"`
+	RoleID           uint        `json:"role_id"`
+	RoleName         string      `json:"role_name"`
+	CreatedAt        interface{} `json:"created_at"`
+}
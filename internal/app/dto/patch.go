@@ -0,0 +1,32 @@
+package dto
+
+// Tipos de contenido admitidos por los endpoints PATCH
+const (
+	MergePatchContentType = "application/merge-patch+json" // RFC 7396
+	JSONPatchContentType  = "application/json-patch+json"  // RFC 6902
+)
+
+// Patch cuerpo de una petición PATCH y su tipo de contenido
+type Patch struct {
+	ContentType string
+	Body        []byte
+}
+
+// UserDocument representación editable de un usuario sobre la que se aplican los PATCH.
+// Password no aparece en el documento actual: se añade para cambiar la contraseña.
+type UserDocument struct {
+	Name     string  `json:"name"`
+	UserName string  `json:"user_name"`
+	Email    string  `json:"email"`
+	RoleID   uint    `json:"role_id"`
+	IsActive *bool   `json:"is_active"`
+	Password *string `json:"password,omitempty"`
+}
+
+// RoleDocument representación editable de un rol sobre la que se aplican los PATCH
+type RoleDocument struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	Description string `json:"description"`
+	IsActive    *bool  `json:"is_active"`
+}
@@ -0,0 +1,28 @@
+package dto
+
+import "time"
+
+// DataExportResponse estructura para respuestas de exportaciones de datos personales
+type DataExportResponse struct {
+	ID          uint       `json:"id"`
+	Status      string     `json:"status"`
+	Size        int64      `json:"size"`
+	Error       string     `json:"error,omitempty"`
+	CompletedAt *time.Time `json:"completed_at"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	// DownloadURL solo cuando el archivo está listo
+	DownloadURL string `json:"download_url,omitempty"`
+}
+
+// ErasureRequestResponse estructura para respuestas de solicitudes de supresión de cuenta
+type ErasureRequestResponse struct {
+	ID            uint       `json:"id"`
+	UserID        uint       `json:"user_id"`
+	Status        string     `json:"status"`
+	RequestedByID *uint      `json:"requested_by_id"`
+	ScheduledFor  time.Time  `json:"scheduled_for"`
+	CompletedAt   *time.Time `json:"completed_at"`
+	CancelledAt   *time.Time `json:"cancelled_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
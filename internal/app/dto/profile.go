@@ -0,0 +1,42 @@
+package dto
+
+import "time"
+
+// UpdateProfileRequest estructura para que el usuario edite su propio perfil. Solo admite
+// campos seguros: el rol y el estado de la cuenta se gestionan desde /users. Un campo ausente
+// no se modifica; avatar_url vacío quita el avatar.
+type UpdateProfileRequest struct {
+	Name      *string `json:"name" binding:"omitempty,max=100"`
+	UserName  *string `json:"user_name" binding:"omitempty,max=100"`
+	Email     *string `json:"email" binding:"omitempty,email,max=100"`
+	AvatarURL *string `json:"avatar_url" binding:"omitempty,url,max=500"`
+}
+
+// ProfileResponse perfil del usuario con el cambio de email pendiente de confirmar, si lo hay
+type ProfileResponse struct {
+	User         *UserResponse `json:"user"`
+	PendingEmail string        `json:"pending_email,omitempty"`
+}
+
+// ConfirmEmailChangeRequest estructura para confirmar un cambio de email con el token del enlace
+type ConfirmEmailChangeRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// DeleteProfileRequest estructura para dar de baja la propia cuenta
+type DeleteProfileRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// ReactivateAccountRequest estructura para reactivar una cuenta dada de baja con el token del enlace
+type ReactivateAccountRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// AccountDeletionResponse estructura para respuestas de bajas de cuenta
+type AccountDeletionResponse struct {
+	ID           uint      `json:"id"`
+	Status       string    `json:"status"`
+	ScheduledFor time.Time `json:"scheduled_for"`
+	CreatedAt    time.Time `json:"created_at"`
+}
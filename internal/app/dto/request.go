@@ -0,0 +1,8 @@
+package dto
+
+// RequestMeta datos del cliente que origina la petición, usados para historial y auditoría
+type RequestMeta struct {
+	IPAddress string
+	UserAgent string
+	RequestID string
+}
@@ -0,0 +1,20 @@
+package dto
+
+import "time"
+
+// FieldChange valor anterior y nuevo de un campo entre dos revisiones
+type FieldChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// RevisionResponse estructura para respuestas del historial de revisiones
+type RevisionResponse struct {
+	Revision uint                   `json:"revision"`
+	Snapshot map[string]interface{} `json:"snapshot"`
+	// Changes diferencias con la revisión anterior
+	Changes map[string]FieldChange `json:"changes"`
+	// DiffToCurrent lo que cambiaría al restaurar esta revisión (estado actual → revisión); solo en el detalle
+	DiffToCurrent map[string]FieldChange `json:"diff_to_current,omitempty"`
+	CreatedAt     time.Time              `json:"created_at"`
+}
@@ -1,5 +1,7 @@
 package dto
 
+import "time"
+
 // CreateRoleRequest estructura para crear un rol
 type CreateRoleRequest struct {
 	Name        string `json:"name" binding:"required"`
@@ -23,6 +25,12 @@ type RoleResponse struct {
 	DisplayName string      `json:"display_name"`
 	Description string      `json:"description"`
 	IsActive    bool        `json:"is_active"`
+	// OrganizationID nil en los roles de sistema compartidos por todas las organizaciones
+	OrganizationID *uint    `json:"organization_id"`
+	// Version versión de la fila; el ETag de GET /roles/:id se deriva de ella
+	Version     uint        `json:"version"`
 	CreatedAt   interface{} `json:"created_at"`
 	UpdatedAt   interface{} `json:"updated_at"`
+	// DeletedAt solo aparece en los registros de la papelera (?trashed=with|only)
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
\ No newline at end of file
@@ -0,0 +1,43 @@
+package dto
+
+// SamlProviderRequest estructura para crear o actualizar un IdP SAML.
+// Se acepta el XML de metadatos del IdP o, en su defecto, entity ID + URL de SSO + certificado.
+type SamlProviderRequest struct {
+	Slug              string            `json:"slug" binding:"required"`
+	Name              string            `json:"name" binding:"required"`
+	IdPEntityID       string            `json:"idp_entity_id"`
+	SSOURL            string            `json:"sso_url"`
+	Certificate       string            `json:"certificate"`
+	MetadataXML       string            `json:"metadata_xml"`
+	AttributeMapping  map[string]string `json:"attribute_mapping"`
+	GroupAttribute    string            `json:"group_attribute"`
+	GroupRoleMapping  map[string]string `json:"group_role_mapping"`
+	DefaultRole       string            `json:"default_role"`
+	AutoProvision     bool              `json:"auto_provision"`
+	AllowedDomains    []string          `json:"allowed_domains"`
+	AllowIdPInitiated bool              `json:"allow_idp_initiated"`
+	IsActive          *bool             `json:"is_active"`
+}
+
+// SamlProviderResponse estructura para respuestas de IdPs SAML
+type SamlProviderResponse struct {
+	ID                uint              `json:"id"`
+	Slug              string            `json:"slug"`
+	Name              string            `json:"name"`
+	IdPEntityID       string            `json:"idp_entity_id"`
+	SSOURL            string            `json:"sso_url"`
+	HasMetadataXML    bool              `json:"has_metadata_xml"`
+	AttributeMapping  map[string]string `json:"attribute_mapping"`
+	GroupAttribute    string            `json:"group_attribute"`
+	GroupRoleMapping  map[string]string `json:"group_role_mapping"`
+	DefaultRole       string            `json:"default_role"`
+	AutoProvision     bool              `json:"auto_provision"`
+	AllowedDomains    []string          `json:"allowed_domains"`
+	AllowIdPInitiated bool              `json:"allow_idp_initiated"`
+	IsActive          bool              `json:"is_active"`
+	SPEntityID        string            `json:"sp_entity_id"`
+	ACSURL            string            `json:"acs_url"`
+	LoginURL          string            `json:"login_url"`
+	CreatedAt         interface{}       `json:"created_at"`
+	UpdatedAt         interface{}       `json:"updated_at"`
+}
@@ -0,0 +1,99 @@
+package dto
+
+// URNs de esquemas SCIM 2.0 (RFC 7643 / RFC 7644)
+const (
+	ScimUserSchema         = "urn:ietf:params:scim:schemas:core:2.0:User"
+	ScimGroupSchema        = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	ScimListResponseSchema = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	ScimPatchOpSchema      = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	ScimErrorSchema        = "urn:ietf:params:scim:api:messages:2.0:Error"
+	ScimSPConfigSchema     = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	ScimSchemaSchema       = "urn:ietf:params:scim:schemas:core:2.0:Schema"
+	ScimResourceTypeSchema = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+)
+
+// ScimMeta metadatos comunes de un recurso SCIM
+type ScimMeta struct {
+	ResourceType string      `json:"resourceType"`
+	Created      interface{} `json:"created,omitempty"`
+	LastModified interface{} `json:"lastModified,omitempty"`
+	Location     string      `json:"location,omitempty"`
+}
+
+// ScimName nombre compuesto de un usuario SCIM
+type ScimName struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+// ScimMultiValued atributo multivaluado (emails, groups, members)
+type ScimMultiValued struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+}
+
+// ScimUser representación SCIM de models.User
+type ScimUser struct {
+	Schemas     []string          `json:"schemas"`
+	ID          string            `json:"id,omitempty"`
+	ExternalID  string            `json:"externalId,omitempty"`
+	UserName    string            `json:"userName"`
+	Name        *ScimName         `json:"name,omitempty"`
+	DisplayName string            `json:"displayName,omitempty"`
+	Emails      []ScimMultiValued `json:"emails,omitempty"`
+	Active      *bool             `json:"active,omitempty"`
+	Password    string            `json:"password,omitempty"`
+	Groups      []ScimMultiValued `json:"groups,omitempty"`
+	Meta        *ScimMeta         `json:"meta,omitempty"`
+}
+
+// ScimGroup representación SCIM de models.Role
+type ScimGroup struct {
+	Schemas     []string          `json:"schemas"`
+	ID          string            `json:"id,omitempty"`
+	ExternalID  string            `json:"externalId,omitempty"`
+	DisplayName string            `json:"displayName"`
+	Members     []ScimMultiValued `json:"members,omitempty"`
+	Meta        *ScimMeta         `json:"meta,omitempty"`
+}
+
+// ScimListResponse respuesta paginada de una consulta SCIM
+type ScimListResponse struct {
+	Schemas      []string      `json:"schemas"`
+	TotalResults int64         `json:"totalResults"`
+	StartIndex   int           `json:"startIndex"`
+	ItemsPerPage int           `json:"itemsPerPage"`
+	Resources    []interface{} `json:"Resources"`
+}
+
+// ScimPatchOperation operación individual de un PATCH SCIM
+type ScimPatchOperation struct {
+	Op    string      `json:"op" binding:"required"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+// ScimPatchRequest cuerpo de un PATCH SCIM
+type ScimPatchRequest struct {
+	Schemas    []string             `json:"schemas"`
+	Operations []ScimPatchOperation `json:"Operations" binding:"required,dive"`
+}
+
+// ScimListQuery parámetros de consulta de listados SCIM
+type ScimListQuery struct {
+	Filter     string
+	StartIndex int
+	Count      int
+}
+
+// ScimErrorResponse error en formato SCIM
+type ScimErrorResponse struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
@@ -0,0 +1,30 @@
+package dto
+
+// SessionPolicyRequest estructura para crear o actualizar un perfil de sesión.
+// Debe indicarse un rol o un cliente; las duraciones van en minutos y 0 en inactividad
+// o duración máxima significa sin límite.
+type SessionPolicyRequest struct {
+	Name                   string `json:"name" binding:"required"`
+	RoleID                 *uint  `json:"role_id"`
+	ClientID               string `json:"client_id"`
+	RememberMe             bool   `json:"remember_me"`
+	AccessTokenMinutes     int    `json:"access_token_minutes" binding:"required,min=1"`
+	RefreshTokenMinutes    int    `json:"refresh_token_minutes" binding:"required,min=1"`
+	IdleTimeoutMinutes     int    `json:"idle_timeout_minutes" binding:"min=0"`
+	AbsoluteTimeoutMinutes int    `json:"absolute_timeout_minutes" binding:"min=0"`
+}
+
+// SessionPolicyResponse estructura para respuestas de perfiles de sesión
+type SessionPolicyResponse struct {
+	ID                     uint        `json:"id"`
+	Name                   string      `json:"name"`
+	RoleID                 *uint       `json:"role_id"`
+	ClientID               string      `json:"client_id"`
+	RememberMe             bool        `json:"remember_me"`
+	AccessTokenMinutes     int         `json:"access_token_minutes"`
+	RefreshTokenMinutes    int         `json:"refresh_token_minutes"`
+	IdleTimeoutMinutes     int         `json:"idle_timeout_minutes"`
+	AbsoluteTimeoutMinutes int         `json:"absolute_timeout_minutes"`
+	CreatedAt              interface{} `json:"created_at"`
+	UpdatedAt              interface{} `json:"updated_at"`
+}
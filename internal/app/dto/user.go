@@ -1,6 +1,9 @@
 package dto
 
-import "megabaseGo/internal/models"
+import (
+	"megabaseGo/internal/models"
+	"time"
+)
 
 // CreateUserRequest estructura para crear un usuario
 type CreateUserRequest struct {
@@ -31,7 +34,22 @@ type UserResponse struct {
 	RoleID      uint        `json:"role_id"`
 	Role        models.Role `json:"role"`
 	IsActive    bool        `json:"is_active"`
+	AvatarURL   string      `json:"avatar_url,omitempty"`
+	// Avatars URLs de las versiones de un avatar subido, indexadas por su lado en píxeles
+	Avatars     map[string]string `json:"avatars,omitempty"`
+	// Version versión de la fila; el ETag de GET /users/:id se deriva de ella
+	Version     uint        `json:"version"`
 	LastLoginAt interface{} `json:"last_login_at"`
 	CreatedAt   interface{} `json:"created_at"`
 	UpdatedAt   interface{} `json:"updated_at"`
-}
\ No newline at end of file
+	// DeletedAt solo aparece en los registros de la papelera (?trashed=with|only)
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// UserSearchResult usuario encontrado por GET /users/search con su puntuación y los campos
+// en los que coincide, con los términos buscados marcados con <mark> (el resto va escapado como HTML)
+type UserSearchResult struct {
+	UserResponse
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights,omitempty"`
+}
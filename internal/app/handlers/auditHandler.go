@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"megabaseGo/internal/app/dto"
+	"megabaseGo/internal/app/services"
+	"megabaseGo/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuditHandler struct {
+	auditService *services.AuditService
+}
+
+// NewAuditHandler crea una nueva instancia del handler de auditoría
+func NewAuditHandler() *AuditHandler {
+	return &AuditHandler{
+		auditService: services.NewAuditService(),
+	}
+}
+
+// GetAuditLogs maneja la búsqueda en el log de auditoría (admin)
+func (h *AuditHandler) GetAuditLogs(c *gin.Context) {
+	filter, err := h.parseFilter(c)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	entries, total, err := h.auditService.Search(filter, limit, offset)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleData(c, http.StatusOK, gin.H{
+		"entries": entries,
+		"count":   len(entries),
+		"total":   total,
+	})
+}
+
+// ExportAuditLogs maneja la exportación del log de auditoría en JSON Lines (admin).
+// Admite los mismos filtros que GetAuditLogs y exporta en orden cronológico.
+func (h *AuditHandler) ExportAuditLogs(c *gin.Context) {
+	filter, err := h.parseFilter(c)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("Content-Disposition", `attachment; filename="audit-`+time.Now().UTC().Format("20060102T150405Z")+`.jsonl"`)
+	c.Status(http.StatusOK)
+
+	encoder := json.NewEncoder(c.Writer)
+	err = h.auditService.Export(filter, func(entry *dto.AuditLogResponse) error {
+		return encoder.Encode(entry)
+	})
+	if err != nil {
+		// La respuesta ya está en curso: solo queda cortar la exportación
+		log.Printf("Error exportando log de auditoría: %v", err)
+	}
+}
+
+func (h *AuditHandler) parseFilter(c *gin.Context) (*dto.AuditFilter, error) {
+	filter := &dto.AuditFilter{
+		Action:     c.Query("action"),
+		TargetType: c.Query("target_type"),
+		TargetID:   c.Query("target_id"),
+		RequestID:  c.Query("request_id"),
+		IPAddress:  c.Query("ip_address"),
+	}
+
+	for param, target := range map[string]**uint{
+		"actor_id":        &filter.ActorID,
+		"organization_id": &filter.OrganizationID,
+	} {
+		if value := c.Query(param); value != "" {
+			id, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				return nil, utils.NewBadRequestError("Invalid " + param)
+			}
+			parsed := uint(id)
+			*target = &parsed
+		}
+	}
+
+	for param, target := range map[string]**time.Time{
+		"from": &filter.From,
+		"to":   &filter.To,
+	} {
+		if value := c.Query(param); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return nil, utils.NewBadRequestError("Invalid " + param + ": expected RFC 3339 timestamp")
+			}
+			*target = &parsed
+		}
+	}
+
+	return filter, nil
+}
@@ -6,18 +6,21 @@ import (
 	"megabaseGo/internal/app/dto"
 	"megabaseGo/internal/app/middleware"
 	"megabaseGo/internal/app/services"
+	"megabaseGo/internal/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
 type AuthHandler struct {
 	authService *services.AuthService
+	cookies     *utils.CookieManager
 }
 
 // NewAuthHandler crea una nueva instancia del handler de autenticación
 func NewAuthHandler() *AuthHandler {
 	return &AuthHandler{
 		authService: services.NewAuthService(),
+		cookies:     utils.NewCookieManager(),
 	}
 }
 
@@ -33,13 +36,15 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	authResponse, err := h.authService.Login(&req)
+	authResponse, err := h.authService.Login(&req, requestMeta(c))
 	if err != nil {
 		switch err.Error() {
 		case "invalid credentials":
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username/email or password"})
 		case "user account is disabled":
 			c.JSON(http.StatusForbidden, gin.H{"error": "User account is disabled"})
+		case "not a member of this organization":
+			c.JSON(http.StatusForbidden, gin.H{"error": "Not a member of this organization"})
 		default:
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error":   "Login failed",
@@ -49,6 +54,13 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	if h.cookies.WantsCookies(c) {
+		if err := applySessionCookies(c, h.cookies, authResponse); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create session"})
+			return
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Login successful",
 		"data":    authResponse,
@@ -67,7 +79,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
-	authResponse, err := h.authService.Register(&req)
+	authResponse, err := h.authService.Register(&req, requestMeta(c))
 	if err != nil {
 		switch err.Error() {
 		case "role not found":
@@ -93,19 +105,28 @@ func (h *AuthHandler) Register(c *gin.Context) {
 func (h *AuthHandler) RefreshToken(c *gin.Context) {
 	var req dto.RefreshTokenRequest
 
+	// Los clientes de navegador envían el refresh token en la cookie en lugar del cuerpo
+	fromCookie := false
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Invalid request data",
-			"details": err.Error(),
-		})
-		return
+		cookieToken, ok := h.cookies.GetRefreshToken(c)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid request data",
+				"details": err.Error(),
+			})
+			return
+		}
+		req.RefreshToken = cookieToken
+		fromCookie = true
 	}
 
-	authResponse, err := h.authService.RefreshToken(&req)
+	authResponse, err := h.authService.RefreshToken(&req, requestMeta(c))
 	if err != nil {
 		switch err.Error() {
 		case "invalid refresh token":
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		case "session expired":
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session expired, please log in again"})
 		case "user not found":
 			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		case "user account is disabled":
@@ -119,25 +140,49 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 		return
 	}
 
+	if fromCookie || h.cookies.WantsCookies(c) {
+		if err := applySessionCookies(c, h.cookies, authResponse); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh session"})
+			return
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Token refreshed successfully",
 		"data":    authResponse,
 	})
 }
 
-// Logout maneja el cierre de sesión (invalidar token del lado del cliente)
+// Logout maneja el cierre de sesión.
+// Se revoca la sesión del refresh token (cuerpo o cookie), así que ya no se puede renovar;
+// los access tokens emitidos siguen siendo válidos hasta su expiración.
+// En modo cookies se eliminan las cookies de sesión.
 func (h *AuthHandler) Logout(c *gin.Context) {
-	// En este caso simple, el logout es manejado por el cliente
-	// removiendo el token. En implementaciones más avanzadas,
-	// podrías mantener una blacklist de tokens invalidados.
-	
+	var req dto.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		req.RefreshToken, _ = h.cookies.GetRefreshToken(c)
+	}
+	if req.RefreshToken != "" {
+		if err := h.authService.Logout(req.RefreshToken, requestMeta(c)); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Logout failed",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+
+	if h.cookies.Enabled() {
+		h.cookies.ClearAuthCookies(c)
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Logout successful",
 	})
 }
 
-// GetProfile obtiene el perfil del usuario actual
-func (h *AuthHandler) GetProfile(c *gin.Context) {
+// ChangePassword maneja el cambio de contraseña
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
 	userID, exists := middleware.GetCurrentUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{
@@ -146,13 +191,25 @@ func (h *AuthHandler) GetProfile(c *gin.Context) {
 		return
 	}
 
-	user, err := h.authService.GetCurrentUser(userID)
+	var req dto.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	err := h.authService.ChangePassword(userID, &req, requestMeta(c))
 	if err != nil {
-		if err.Error() == "user not found" {
+		switch err.Error() {
+		case "user not found":
 			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
-		} else {
+		case "current password is incorrect":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Current password is incorrect"})
+		default:
 			c.JSON(http.StatusInternalServerError, gin.H{
-				"error":   "Failed to fetch user profile",
+				"error":   "Failed to change password",
 				"details": err.Error(),
 			})
 		}
@@ -160,12 +217,12 @@ func (h *AuthHandler) GetProfile(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"user": user,
+		"message": "Password changed successfully",
 	})
 }
 
-// ChangePassword maneja el cambio de contraseña
-func (h *AuthHandler) ChangePassword(c *gin.Context) {
+// Reauthenticate maneja la re-autenticación para operaciones sensibles
+func (h *AuthHandler) Reauthenticate(c *gin.Context) {
 	userID, exists := middleware.GetCurrentUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{
@@ -174,7 +231,7 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 		return
 	}
 
-	var req dto.ChangePasswordRequest
+	var req dto.ReauthenticateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "Invalid request data",
@@ -183,24 +240,46 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 		return
 	}
 
-	err := h.authService.ChangePassword(userID, &req)
+	sessionID := ""
+	var organizationID uint
+	if claims, ok := middleware.GetCurrentUserClaims(c); ok {
+		sessionID = claims.SessionID
+		organizationID = claims.OrganizationID
+	}
+
+	reauthResponse, err := h.authService.Reauthenticate(userID, sessionID, organizationID, &req, requestMeta(c))
 	if err != nil {
 		switch err.Error() {
+		case "invalid credentials":
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid password"})
 		case "user not found":
 			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
-		case "current password is incorrect":
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Current password is incorrect"})
+		case "user account is disabled":
+			c.JSON(http.StatusForbidden, gin.H{"error": "User account is disabled"})
+		case "password not set":
+			c.JSON(http.StatusConflict, gin.H{
+				"error":          "Account has no password; re-authenticate with your identity provider",
+				"reauthenticate": "POST /api/v1/auth/reauthenticate/saml/:slug",
+			})
 		default:
 			c.JSON(http.StatusInternalServerError, gin.H{
-				"error":   "Failed to change password",
+				"error":   "Reauthentication failed",
 				"details": err.Error(),
 			})
 		}
 		return
 	}
 
+	// En modo cookies el token elevado reemplaza la cookie del access token
+	if viaCookie, _ := c.Get("auth_via_cookie"); viaCookie == true {
+		h.cookies.SetAccessCookie(c, reauthResponse.AccessToken, int(reauthResponse.ExpiresIn))
+		reauthResponse.AccessToken = ""
+		reauthResponse.TokenType = "Cookie"
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Password changed successfully",
+		"message": "Reauthentication successful",
+		"data":    reauthResponse,
 	})
 }
 
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"megabaseGo/internal/app/dto"
+	"megabaseGo/internal/app/middleware"
+	"megabaseGo/internal/app/services"
+	"megabaseGo/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DeviceAuthHandler struct {
+	deviceAuthService *services.DeviceAuthService
+}
+
+// NewDeviceAuthHandler crea una nueva instancia del handler de autorización de dispositivos
+func NewDeviceAuthHandler() *DeviceAuthHandler {
+	return &DeviceAuthHandler{
+		deviceAuthService: services.NewDeviceAuthService(),
+	}
+}
+
+// RequestCode maneja la emisión del device_code y user_code
+func (h *DeviceAuthHandler) RequestCode(c *gin.Context) {
+	var req dto.DeviceCodeRequest
+
+	if err := c.ShouldBind(&req); err != nil {
+		h.handleOAuthError(c, utils.NewValidationError(err.Error()))
+		return
+	}
+
+	response, err := h.deviceAuthService.RequestCode(&req)
+	if err != nil {
+		h.handleOAuthError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	utils.HandleData(c, http.StatusOK, response)
+}
+
+// Token maneja el polling del dispositivo hasta que el usuario aprueba
+func (h *DeviceAuthHandler) Token(c *gin.Context) {
+	var req dto.DeviceTokenRequest
+
+	if err := c.ShouldBind(&req); err != nil {
+		h.handleOAuthError(c, utils.NewValidationError(err.Error()))
+		return
+	}
+
+	authResponse, err := h.deviceAuthService.PollToken(&req, requestMeta(c))
+	if err != nil {
+		h.handleOAuthError(c, err)
+		return
+	}
+
+	// Respuesta de token OAuth2: los campos van en la raíz, sin envolver en "data"
+	c.Header("Cache-Control", "no-store")
+	utils.HandleData(c, http.StatusOK, authResponse)
+}
+
+// GetPending maneja la consulta de una solicitud pendiente por user_code
+func (h *DeviceAuthHandler) GetPending(c *gin.Context) {
+	userCode := c.Query("user_code")
+	if userCode == "" {
+		utils.HandleError(c, utils.NewBadRequestError("user_code is required"))
+		return
+	}
+
+	authorization, err := h.deviceAuthService.GetPending(userCode)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleData(c, http.StatusOK, gin.H{"device_authorization": authorization})
+}
+
+// Approve maneja la aprobación del código por el usuario autenticado
+func (h *DeviceAuthHandler) Approve(c *gin.Context) {
+	claims, exists := middleware.GetCurrentUserClaims(c)
+	if !exists {
+		utils.HandleError(c, utils.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	var req dto.DeviceVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.HandleValidationError(c, err)
+		return
+	}
+
+	// La sesión del dispositivo hereda la comprobación de contraseña del aprobador, si la hubo
+	var authTime *time.Time
+	if claims.AuthTime != nil {
+		authTime = &claims.AuthTime.Time
+	}
+
+	if err := h.deviceAuthService.Approve(claims.UserID, authTime, req.UserCode); err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleSuccess(c, http.StatusOK, "Device authorized successfully", nil)
+}
+
+// Deny maneja el rechazo del código por el usuario autenticado
+func (h *DeviceAuthHandler) Deny(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		utils.HandleError(c, utils.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	var req dto.DeviceVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.HandleValidationError(c, err)
+		return
+	}
+
+	if err := h.deviceAuthService.Deny(userID, req.UserCode); err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleSuccess(c, http.StatusOK, "Device authorization denied", nil)
+}
+
+// handleOAuthError responde con el formato de error de OAuth2 (error + error_description)
+func (h *DeviceAuthHandler) handleOAuthError(c *gin.Context, err error) {
+	c.Header("Cache-Control", "no-store")
+	if apiErr, ok := utils.IsAPIError(err); ok {
+		response := gin.H{"error": apiErr.Message}
+		if apiErr.Details != "" {
+			response["error_description"] = apiErr.Details
+		}
+		c.JSON(apiErr.GetStatusCode(), response)
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"megabaseGo/internal/app/services"
+	"megabaseGo/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// etag ETag fuerte de la versión de un recurso
+func etag(version uint) string {
+	return `"` + strconv.FormatUint(uint64(version), 10) + `"`
+}
+
+// setETag añade el ETag de la versión a la respuesta
+func setETag(c *gin.Context, version uint) {
+	c.Header("ETag", etag(version))
+}
+
+// notModified añade el ETag y, si coincide con If-None-Match (comparación débil, RFC 9110 §13.1.2),
+// responde 304 sin cuerpo y devuelve true
+func notModified(c *gin.Context, version uint) bool {
+	setETag(c, version)
+	header := c.GetHeader("If-None-Match")
+	if header == "" {
+		return false
+	}
+	current := etag(version)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == current {
+			c.Status(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// ifMatchRequired indica si PUT, PATCH y DELETE exigen If-Match (REQUIRE_IF_MATCH)
+func ifMatchRequired() bool {
+	return utils.GetEnv("REQUIRE_IF_MATCH", "false") == "true"
+}
+
+// ifMatch lee la cabecera If-Match como precondición de una modificación. Sin cabecera (o con *)
+// no hay precondición, salvo que REQUIRE_IF_MATCH la exija: entonces responde 428 y devuelve ok=false.
+// Los ETag débiles nunca coinciden (comparación fuerte, RFC 9110 §13.1.1).
+func ifMatch(c *gin.Context) (*services.Precondition, bool) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" {
+		if ifMatchRequired() {
+			utils.HandleError(c, utils.NewPreconditionRequiredError("If-Match header with the resource ETag is required"))
+			return nil, false
+		}
+		return nil, true
+	}
+	if header == "*" {
+		return nil, true
+	}
+
+	precondition := &services.Precondition{}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if !strings.HasPrefix(tag, `"`) || !strings.HasSuffix(tag, `"`) || len(tag) < 2 {
+			continue
+		}
+		if version, err := strconv.ParseUint(tag[1:len(tag)-1], 10, 32); err == nil {
+			precondition.Versions = append(precondition.Versions, uint(version))
+		}
+	}
+	return precondition, true
+}
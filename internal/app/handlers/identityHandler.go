@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"megabaseGo/internal/app/dto"
+	"megabaseGo/internal/app/middleware"
+	"megabaseGo/internal/app/services"
+	"megabaseGo/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type IdentityHandler struct {
+	identityService *services.IdentityService
+}
+
+// NewIdentityHandler crea una nueva instancia del handler de identidades vinculadas
+func NewIdentityHandler() *IdentityHandler {
+	return &IdentityHandler{
+		identityService: services.NewIdentityService(),
+	}
+}
+
+// GetMyIdentities maneja el listado de métodos de login del usuario actual
+func (h *IdentityHandler) GetMyIdentities(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		utils.HandleError(c, utils.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	identities, err := h.identityService.ListIdentities(userID)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleData(c, http.StatusOK, identities)
+}
+
+// LinkSaml maneja el inicio de la vinculación de una identidad SAML (requiere autenticación reciente)
+func (h *IdentityHandler) LinkSaml(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		utils.HandleError(c, utils.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	var req dto.LinkIdentityRequest
+	if err := c.ShouldBindJSON(&req); err != nil && c.Request.ContentLength > 0 {
+		utils.HandleValidationError(c, err)
+		return
+	}
+
+	redirectURL, err := h.identityService.StartSamlLink(userID, c.Param("slug"), &req)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	// La vinculación termina en el ACS cuando el IdP devuelve la aserción
+	utils.HandleData(c, http.StatusOK, gin.H{"redirect_url": redirectURL})
+}
+
+// Unlink maneja la desvinculación de una identidad externa (requiere autenticación reciente)
+func (h *IdentityHandler) Unlink(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		utils.HandleError(c, utils.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	identityID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.HandleError(c, utils.NewBadRequestError("Invalid identity ID"))
+		return
+	}
+
+	if err := h.identityService.Unlink(userID, uint(identityID), currentActor(c)); err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleSuccess(c, http.StatusOK, "Identity unlinked successfully", nil)
+}
+
+// GetDuplicateAccounts maneja la detección de cuentas duplicadas por email verificado (admin)
+func (h *IdentityHandler) GetDuplicateAccounts(c *gin.Context) {
+	duplicates, err := h.identityService.FindDuplicateAccounts()
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleData(c, http.StatusOK, gin.H{
+		"duplicates": duplicates,
+		"count":      len(duplicates),
+	})
+}
+
+// MergeAccounts maneja la fusión de una cuenta duplicada en otra (admin)
+func (h *IdentityHandler) MergeAccounts(c *gin.Context) {
+	var req dto.MergeAccountsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.HandleValidationError(c, err)
+		return
+	}
+
+	user, err := h.identityService.MergeAccounts(&req, currentActor(c))
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleSuccess(c, http.StatusOK, "Accounts merged successfully", gin.H{"user": user})
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"strconv"
+	"strings"
+
+	"megabaseGo/internal/app/dto"
+	"megabaseGo/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Parámetros de la query string reservados para la paginación; el resto se pasan como filtros
+var listParams = map[string]bool{"page": true, "per_page": true, "cursor": true, "sort": true}
+
+// listQuery lee paginación, orden y filtros de la query string.
+// Si page o per_page no son válidos responde 400 y devuelve ok=false.
+func listQuery(c *gin.Context) (*dto.ListQuery, bool) {
+	query := &dto.ListQuery{
+		Cursor:  c.Query("cursor"),
+		Sort:    c.Query("sort"),
+		Filters: make(map[string]string),
+	}
+
+	for name, target := range map[string]*int{"page": &query.Page, "per_page": &query.PerPage} {
+		value := c.Query(name)
+		if value == "" {
+			continue
+		}
+		number, err := strconv.Atoi(value)
+		if err != nil || number <= 0 {
+			utils.HandleError(c, utils.NewBadRequestError("Invalid "+name+": expected a positive integer"))
+			return nil, false
+		}
+		*target = number
+	}
+	if query.Cursor != "" && query.Page != 0 {
+		utils.HandleError(c, utils.NewBadRequestError("page and cursor cannot be combined"))
+		return nil, false
+	}
+
+	for name, values := range c.Request.URL.Query() {
+		if !listParams[name] && len(values) > 0 {
+			query.Filters[name] = values[0]
+		}
+	}
+	return query, true
+}
+
+// setPageLinks añade la cabecera Link (RFC 8288) con las páginas first, prev, next y last.
+// Con paginación por cursor solo hay first y next: el cursor no permite retroceder.
+func setPageLinks(c *gin.Context, meta *dto.PageMeta) {
+	var links []string
+	link := func(rel string, set map[string]string) {
+		values := c.Request.URL.Query()
+		values.Del("page")
+		values.Del("cursor")
+		for name, value := range set {
+			values.Set(name, value)
+		}
+		target := *c.Request.URL
+		target.RawQuery = values.Encode()
+		links = append(links, "<"+target.RequestURI()+`>; rel="`+rel+`"`)
+	}
+
+	link("first", nil)
+	if meta.Page > 1 {
+		link("prev", map[string]string{"page": strconv.Itoa(meta.Page - 1)})
+	}
+	if meta.NextCursor != "" {
+		if meta.Page > 0 {
+			link("next", map[string]string{"page": strconv.Itoa(meta.Page + 1)})
+		} else {
+			link("next", map[string]string{"cursor": meta.NextCursor})
+		}
+	}
+	if meta.Page > 0 && meta.TotalPages > 0 {
+		link("last", map[string]string{"page": strconv.Itoa(meta.TotalPages)})
+	}
+
+	c.Header("Link", strings.Join(links, ", "))
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"megabaseGo/internal/app/middleware"
+	"megabaseGo/internal/app/services"
+	"megabaseGo/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type LoginHistoryHandler struct {
+	loginHistoryService *services.LoginHistoryService
+}
+
+// NewLoginHistoryHandler crea una nueva instancia del handler de historial de login
+func NewLoginHistoryHandler() *LoginHistoryHandler {
+	return &LoginHistoryHandler{
+		loginHistoryService: services.NewLoginHistoryService(),
+	}
+}
+
+// GetMyLogins maneja la obtención del historial de login del usuario actual
+func (h *LoginHistoryHandler) GetMyLogins(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		utils.HandleError(c, utils.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	h.respondWithLogins(c, h.loginHistoryService, userID)
+}
+
+// GetUserLogins maneja la obtención del historial de login de un usuario (admin); con
+// organización activa solo de sus miembros
+func (h *LoginHistoryHandler) GetUserLogins(c *gin.Context) {
+	id := c.Param("id")
+	userID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		utils.HandleError(c, utils.NewBadRequestError("Invalid user ID"))
+		return
+	}
+
+	h.respondWithLogins(c, h.loginHistoryService.ForTenant(currentTenant(c)), uint(userID))
+}
+
+func (h *LoginHistoryHandler) respondWithLogins(c *gin.Context, loginHistoryService *services.LoginHistoryService, userID uint) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	logins, total, err := loginHistoryService.GetUserLogins(userID, limit, offset)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleData(c, http.StatusOK, gin.H{
+		"logins": logins,
+		"count":  len(logins),
+		"total":  total,
+	})
+}
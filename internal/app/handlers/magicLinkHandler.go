@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"net/http"
+
+	"megabaseGo/internal/app/dto"
+	"megabaseGo/internal/app/services"
+	"megabaseGo/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MagicLinkHandler struct {
+	magicLinkService *services.MagicLinkService
+	authService      *services.AuthService
+	cookies          *utils.CookieManager
+}
+
+// NewMagicLinkHandler crea una nueva instancia del handler de enlaces mágicos
+func NewMagicLinkHandler() *MagicLinkHandler {
+	return &MagicLinkHandler{
+		magicLinkService: services.NewMagicLinkService(),
+		authService:      services.NewAuthService(),
+		cookies:          utils.NewCookieManager(),
+	}
+}
+
+// RequestLink maneja la solicitud de un enlace mágico
+func (h *MagicLinkHandler) RequestLink(c *gin.Context) {
+	var req dto.MagicLinkRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.HandleValidationError(c, err)
+		return
+	}
+
+	if err := h.magicLinkService.RequestLink(&req, c.ClientIP()); err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleSuccess(c, http.StatusAccepted, "If the email is registered, a login link has been sent", nil)
+}
+
+// VerifyLink maneja el canje de un enlace mágico por tokens
+func (h *MagicLinkHandler) VerifyLink(c *gin.Context) {
+	var req dto.MagicLinkVerifyRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.HandleValidationError(c, err)
+		return
+	}
+
+	authResponse, err := h.magicLinkService.VerifyLink(&req, requestMeta(c))
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	if h.cookies.WantsCookies(c) {
+		if err := applySessionCookies(c, h.cookies, authResponse); err != nil {
+			utils.HandleError(c, utils.NewInternalServerError("Failed to create session"))
+			return
+		}
+	}
+
+	utils.HandleSuccess(c, http.StatusOK, "Login successful", gin.H{"data": authResponse})
+}
@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"megabaseGo/internal/app/dto"
+	"megabaseGo/internal/app/middleware"
+	"megabaseGo/internal/app/services"
+	"megabaseGo/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OrganizationHandler struct {
+	organizationService *services.OrganizationService
+}
+
+// NewOrganizationHandler crea una nueva instancia del handler de organizaciones
+func NewOrganizationHandler() *OrganizationHandler {
+	return &OrganizationHandler{
+		organizationService: services.NewOrganizationService(),
+	}
+}
+
+// GetMyOrganizations maneja el listado de organizaciones del usuario actual
+func (h *OrganizationHandler) GetMyOrganizations(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		utils.HandleError(c, utils.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	organizations, err := h.organizationService.ListUserOrganizations(userID)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleData(c, http.StatusOK, gin.H{
+		"organizations": organizations,
+		"count":         len(organizations),
+	})
+}
+
+// CreateOrganization maneja la creación de una organización (admin)
+func (h *OrganizationHandler) CreateOrganization(c *gin.Context) {
+	var req dto.OrganizationRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.HandleValidationError(c, err)
+		return
+	}
+
+	organization, err := h.organizationService.CreateOrganization(&req)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleSuccess(c, http.StatusCreated, "Organization created successfully", gin.H{"organization": organization})
+}
+
+// GetOrganizations maneja el listado de organizaciones (admin)
+func (h *OrganizationHandler) GetOrganizations(c *gin.Context) {
+	organizations, err := h.organizationService.GetOrganizations()
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleData(c, http.StatusOK, gin.H{
+		"organizations": organizations,
+		"count":         len(organizations),
+	})
+}
+
+// GetOrganization maneja la obtención de una organización por ID (admin)
+func (h *OrganizationHandler) GetOrganization(c *gin.Context) {
+	id, ok := h.organizationID(c)
+	if !ok {
+		return
+	}
+
+	organization, err := h.organizationService.GetOrganization(id)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleData(c, http.StatusOK, gin.H{"organization": organization})
+}
+
+// UpdateOrganization maneja la actualización de una organización (admin)
+func (h *OrganizationHandler) UpdateOrganization(c *gin.Context) {
+	id, ok := h.organizationID(c)
+	if !ok {
+		return
+	}
+
+	var req dto.OrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.HandleValidationError(c, err)
+		return
+	}
+
+	organization, err := h.organizationService.UpdateOrganization(id, &req)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleSuccess(c, http.StatusOK, "Organization updated successfully", gin.H{"organization": organization})
+}
+
+// DeleteOrganization maneja la eliminación de una organización (admin)
+func (h *OrganizationHandler) DeleteOrganization(c *gin.Context) {
+	id, ok := h.organizationID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.organizationService.DeleteOrganization(id); err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleSuccess(c, http.StatusOK, "Organization deleted successfully", nil)
+}
+
+// GetMembers maneja el listado de miembros de una organización (admin)
+func (h *OrganizationHandler) GetMembers(c *gin.Context) {
+	id, ok := h.organizationID(c)
+	if !ok {
+		return
+	}
+
+	members, err := h.organizationService.ListMembers(id)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleData(c, http.StatusOK, gin.H{
+		"members": members,
+		"count":   len(members),
+	})
+}
+
+// AddMember maneja la incorporación de un usuario a una organización (admin)
+func (h *OrganizationHandler) AddMember(c *gin.Context) {
+	id, ok := h.organizationID(c)
+	if !ok {
+		return
+	}
+
+	var req dto.MembershipRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.HandleValidationError(c, err)
+		return
+	}
+
+	member, err := h.organizationService.AddMember(id, &req)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleSuccess(c, http.StatusCreated, "Member added successfully", gin.H{"member": member})
+}
+
+// UpdateMember maneja el cambio de rol de un miembro (admin)
+func (h *OrganizationHandler) UpdateMember(c *gin.Context) {
+	id, ok := h.organizationID(c)
+	if !ok {
+		return
+	}
+	userID, ok := h.memberID(c)
+	if !ok {
+		return
+	}
+
+	var req dto.UpdateMembershipRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.HandleValidationError(c, err)
+		return
+	}
+
+	member, err := h.organizationService.UpdateMember(id, userID, &req)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleSuccess(c, http.StatusOK, "Member updated successfully", gin.H{"member": member})
+}
+
+// RemoveMember maneja la salida de un usuario de una organización (admin)
+func (h *OrganizationHandler) RemoveMember(c *gin.Context) {
+	id, ok := h.organizationID(c)
+	if !ok {
+		return
+	}
+	userID, ok := h.memberID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.organizationService.RemoveMember(id, userID); err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleSuccess(c, http.StatusOK, "Member removed successfully", nil)
+}
+
+func (h *OrganizationHandler) organizationID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.HandleError(c, utils.NewBadRequestError("Invalid organization ID"))
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func (h *OrganizationHandler) memberID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("userId"), 10, 32)
+	if err != nil {
+		utils.HandleError(c, utils.NewBadRequestError("Invalid user ID"))
+		return 0, false
+	}
+	return uint(id), true
+}
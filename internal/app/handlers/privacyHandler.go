@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"megabaseGo/internal/app/middleware"
+	"megabaseGo/internal/app/services"
+	"megabaseGo/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PrivacyHandler struct {
+	privacyService *services.PrivacyService
+}
+
+// NewPrivacyHandler crea una nueva instancia del handler de privacidad (RGPD)
+func NewPrivacyHandler() *PrivacyHandler {
+	return &PrivacyHandler{
+		privacyService: services.NewPrivacyService(),
+	}
+}
+
+// RequestExport maneja la solicitud de una copia de los datos del usuario actual.
+// El archivo se genera en segundo plano: responde 202 con la exportación pendiente.
+func (h *PrivacyHandler) RequestExport(c *gin.Context) {
+	userID, ok := h.currentUserID(c)
+	if !ok {
+		return
+	}
+
+	export, err := h.privacyService.RequestExport(userID, currentActor(c))
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleSuccess(c, http.StatusAccepted, "Data export requested", gin.H{"export": export})
+}
+
+// GetExports maneja la obtención de las exportaciones del usuario actual
+func (h *PrivacyHandler) GetExports(c *gin.Context) {
+	userID, ok := h.currentUserID(c)
+	if !ok {
+		return
+	}
+
+	exports, err := h.privacyService.GetExports(userID)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleData(c, http.StatusOK, gin.H{
+		"exports": exports,
+		"count":   len(exports),
+	})
+}
+
+// GetExport maneja la consulta del estado de una exportación del usuario actual
+func (h *PrivacyHandler) GetExport(c *gin.Context) {
+	userID, exportID, ok := h.exportParams(c)
+	if !ok {
+		return
+	}
+
+	export, err := h.privacyService.GetExport(userID, exportID)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleData(c, http.StatusOK, gin.H{"export": export})
+}
+
+// DownloadExport maneja la descarga del archivo de una exportación lista
+func (h *PrivacyHandler) DownloadExport(c *gin.Context) {
+	userID, exportID, ok := h.exportParams(c)
+	if !ok {
+		return
+	}
+
+	path, err := h.privacyService.ExportFile(userID, exportID)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.FileAttachment(path, fmt.Sprintf("data-export-%d.zip", exportID))
+}
+
+// RequestErasure maneja la solicitud de supresión de la cuenta del usuario actual
+func (h *PrivacyHandler) RequestErasure(c *gin.Context) {
+	userID, ok := h.currentUserID(c)
+	if !ok {
+		return
+	}
+
+	request, err := h.privacyService.RequestErasure(userID, currentActor(c))
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleSuccess(c, http.StatusAccepted, "Account erasure requested", gin.H{"erasure_request": request})
+}
+
+// GetErasure maneja la consulta de la solicitud de supresión pendiente del usuario actual
+func (h *PrivacyHandler) GetErasure(c *gin.Context) {
+	userID, ok := h.currentUserID(c)
+	if !ok {
+		return
+	}
+
+	request, err := h.privacyService.GetErasureRequest(userID)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleData(c, http.StatusOK, gin.H{"erasure_request": request})
+}
+
+// CancelErasure maneja la cancelación de la solicitud de supresión pendiente del usuario actual
+func (h *PrivacyHandler) CancelErasure(c *gin.Context) {
+	userID, ok := h.currentUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.privacyService.CancelErasure(userID, currentActor(c)); err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleSuccess(c, http.StatusOK, "Account erasure cancelled", nil)
+}
+
+// EraseUser maneja la supresión inmediata de los datos de un usuario (admin)
+func (h *PrivacyHandler) EraseUser(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.HandleError(c, utils.NewBadRequestError("Invalid user ID"))
+		return
+	}
+
+	request, err := h.privacyService.EraseUser(uint(userID), currentActor(c))
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleSuccess(c, http.StatusOK, "User data erased", gin.H{"erasure_request": request})
+}
+
+func (h *PrivacyHandler) currentUserID(c *gin.Context) (uint, bool) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		utils.HandleError(c, utils.NewUnauthorizedError("User not authenticated"))
+		return 0, false
+	}
+	return userID, true
+}
+
+func (h *PrivacyHandler) exportParams(c *gin.Context) (uint, uint, bool) {
+	userID, ok := h.currentUserID(c)
+	if !ok {
+		return 0, 0, false
+	}
+	exportID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.HandleError(c, utils.NewBadRequestError("Invalid export ID"))
+		return 0, 0, false
+	}
+	return userID, uint(exportID), true
+}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"megabaseGo/internal/app/dto"
+	"megabaseGo/internal/app/middleware"
+	"megabaseGo/internal/app/services"
+	"megabaseGo/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ProfileHandler struct {
+	profileService *services.ProfileService
+}
+
+// NewProfileHandler crea una nueva instancia del handler de perfil
+func NewProfileHandler() *ProfileHandler {
+	return &ProfileHandler{
+		profileService: services.NewProfileService(),
+	}
+}
+
+// GetProfile maneja la obtención del perfil del usuario actual
+func (h *ProfileHandler) GetProfile(c *gin.Context) {
+	userID, ok := h.currentUserID(c)
+	if !ok {
+		return
+	}
+
+	profile, err := h.profileService.GetProfile(userID)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleData(c, http.StatusOK, profile)
+}
+
+// UpdateProfile maneja la edición del perfil del usuario actual
+func (h *ProfileHandler) UpdateProfile(c *gin.Context) {
+	userID, ok := h.currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req dto.UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.HandleValidationError(c, err)
+		return
+	}
+
+	// Solo el cambio de email exige step-up; el resto del perfil se edita con la sesión normal
+	claims, _ := middleware.GetCurrentUserClaims(c)
+	recentAuth := middleware.RecentlyAuthenticated(claims, middleware.StepUpMaxAge())
+
+	profile, err := h.profileService.UpdateProfile(userID, &req, recentAuth, currentActor(c))
+	if errors.Is(err, services.ErrReauthenticationRequired) {
+		middleware.AbortReauthenticationRequired(c)
+		return
+	}
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	message := "Profile updated successfully"
+	if profile.PendingEmail != "" && req.Email != nil {
+		message = "Profile updated; check the new email address to confirm the change"
+	}
+	utils.HandleSuccess(c, http.StatusOK, message, gin.H{
+		"user":          profile.User,
+		"pending_email": profile.PendingEmail,
+	})
+}
+
+// UploadAvatar maneja la subida del avatar del usuario actual (multipart, campo "avatar")
+func (h *ProfileHandler) UploadAvatar(c *gin.Context) {
+	userID, ok := h.currentUserID(c)
+	if !ok {
+		return
+	}
+	file, ok := avatarUpload(c)
+	if !ok {
+		return
+	}
+	defer file.Close()
+
+	profile, err := h.profileService.UploadAvatar(userID, file, currentActor(c))
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleSuccess(c, http.StatusOK, "Avatar updated", gin.H{"user": profile.User})
+}
+
+// RemoveAvatar maneja la eliminación del avatar del usuario actual
+func (h *ProfileHandler) RemoveAvatar(c *gin.Context) {
+	userID, ok := h.currentUserID(c)
+	if !ok {
+		return
+	}
+
+	profile, err := h.profileService.RemoveAvatar(userID, currentActor(c))
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleSuccess(c, http.StatusOK, "Avatar removed", gin.H{"user": profile.User})
+}
+
+// ConfirmEmailChange maneja la confirmación de un cambio de email con el token del enlace
+func (h *ProfileHandler) ConfirmEmailChange(c *gin.Context) {
+	var req dto.ConfirmEmailChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.HandleValidationError(c, err)
+		return
+	}
+
+	profile, err := h.profileService.ConfirmEmailChange(req.Token, requestMeta(c))
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleSuccess(c, http.StatusOK, "Email changed successfully", gin.H{"user": profile.User})
+}
+
+// DeleteProfile maneja la baja de la cuenta del usuario actual
+func (h *ProfileHandler) DeleteProfile(c *gin.Context) {
+	userID, ok := h.currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req dto.DeleteProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.HandleValidationError(c, err)
+		return
+	}
+
+	deletion, err := h.profileService.DeleteAccount(userID, &req, currentActor(c))
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleSuccess(c, http.StatusAccepted, "Account deactivated; it will be deleted when the grace period ends", gin.H{"account_deletion": deletion})
+}
+
+// ReactivateAccount maneja la reactivación de una cuenta dada de baja con el token del enlace
+func (h *ProfileHandler) ReactivateAccount(c *gin.Context) {
+	var req dto.ReactivateAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.HandleValidationError(c, err)
+		return
+	}
+
+	if err := h.profileService.ReactivateAccount(req.Token, requestMeta(c)); err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleSuccess(c, http.StatusOK, "Account reactivated; you can log in again", nil)
+}
+
+func (h *ProfileHandler) currentUserID(c *gin.Context) (uint, bool) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		utils.HandleError(c, utils.NewUnauthorizedError("User not authenticated"))
+		return 0, false
+	}
+	return userID, true
+}
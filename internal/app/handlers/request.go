@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"megabaseGo/internal/app/dto"
+	"megabaseGo/internal/app/middleware"
+	"megabaseGo/internal/app/services"
+	"megabaseGo/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestMeta extrae del contexto los datos del cliente para historial y auditoría
+func requestMeta(c *gin.Context) *dto.RequestMeta {
+	return &dto.RequestMeta{
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		RequestID: middleware.GetRequestID(c),
+	}
+}
+
+// currentActor devuelve el usuario autenticado como actor de las operaciones auditadas
+func currentActor(c *gin.Context) *services.Actor {
+	actor := &services.Actor{Meta: requestMeta(c)}
+	if claims, ok := middleware.GetCurrentUserClaims(c); ok {
+		actor.UserID = claims.UserID
+		actor.UserName = claims.UserName
+	}
+	return actor
+}
+
+// currentTenant devuelve la organización resuelta por TenantMiddleware
+func currentTenant(c *gin.Context) *services.Tenant {
+	tenant, _ := middleware.GetCurrentTenant(c)
+	return tenant
+}
+
+// revisionParams lee el ID del recurso y, si la ruta lo tiene, el número de revisión.
+// Si alguno no es válido responde 400 y devuelve ok=false.
+func revisionParams(c *gin.Context, resource string) (id, revision uint, ok bool) {
+	parsedID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.HandleError(c, utils.NewBadRequestError("Invalid "+resource+" ID"))
+		return 0, 0, false
+	}
+	if rev := c.Param("rev"); rev != "" {
+		parsedRev, err := strconv.ParseUint(rev, 10, 32)
+		if err != nil || parsedRev == 0 {
+			utils.HandleError(c, utils.NewBadRequestError("Invalid revision number"))
+			return 0, 0, false
+		}
+		revision = uint(parsedRev)
+	}
+	return uint(parsedID), revision, true
+}
+
+// revisionTime lee el parámetro ?at= (RFC 3339) del historial de revisiones; nil si no viene
+func revisionTime(c *gin.Context) (*time.Time, error) {
+	value := c.Query("at")
+	if value == "" {
+		return nil, nil
+	}
+	at, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, utils.NewBadRequestError("Invalid at: expected RFC 3339 timestamp")
+	}
+	return &at, nil
+}
+
+// Margen para las cabeceras multipart sobre el tamaño máximo del avatar
+const multipartOverhead = 64 << 10
+
+// avatarUpload abre el fichero del campo "avatar" de un formulario multipart. Limita el cuerpo
+// de la petición para no leer subidas desmesuradas; si falta el fichero responde 400 y devuelve ok=false.
+func avatarUpload(c *gin.Context) (io.ReadCloser, bool) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, services.AvatarMaxBytes()+multipartOverhead)
+	header, err := c.FormFile("avatar")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			utils.HandleError(c, utils.NewPayloadTooLargeError("avatar is too large"))
+		} else {
+			utils.HandleError(c, utils.NewBadRequestError("multipart field 'avatar' with the image is required"))
+		}
+		return nil, false
+	}
+	file, err := header.Open()
+	if err != nil {
+		utils.HandleError(c, err)
+		return nil, false
+	}
+	return file, true
+}
+
+// Tamaño máximo del cuerpo de un PATCH
+const maxPatchBytes = 1 << 20
+
+// patchBody lee el cuerpo de un PATCH. Admite merge patch (también como application/json) y
+// JSON Patch; con otro tipo responde 415 indicando los admitidos en Accept-Patch y devuelve ok=false.
+func patchBody(c *gin.Context) (*dto.Patch, bool) {
+	contentType := c.ContentType()
+	if contentType == "application/json" {
+		contentType = dto.MergePatchContentType
+	}
+	if contentType != dto.MergePatchContentType && contentType != dto.JSONPatchContentType {
+		c.Header("Accept-Patch", dto.MergePatchContentType+", "+dto.JSONPatchContentType)
+		utils.HandleError(c, utils.NewUnsupportedMediaTypeError("PATCH requires "+dto.MergePatchContentType+" or "+dto.JSONPatchContentType))
+		return nil, false
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxPatchBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			utils.HandleError(c, utils.NewPayloadTooLargeError("patch document is too large"))
+		} else {
+			utils.HandleError(c, utils.NewBadRequestError("could not read request body"))
+		}
+		return nil, false
+	}
+	return &dto.Patch{ContentType: contentType, Body: body}, true
+}
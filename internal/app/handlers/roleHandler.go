@@ -6,6 +6,7 @@ import (
 
 	"megabaseGo/internal/app/dto"
 	"megabaseGo/internal/app/services"
+	"megabaseGo/internal/utils"
 
 	"github.com/gin-gonic/gin"
 )
@@ -33,7 +34,7 @@ func (h *RoleHandler) CreateRole(c *gin.Context) {
 		return
 	}
 
-	role, err := h.roleService.CreateRole(&req)
+	role, err := h.roleService.ForTenant(currentTenant(c)).ForActor(currentActor(c)).CreateRole(&req)
 	if err != nil {
 		if err.Error() == "role with this name already exists" {
 			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
@@ -52,12 +53,20 @@ func (h *RoleHandler) CreateRole(c *gin.Context) {
 	})
 }
 
-// GetRoles maneja la obtención de todos los roles
+// GetRoles maneja el listado paginado de roles
 func (h *RoleHandler) GetRoles(c *gin.Context) {
+	params, ok := listQuery(c)
+	if !ok {
+		return
+	}
 	includeInactive := c.Query("include_inactive") == "true"
 
-	roles, err := h.roleService.GetRoles(includeInactive)
+	roles, meta, err := h.roleService.ForTenant(currentTenant(c)).GetRoles(params, includeInactive, c.Query("trashed"))
 	if err != nil {
+		if _, ok := utils.IsAPIError(err); ok {
+			utils.HandleError(c, err)
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to fetch roles",
 			"details": err.Error(),
@@ -65,9 +74,10 @@ func (h *RoleHandler) GetRoles(c *gin.Context) {
 		return
 	}
 
+	setPageLinks(c, meta)
 	c.JSON(http.StatusOK, gin.H{
 		"roles": roles,
-		"count": len(roles),
+		"meta":  meta,
 	})
 }
 
@@ -82,7 +92,7 @@ func (h *RoleHandler) GetRole(c *gin.Context) {
 		return
 	}
 
-	role, err := h.roleService.GetRoleByID(uint(roleID))
+	role, err := h.roleService.ForTenant(currentTenant(c)).GetRoleByID(uint(roleID))
 	if err != nil {
 		if err.Error() == "role not found" {
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
@@ -95,6 +105,9 @@ func (h *RoleHandler) GetRole(c *gin.Context) {
 		return
 	}
 
+	if notModified(c, role.Version) {
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"role": role,
 	})
@@ -111,6 +124,11 @@ func (h *RoleHandler) UpdateRole(c *gin.Context) {
 		return
 	}
 
+	precondition, ok := ifMatch(c)
+	if !ok {
+		return
+	}
+
 	var req dto.UpdateRoleRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -120,8 +138,12 @@ func (h *RoleHandler) UpdateRole(c *gin.Context) {
 		return
 	}
 
-	role, err := h.roleService.UpdateRole(uint(roleID), &req)
+	role, err := h.roleService.ForTenant(currentTenant(c)).ForActor(currentActor(c)).IfMatch(precondition).UpdateRole(uint(roleID), &req)
 	if err != nil {
+		if _, ok := utils.IsAPIError(err); ok {
+			utils.HandleError(c, err)
+			return
+		}
 		switch err.Error() {
 		case "role not found":
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
@@ -136,6 +158,53 @@ func (h *RoleHandler) UpdateRole(c *gin.Context) {
 		return
 	}
 
+	setETag(c, role.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Role updated successfully",
+		"role":    role,
+	})
+}
+
+// PatchRole maneja la actualización parcial de roles (merge patch o JSON Patch)
+func (h *RoleHandler) PatchRole(c *gin.Context) {
+	roleID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid role ID",
+		})
+		return
+	}
+
+	precondition, ok := ifMatch(c)
+	if !ok {
+		return
+	}
+	patch, ok := patchBody(c)
+	if !ok {
+		return
+	}
+
+	role, err := h.roleService.ForTenant(currentTenant(c)).ForActor(currentActor(c)).IfMatch(precondition).PatchRole(uint(roleID), patch)
+	if err != nil {
+		if _, ok := utils.IsAPIError(err); ok {
+			utils.HandleError(c, err)
+			return
+		}
+		switch err.Error() {
+		case "role not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case "role with this name already exists":
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to update role",
+				"details": err.Error(),
+			})
+		}
+		return
+	}
+
+	setETag(c, role.Version)
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Role updated successfully",
 		"role":    role,
@@ -153,8 +222,17 @@ func (h *RoleHandler) DeleteRole(c *gin.Context) {
 		return
 	}
 
-	err = h.roleService.DeleteRole(uint(roleID))
+	precondition, ok := ifMatch(c)
+	if !ok {
+		return
+	}
+
+	err = h.roleService.ForTenant(currentTenant(c)).ForActor(currentActor(c)).IfMatch(precondition).DeleteRole(uint(roleID))
 	if err != nil {
+		if _, ok := utils.IsAPIError(err); ok {
+			utils.HandleError(c, err)
+			return
+		}
 		switch err.Error() {
 		case "role not found":
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
@@ -173,3 +251,92 @@ func (h *RoleHandler) DeleteRole(c *gin.Context) {
 		"message": "Role deleted successfully",
 	})
 }
+
+// GetRoleRevisions maneja la obtención del historial de revisiones de un rol (?at= para un momento concreto)
+func (h *RoleHandler) GetRoleRevisions(c *gin.Context) {
+	id, _, ok := revisionParams(c, "role")
+	if !ok {
+		return
+	}
+	at, err := revisionTime(c)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	revisions, err := h.roleService.ForTenant(currentTenant(c)).GetRevisions(id, at)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleData(c, http.StatusOK, gin.H{
+		"revisions": revisions,
+		"count":     len(revisions),
+	})
+}
+
+// GetRoleRevision maneja la obtención de una revisión con sus diferencias
+func (h *RoleHandler) GetRoleRevision(c *gin.Context) {
+	id, rev, ok := revisionParams(c, "role")
+	if !ok {
+		return
+	}
+
+	revision, err := h.roleService.ForTenant(currentTenant(c)).GetRevision(id, rev)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleData(c, http.StatusOK, gin.H{"revision": revision})
+}
+
+// RestoreRoleRevision maneja la restauración de una revisión anterior (admin)
+func (h *RoleHandler) RestoreRoleRevision(c *gin.Context) {
+	id, rev, ok := revisionParams(c, "role")
+	if !ok {
+		return
+	}
+
+	role, err := h.roleService.ForTenant(currentTenant(c)).ForActor(currentActor(c)).RestoreRevision(id, rev)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleSuccess(c, http.StatusOK, "Role revision restored successfully", gin.H{"role": role})
+}
+
+// RestoreRole maneja la restauración de un rol de la papelera (admin)
+func (h *RoleHandler) RestoreRole(c *gin.Context) {
+	roleID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.HandleError(c, utils.NewBadRequestError("Invalid role ID"))
+		return
+	}
+
+	role, err := h.roleService.ForTenant(currentTenant(c)).ForActor(currentActor(c)).RestoreRole(uint(roleID))
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleSuccess(c, http.StatusOK, "Role restored successfully", gin.H{"role": role})
+}
+
+// PurgeRole maneja la eliminación definitiva de un rol (admin)
+func (h *RoleHandler) PurgeRole(c *gin.Context) {
+	roleID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.HandleError(c, utils.NewBadRequestError("Invalid role ID"))
+		return
+	}
+
+	if err := h.roleService.ForTenant(currentTenant(c)).ForActor(currentActor(c)).PurgeRole(uint(roleID)); err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleSuccess(c, http.StatusOK, "Role permanently deleted", nil)
+}
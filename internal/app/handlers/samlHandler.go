@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"megabaseGo/internal/app/dto"
+	"megabaseGo/internal/app/middleware"
+	"megabaseGo/internal/app/services"
+	"megabaseGo/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SamlHandler struct {
+	samlService *services.SamlService
+	authService *services.AuthService
+	cookies     *utils.CookieManager
+}
+
+// NewSamlHandler crea una nueva instancia del handler SAML
+func NewSamlHandler() *SamlHandler {
+	return &SamlHandler{
+		samlService: services.NewSamlService(),
+		authService: services.NewAuthService(),
+		cookies:     utils.NewCookieManager(),
+	}
+}
+
+// Metadata maneja la publicación de los metadatos del SP para un IdP
+func (h *SamlHandler) Metadata(c *gin.Context) {
+	metadata, err := h.samlService.Metadata(c.Param("slug"))
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	c.Data(http.StatusOK, "application/samlmetadata+xml", metadata)
+}
+
+// Login maneja el inicio de un login SAML iniciado por el SP (redirige al IdP)
+func (h *SamlHandler) Login(c *gin.Context) {
+	redirectURL, err := h.samlService.StartLogin(c.Param("slug"), c.Query("redirect_to"))
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	c.Redirect(http.StatusFound, redirectURL)
+}
+
+// Reauthenticate maneja el inicio de una re-autenticación (step-up) con el IdP para las
+// cuentas sin contraseña; el ACS devuelve el token elevado
+func (h *SamlHandler) Reauthenticate(c *gin.Context) {
+	claims, exists := middleware.GetCurrentUserClaims(c)
+	if !exists {
+		utils.HandleError(c, utils.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	var req dto.SamlReauthenticateRequest
+	if err := c.ShouldBindJSON(&req); err != nil && c.Request.ContentLength > 0 {
+		utils.HandleValidationError(c, err)
+		return
+	}
+
+	redirectURL, err := h.samlService.StartReauthentication(c.Param("slug"), claims.UserID, claims.SessionID, req.RedirectTo)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleData(c, http.StatusOK, gin.H{"redirect_url": redirectURL})
+}
+
+// ACS maneja la respuesta del IdP (Assertion Consumer Service, binding HTTP-POST)
+func (h *SamlHandler) ACS(c *gin.Context) {
+	outcome, err := h.samlService.ConsumeResponse(c.Param("slug"), c.Request, requestMeta(c))
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	authResponse, redirectTo := outcome.Auth, outcome.RedirectTo
+
+	// Re-autenticación: en modo cookies el token elevado reemplaza la cookie del access token
+	if reauth := outcome.Reauth; reauth != nil {
+		if h.cookies.Enabled() {
+			h.cookies.SetAccessCookie(c, reauth.AccessToken, int(reauth.ExpiresIn))
+			c.Redirect(http.StatusSeeOther, redirectTo)
+			return
+		}
+		utils.HandleSuccess(c, http.StatusOK, "Reauthentication successful", gin.H{
+			"data":        reauth,
+			"redirect_to": redirectTo,
+		})
+		return
+	}
+
+	// Vinculación de identidad completada: no hay sesión nueva, solo se vuelve a la aplicación
+	if authResponse == nil {
+		c.Redirect(http.StatusSeeOther, redirectTo)
+		return
+	}
+
+	// En modo cookies el navegador vuelve a la aplicación con la sesión ya establecida
+	if h.cookies.Enabled() {
+		if err := applySessionCookies(c, h.cookies, authResponse); err != nil {
+			utils.HandleError(c, utils.NewInternalServerError("Failed to create session"))
+			return
+		}
+		c.Redirect(http.StatusSeeOther, redirectTo)
+		return
+	}
+
+	utils.HandleSuccess(c, http.StatusOK, "Login successful", gin.H{
+		"data":        authResponse,
+		"redirect_to": redirectTo,
+	})
+}
+
+// CreateProvider maneja el registro de un IdP SAML
+func (h *SamlHandler) CreateProvider(c *gin.Context) {
+	var req dto.SamlProviderRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.HandleValidationError(c, err)
+		return
+	}
+
+	provider, err := h.samlService.CreateProvider(&req, currentActor(c))
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleSuccess(c, http.StatusCreated, "SAML provider created successfully", gin.H{"provider": provider})
+}
+
+// GetProviders maneja el listado de IdPs SAML
+func (h *SamlHandler) GetProviders(c *gin.Context) {
+	providers, err := h.samlService.GetProviders()
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleData(c, http.StatusOK, gin.H{
+		"providers": providers,
+		"count":     len(providers),
+	})
+}
+
+// GetProvider maneja la obtención de un IdP SAML por ID
+func (h *SamlHandler) GetProvider(c *gin.Context) {
+	id, ok := h.providerID(c)
+	if !ok {
+		return
+	}
+
+	provider, err := h.samlService.GetProvider(id)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleData(c, http.StatusOK, gin.H{"provider": provider})
+}
+
+// UpdateProvider maneja la actualización de un IdP SAML
+func (h *SamlHandler) UpdateProvider(c *gin.Context) {
+	id, ok := h.providerID(c)
+	if !ok {
+		return
+	}
+
+	var req dto.SamlProviderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.HandleValidationError(c, err)
+		return
+	}
+
+	provider, err := h.samlService.UpdateProvider(id, &req, currentActor(c))
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleSuccess(c, http.StatusOK, "SAML provider updated successfully", gin.H{"provider": provider})
+}
+
+// DeleteProvider maneja la eliminación de un IdP SAML
+func (h *SamlHandler) DeleteProvider(c *gin.Context) {
+	id, ok := h.providerID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.samlService.DeleteProvider(id, currentActor(c)); err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleSuccess(c, http.StatusOK, "SAML provider deleted successfully", nil)
+}
+
+func (h *SamlHandler) providerID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.HandleError(c, utils.NewBadRequestError("Invalid SAML provider ID"))
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,316 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"megabaseGo/internal/app/dto"
+	"megabaseGo/internal/app/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+const scimContentType = "application/scim+json"
+
+type ScimHandler struct {
+	scimService *services.ScimService
+}
+
+// NewScimHandler crea una nueva instancia del handler SCIM
+func NewScimHandler() *ScimHandler {
+	return &ScimHandler{
+		scimService: services.NewScimService(),
+	}
+}
+
+// ListUsers maneja GET /Users
+func (h *ScimHandler) ListUsers(c *gin.Context) {
+	response, err := h.scimService.ListUsers(h.listQuery(c))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	h.respond(c, http.StatusOK, response)
+}
+
+// GetUser maneja GET /Users/:id
+func (h *ScimHandler) GetUser(c *gin.Context) {
+	id, ok := h.resourceID(c)
+	if !ok {
+		return
+	}
+	user, err := h.scimService.GetUser(id)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	h.respond(c, http.StatusOK, user)
+}
+
+// CreateUser maneja POST /Users
+func (h *ScimHandler) CreateUser(c *gin.Context) {
+	var req dto.ScimUser
+	if !h.bind(c, &req) {
+		return
+	}
+	user, err := h.scimService.CreateUser(&req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.Header("Location", user.Meta.Location)
+	h.respond(c, http.StatusCreated, user)
+}
+
+// ReplaceUser maneja PUT /Users/:id
+func (h *ScimHandler) ReplaceUser(c *gin.Context) {
+	id, ok := h.resourceID(c)
+	if !ok {
+		return
+	}
+	var req dto.ScimUser
+	if !h.bind(c, &req) {
+		return
+	}
+	user, err := h.scimService.ReplaceUser(id, &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	h.respond(c, http.StatusOK, user)
+}
+
+// PatchUser maneja PATCH /Users/:id
+func (h *ScimHandler) PatchUser(c *gin.Context) {
+	id, ok := h.resourceID(c)
+	if !ok {
+		return
+	}
+	var req dto.ScimPatchRequest
+	if !h.bind(c, &req) {
+		return
+	}
+	user, err := h.scimService.PatchUser(id, &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	h.respond(c, http.StatusOK, user)
+}
+
+// DeleteUser maneja DELETE /Users/:id
+func (h *ScimHandler) DeleteUser(c *gin.Context) {
+	id, ok := h.resourceID(c)
+	if !ok {
+		return
+	}
+	if err := h.scimService.DeleteUser(id); err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListGroups maneja GET /Groups
+func (h *ScimHandler) ListGroups(c *gin.Context) {
+	response, err := h.scimService.ListGroups(h.listQuery(c))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	h.respond(c, http.StatusOK, response)
+}
+
+// GetGroup maneja GET /Groups/:id
+func (h *ScimHandler) GetGroup(c *gin.Context) {
+	id, ok := h.resourceID(c)
+	if !ok {
+		return
+	}
+	group, err := h.scimService.GetGroup(id)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	h.respond(c, http.StatusOK, group)
+}
+
+// CreateGroup maneja POST /Groups
+func (h *ScimHandler) CreateGroup(c *gin.Context) {
+	var req dto.ScimGroup
+	if !h.bind(c, &req) {
+		return
+	}
+	group, err := h.scimService.CreateGroup(&req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.Header("Location", group.Meta.Location)
+	h.respond(c, http.StatusCreated, group)
+}
+
+// ReplaceGroup maneja PUT /Groups/:id
+func (h *ScimHandler) ReplaceGroup(c *gin.Context) {
+	id, ok := h.resourceID(c)
+	if !ok {
+		return
+	}
+	var req dto.ScimGroup
+	if !h.bind(c, &req) {
+		return
+	}
+	group, err := h.scimService.ReplaceGroup(id, &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	h.respond(c, http.StatusOK, group)
+}
+
+// PatchGroup maneja PATCH /Groups/:id
+func (h *ScimHandler) PatchGroup(c *gin.Context) {
+	id, ok := h.resourceID(c)
+	if !ok {
+		return
+	}
+	var req dto.ScimPatchRequest
+	if !h.bind(c, &req) {
+		return
+	}
+	group, err := h.scimService.PatchGroup(id, &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	h.respond(c, http.StatusOK, group)
+}
+
+// DeleteGroup maneja DELETE /Groups/:id
+func (h *ScimHandler) DeleteGroup(c *gin.Context) {
+	id, ok := h.resourceID(c)
+	if !ok {
+		return
+	}
+	if err := h.scimService.DeleteGroup(id); err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ServiceProviderConfig maneja GET /ServiceProviderConfig
+func (h *ScimHandler) ServiceProviderConfig(c *gin.Context) {
+	h.respond(c, http.StatusOK, h.scimService.ServiceProviderConfig())
+}
+
+// ResourceTypes maneja GET /ResourceTypes
+func (h *ScimHandler) ResourceTypes(c *gin.Context) {
+	h.respond(c, http.StatusOK, h.discoveryList(h.scimService.ResourceTypes()))
+}
+
+// ResourceType maneja GET /ResourceTypes/:id
+func (h *ScimHandler) ResourceType(c *gin.Context) {
+	resourceType, ok := h.scimService.ResourceType(c.Param("id"))
+	if !ok {
+		h.handleError(c, &services.ScimError{Status: http.StatusNotFound, Detail: "resource type not found"})
+		return
+	}
+	h.respond(c, http.StatusOK, resourceType)
+}
+
+// Schemas maneja GET /Schemas
+func (h *ScimHandler) Schemas(c *gin.Context) {
+	h.respond(c, http.StatusOK, h.discoveryList(h.scimService.Schemas()))
+}
+
+// Schema maneja GET /Schemas/:id
+func (h *ScimHandler) Schema(c *gin.Context) {
+	schema, ok := h.scimService.Schema(c.Param("id"))
+	if !ok {
+		h.handleError(c, &services.ScimError{Status: http.StatusNotFound, Detail: "schema not found"})
+		return
+	}
+	h.respond(c, http.StatusOK, schema)
+}
+
+func (h *ScimHandler) discoveryList(items []map[string]interface{}) *dto.ScimListResponse {
+	resources := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		resources = append(resources, item)
+	}
+	return &dto.ScimListResponse{
+		Schemas:      []string{dto.ScimListResponseSchema},
+		TotalResults: int64(len(resources)),
+		StartIndex:   1,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	}
+}
+
+// listQuery interpreta filter, startIndex (base 1) y count según RFC 7644 §3.4.2.4
+func (h *ScimHandler) listQuery(c *gin.Context) *dto.ScimListQuery {
+	query := &dto.ScimListQuery{
+		Filter:     c.Query("filter"),
+		StartIndex: 1,
+		Count:      100,
+	}
+	if startIndex, err := strconv.Atoi(c.Query("startIndex")); err == nil && startIndex > 1 {
+		query.StartIndex = startIndex
+	}
+	if count, err := strconv.Atoi(c.Query("count")); err == nil {
+		query.Count = count
+	}
+	if query.Count < 0 {
+		query.Count = 0
+	}
+	if query.Count > h.scimService.MaxResults() {
+		query.Count = h.scimService.MaxResults()
+	}
+	return query
+}
+
+func (h *ScimHandler) resourceID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.handleError(c, &services.ScimError{Status: http.StatusNotFound, Detail: "resource " + c.Param("id") + " not found"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func (h *ScimHandler) bind(c *gin.Context, target interface{}) bool {
+	if err := c.ShouldBindJSON(target); err != nil {
+		h.handleError(c, &services.ScimError{Status: http.StatusBadRequest, ScimType: "invalidSyntax", Detail: err.Error()})
+		return false
+	}
+	return true
+}
+
+func (h *ScimHandler) respond(c *gin.Context, status int, body interface{}) {
+	c.Header("Content-Type", scimContentType)
+	c.JSON(status, body)
+}
+
+// handleError renderiza los errores con el esquema de error SCIM
+func (h *ScimHandler) handleError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	response := dto.ScimErrorResponse{
+		Schemas: []string{dto.ScimErrorSchema},
+		Detail:  "Internal server error",
+	}
+
+	var scimErr *services.ScimError
+	if errors.As(err, &scimErr) {
+		status = scimErr.Status
+		response.ScimType = scimErr.ScimType
+		response.Detail = scimErr.Detail
+	} else {
+		log.Printf("Error SCIM: %v", err)
+	}
+
+	response.Status = strconv.Itoa(status)
+	h.respond(c, status, response)
+}
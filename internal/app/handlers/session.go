@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"megabaseGo/internal/app/dto"
+	"megabaseGo/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// applySessionCookies mueve los tokens de la respuesta a cookies HttpOnly.
+// Los tokens se quitan del cuerpo para que el navegador no pueda guardarlos en localStorage.
+// Las cookies duran lo mismo que los tokens, según el perfil de la sesión.
+func applySessionCookies(c *gin.Context, cookies *utils.CookieManager, authResponse *dto.AuthResponse) error {
+	if err := cookies.SetAuthCookies(
+		c,
+		authResponse.AccessToken,
+		authResponse.RefreshToken,
+		int(authResponse.ExpiresIn),
+		int(authResponse.RefreshExpiresIn),
+	); err != nil {
+		return err
+	}
+
+	authResponse.AccessToken = ""
+	authResponse.RefreshToken = ""
+	authResponse.TokenType = "Cookie"
+	return nil
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"megabaseGo/internal/app/dto"
+	"megabaseGo/internal/app/services"
+	"megabaseGo/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SessionPolicyHandler struct {
+	sessionService *services.SessionService
+}
+
+// NewSessionPolicyHandler crea una nueva instancia del handler de perfiles de sesión
+func NewSessionPolicyHandler() *SessionPolicyHandler {
+	return &SessionPolicyHandler{
+		sessionService: services.NewSessionService(),
+	}
+}
+
+// CreatePolicy maneja la creación de un perfil de sesión
+func (h *SessionPolicyHandler) CreatePolicy(c *gin.Context) {
+	var req dto.SessionPolicyRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.HandleValidationError(c, err)
+		return
+	}
+
+	policy, err := h.sessionService.CreatePolicy(&req)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleSuccess(c, http.StatusCreated, "Session policy created successfully", gin.H{"policy": policy})
+}
+
+// GetPolicies maneja el listado de perfiles de sesión
+func (h *SessionPolicyHandler) GetPolicies(c *gin.Context) {
+	policies, err := h.sessionService.GetPolicies()
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleData(c, http.StatusOK, gin.H{
+		"policies": policies,
+		"count":    len(policies),
+	})
+}
+
+// GetPolicy maneja la obtención de un perfil de sesión por ID
+func (h *SessionPolicyHandler) GetPolicy(c *gin.Context) {
+	id, ok := h.policyID(c)
+	if !ok {
+		return
+	}
+
+	policy, err := h.sessionService.GetPolicy(id)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleData(c, http.StatusOK, gin.H{"policy": policy})
+}
+
+// UpdatePolicy maneja la actualización de un perfil de sesión
+func (h *SessionPolicyHandler) UpdatePolicy(c *gin.Context) {
+	id, ok := h.policyID(c)
+	if !ok {
+		return
+	}
+
+	var req dto.SessionPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.HandleValidationError(c, err)
+		return
+	}
+
+	policy, err := h.sessionService.UpdatePolicy(id, &req)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleSuccess(c, http.StatusOK, "Session policy updated successfully", gin.H{"policy": policy})
+}
+
+// DeletePolicy maneja la eliminación de un perfil de sesión
+func (h *SessionPolicyHandler) DeletePolicy(c *gin.Context) {
+	id, ok := h.policyID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.sessionService.DeletePolicy(id); err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleSuccess(c, http.StatusOK, "Session policy deleted successfully", nil)
+}
+
+func (h *SessionPolicyHandler) policyID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.HandleError(c, utils.NewBadRequestError("Invalid session policy ID"))
+		return 0, false
+	}
+	return uint(id), true
+}
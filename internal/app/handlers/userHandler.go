@@ -31,7 +31,7 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 		return
 	}
 
-	user, err := h.userService.CreateUser(&req)
+	user, err := h.userService.ForTenant(currentTenant(c)).ForActor(currentActor(c)).CreateUser(&req)
 	if err != nil {
 		utils.HandleError(c, err) // ✅ Una sola línea!
 		return
@@ -40,27 +40,46 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 	utils.HandleSuccess(c, http.StatusCreated, "User created successfully", gin.H{"user": user})
 }
 
-// GetUsers maneja la obtención de todos los usuarios
+// GetUsers maneja el listado paginado de usuarios
 func (h *UserHandler) GetUsers(c *gin.Context) {
-	includeInactive := c.Query("include_inactive") == "true"
-	
-	var roleID *uint
-	if roleIDStr := c.Query("role_id"); roleIDStr != "" {
-		if id, err := strconv.ParseUint(roleIDStr, 10, 32); err == nil {
-			roleIDUint := uint(id)
-			roleID = &roleIDUint
-		}
+	params, ok := listQuery(c)
+	if !ok {
+		return
 	}
+	includeInactive := c.Query("include_inactive") == "true"
 
-	users, err := h.userService.GetUsers(includeInactive, roleID)
+	users, meta, err := h.userService.ForTenant(currentTenant(c)).GetUsers(params, includeInactive, c.Query("trashed"))
 	if err != nil {
-		utils.HandleError(c, err) // ✅ Una sola línea!
+		utils.HandleError(c, err)
 		return
 	}
 
+	setPageLinks(c, meta)
 	utils.HandleData(c, http.StatusOK, gin.H{
 		"users": users,
-		"count": len(users),
+		"meta":  meta,
+	})
+}
+
+// SearchUsers maneja la búsqueda de usuarios por nombre, username o email (?q=), paginada como el listado
+func (h *UserHandler) SearchUsers(c *gin.Context) {
+	params, ok := listQuery(c)
+	if !ok {
+		return
+	}
+	delete(params.Filters, "q")
+	includeInactive := c.Query("include_inactive") == "true"
+
+	results, meta, err := h.userService.ForTenant(currentTenant(c)).SearchUsers(c.Query("q"), params, includeInactive)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	setPageLinks(c, meta)
+	utils.HandleData(c, http.StatusOK, gin.H{
+		"users": results,
+		"meta":  meta,
 	})
 }
 
@@ -73,12 +92,16 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 		return
 	}
 
-	user, err := h.userService.GetUserByID(uint(userID))
+	user, err := h.userService.ForTenant(currentTenant(c)).GetUserByID(uint(userID))
 	if err != nil {
 		utils.HandleError(c, err) // ✅ Una sola línea!
 		return
 	}
 
+	// Cambiar el rol de la membresía también incrementa la versión (OrganizationService.UpdateMember)
+	if notModified(c, user.Version) {
+		return
+	}
 	utils.HandleData(c, http.StatusOK, gin.H{"user": user})
 }
 
@@ -91,18 +114,51 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 		return
 	}
 
+	precondition, ok := ifMatch(c)
+	if !ok {
+		return
+	}
+
 	var req dto.UpdateUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.HandleValidationError(c, err)
 		return
 	}
 
-	user, err := h.userService.UpdateUser(uint(userID), &req)
+	user, err := h.userService.ForTenant(currentTenant(c)).ForActor(currentActor(c)).IfMatch(precondition).UpdateUser(uint(userID), &req)
 	if err != nil {
 		utils.HandleError(c, err) // ✅ Una sola línea!
 		return
 	}
 
+	setETag(c, user.Version)
+	utils.HandleSuccess(c, http.StatusOK, "User updated successfully", gin.H{"user": user})
+}
+
+// PatchUser maneja la actualización parcial de usuarios (merge patch o JSON Patch)
+func (h *UserHandler) PatchUser(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.HandleError(c, utils.NewBadRequestError("Invalid user ID"))
+		return
+	}
+
+	precondition, ok := ifMatch(c)
+	if !ok {
+		return
+	}
+	patch, ok := patchBody(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.userService.ForTenant(currentTenant(c)).ForActor(currentActor(c)).IfMatch(precondition).PatchUser(uint(userID), patch)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	setETag(c, user.Version)
 	utils.HandleSuccess(c, http.StatusOK, "User updated successfully", gin.H{"user": user})
 }
 
@@ -115,11 +171,144 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 		return
 	}
 
-	err = h.userService.DeleteUser(uint(userID))
+	precondition, ok := ifMatch(c)
+	if !ok {
+		return
+	}
+
+	err = h.userService.ForTenant(currentTenant(c)).ForActor(currentActor(c)).IfMatch(precondition).DeleteUser(uint(userID))
 	if err != nil {
 		utils.HandleError(c, err) // ✅ Una sola línea!
 		return
 	}
 
 	utils.HandleSuccess(c, http.StatusOK, "User deleted successfully", nil)
-}
\ No newline at end of file
+}
+
+// RestoreUser maneja la restauración de un usuario de la papelera (admin)
+func (h *UserHandler) RestoreUser(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.HandleError(c, utils.NewBadRequestError("Invalid user ID"))
+		return
+	}
+
+	user, err := h.userService.ForTenant(currentTenant(c)).ForActor(currentActor(c)).RestoreUser(uint(userID))
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleSuccess(c, http.StatusOK, "User restored successfully", gin.H{"user": user})
+}
+
+// PurgeUser maneja la eliminación definitiva de un usuario y sus datos asociados (admin)
+func (h *UserHandler) PurgeUser(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.HandleError(c, utils.NewBadRequestError("Invalid user ID"))
+		return
+	}
+
+	if err := h.userService.ForTenant(currentTenant(c)).ForActor(currentActor(c)).PurgeUser(uint(userID)); err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleSuccess(c, http.StatusOK, "User permanently deleted", nil)
+}
+
+// UploadAvatar maneja la subida del avatar de un usuario (admin, multipart, campo "avatar")
+func (h *UserHandler) UploadAvatar(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.HandleError(c, utils.NewBadRequestError("Invalid user ID"))
+		return
+	}
+	file, ok := avatarUpload(c)
+	if !ok {
+		return
+	}
+	defer file.Close()
+
+	user, err := h.userService.ForTenant(currentTenant(c)).ForActor(currentActor(c)).UpdateAvatar(uint(userID), file)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleSuccess(c, http.StatusOK, "Avatar updated", gin.H{"user": user})
+}
+
+// RemoveAvatar maneja la eliminación del avatar de un usuario (admin)
+func (h *UserHandler) RemoveAvatar(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.HandleError(c, utils.NewBadRequestError("Invalid user ID"))
+		return
+	}
+
+	user, err := h.userService.ForTenant(currentTenant(c)).ForActor(currentActor(c)).RemoveAvatar(uint(userID))
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleSuccess(c, http.StatusOK, "Avatar removed", gin.H{"user": user})
+}
+
+// GetUserRevisions maneja la obtención del historial de revisiones de un usuario (?at= para un momento concreto)
+func (h *UserHandler) GetUserRevisions(c *gin.Context) {
+	id, _, ok := revisionParams(c, "user")
+	if !ok {
+		return
+	}
+	at, err := revisionTime(c)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	revisions, err := h.userService.ForTenant(currentTenant(c)).GetRevisions(id, at)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleData(c, http.StatusOK, gin.H{
+		"revisions": revisions,
+		"count":     len(revisions),
+	})
+}
+
+// GetUserRevision maneja la obtención de una revisión con sus diferencias
+func (h *UserHandler) GetUserRevision(c *gin.Context) {
+	id, rev, ok := revisionParams(c, "user")
+	if !ok {
+		return
+	}
+
+	revision, err := h.userService.ForTenant(currentTenant(c)).GetRevision(id, rev)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleData(c, http.StatusOK, gin.H{"revision": revision})
+}
+
+// RestoreUserRevision maneja la restauración de una revisión anterior (admin)
+func (h *UserHandler) RestoreUserRevision(c *gin.Context) {
+	id, rev, ok := revisionParams(c, "user")
+	if !ok {
+		return
+	}
+
+	user, err := h.userService.ForTenant(currentTenant(c)).ForActor(currentActor(c)).RestoreRevision(id, rev)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.HandleSuccess(c, http.StatusOK, "User revision restored successfully", gin.H{"user": user})
+}
@@ -3,6 +3,7 @@ package middleware
 import (
 	"net/http"
 	"strings"
+	"time"
 
 	"megabaseGo/internal/app/services"
 	"megabaseGo/internal/utils"
@@ -13,12 +14,14 @@ import (
 // AuthMiddleware maneja la autenticación JWT
 type AuthMiddleware struct {
 	authService *services.AuthService
+	cookies     *utils.CookieManager
 }
 
 // NewAuthMiddleware crea una nueva instancia del middleware de autenticación
 func NewAuthMiddleware() *AuthMiddleware {
 	return &AuthMiddleware{
 		authService: services.NewAuthService(),
+		cookies:     utils.NewCookieManager(),
 	}
 }
 
@@ -27,6 +30,13 @@ func (m *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Obtener token del header Authorization
 		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" {
+			// En modo cookies el navegador envía el access token en una cookie HttpOnly
+			if cookieToken, ok := m.cookies.GetAccessToken(c); ok {
+				authHeader = "Bearer " + cookieToken
+				c.Set("auth_via_cookie", true)
+			}
+		}
 		if authHeader == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error": "Authorization header required",
@@ -69,26 +79,35 @@ func (m *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 	}
 }
 
-// RequireRole middleware que requiere un rol específico
+// RequireRole middleware que requiere un rol específico.
+// Debe usarse después de RequireAuth, que deja el rol del usuario en el contexto.
 func (m *AuthMiddleware) RequireRole(roleName string) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// Primero verificar autenticación
-		m.RequireAuth()(c)
-		if c.IsAborted() {
-			return
-		}
+	return m.RequireAnyRole(roleName)
+}
 
-		// Verificar rol
+// RequireAnyRole middleware que requiere uno de varios roles.
+// Debe usarse después de RequireAuth, que deja el rol del usuario en el contexto.
+func (m *AuthMiddleware) RequireAnyRole(roleNames ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
 		userRoleName, exists := c.Get("role_name")
 		if !exists {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Role information not found",
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "User not authenticated",
 			})
 			c.Abort()
 			return
 		}
 
-		if userRoleName != roleName {
+		// Verificar si el usuario tiene alguno de los roles permitidos
+		roleMatches := false
+		for _, roleName := range roleNames {
+			if userRoleName == roleName {
+				roleMatches = true
+				break
+			}
+		}
+
+		if !roleMatches {
 			c.JSON(http.StatusForbidden, gin.H{
 				"error": "Insufficient permissions",
 			})
@@ -100,38 +119,21 @@ func (m *AuthMiddleware) RequireRole(roleName string) gin.HandlerFunc {
 	}
 }
 
-// RequireAnyRole middleware que requiere uno de varios roles
-func (m *AuthMiddleware) RequireAnyRole(roleNames ...string) gin.HandlerFunc {
+// RequireRecentAuth middleware que exige que el usuario haya demostrado su contraseña
+// hace menos de maxAge (claim auth_time). Debe usarse después de RequireAuth.
+func (m *AuthMiddleware) RequireRecentAuth(maxAge time.Duration) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Primero verificar autenticación
-		m.RequireAuth()(c)
-		if c.IsAborted() {
-			return
-		}
-
-		// Verificar si el usuario tiene alguno de los roles permitidos
-		userRoleName, exists := c.Get("role_name")
+		claims, exists := GetCurrentUserClaims(c)
 		if !exists {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Role information not found",
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "User not authenticated",
 			})
 			c.Abort()
 			return
 		}
 
-		roleMatches := false
-		for _, roleName := range roleNames {
-			if userRoleName == roleName {
-				roleMatches = true
-				break
-			}
-		}
-
-		if !roleMatches {
-			c.JSON(http.StatusForbidden, gin.H{
-				"error": "Insufficient permissions",
-			})
-			c.Abort()
+		if !RecentlyAuthenticated(claims, maxAge) {
+			AbortReauthenticationRequired(c)
 			return
 		}
 
@@ -139,10 +141,38 @@ func (m *AuthMiddleware) RequireAnyRole(roleNames ...string) gin.HandlerFunc {
 	}
 }
 
+// StepUpMaxAge antigüedad máxima de la última comprobación de contraseña para las operaciones
+// sensibles (STEP_UP_MAX_AGE_MINUTES)
+func StepUpMaxAge() time.Duration {
+	return time.Duration(utils.GetEnvInt("STEP_UP_MAX_AGE_MINUTES", 5)) * time.Minute
+}
+
+// RecentlyAuthenticated indica si el token demuestra una comprobación de contraseña de hace
+// menos de maxAge; sirve a los handlers que solo exigen step-up para parte de la petición
+func RecentlyAuthenticated(claims *utils.JWTClaims, maxAge time.Duration) bool {
+	return claims != nil && claims.AuthTime != nil && time.Since(claims.AuthTime.Time) <= maxAge
+}
+
+// AbortReauthenticationRequired responde 403 indicando al cliente cómo re-autenticarse
+func AbortReauthenticationRequired(c *gin.Context) {
+	c.JSON(http.StatusForbidden, gin.H{
+		"error":                     "Recent authentication required",
+		"reauthentication_required": true,
+		"reauthenticate":            "POST /api/v1/auth/reauthenticate",
+	})
+	c.Abort()
+}
+
 // OptionalAuth middleware que permite autenticación opcional
 func (m *AuthMiddleware) OptionalAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" {
+			if cookieToken, ok := m.cookies.GetAccessToken(c); ok {
+				authHeader = "Bearer " + cookieToken
+				c.Set("auth_via_cookie", true)
+			}
+		}
 		if authHeader == "" {
 			// No hay token, continuar sin autenticación
 			c.Next()
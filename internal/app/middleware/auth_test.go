@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequireRole(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := &AuthMiddleware{}
+
+	tests := []struct {
+		name       string
+		roleName   string // "" = sin autenticar
+		middleware gin.HandlerFunc
+		wantStatus int
+		wantRun    bool
+	}{
+		{"admin allowed", "admin", m.RequireRole("admin"), http.StatusOK, true},
+		{"non-admin forbidden", "user", m.RequireRole("admin"), http.StatusForbidden, false},
+		{"unauthenticated", "", m.RequireRole("admin"), http.StatusUnauthorized, false},
+		{"any role allowed", "editor", m.RequireAnyRole("admin", "editor"), http.StatusOK, true},
+		{"any role forbidden", "user", m.RequireAnyRole("admin", "editor"), http.StatusForbidden, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ran := false
+			router := gin.New()
+			router.GET("/secret", func(c *gin.Context) {
+				if tt.roleName != "" {
+					c.Set("role_name", tt.roleName)
+				}
+			}, tt.middleware, func(c *gin.Context) {
+				ran = true
+				c.JSON(http.StatusOK, gin.H{"secret": true})
+			})
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/secret", nil))
+
+			if recorder.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d (body %s)", recorder.Code, tt.wantStatus, recorder.Body.String())
+			}
+			if ran != tt.wantRun {
+				t.Errorf("handler ran = %v, want %v", ran, tt.wantRun)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+
+	"megabaseGo/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CSRFMiddleware protege las rutas que modifican estado cuando la sesión viaja en cookies
+type CSRFMiddleware struct {
+	cookies *utils.CookieManager
+}
+
+// NewCSRFMiddleware crea una nueva instancia del middleware CSRF
+func NewCSRFMiddleware() *CSRFMiddleware {
+	return &CSRFMiddleware{
+		cookies: utils.NewCookieManager(),
+	}
+}
+
+// Protect exige el token CSRF (double-submit) en peticiones POST, PUT, PATCH y DELETE
+// que llevan cookies de sesión. Los clientes con Authorization: Bearer no se ven afectados.
+func (m *CSRFMiddleware) Protect() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+
+		if c.GetHeader("Authorization") != "" || !m.cookies.HasSessionCookies(c) {
+			c.Next()
+			return
+		}
+
+		if !m.cookies.ValidCSRF(c) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Invalid or missing CSRF token",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
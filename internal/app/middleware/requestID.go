@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"regexp"
+
+	"megabaseGo/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader cabecera con el identificador de la petición (se acepta del cliente o proxy y se devuelve)
+const RequestIDHeader = "X-Request-ID"
+
+// Solo se reutilizan identificadores cortos y sin caracteres raros; el resto se sustituye
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// RequestID asigna a cada petición un identificador que se devuelve en la respuesta y
+// queda en el log de auditoría para correlacionar eventos de una misma petición
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !requestIDPattern.MatchString(requestID) {
+			requestID, _ = utils.GenerateRandomToken(16)
+		}
+
+		c.Set("request_id", requestID)
+		c.Header(RequestIDHeader, requestID)
+		c.Next()
+	}
+}
+
+// GetRequestID obtiene el identificador de la petición actual
+func GetRequestID(c *gin.Context) string {
+	return c.GetString("request_id")
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"megabaseGo/internal/app/dto"
+	"megabaseGo/internal/app/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ScimMiddleware autentica a los clientes de aprovisionamiento SCIM
+type ScimMiddleware struct {
+	scimService *services.ScimService
+}
+
+// NewScimMiddleware crea una nueva instancia del middleware SCIM
+func NewScimMiddleware() *ScimMiddleware {
+	return &ScimMiddleware{
+		scimService: services.NewScimService(),
+	}
+}
+
+// RequireClient exige un token bearer de un cliente SCIM activo
+func (m *ScimMiddleware) RequireClient() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		parts := strings.SplitN(c.GetHeader("Authorization"), " ", 2)
+		if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") || parts[1] == "" {
+			m.unauthorized(c, "Authorization: Bearer <token> required")
+			return
+		}
+
+		client, err := m.scimService.AuthenticateClient(parts[1])
+		if err != nil {
+			m.unauthorized(c, "Invalid or revoked SCIM token")
+			return
+		}
+
+		c.Set("scim_client_id", client.ID)
+		c.Set("scim_client_name", client.Name)
+		c.Next()
+	}
+}
+
+func (m *ScimMiddleware) unauthorized(c *gin.Context, detail string) {
+	c.Header("WWW-Authenticate", `Bearer realm="scim"`)
+	c.Header("Content-Type", "application/scim+json")
+	c.AbortWithStatusJSON(http.StatusUnauthorized, dto.ScimErrorResponse{
+		Schemas: []string{dto.ScimErrorSchema},
+		Status:  "401",
+		Detail:  detail,
+	})
+}
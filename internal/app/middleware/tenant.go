@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"net/http"
+
+	"megabaseGo/internal/app/services"
+	"megabaseGo/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OrganizationHeader cabecera con la que el cliente elige la organización activa (slug o ID)
+const OrganizationHeader = "X-Organization"
+
+// TenantMiddleware resuelve la organización (tenant) de cada petición autenticada
+type TenantMiddleware struct {
+	organizationService *services.OrganizationService
+}
+
+// NewTenantMiddleware crea una nueva instancia del middleware de tenant
+func NewTenantMiddleware() *TenantMiddleware {
+	return &TenantMiddleware{
+		organizationService: services.NewOrganizationService(),
+	}
+}
+
+// RequireTenant exige una organización activa: la de la cabecera X-Organization, la del token
+// o la única del usuario. El usuario debe ser miembro; debe ir después de RequireAuth.
+func (m *TenantMiddleware) RequireTenant() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := GetCurrentUserID(c)
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "User not authenticated",
+			})
+			c.Abort()
+			return
+		}
+
+		var tokenOrganizationID uint
+		if claims, ok := GetCurrentUserClaims(c); ok {
+			tokenOrganizationID = claims.OrganizationID
+		}
+
+		tenant, err := m.organizationService.ResolveTenant(userID, c.GetHeader(OrganizationHeader), tokenOrganizationID)
+		if err != nil {
+			utils.HandleError(c, err)
+			c.Abort()
+			return
+		}
+		if tenant == nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Organization required: send the " + OrganizationHeader + " header",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Set("tenant", tenant)
+		c.Next()
+	}
+}
+
+// GetCurrentTenant obtiene la organización activa de la petición
+func GetCurrentTenant(c *gin.Context) (*services.Tenant, bool) {
+	tenant, exists := c.Get("tenant")
+	if !exists {
+		return nil, false
+	}
+	return tenant.(*services.Tenant), true
+}
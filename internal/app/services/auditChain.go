@@ -0,0 +1,299 @@
+package services
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"megabaseGo/internal/database"
+	"megabaseGo/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Clave del advisory lock que serializa las inserciones en la cadena de auditoría
+const auditChainLockKey = 7_301_038
+
+// AuditChainReport resultado de verificar la cadena de auditoría
+type AuditChainReport struct {
+	// Checked entradas encadenadas verificadas
+	Checked int64
+	// Legacy entradas anteriores a la cadena (sin hash), que no se pueden verificar
+	Legacy int64
+	// HeadID y HeadHash última entrada válida de la cadena
+	HeadID   uint
+	HeadHash string
+	// AnchorsChecked anclajes del fichero de anclaje contrastados con la cadena
+	AnchorsChecked int
+	// BrokenAt primera entrada que rompe la cadena; 0 si está íntegra
+	BrokenAt uint
+	Reason   string
+}
+
+// Valid indica si la cadena está íntegra
+func (r *AuditChainReport) Valid() bool {
+	return r.BrokenAt == 0 && r.Reason == ""
+}
+
+// AuditAnchor copia de la cabeza de la cadena guardada fuera de la base de datos
+type AuditAnchor struct {
+	AnchoredAt time.Time `json:"anchored_at"`
+	EntryID    uint      `json:"entry_id"`
+	Hash       string    `json:"hash"`
+	Signature  string    `json:"signature,omitempty"`
+}
+
+// auditHashInput forma canónica de una entrada para calcular su hash. Los JSON se
+// decodifican y se vuelven a codificar porque jsonb no conserva el formato original.
+type auditHashInput struct {
+	PrevHash       string      `json:"prev_hash"`
+	ActorID        *uint       `json:"actor_id"`
+	ActorName      string      `json:"actor_name"`
+	Action         string      `json:"action"`
+	TargetType     string      `json:"target_type"`
+	TargetID       string      `json:"target_id"`
+	OrganizationID *uint       `json:"organization_id"`
+	Changes        interface{} `json:"changes"`
+	Metadata       interface{} `json:"metadata"`
+	IPAddress      string      `json:"ip_address"`
+	UserAgent      string      `json:"user_agent"`
+	RequestID      string      `json:"request_id"`
+	CreatedAt      string      `json:"created_at"`
+}
+
+// appendToChain inserta la entrada enlazada con la última de la cadena. El advisory lock
+// dura hasta el final de la transacción exterior, así que no hay dos entradas con el mismo padre.
+func (s *AuditService) appendToChain(db *gorm.DB, entry *models.AuditLog) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", auditChainLockKey).Error; err != nil {
+			return err
+		}
+
+		var previous models.AuditLog
+		if err := tx.Select("id", "hash").Order("id DESC").Limit(1).Find(&previous).Error; err != nil {
+			return err
+		}
+
+		// Postgres guarda microsegundos: se trunca antes de calcular el hash para poder recalcularlo
+		entry.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+		entry.PrevHash = previous.Hash
+		entry.Hash = auditEntryHash(entry)
+		entry.Signature = s.sign(entry.Hash)
+		return tx.Create(entry).Error
+	})
+}
+
+// VerifyChain recorre toda la cadena en orden comprobando enlaces, hashes y firmas, y
+// contrasta los anclajes guardados en AUDIT_ANCHOR_FILE. Se detiene en el primer fallo.
+func (s *AuditService) VerifyChain() (*AuditChainReport, error) {
+	report := &AuditChainReport{}
+	signed := false
+
+	var entries []models.AuditLog
+	result := database.GetDB().Order("id").FindInBatches(&entries, 1000, func(tx *gorm.DB, batch int) error {
+		for i := range entries {
+			entry := &entries[i]
+
+			if entry.Hash == "" {
+				if report.Checked == 0 {
+					report.Legacy++
+					continue
+				}
+				return s.breakChain(report, entry.ID, "entry without hash after the start of the chain")
+			}
+
+			if entry.PrevHash != report.HeadHash {
+				return s.breakChain(report, entry.ID, "previous hash does not match the preceding entry (entry removed or reordered)")
+			}
+			if auditEntryHash(entry) != entry.Hash {
+				return s.breakChain(report, entry.ID, "hash does not match the entry content (entry altered)")
+			}
+
+			// Con clave, una vez aparece una entrada firmada todas las siguientes deben estarlo
+			if len(s.signingKey) > 0 && (entry.Signature != "" || signed) {
+				if !hmac.Equal([]byte(entry.Signature), []byte(s.sign(entry.Hash))) {
+					return s.breakChain(report, entry.ID, "invalid signature")
+				}
+				signed = true
+			}
+
+			report.Checked++
+			report.HeadID = entry.ID
+			report.HeadHash = entry.Hash
+		}
+		return nil
+	})
+	if result.Error != nil && !errors.Is(result.Error, errAuditChainBroken) {
+		return nil, result.Error
+	}
+	if !report.Valid() {
+		return report, nil
+	}
+
+	return report, s.verifyAnchors(report)
+}
+
+var errAuditChainBroken = errors.New("audit chain broken")
+
+func (s *AuditService) breakChain(report *AuditChainReport, entryID uint, reason string) error {
+	report.BrokenAt = entryID
+	report.Reason = reason
+	return errAuditChainBroken
+}
+
+// verifyAnchors comprueba que cada cabeza anclada sigue en la cadena con el mismo hash;
+// detecta el borrado de las últimas entradas, que la cadena por sí sola no delata
+func (s *AuditService) verifyAnchors(report *AuditChainReport) error {
+	file, err := os.Open(s.anchorFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	db := database.GetDB()
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		var anchor AuditAnchor
+		if err := json.Unmarshal(scanner.Bytes(), &anchor); err != nil {
+			return fmt.Errorf("invalid anchor at %s:%d: %w", s.anchorFile, line, err)
+		}
+		report.AnchorsChecked++
+
+		var entry models.AuditLog
+		err := db.Select("id", "hash").Where("id = ?", anchor.EntryID).Take(&entry).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			report.BrokenAt = anchor.EntryID
+			report.Reason = fmt.Sprintf("anchored entry is missing (anchor line %d)", line)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if entry.Hash != anchor.Hash {
+			report.BrokenAt = anchor.EntryID
+			report.Reason = fmt.Sprintf("entry hash differs from the anchored hash (anchor line %d)", line)
+			return nil
+		}
+	}
+	return scanner.Err()
+}
+
+// AnchorHead añade la cabeza actual de la cadena al fichero de anclaje (AUDIT_ANCHOR_FILE)
+// para archivarla fuera de la base de datos. Devuelve nil si la cadena está vacía.
+func (s *AuditService) AnchorHead() (*AuditAnchor, error) {
+	var head models.AuditLog
+	err := database.GetDB().Select("id", "hash", "signature").Where("hash <> ''").Order("id DESC").Take(&head).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	anchor := &AuditAnchor{
+		AnchoredAt: time.Now().UTC(),
+		EntryID:    head.ID,
+		Hash:       head.Hash,
+		Signature:  head.Signature,
+	}
+	line, err := json.Marshal(anchor)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.anchorFile), 0o750); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(s.anchorFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o640)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return nil, err
+	}
+	return anchor, file.Sync()
+}
+
+// RunAnchoring ancla la cabeza de la cadena cada interval mientras el proceso esté vivo.
+// Solo escribe si han llegado entradas nuevas desde el último anclaje.
+func (s *AuditService) RunAnchoring(interval time.Duration) {
+	var lastEntryID uint
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		var head models.AuditLog
+		if err := database.GetDB().Select("id").Order("id DESC").Limit(1).Find(&head).Error; err != nil {
+			log.Printf("Error leyendo la cabeza de la cadena de auditoría: %v", err)
+			continue
+		}
+		if head.ID == 0 || head.ID == lastEntryID {
+			continue
+		}
+
+		anchor, err := s.AnchorHead()
+		if err != nil {
+			log.Printf("Error anclando la cadena de auditoría: %v", err)
+			continue
+		}
+		if anchor != nil {
+			lastEntryID = anchor.EntryID
+		}
+	}
+}
+
+func (s *AuditService) sign(hash string) string {
+	if len(s.signingKey) == 0 {
+		return ""
+	}
+	mac := hmac.New(sha256.New, s.signingKey)
+	mac.Write([]byte(hash))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// auditEntryHash calcula el hash de una entrada a partir de su forma canónica
+func auditEntryHash(entry *models.AuditLog) string {
+	input := auditHashInput{
+		PrevHash:       entry.PrevHash,
+		ActorID:        entry.ActorID,
+		ActorName:      entry.ActorName,
+		Action:         entry.Action,
+		TargetType:     entry.TargetType,
+		TargetID:       entry.TargetID,
+		OrganizationID: entry.OrganizationID,
+		Changes:        canonicalJSON(entry.Changes),
+		Metadata:       canonicalJSON(entry.Metadata),
+		IPAddress:      entry.IPAddress,
+		UserAgent:      entry.UserAgent,
+		RequestID:      entry.RequestID,
+		CreatedAt:      entry.CreatedAt.UTC().Format(time.RFC3339Nano),
+	}
+	encoded, _ := json.Marshal(input)
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:])
+}
+
+// canonicalJSON decodifica un JSON para volver a codificarlo con claves ordenadas y sin espacios
+func canonicalJSON(raw json.RawMessage) interface{} {
+	if len(raw) == 0 {
+		return nil
+	}
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return string(raw)
+	}
+	return value
+}
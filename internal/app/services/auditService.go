@@ -0,0 +1,367 @@
+package services
+
+import (
+	"encoding/json"
+	"log"
+	"reflect"
+	"strconv"
+
+	"megabaseGo/internal/app/dto"
+	"megabaseGo/internal/database"
+	"megabaseGo/internal/database/encryption"
+	"megabaseGo/internal/models"
+	"megabaseGo/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// Actor usuario que realiza una operación auditada y datos de la petición desde la que la hace
+type Actor struct {
+	UserID   uint
+	UserName string
+	Meta     *dto.RequestMeta
+}
+
+// auditEvent datos de un evento a registrar en el log de auditoría
+type auditEvent struct {
+	Action         string
+	TargetType     string
+	TargetID       uint
+	OrganizationID *uint
+	Changes        auditChanges
+	Metadata       map[string]interface{}
+}
+
+// auditChange valor anterior y nuevo de un campo
+type auditChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+type auditChanges map[string]auditChange
+
+// Los secretos nunca llegan al log: solo consta que cambiaron
+const auditRedacted = "[redacted]"
+
+// Contexto del AAD de los valores cifrados en cambios y metadatos
+const auditEncryptionScope = "audit_logs"
+
+// auditEncryptedFields campos personales guardados cifrados: los cifrados del usuario y el
+// identificador (email o usuario) de los intentos de login
+var auditEncryptedFields = append(append([]string{}, models.UserEncryptedFields...), "identifier")
+
+// sealsPersonalFields indica si los campos de auditEncryptedFields de una entrada son datos
+// personales: en las de usuarios y en los logins fallidos (también los de cuentas inexistentes).
+// En el resto (p.ej. el nombre de un rol) se guardan en claro para poder buscarlos.
+func sealsPersonalFields(action, targetType string) bool {
+	return targetType == models.AuditTargetUser || action == models.AuditLoginFailed
+}
+
+type AuditService struct {
+	signingKey []byte
+	anchorFile string
+}
+
+// NewAuditService crea una nueva instancia del servicio de auditoría
+func NewAuditService() *AuditService {
+	return &AuditService{
+		signingKey: []byte(utils.GetEnv("AUDIT_SIGNING_KEY", "")),
+		anchorFile: utils.GetEnv("AUDIT_ANCHOR_FILE", "storage/audit/anchors.jsonl"),
+	}
+}
+
+// Record inserta un evento usando db, normalmente la transacción de la operación auditada:
+// si no se puede registrar, la operación tampoco se confirma. La entrada se encadena a la
+// anterior con su hash (ver auditChain.go).
+func (s *AuditService) Record(db *gorm.DB, actor *Actor, event auditEvent) error {
+	entry := models.AuditLog{
+		Action:         event.Action,
+		TargetType:     event.TargetType,
+		OrganizationID: event.OrganizationID,
+	}
+	if event.TargetID != 0 {
+		entry.TargetID = strconv.FormatUint(uint64(event.TargetID), 10)
+	}
+	if actor != nil {
+		if actor.UserID != 0 {
+			entry.ActorID = &actor.UserID
+		}
+		entry.ActorName = actor.UserName
+		if actor.Meta != nil {
+			entry.IPAddress = actor.Meta.IPAddress
+			entry.UserAgent = truncate(actor.Meta.UserAgent, 512)
+			entry.RequestID = actor.Meta.RequestID
+		}
+	}
+
+	seal := sealsPersonalFields(event.Action, event.TargetType)
+	var err error
+	if len(event.Changes) > 0 {
+		changes := event.Changes
+		if seal {
+			if changes, err = sealAuditChanges(changes); err != nil {
+				return err
+			}
+		}
+		if entry.Changes, err = json.Marshal(changes); err != nil {
+			return err
+		}
+	}
+	if len(event.Metadata) > 0 {
+		metadata := event.Metadata
+		if seal {
+			metadata = make(map[string]interface{}, len(event.Metadata))
+			for key, value := range event.Metadata {
+				metadata[key] = value
+			}
+			if err := encryption.SealFields(auditEncryptionScope, metadata, auditEncryptedFields); err != nil {
+				return err
+			}
+		}
+		if entry.Metadata, err = json.Marshal(metadata); err != nil {
+			return err
+		}
+	}
+
+	return s.appendToChain(db, &entry)
+}
+
+// Log registra un evento fuera de una transacción (eventos de autenticación).
+// Un fallo se anota en el log de la aplicación pero no interrumpe el flujo.
+func (s *AuditService) Log(actor *Actor, event auditEvent) {
+	if err := s.Record(database.GetDB(), actor, event); err != nil {
+		log.Printf("Error registrando evento de auditoría %s: %v", event.Action, err)
+	}
+}
+
+// Search busca entradas del log de auditoría, de la más reciente a la más antigua
+func (s *AuditService) Search(filter *dto.AuditFilter, limit, offset int) ([]dto.AuditLogResponse, int64, error) {
+	query := s.filtered(filter)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var entries []models.AuditLog
+	if err := query.Order("id DESC").Limit(limit).Offset(offset).Find(&entries).Error; err != nil {
+		return nil, 0, err
+	}
+
+	responses, err := s.toAuditLogResponses(entries)
+	if err != nil {
+		return nil, 0, err
+	}
+	return responses, total, nil
+}
+
+// Export recorre en orden cronológico y por lotes todas las entradas que cumplen el filtro
+func (s *AuditService) Export(filter *dto.AuditFilter, emit func(entry *dto.AuditLogResponse) error) error {
+	var entries []models.AuditLog
+	var emitErr error
+	result := s.filtered(filter).FindInBatches(&entries, 500, func(tx *gorm.DB, batch int) error {
+		responses, err := s.toAuditLogResponses(entries)
+		if err != nil {
+			return err
+		}
+		for i := range responses {
+			if emitErr = emit(&responses[i]); emitErr != nil {
+				return emitErr
+			}
+		}
+		return nil
+	})
+	if emitErr != nil {
+		return emitErr
+	}
+	return result.Error
+}
+
+func (s *AuditService) filtered(filter *dto.AuditFilter) *gorm.DB {
+	query := database.GetDB().Model(&models.AuditLog{})
+	if filter == nil {
+		return query
+	}
+	if filter.ActorID != nil {
+		query = query.Where("actor_id = ?", *filter.ActorID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.TargetType != "" {
+		query = query.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		query = query.Where("target_id = ?", filter.TargetID)
+	}
+	if filter.OrganizationID != nil {
+		query = query.Where("organization_id = ?", *filter.OrganizationID)
+	}
+	if filter.RequestID != "" {
+		query = query.Where("request_id = ?", filter.RequestID)
+	}
+	if filter.IPAddress != "" {
+		query = query.Where("ip_address = ?", filter.IPAddress)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+	return query
+}
+
+// toAuditLogResponses convierte las entradas seudonimizando las de usuarios suprimidos
+func (s *AuditService) toAuditLogResponses(entries []models.AuditLog) ([]dto.AuditLogResponse, error) {
+	responses := make([]dto.AuditLogResponse, 0, len(entries))
+	for i := range entries {
+		response, err := s.toAuditLogResponse(&entries[i])
+		if err != nil {
+			return nil, err
+		}
+		responses = append(responses, *response)
+	}
+	if err := pseudonymizeAuditEntries(database.GetDB(), responses); err != nil {
+		return nil, err
+	}
+	return responses, nil
+}
+
+func (s *AuditService) toAuditLogResponse(entry *models.AuditLog) (*dto.AuditLogResponse, error) {
+	changes, metadata := entry.Changes, entry.Metadata
+	if sealsPersonalFields(entry.Action, entry.TargetType) {
+		var err error
+		if changes, err = openAuditChanges(entry.Changes); err != nil {
+			return nil, err
+		}
+		if metadata, err = openAuditMetadata(entry.Metadata); err != nil {
+			return nil, err
+		}
+	}
+	return &dto.AuditLogResponse{
+		ID:             entry.ID,
+		ActorID:        entry.ActorID,
+		ActorName:      entry.ActorName,
+		Action:         entry.Action,
+		TargetType:     entry.TargetType,
+		TargetID:       entry.TargetID,
+		OrganizationID: entry.OrganizationID,
+		Changes:        changes,
+		Metadata:       metadata,
+		IPAddress:      entry.IPAddress,
+		UserAgent:      entry.UserAgent,
+		RequestID:      entry.RequestID,
+		CreatedAt:      entry.CreatedAt,
+		PrevHash:       entry.PrevHash,
+		Hash:           entry.Hash,
+		Signature:      entry.Signature,
+	}, nil
+}
+
+// sealAuditChanges copia los cambios cifrando los valores de los campos cifrados del usuario.
+// La cadena de hashes se calcula sobre el JSON ya cifrado, así que verificarla no necesita las claves.
+func sealAuditChanges(changes auditChanges) (auditChanges, error) {
+	sealed := make(auditChanges, len(changes))
+	for field, change := range changes {
+		sealed[field] = change
+	}
+	for _, field := range auditEncryptedFields {
+		change, exists := sealed[field]
+		if !exists {
+			continue
+		}
+		values := map[string]interface{}{"old": change.Old, "new": change.New}
+		if err := encryption.SealFields(auditEncryptionScope+"."+field, values, []string{"old", "new"}); err != nil {
+			return nil, err
+		}
+		sealed[field] = auditChange{Old: values["old"], New: values["new"]}
+	}
+	return sealed, nil
+}
+
+// openAuditChanges descifra los cambios guardados por sealAuditChanges. Las entradas anteriores
+// al cifrado siguen en claro: el log es de solo inserción y no se reescriben.
+func openAuditChanges(raw json.RawMessage) (json.RawMessage, error) {
+	if len(raw) == 0 {
+		return raw, nil
+	}
+	var changes map[string]interface{}
+	if err := json.Unmarshal(raw, &changes); err != nil {
+		return raw, nil
+	}
+	for _, field := range auditEncryptedFields {
+		change, isChange := changes[field].(map[string]interface{})
+		if !isChange {
+			continue
+		}
+		if err := encryption.OpenFields(auditEncryptionScope+"."+field, change, []string{"old", "new"}); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(changes)
+}
+
+// openAuditMetadata descifra los campos cifrados de los metadatos de una entrada
+func openAuditMetadata(raw json.RawMessage) (json.RawMessage, error) {
+	if len(raw) == 0 {
+		return raw, nil
+	}
+	var metadata map[string]interface{}
+	if err := json.Unmarshal(raw, &metadata); err != nil {
+		return raw, nil
+	}
+	if err := encryption.OpenFields(auditEncryptionScope, metadata, auditEncryptedFields); err != nil {
+		return nil, err
+	}
+	return json.Marshal(metadata)
+}
+
+// auditDiff compara dos estados y devuelve solo los campos que cambian.
+// Un estado nil representa la ausencia del objeto (creación o borrado).
+func auditDiff(before, after map[string]interface{}) auditChanges {
+	changes := auditChanges{}
+	for field, newValue := range after {
+		oldValue, existed := before[field]
+		if !existed || !reflect.DeepEqual(oldValue, newValue) {
+			changes[field] = auditChange{Old: oldValue, New: newValue}
+		}
+	}
+	for field, oldValue := range before {
+		if _, exists := after[field]; !exists {
+			changes[field] = auditChange{Old: oldValue}
+		}
+	}
+	return changes
+}
+
+// userAuditState campos auditados de un usuario; roleID es el rol efectivo (el de la membresía con tenant)
+func userAuditState(user *models.User, roleID uint) map[string]interface{} {
+	return map[string]interface{}{
+		"name":      user.Name,
+		"user_name": user.UserName,
+		"email":     user.Email,
+		"role_id":   roleID,
+		"is_active": user.IsActive,
+	}
+}
+
+// roleAuditState campos auditados de un rol
+func roleAuditState(role *models.Role) map[string]interface{} {
+	return map[string]interface{}{
+		"name":         role.Name,
+		"display_name": role.DisplayName,
+		"description":  role.Description,
+		"is_active":    role.IsActive,
+	}
+}
+
+// actorFor construye el actor de un evento de autenticación en el que el propio usuario actúa
+func actorFor(user *models.User, meta *dto.RequestMeta) *Actor {
+	actor := &Actor{Meta: meta}
+	if user != nil {
+		actor.UserID = user.ID
+		actor.UserName = user.UserName
+	}
+	return actor
+}
@@ -13,28 +13,36 @@ import (
 )
 
 type AuthService struct {
-	userService *UserService
-	jwtManager  *utils.JWTManager
-	hasher      utils.PasswordHasher
+	userService  *UserService
+	loginHistory *LoginHistoryService
+	audit        *AuditService
+	sessions     *SessionService
+	jwtManager   *utils.JWTManager
+	hasher       utils.PasswordHasher
 }
 
 // NewAuthService crea una nueva instancia del servicio de autenticación
 func NewAuthService() *AuthService {
 	return &AuthService{
-		userService: NewUserService(),
-		jwtManager:  utils.NewJWTManager(),
-		hasher:      utils.NewBcryptHasher(),
+		userService:  NewUserService(),
+		loginHistory: NewLoginHistoryService(),
+		audit:        NewAuditService(),
+		sessions:     NewSessionService(),
+		jwtManager:   utils.NewJWTManager(),
+		hasher:       utils.NewBcryptHasher(),
 	}
 }
 
-// Login autentica un usuario y retorna tokens
-func (s *AuthService) Login(req *dto.LoginRequest) (*dto.AuthResponse, error) {
+// Login autentica un usuario y retorna tokens. Cada intento queda en el historial de login y en la auditoría.
+func (s *AuthService) Login(req *dto.LoginRequest, meta *dto.RequestMeta) (*dto.AuthResponse, error) {
 	db := database.GetDB()
+	identifier := req.Identifier()
 
-	// Buscar usuario por username con rol
+	// Buscar usuario por username o email (sin distinguir mayúsculas) con rol
 	var user models.User
-	if err := db.Preload("Role").Where("user_name = ?", req.UserName).First(&user).Error; err != nil {
+	if err := db.Preload("Role").Scopes(byLoginIdentifier(identifier)).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
+			s.recordLoginFailure(nil, identifier, "unknown_user", models.LoginMethodPassword, meta)
 			return nil, errors.New("invalid credentials")
 		}
 		return nil, err
@@ -42,49 +50,40 @@ func (s *AuthService) Login(req *dto.LoginRequest) (*dto.AuthResponse, error) {
 
 	// Verificar que el usuario esté activo
 	if !user.IsActive {
+		s.recordLoginFailure(&user.ID, identifier, "account_disabled", models.LoginMethodPassword, meta)
 		return nil, errors.New("user account is disabled")
 	}
 
+	// Las cuentas creadas por SSO/SCIM no admiten login con contraseña
+	if !user.HasPassword {
+		s.recordLoginFailure(&user.ID, identifier, "password_not_set", models.LoginMethodPassword, meta)
+		return nil, errors.New("invalid credentials")
+	}
+
 	// Verificar contraseña
 	if err := s.hasher.ComparePassword(user.Password, req.Password); err != nil {
+		s.recordLoginFailure(&user.ID, identifier, "invalid_password", models.LoginMethodPassword, meta)
 		return nil, errors.New("invalid credentials")
 	}
 
 	// Actualizar último login
 	user.LastLoginAt = time.Now()
 	db.Save(&user)
-
-	// Generar tokens
-	accessToken, err := s.jwtManager.GenerateToken(
-		user.ID,
-		user.UserName,
-		user.Email,
-		user.RoleID,
-		user.Role.Name,
-	)
-	if err != nil {
-		return nil, errors.New("failed to generate access token")
-	}
-
-	refreshToken, err := s.jwtManager.GenerateRefreshToken(user.ID)
-	if err != nil {
-		return nil, errors.New("failed to generate refresh token")
-	}
-
-	// Crear respuesta
-	userResponse := s.toUserResponse(&user)
-
-	return &dto.AuthResponse{
-		User:         *userResponse,
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-		TokenType:    "Bearer",
-		ExpiresIn:    s.jwtManager.GetTokenDuration(),
-	}, nil
+	s.recordLogin(&user, models.LoginMethodPassword, meta)
+
+	// Generar tokens; "recordarme" y el cliente eligen el perfil de sesión
+	authTime := user.LastLoginAt
+	return s.issueAuthResponse(&user, authTime, sessionOptions{
+		ClientID:       req.ClientID,
+		RememberMe:     req.RememberMe,
+		Organization:   req.Organization,
+		PasswordAuthAt: &authTime,
+		Meta:           meta,
+	})
 }
 
 // Register registra un nuevo usuario
-func (s *AuthService) Register(req *dto.RegisterRequest) (*dto.AuthResponse, error) {
+func (s *AuthService) Register(req *dto.RegisterRequest, meta *dto.RequestMeta) (*dto.AuthResponse, error) {
 	// Usar el UserService para crear el usuario
 	createUserReq := &dto.CreateUserRequest{
 		Name:     req.Name,
@@ -94,7 +93,7 @@ func (s *AuthService) Register(req *dto.RegisterRequest) (*dto.AuthResponse, err
 		RoleID:   req.RoleID,
 	}
 
-	userResponse, err := s.userService.CreateUser(createUserReq)
+	createdUser, err := s.userService.ForActor(&Actor{Meta: meta}).CreateUser(createUserReq)
 	if err != nil {
 		return nil, err
 	}
@@ -102,40 +101,25 @@ func (s *AuthService) Register(req *dto.RegisterRequest) (*dto.AuthResponse, err
 	// Obtener el usuario completo con rol para generar tokens
 	db := database.GetDB()
 	var user models.User
-	if err := db.Preload("Role").First(&user, userResponse.ID).Error; err != nil {
+	if err := db.Preload("Role").First(&user, createdUser.ID).Error; err != nil {
 		return nil, err
 	}
 
-	// Generar tokens
-	accessToken, err := s.jwtManager.GenerateToken(
-		user.ID,
-		user.UserName,
-		user.Email,
-		user.RoleID,
-		user.Role.Name,
-	)
-	if err != nil {
-		return nil, errors.New("failed to generate access token")
-	}
-
-	refreshToken, err := s.jwtManager.GenerateRefreshToken(user.ID)
-	if err != nil {
-		return nil, errors.New("failed to generate refresh token")
-	}
+	s.audit.Log(actorFor(&user, meta), auditEvent{
+		Action:     models.AuditRegister,
+		TargetType: models.AuditTargetUser,
+		TargetID:   user.ID,
+	})
 
-	return &dto.AuthResponse{
-		User:         *userResponse,
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-		TokenType:    "Bearer",
-		ExpiresIn:    s.jwtManager.GetTokenDuration(),
-	}, nil
+	// Generar tokens
+	return s.issueAuthResponse(&user, time.Now(), sessionOptions{Meta: meta})
 }
 
-// RefreshToken genera un nuevo access token usando el refresh token
-func (s *AuthService) RefreshToken(req *dto.RefreshTokenRequest) (*dto.AuthResponse, error) {
+// RefreshToken genera un nuevo par de tokens usando el refresh token.
+// La sesión debe seguir abierta y dentro de su tiempo de inactividad y su duración máxima.
+func (s *AuthService) RefreshToken(req *dto.RefreshTokenRequest, meta *dto.RequestMeta) (*dto.AuthResponse, error) {
 	// Validar refresh token
-	userID, err := s.jwtManager.ValidateRefreshToken(req.RefreshToken)
+	userID, sessionID, authTime, err := s.jwtManager.ValidateRefreshToken(req.RefreshToken)
 	if err != nil {
 		return nil, errors.New("invalid refresh token")
 	}
@@ -155,36 +139,42 @@ func (s *AuthService) RefreshToken(req *dto.RefreshTokenRequest) (*dto.AuthRespo
 		return nil, errors.New("user account is disabled")
 	}
 
-	// Generar nuevos tokens
-	accessToken, err := s.jwtManager.GenerateToken(
-		user.ID,
-		user.UserName,
-		user.Email,
-		user.RoleID,
-		user.Role.Name,
-	)
-	if err != nil {
-		return nil, errors.New("failed to generate access token")
+	// Los refresh tokens anteriores a las sesiones de servidor abren una con el perfil por defecto
+	if sessionID == "" {
+		s.audit.Log(actorFor(&user, meta), s.authEvent(models.AuditTokenRefresh, &user, nil))
+		return s.issueAuthResponse(&user, authTime, sessionOptions{Meta: meta})
 	}
 
-	newRefreshToken, err := s.jwtManager.GenerateRefreshToken(user.ID)
+	session, err := s.sessions.Touch(sessionID, user.ID)
 	if err != nil {
-		return nil, errors.New("failed to generate refresh token")
+		return nil, err
 	}
+	s.audit.Log(actorFor(&user, meta), s.authEvent(models.AuditTokenRefresh, &user, map[string]interface{}{"session_id": session.ID}))
 
-	userResponse := s.toUserResponse(&user)
+	// Generar nuevos tokens conservando el momento de la autenticación original
+	return s.issueSessionTokens(&user, session)
+}
 
-	return &dto.AuthResponse{
-		User:         *userResponse,
-		AccessToken:  accessToken,
-		RefreshToken: newRefreshToken,
-		TokenType:    "Bearer",
-		ExpiresIn:    s.jwtManager.GetTokenDuration(),
-	}, nil
+// Logout revoca la sesión del refresh token; un token inválido o caducado no tiene sesión que cerrar
+func (s *AuthService) Logout(refreshToken string, meta *dto.RequestMeta) error {
+	userID, sessionID, _, err := s.jwtManager.ValidateRefreshToken(refreshToken)
+	if err != nil || sessionID == "" {
+		return nil
+	}
+	if err := s.sessions.Revoke(sessionID); err != nil {
+		return err
+	}
+
+	// El nombre es solo informativo: si el usuario ya no existe se registra solo su ID
+	var user models.User
+	user.ID = userID
+	database.GetDB().Select("id", "user_name").Where("id = ?", userID).Take(&user)
+	s.audit.Log(actorFor(&user, meta), s.authEvent(models.AuditLogout, &user, map[string]interface{}{"session_id": sessionID}))
+	return nil
 }
 
 // ChangePassword cambia la contraseña de un usuario autenticado
-func (s *AuthService) ChangePassword(userID uint, req *dto.ChangePasswordRequest) error {
+func (s *AuthService) ChangePassword(userID uint, req *dto.ChangePasswordRequest, meta *dto.RequestMeta) error {
 	db := database.GetDB()
 
 	// Obtener usuario
@@ -198,6 +188,7 @@ func (s *AuthService) ChangePassword(userID uint, req *dto.ChangePasswordRequest
 
 	// Verificar contraseña actual
 	if err := s.hasher.ComparePassword(user.Password, req.CurrentPassword); err != nil {
+		s.audit.Log(actorFor(&user, meta), s.authEvent(models.AuditPasswordChangeFailed, &user, map[string]interface{}{"reason": "invalid_password"}))
 		return errors.New("current password is incorrect")
 	}
 
@@ -209,11 +200,69 @@ func (s *AuthService) ChangePassword(userID uint, req *dto.ChangePasswordRequest
 
 	// Actualizar contraseña
 	user.Password = hashedPassword
-	if err := db.Save(&user).Error; err != nil {
-		return err
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&user).Error; err != nil {
+			return err
+		}
+		event := s.authEvent(models.AuditPasswordChange, &user, nil)
+		event.Changes = auditChanges{"password": {Old: auditRedacted, New: auditRedacted}}
+		return s.audit.Record(tx, actorFor(&user, meta), event)
+	})
+}
+
+// Reauthenticate verifica de nuevo la contraseña y emite un token elevado de vida corta
+// asociado a la misma sesión y organización. Las cuentas sin contraseña (SSO/SCIM) se
+// re-autentican con su IdP (SamlService.StartReauthentication).
+func (s *AuthService) Reauthenticate(userID uint, sessionID string, organizationID uint, req *dto.ReauthenticateRequest, meta *dto.RequestMeta) (*dto.ReauthenticateResponse, error) {
+	db := database.GetDB()
+
+	var user models.User
+	if err := db.Preload("Role").First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
 	}
 
-	return nil
+	if !user.IsActive {
+		return nil, errors.New("user account is disabled")
+	}
+
+	if !user.HasPassword {
+		s.audit.Log(actorFor(&user, meta), s.authEvent(models.AuditReauthenticateFailed, &user, map[string]interface{}{"reason": "password_not_set"}))
+		return nil, errors.New("password not set")
+	}
+
+	if err := s.hasher.ComparePassword(user.Password, req.Password); err != nil {
+		s.audit.Log(actorFor(&user, meta), s.authEvent(models.AuditReauthenticateFailed, &user, map[string]interface{}{"reason": "invalid_password"}))
+		return nil, errors.New("invalid credentials")
+	}
+
+	return s.elevate(&user, sessionID, organizationID, models.LoginMethodPassword, meta)
+}
+
+// elevate emite el token elevado tras una re-autenticación correcta con el método indicado
+func (s *AuthService) elevate(user *models.User, sessionID string, organizationID uint, method string, meta *dto.RequestMeta) (*dto.ReauthenticateResponse, error) {
+	elevatedToken, err := s.jwtManager.GenerateElevatedToken(
+		user.ID,
+		user.UserName,
+		user.Email,
+		user.RoleID,
+		user.Role.Name,
+		sessionID,
+		organizationID,
+	)
+	if err != nil {
+		return nil, errors.New("failed to generate access token")
+	}
+
+	s.audit.Log(actorFor(user, meta), s.authEvent(models.AuditReauthenticate, user, map[string]interface{}{"session_id": sessionID, "method": method}))
+
+	return &dto.ReauthenticateResponse{
+		AccessToken: elevatedToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   s.jwtManager.GetElevatedTokenDuration(),
+	}, nil
 }
 
 // GetCurrentUser obtiene la información del usuario actual
@@ -226,9 +275,100 @@ func (s *AuthService) ValidateToken(tokenString string) (*utils.JWTClaims, error
 	return s.jwtManager.ValidateToken(tokenString)
 }
 
+// recordLogin registra un inicio de sesión correcto en el historial de login y en la auditoría
+func (s *AuthService) recordLogin(user *models.User, method string, meta *dto.RequestMeta) {
+	s.loginHistory.RecordSuccess(user, method, meta)
+	s.audit.Log(actorFor(user, meta), s.authEvent(models.AuditLogin, user, map[string]interface{}{"method": method}))
+}
+
+// recordLoginFailure registra un intento de login fallido en el historial de login y en la auditoría
+func (s *AuthService) recordLoginFailure(userID *uint, identifier, reason, method string, meta *dto.RequestMeta) {
+	s.loginHistory.RecordFailure(userID, identifier, reason, method, meta)
+
+	event := auditEvent{
+		Action:   models.AuditLoginFailed,
+		Metadata: map[string]interface{}{"identifier": identifier, "reason": reason, "method": method},
+	}
+	if userID != nil {
+		event.TargetType = models.AuditTargetUser
+		event.TargetID = *userID
+	}
+	// Quien falla el login no está identificado: la entrada no tiene actor, solo IP y agente
+	s.audit.Log(&Actor{Meta: meta}, event)
+}
+
+// authEvent evento de autenticación de un usuario sobre su propia cuenta
+func (s *AuthService) authEvent(action string, user *models.User, metadata map[string]interface{}) auditEvent {
+	return auditEvent{
+		Action:     action,
+		TargetType: models.AuditTargetUser,
+		TargetID:   user.ID,
+		Metadata:   metadata,
+	}
+}
+
+// issueAuthResponse abre una sesión y genera el par de tokens para un usuario con su rol cargado.
+// authTime es el momento del login, desde el que cuenta la duración máxima de la sesión; el
+// claim auth_time solo lo lleva si opts.PasswordAuthAt indica que se comprobó la contraseña.
+func (s *AuthService) issueAuthResponse(user *models.User, authTime time.Time, opts sessionOptions) (*dto.AuthResponse, error) {
+	session, err := s.sessions.Open(user, authTime, opts)
+	if err != nil {
+		// Los errores de negocio (p. ej. organización no permitida) llegan tal cual al cliente
+		if _, ok := utils.IsAPIError(err); ok {
+			return nil, err
+		}
+		return nil, errors.New("failed to create session")
+	}
+	return s.issueSessionTokens(user, session)
+}
+
+// issueSessionTokens genera el par de tokens de una sesión con las duraciones de su perfil
+func (s *AuthService) issueSessionTokens(user *models.User, session *models.Session) (*dto.AuthResponse, error) {
+	now := time.Now()
+	accessExpiresAt, refreshExpiresAt := s.sessions.TokenExpiries(session, now)
+
+	var organizationID uint
+	if session.OrganizationID != nil {
+		organizationID = *session.OrganizationID
+	}
+
+	accessToken, err := s.jwtManager.GenerateToken(
+		user.ID,
+		user.UserName,
+		user.Email,
+		user.RoleID,
+		user.Role.Name,
+		session.PasswordAuthAt,
+		session.ID,
+		organizationID,
+		accessExpiresAt,
+	)
+	if err != nil {
+		return nil, errors.New("failed to generate access token")
+	}
+
+	refreshToken, err := s.jwtManager.GenerateRefreshToken(user.ID, session.AuthTime, session.ID, refreshExpiresAt)
+	if err != nil {
+		return nil, errors.New("failed to generate refresh token")
+	}
+
+	response := &dto.AuthResponse{
+		User:             *s.toUserResponse(user),
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken,
+		TokenType:        "Bearer",
+		ExpiresIn:        int64(accessExpiresAt.Sub(now).Seconds()),
+		RefreshExpiresIn: int64(refreshExpiresAt.Sub(now).Seconds()),
+	}
+	if session.ExpiresAt != nil {
+		response.SessionExpiresAt = *session.ExpiresAt
+	}
+	return response, nil
+}
+
 // toUserResponse convierte un modelo User a UserResponse
 func (s *AuthService) toUserResponse(user *models.User) *dto.UserResponse {
-	return &dto.UserResponse{
+	response := &dto.UserResponse{
 		ID:          user.ID,
 		Name:        user.Name,
 		UserName:    user.UserName,
@@ -236,8 +376,11 @@ func (s *AuthService) toUserResponse(user *models.User) *dto.UserResponse {
 		RoleID:      user.RoleID,
 		Role:        user.Role,
 		IsActive:    user.IsActive,
+		Version:     user.Version,
 		LastLoginAt: user.LastLoginAt,
 		CreatedAt:   user.CreatedAt,
 		UpdatedAt:   user.UpdatedAt,
 	}
+	response.AvatarURL, response.Avatars = avatarURLs(user)
+	return response
 }
\ No newline at end of file
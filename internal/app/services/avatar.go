@@ -0,0 +1,220 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif" // registra el decodificador GIF para image.Decode
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+
+	"megabaseGo/internal/models"
+	"megabaseGo/internal/storage"
+	"megabaseGo/internal/utils"
+)
+
+// Lados en píxeles de las versiones cuadradas que se generan de cada avatar
+var avatarSizes = []int{32, 64, 128, 256}
+
+// Versión que se devuelve como avatar_url
+const defaultAvatarSize = 128
+
+// Límites de la imagen original: evitan que una imagen pequeña comprimida ocupe gigas al decodificarla
+const (
+	minAvatarSide   = 16
+	maxAvatarPixels = 40_000_000
+)
+
+// Formatos admitidos según el contenido (no la extensión ni el Content-Type del cliente)
+var avatarFormats = map[string]bool{"image/jpeg": true, "image/png": true, "image/gif": true}
+
+// avatarStorage backend de los avatares, creado con la configuración del entorno en el primer uso
+var avatarStorage = sync.OnceValues(storage.New)
+
+// AvatarMaxBytes tamaño máximo del fichero subido (AVATAR_MAX_BYTES, 5 MiB por defecto)
+func AvatarMaxBytes() int64 {
+	return int64(utils.GetEnvInt("AVATAR_MAX_BYTES", 5<<20))
+}
+
+// avatarVersion imagen redimensionada lista para guardar
+type avatarVersion struct {
+	size        int
+	data        []byte
+	contentType string
+}
+
+// processAvatar valida la imagen subida y genera sus versiones cuadradas. Las JPEG se guardan
+// como JPEG; PNG y GIF (solo el primer fotograma) como PNG para conservar la transparencia.
+// Devuelve también la extensión de los ficheros.
+func processAvatar(file io.Reader) ([]avatarVersion, string, error) {
+	maxBytes := AvatarMaxBytes()
+	data, err := io.ReadAll(io.LimitReader(file, maxBytes+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, "", utils.NewPayloadTooLargeError(fmt.Sprintf("avatar must not exceed %d bytes", maxBytes))
+	}
+
+	contentType := http.DetectContentType(data)
+	if !avatarFormats[contentType] {
+		return nil, "", utils.NewUnsupportedMediaTypeError("avatar must be a JPEG, PNG or GIF image")
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", utils.NewBadRequestError("avatar is not a valid image")
+	}
+	if config.Width < minAvatarSide || config.Height < minAvatarSide {
+		return nil, "", utils.NewBadRequestError(fmt.Sprintf("avatar must be at least %dx%d pixels", minAvatarSide, minAvatarSide))
+	}
+	if config.Width*config.Height > maxAvatarPixels {
+		return nil, "", utils.NewBadRequestError("avatar dimensions are too large")
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", utils.NewBadRequestError("avatar is not a valid image")
+	}
+	square := cropSquare(img)
+
+	ext, outputType := "png", "image/png"
+	if contentType == "image/jpeg" {
+		ext, outputType = "jpg", "image/jpeg"
+	}
+	versions := make([]avatarVersion, 0, len(avatarSizes))
+	for _, size := range avatarSizes {
+		var buf bytes.Buffer
+		resized := resizeSquare(square, size)
+		if ext == "jpg" {
+			err = jpeg.Encode(&buf, resized, &jpeg.Options{Quality: 85})
+		} else {
+			err = png.Encode(&buf, resized)
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		versions = append(versions, avatarVersion{size: size, data: buf.Bytes(), contentType: outputType})
+	}
+	return versions, ext, nil
+}
+
+// cropSquare recorta el cuadrado central y lo copia a RGBA (premultiplicado, para promediar bien los bordes transparentes)
+func cropSquare(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	side := bounds.Dx()
+	if bounds.Dy() < side {
+		side = bounds.Dy()
+	}
+	origin := image.Pt(bounds.Min.X+(bounds.Dx()-side)/2, bounds.Min.Y+(bounds.Dy()-side)/2)
+
+	square := image.NewRGBA(image.Rect(0, 0, side, side))
+	draw.Draw(square, square.Bounds(), img, origin, draw.Src)
+	return square
+}
+
+// resizeSquare escala un cuadrado a size x size promediando el área de origen de cada píxel;
+// al ampliar, cada píxel toma el de origen más cercano
+func resizeSquare(src *image.RGBA, size int) *image.RGBA {
+	side := src.Bounds().Dx()
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+
+	for y := 0; y < size; y++ {
+		y0 := y * side / size
+		y1 := max((y+1)*side/size, y0+1)
+		for x := 0; x < size; x++ {
+			x0 := x * side / size
+			x1 := max((x+1)*side/size, x0+1)
+
+			var r, g, b, a, count uint32
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					pixel := row[sx*4 : sx*4+4]
+					r += uint32(pixel[0])
+					g += uint32(pixel[1])
+					b += uint32(pixel[2])
+					a += uint32(pixel[3])
+					count++
+				}
+			}
+			offset := y*dst.Stride + x*4
+			dst.Pix[offset] = uint8(r / count)
+			dst.Pix[offset+1] = uint8(g / count)
+			dst.Pix[offset+2] = uint8(b / count)
+			dst.Pix[offset+3] = uint8(a / count)
+		}
+	}
+	return dst
+}
+
+// avatarObjectKey clave de una versión: avatars/12/abc.jpg -> avatars/12/abc-128.jpg
+func avatarObjectKey(avatarKey string, size int) string {
+	ext := path.Ext(avatarKey)
+	return strings.TrimSuffix(avatarKey, ext) + "-" + strconv.Itoa(size) + ext
+}
+
+// avatarURLs URL del avatar (la versión por defecto si es una imagen subida) y las de todas las
+// versiones indexadas por tamaño. Sin imagen subida devuelve la URL externa del perfil.
+func avatarURLs(user *models.User) (string, map[string]string) {
+	if user.AvatarKey == "" {
+		return user.AvatarURL, nil
+	}
+	store, err := avatarStorage()
+	if err != nil {
+		log.Printf("Error configurando el almacenamiento de avatares: %v", err)
+		return "", nil
+	}
+	urls := make(map[string]string, len(avatarSizes))
+	for _, size := range avatarSizes {
+		urls[strconv.Itoa(size)] = store.URL(avatarObjectKey(user.AvatarKey, size))
+	}
+	return urls[strconv.Itoa(defaultAvatarSize)], urls
+}
+
+// storeAvatar guarda las versiones bajo una clave nueva y la devuelve
+func storeAvatar(prefix string, versions []avatarVersion, ext string) (string, error) {
+	store, err := avatarStorage()
+	if err != nil {
+		return "", err
+	}
+	token, err := utils.GenerateRandomToken(12)
+	if err != nil {
+		return "", err
+	}
+	avatarKey := prefix + "/" + token + "." + ext
+
+	for _, version := range versions {
+		key := avatarObjectKey(avatarKey, version.size)
+		if err := store.Put(key, bytes.NewReader(version.data), int64(len(version.data)), version.contentType); err != nil {
+			deleteAvatar(avatarKey)
+			return "", err
+		}
+	}
+	return avatarKey, nil
+}
+
+// deleteAvatar borra las versiones de un avatar; los fallos solo se registran, el avatar ya no está referenciado
+func deleteAvatar(avatarKey string) {
+	if avatarKey == "" {
+		return
+	}
+	store, err := avatarStorage()
+	if err != nil {
+		log.Printf("Error configurando el almacenamiento de avatares: %v", err)
+		return
+	}
+	for _, size := range avatarSizes {
+		if err := store.Delete(avatarObjectKey(avatarKey, size)); err != nil {
+			log.Printf("Error borrando el avatar %s: %v", avatarObjectKey(avatarKey, size), err)
+		}
+	}
+}
@@ -0,0 +1,311 @@
+package services
+
+import (
+	"crypto/rand"
+	"errors"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"megabaseGo/internal/app/dto"
+	"megabaseGo/internal/database"
+	"megabaseGo/internal/models"
+	"megabaseGo/internal/utils"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// DeviceCodeGrantType grant_type definido por RFC 8628
+const DeviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+// Alfabeto sin vocales ni caracteres ambiguos recomendado por RFC 8628 §6.1
+const userCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+
+// Intentos de generar un user_code que no coincida con otro pendiente
+const userCodeAttempts = 5
+
+type DeviceAuthService struct {
+	authService     *AuthService
+	verificationURI string
+	allowedClients  []string
+	ttl             time.Duration
+	interval        int
+}
+
+// NewDeviceAuthService crea una nueva instancia del servicio de autorización de dispositivos
+func NewDeviceAuthService() *DeviceAuthService {
+	var allowedClients []string
+	if clients := utils.GetEnv("DEVICE_CLIENT_IDS", ""); clients != "" {
+		allowedClients = strings.Split(clients, ",")
+	}
+
+	return &DeviceAuthService{
+		authService:     NewAuthService(),
+		verificationURI: utils.GetEnv("DEVICE_VERIFICATION_URL", "http://localhost:3000/device"),
+		allowedClients:  allowedClients,
+		ttl:             time.Duration(utils.GetEnvInt("DEVICE_CODE_TTL_MINUTES", 10)) * time.Minute,
+		interval:        utils.GetEnvInt("DEVICE_POLL_INTERVAL_SECONDS", 5),
+	}
+}
+
+// RequestCode crea una autorización pendiente y devuelve el device_code y el user_code
+func (s *DeviceAuthService) RequestCode(req *dto.DeviceCodeRequest) (*dto.DeviceCodeResponse, error) {
+	if !s.isAllowedClient(req.ClientID) {
+		return nil, deviceError("invalid_client", "unknown client_id")
+	}
+
+	deviceCode, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, errors.New("failed to generate device code")
+	}
+
+	authorization := models.DeviceAuthorization{
+		DeviceCodeHash: utils.HashToken(deviceCode),
+		ClientID:       req.ClientID,
+		Scope:          req.Scope,
+		Status:         models.DeviceAuthPending,
+		Interval:       s.interval,
+		ExpiresAt:      time.Now().Add(s.ttl),
+	}
+
+	db := database.GetDB()
+	s.purgeExpired(db)
+
+	// El user_code solo es único entre las autorizaciones pendientes; ante una colisión se genera otro
+	for attempt := 1; ; attempt++ {
+		if authorization.UserCode, err = generateUserCode(); err != nil {
+			return nil, errors.New("failed to generate user code")
+		}
+		err = db.Create(&authorization).Error
+		if err == nil {
+			break
+		}
+		if !isUniqueViolation(err) {
+			return nil, err
+		}
+		if attempt == userCodeAttempts {
+			return nil, errors.New("failed to generate a unique user code")
+		}
+		authorization.ID = 0
+	}
+
+	return &dto.DeviceCodeResponse{
+		DeviceCode:              deviceCode,
+		UserCode:                authorization.UserCode,
+		VerificationURI:         s.verificationURI,
+		VerificationURIComplete: s.verificationURI + "?user_code=" + url.QueryEscape(authorization.UserCode),
+		ExpiresIn:               int64(s.ttl.Seconds()),
+		Interval:                s.interval,
+	}, nil
+}
+
+// GetPending obtiene una autorización pendiente por user_code para mostrarla al usuario
+func (s *DeviceAuthService) GetPending(userCode string) (*dto.DeviceAuthorizationResponse, error) {
+	authorization, err := s.findPending(database.GetDB(), userCode)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.DeviceAuthorizationResponse{
+		UserCode:  authorization.UserCode,
+		ClientID:  authorization.ClientID,
+		Scope:     authorization.Scope,
+		Status:    authorization.Status,
+		ExpiresAt: authorization.ExpiresAt,
+	}, nil
+}
+
+// Approve vincula la autorización al usuario autenticado que confirmó el código. authTime es el
+// auth_time del aprobador (su última comprobación de contraseña); nil si su sesión no lo tiene.
+func (s *DeviceAuthService) Approve(userID uint, authTime *time.Time, userCode string) error {
+	return s.resolve(userCode, map[string]interface{}{
+		"status":    models.DeviceAuthApproved,
+		"user_id":   userID,
+		"auth_time": authTime,
+	})
+}
+
+// Deny rechaza la autorización; el dispositivo recibirá access_denied
+func (s *DeviceAuthService) Deny(userID uint, userCode string) error {
+	return s.resolve(userCode, map[string]interface{}{
+		"status":  models.DeviceAuthDenied,
+		"user_id": userID,
+	})
+}
+
+// PollToken implementa el endpoint de token del dispositivo (RFC 8628 §3.4/3.5)
+func (s *DeviceAuthService) PollToken(req *dto.DeviceTokenRequest, meta *dto.RequestMeta) (*dto.AuthResponse, error) {
+	if req.GrantType != DeviceCodeGrantType {
+		return nil, deviceError("unsupported_grant_type", "grant_type must be "+DeviceCodeGrantType)
+	}
+
+	db := database.GetDB()
+	var authorization models.DeviceAuthorization
+	if err := db.Where("device_code_hash = ?", utils.HashToken(req.DeviceCode)).First(&authorization).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, deviceError("invalid_grant", "unknown device_code")
+		}
+		return nil, err
+	}
+
+	if authorization.ClientID != req.ClientID {
+		return nil, deviceError("invalid_grant", "device_code was issued to another client")
+	}
+
+	now := time.Now()
+	if now.After(authorization.ExpiresAt) {
+		return nil, deviceError("expired_token", "the device_code has expired")
+	}
+
+	switch authorization.Status {
+	case models.DeviceAuthDenied:
+		return nil, deviceError("access_denied", "the user denied the authorization request")
+	case models.DeviceAuthConsumed:
+		return nil, deviceError("invalid_grant", "the device_code has already been used")
+	case models.DeviceAuthPending:
+		// Polling demasiado frecuente: se incrementa el intervalo en 5 segundos
+		if authorization.LastPolledAt != nil && now.Sub(*authorization.LastPolledAt) < time.Duration(authorization.Interval)*time.Second {
+			db.Model(&authorization).Updates(map[string]interface{}{
+				"last_polled_at": now,
+				"interval":       authorization.Interval + 5,
+			})
+			return nil, deviceError("slow_down", "polling too frequently")
+		}
+		db.Model(&authorization).Update("last_polled_at", now)
+		return nil, deviceError("authorization_pending", "the user has not yet approved the request")
+	}
+
+	// Aprobada: consumir de forma atómica para que el token solo se emita una vez
+	result := db.Model(&models.DeviceAuthorization{}).
+		Where("id = ? AND status = ?", authorization.ID, models.DeviceAuthApproved).
+		Update("status", models.DeviceAuthConsumed)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected != 1 || authorization.UserID == nil {
+		return nil, deviceError("invalid_grant", "the device_code has already been used")
+	}
+
+	var user models.User
+	if err := db.Preload("Role").First(&user, *authorization.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, deviceError("invalid_grant", "user not found")
+		}
+		return nil, err
+	}
+	if !user.IsActive {
+		return nil, deviceError("access_denied", "user account is disabled")
+	}
+
+	s.authService.recordLogin(&user, models.LoginMethodDeviceCode, meta)
+
+	authTime := now
+	if authorization.AuthTime != nil {
+		authTime = *authorization.AuthTime
+	}
+
+	return s.authService.issueAuthResponse(&user, authTime, sessionOptions{
+		ClientID:       authorization.ClientID,
+		PasswordAuthAt: authorization.AuthTime,
+		Meta:           meta,
+	})
+}
+
+// resolve marca una autorización pendiente como aprobada o rechazada
+func (s *DeviceAuthService) resolve(userCode string, updates map[string]interface{}) error {
+	db := database.GetDB()
+	authorization, err := s.findPending(db, userCode)
+	if err != nil {
+		return err
+	}
+
+	result := db.Model(&models.DeviceAuthorization{}).
+		Where("id = ? AND status = ?", authorization.ID, models.DeviceAuthPending).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected != 1 {
+		return utils.NewConflictError("device authorization has already been resolved")
+	}
+	return nil
+}
+
+func (s *DeviceAuthService) findPending(db *gorm.DB, userCode string) (*models.DeviceAuthorization, error) {
+	var authorization models.DeviceAuthorization
+	err := db.Where("user_code = ? AND status = ? AND expires_at > ?", normalizeUserCode(userCode), models.DeviceAuthPending, time.Now()).
+		First(&authorization).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("Device authorization")
+		}
+		return nil, err
+	}
+	return &authorization, nil
+}
+
+// purgeExpired elimina las autorizaciones caducadas hace más de un TTL: el margen permite que el
+// dispositivo aún reciba expired_token al sondear. Un fallo no impide crear la nueva solicitud.
+func (s *DeviceAuthService) purgeExpired(db *gorm.DB) {
+	if err := db.Where("expires_at < ?", time.Now().Add(-s.ttl)).Delete(&models.DeviceAuthorization{}).Error; err != nil {
+		log.Printf("Error eliminando autorizaciones de dispositivo caducadas: %v", err)
+	}
+}
+
+func (s *DeviceAuthService) isAllowedClient(clientID string) bool {
+	if len(s.allowedClients) == 0 {
+		return true
+	}
+	for _, allowed := range s.allowedClients {
+		if strings.TrimSpace(allowed) == clientID {
+			return true
+		}
+	}
+	return false
+}
+
+// generateUserCode genera un código XXXX-XXXX fácil de teclear
+func generateUserCode() (string, error) {
+	max := big.NewInt(int64(len(userCodeAlphabet)))
+	code := make([]byte, 0, 9)
+	for i := 0; i < 8; i++ {
+		if i == 4 {
+			code = append(code, '-')
+		}
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code = append(code, userCodeAlphabet[n.Int64()])
+	}
+	return string(code), nil
+}
+
+// normalizeUserCode acepta el código en minúsculas y con o sin guion
+func normalizeUserCode(userCode string) string {
+	code := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(userCode))
+	if len(code) == 8 {
+		return code[:4] + "-" + code[4:]
+	}
+	return code
+}
+
+// isUniqueViolation indica si err es una violación de un índice único de PostgreSQL
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// deviceError construye un error con el código definido por RFC 8628 / RFC 6749
+func deviceError(code, description string) *utils.APIError {
+	apiErr := utils.NewBadRequestError(code)
+	apiErr.Details = description
+	if code == "invalid_client" {
+		apiErr.StatusCode = http.StatusUnauthorized
+	}
+	return apiErr
+}
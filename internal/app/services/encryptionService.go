@@ -0,0 +1,147 @@
+package services
+
+import (
+	"encoding/json"
+
+	"megabaseGo/internal/database"
+	"megabaseGo/internal/database/encryption"
+	"megabaseGo/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Tamaño de los lotes al re-cifrar usuarios
+const reencryptBatchSize = 200
+
+// EncryptionService re-cifra los campos cifrados de los usuarios con la clave de datos activa
+type EncryptionService struct{}
+
+// NewEncryptionService crea una nueva instancia del servicio de cifrado
+func NewEncryptionService() *EncryptionService {
+	return &EncryptionService{}
+}
+
+// ReencryptUsers vuelve a guardar el nombre y el email de los usuarios que siguen en claro, que
+// están cifrados con una clave de datos retirada o que no tienen índice ciego. Incluye los
+// usuarios en la papelera.
+// Tras ejecutarlo, las claves de datos retiradas ya no cifran ningún valor.
+func (s *EncryptionService) ReencryptUsers() (int64, error) {
+	activeKey, err := encryption.ActiveKeyID()
+	if err != nil {
+		return 0, err
+	}
+
+	return s.reencryptUsers(database.GetDB(), activeKey)
+}
+
+// ReencryptRevisions cifra con la clave activa el nombre y el email de las instantáneas de usuario
+// guardadas en claro (anteriores al cifrado de instantáneas) o con una clave de datos retirada
+func (s *EncryptionService) ReencryptRevisions() (int64, error) {
+	activeKey, err := encryption.ActiveKeyID()
+	if err != nil {
+		return 0, err
+	}
+
+	db := database.GetDB()
+	var updated int64
+	var lastID uint
+	for {
+		var revisions []models.Revision
+		if err := db.Where("resource_type = ? AND id > ?", models.RevisionUser, lastID).
+			Order("id").Limit(reencryptBatchSize).Find(&revisions).Error; err != nil {
+			return updated, err
+		}
+		if len(revisions) == 0 {
+			return updated, nil
+		}
+		lastID = revisions[len(revisions)-1].ID
+
+		for _, revision := range revisions {
+			if !snapshotNeedsReencryption(revision.Snapshot, activeKey) {
+				continue
+			}
+			snapshot, err := models.OpenRevisionSnapshot(revision.ResourceType, revision.Snapshot)
+			if err != nil {
+				return updated, err
+			}
+			sealed, err := models.SealRevisionSnapshot(revision.ResourceType, snapshot)
+			if err != nil {
+				return updated, err
+			}
+			if err := db.Model(&revision).UpdateColumn("snapshot", sealed).Error; err != nil {
+				return updated, err
+			}
+			updated++
+		}
+	}
+}
+
+// snapshotNeedsReencryption indica si algún campo cifrado de la instantánea está en claro o con otra clave
+func snapshotNeedsReencryption(raw json.RawMessage, activeKey uint) bool {
+	var snapshot map[string]interface{}
+	if err := json.Unmarshal(raw, &snapshot); err != nil {
+		return false
+	}
+	for _, field := range models.UserEncryptedFields {
+		value, ok := snapshot[field].(string)
+		if !ok || value == "" {
+			continue
+		}
+		if keyID, encrypted := encryption.IsEncrypted(value); !encrypted || keyID != activeKey {
+			return true
+		}
+	}
+	return false
+}
+
+// storedUser valores de users tal como están en la BD, sin pasar por el serializador
+type storedUser struct {
+	ID        uint
+	Name      string
+	Email     string
+	EmailBidx string
+}
+
+func (s *EncryptionService) reencryptUsers(db *gorm.DB, activeKey uint) (int64, error) {
+	var updated int64
+	var lastID uint
+	for {
+		var rows []storedUser
+		if err := db.Table("users").Select("id", "name", "email", "email_bidx").
+			Where("id > ?", lastID).Order("id").Limit(reencryptBatchSize).Scan(&rows).Error; err != nil {
+			return updated, err
+		}
+		if len(rows) == 0 {
+			return updated, nil
+		}
+		lastID = rows[len(rows)-1].ID
+
+		for _, row := range rows {
+			if !needsReencryption(row, activeKey) {
+				continue
+			}
+			var user models.User
+			if err := db.Unscoped().Select("id", "name", "email", "email_bidx").First(&user, row.ID).Error; err != nil {
+				return updated, err
+			}
+			// BeforeSave recalcula el índice ciego y el serializador cifra con la clave activa
+			if err := db.Unscoped().Model(&user).Select("name", "email", "email_bidx").Updates(&user).Error; err != nil {
+				return updated, err
+			}
+			updated++
+		}
+	}
+}
+
+// needsReencryption indica si algún campo está en claro o cifrado con otra clave, o si falta el índice ciego
+func needsReencryption(row storedUser, activeKey uint) bool {
+	for _, value := range []string{row.Name, row.Email} {
+		if value == "" {
+			continue
+		}
+		if keyID, encrypted := encryption.IsEncrypted(value); !encrypted || keyID != activeKey {
+			return true
+		}
+	}
+	return row.Email != "" && row.EmailBidx == ""
+}
@@ -0,0 +1,379 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"megabaseGo/internal/app/dto"
+	"megabaseGo/internal/database"
+	"megabaseGo/internal/database/encryption"
+	"megabaseGo/internal/models"
+	"megabaseGo/internal/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IdentityService struct {
+	samlService *SamlService
+	userService *UserService
+	audit       *AuditService
+}
+
+// NewIdentityService crea una nueva instancia del servicio de identidades vinculadas
+func NewIdentityService() *IdentityService {
+	return &IdentityService{
+		samlService: NewSamlService(),
+		userService: NewUserService(),
+		audit:       NewAuditService(),
+	}
+}
+
+// ListIdentities lista los métodos de login de un usuario
+func (s *IdentityService) ListIdentities(userID uint) (*dto.IdentitiesResponse, error) {
+	db := database.GetDB()
+
+	var user models.User
+	if err := db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("User")
+		}
+		return nil, err
+	}
+
+	var identities []models.UserIdentity
+	if err := db.Where("user_id = ?", userID).Order("created_at").Find(&identities).Error; err != nil {
+		return nil, err
+	}
+
+	response := &dto.IdentitiesResponse{
+		HasPassword: user.HasPassword,
+		Identities:  make([]dto.IdentityResponse, 0, len(identities)),
+	}
+	for _, identity := range identities {
+		response.Identities = append(response.Identities, dto.IdentityResponse{
+			ID:            identity.ID,
+			Provider:      identity.Provider,
+			ProviderRef:   identity.ProviderRef,
+			Subject:       identity.Subject,
+			Email:         identity.Email,
+			EmailVerified: identity.EmailVerified,
+			LastUsedAt:    identity.LastUsedAt,
+			CreatedAt:     identity.CreatedAt,
+		})
+	}
+	return response, nil
+}
+
+// StartSamlLink devuelve la URL del IdP en la que el usuario debe autenticarse para vincular esa identidad
+func (s *IdentityService) StartSamlLink(userID uint, slug string, req *dto.LinkIdentityRequest) (string, error) {
+	return s.samlService.StartLink(slug, userID, req.RedirectTo)
+}
+
+// Unlink desvincula una identidad externa, salvo que sea el último método de login de la cuenta
+func (s *IdentityService) Unlink(userID, identityID uint, actor *Actor) error {
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		// Bloquear al usuario evita que dos desvinculaciones simultáneas dejen la cuenta sin métodos
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return utils.NewNotFoundError("User")
+			}
+			return err
+		}
+
+		var identity models.UserIdentity
+		if err := tx.Where("id = ? AND user_id = ?", identityID, userID).First(&identity).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return utils.NewNotFoundError("Identity")
+			}
+			return err
+		}
+
+		var count int64
+		if err := tx.Model(&models.UserIdentity{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+			return err
+		}
+		if !user.HasPassword && count <= 1 {
+			return utils.NewConflictError("cannot remove the last login method of the account")
+		}
+
+		if err := tx.Delete(&identity).Error; err != nil {
+			return err
+		}
+		return s.audit.Record(tx, actor, auditEvent{
+			Action:     models.AuditIdentityUnlink,
+			TargetType: models.AuditTargetUser,
+			TargetID:   user.ID,
+			Metadata: map[string]interface{}{
+				"identity_id":  identity.ID,
+				"provider":     identity.Provider,
+				"provider_ref": identity.ProviderRef,
+			},
+		})
+	})
+}
+
+// FindDuplicateAccounts detecta cuentas con una identidad cuyo email verificado pertenece a otra cuenta
+func (s *IdentityService) FindDuplicateAccounts() ([]dto.DuplicateAccountResponse, error) {
+	var rows []duplicateRow
+	if encryption.Enabled() {
+		var err error
+		if rows, err = s.duplicatesByBlindIndex(); err != nil {
+			return nil, err
+		}
+		return groupDuplicates(rows), nil
+	}
+
+	err := database.GetDB().Raw(`
+		SELECT DISTINCT LOWER(ui.email) AS email, target.id AS target_user_id, ui.user_id AS duplicate_user_id
+		FROM user_identities ui
+		JOIN users target ON LOWER(target.email) = LOWER(ui.email) AND target.deleted_at IS NULL
+		JOIN users duplicate ON duplicate.id = ui.user_id AND duplicate.deleted_at IS NULL
+		WHERE ui.email_verified AND ui.email <> '' AND ui.user_id <> target.id
+		ORDER BY email, duplicate_user_id`).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	return groupDuplicates(rows), nil
+}
+
+// duplicateRow identidad cuyo email verificado coincide con el de otra cuenta
+type duplicateRow struct {
+	Email           string
+	TargetUserID    uint
+	DuplicateUserID uint
+}
+
+// duplicatesByBlindIndex hace el cruce de FindDuplicateAccounts con el email cifrado: la BD no
+// puede comparar users.email, así que se compara el índice ciego calculado para cada identidad
+func (s *IdentityService) duplicatesByBlindIndex() ([]duplicateRow, error) {
+	db := database.GetDB()
+
+	var identities []struct {
+		Email  string
+		UserID uint
+	}
+	err := db.Raw(`
+		SELECT DISTINCT LOWER(ui.email) AS email, ui.user_id
+		FROM user_identities ui
+		JOIN users duplicate ON duplicate.id = ui.user_id AND duplicate.deleted_at IS NULL
+		WHERE ui.email_verified AND ui.email <> ''
+		ORDER BY email, ui.user_id`).Scan(&identities).Error
+	if err != nil || len(identities) == 0 {
+		return nil, err
+	}
+
+	indexes := make([]string, 0, len(identities))
+	emails := make([]string, 0, len(identities))
+	for _, identity := range identities {
+		indexes = append(indexes, models.EmailBlindIndex(identity.Email))
+		emails = append(emails, identity.Email)
+	}
+
+	// Las filas sin índice ciego son anteriores al cifrado y siguen en claro
+	var targets []models.User
+	if err := db.Select("id", "email").
+		Where("email_bidx IN ? OR (email_bidx = '' AND LOWER(email) IN ?)", indexes, emails).
+		Find(&targets).Error; err != nil {
+		return nil, err
+	}
+	targetByEmail := make(map[string]uint, len(targets))
+	for _, target := range targets {
+		targetByEmail[utils.NormalizeEmail(target.Email)] = target.ID
+	}
+
+	var rows []duplicateRow
+	for _, identity := range identities {
+		if targetID, ok := targetByEmail[identity.Email]; ok && targetID != identity.UserID {
+			rows = append(rows, duplicateRow{Email: identity.Email, TargetUserID: targetID, DuplicateUserID: identity.UserID})
+		}
+	}
+	return rows, nil
+}
+
+// groupDuplicates agrupa las filas ordenadas por email y cuenta destino
+func groupDuplicates(rows []duplicateRow) []dto.DuplicateAccountResponse {
+	responses := []dto.DuplicateAccountResponse{}
+	for _, row := range rows {
+		last := len(responses) - 1
+		if last >= 0 && responses[last].Email == row.Email && responses[last].TargetUserID == row.TargetUserID {
+			responses[last].DuplicateUserIDs = append(responses[last].DuplicateUserIDs, row.DuplicateUserID)
+			continue
+		}
+		responses = append(responses, dto.DuplicateAccountResponse{
+			Email:            row.Email,
+			TargetUserID:     row.TargetUserID,
+			DuplicateUserIDs: []uint{row.DuplicateUserID},
+		})
+	}
+	return responses
+}
+
+// MergeAccounts fusiona la cuenta origen en la destino: identidades, historial, organizaciones y contraseña
+// pasan al destino y el origen queda desactivado, sin sesiones y eliminado. Solo se permite entre
+// cuentas que comparten un email verificado.
+func (s *IdentityService) MergeAccounts(req *dto.MergeAccountsRequest, actor *Actor) (*dto.UserResponse, error) {
+	if req.SourceUserID == req.TargetUserID {
+		return nil, utils.NewBadRequestError("source and target accounts must be different")
+	}
+
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var source, target models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&source, req.SourceUserID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return utils.NewNotFoundError("Source user")
+			}
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&target, req.TargetUserID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return utils.NewNotFoundError("Target user")
+			}
+			return err
+		}
+
+		shared, err := shareVerifiedEmail(tx, &source, &target)
+		if err != nil {
+			return err
+		}
+		if !shared {
+			return utils.NewBadRequestError("accounts do not share a verified email")
+		}
+
+		identities := tx.Model(&models.UserIdentity{}).Where("user_id = ?", source.ID).Update("user_id", target.ID)
+		if identities.Error != nil {
+			return identities.Error
+		}
+		if err := tx.Model(&models.LoginAttempt{}).Where("user_id = ?", source.ID).Update("user_id", target.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ? AND used_at IS NULL", source.ID).Delete(&models.MagicLinkToken{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.DeviceAuthorization{}).Where("user_id = ?", source.ID).Update("user_id", target.ID).Error; err != nil {
+			return err
+		}
+		// Las membresías que el destino no tenga pasan a él; las repetidas se descartan
+		memberships := tx.Model(&models.OrganizationMembership{}).
+			Where("user_id = ? AND organization_id NOT IN (SELECT organization_id FROM organization_memberships WHERE user_id = ?)", source.ID, target.ID).
+			Update("user_id", target.ID)
+		if memberships.Error != nil {
+			return memberships.Error
+		}
+		if err := tx.Where("user_id = ?", source.ID).Delete(&models.OrganizationMembership{}).Error; err != nil {
+			return err
+		}
+
+		// Las sesiones abiertas con la cuenta origen dejan de poder renovarse
+		sessions := tx.Model(&models.Session{}).Where("user_id = ? AND revoked_at IS NULL", source.ID).
+			Update("revoked_at", time.Now())
+		if sessions.Error != nil {
+			return sessions.Error
+		}
+
+		// La cuenta fusionada conserva todos los métodos de login, también la contraseña
+		passwordMoved := source.HasPassword && !target.HasPassword
+		if passwordMoved {
+			if err := tx.Model(&target).Updates(map[string]interface{}{
+				"password":     source.Password,
+				"has_password": true,
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(&source).Update("is_active", false).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&source).Error; err != nil {
+			return err
+		}
+
+		if err := s.audit.Record(tx, actor, auditEvent{
+			Action:     models.AuditAccountMerge,
+			TargetType: models.AuditTargetUser,
+			TargetID:   target.ID,
+			Metadata: map[string]interface{}{
+				"source_user_id":    source.ID,
+				"identities_moved":  identities.RowsAffected,
+				"memberships_moved": memberships.RowsAffected,
+				"password_moved":    passwordMoved,
+			},
+		}); err != nil {
+			return err
+		}
+		return s.audit.Record(tx, actor, auditEvent{
+			Action:     models.AuditUserDelete,
+			TargetType: models.AuditTargetUser,
+			TargetID:   source.ID,
+			Changes:    auditChanges{"is_active": {Old: source.IsActive, New: false}},
+			Metadata: map[string]interface{}{
+				"merged_into_user_id": target.ID,
+				"sessions_revoked":    sessions.RowsAffected,
+			},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.userService.GetUserByID(req.TargetUserID)
+}
+
+// shareVerifiedEmail indica si una de las cuentas tiene una identidad con email verificado igual al email de la otra
+func shareVerifiedEmail(db *gorm.DB, a, b *models.User) (bool, error) {
+	var count int64
+	err := db.Model(&models.UserIdentity{}).
+		Where("email_verified AND ((user_id = ? AND LOWER(email) = ?) OR (user_id = ? AND LOWER(email) = ?))",
+			a.ID, strings.ToLower(b.Email), b.ID, strings.ToLower(a.Email)).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// findIdentity busca una identidad externa por proveedor y sujeto; devuelve nil si no existe
+func findIdentity(db *gorm.DB, provider, providerRef, subject string) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+	err := db.Where("provider = ? AND provider_ref = ? AND subject = ?", provider, providerRef, subject).First(&identity).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// linkIdentity vincula una identidad a un usuario o actualiza su último uso si ya lo estaba.
+// Una identidad vinculada a otra cuenta activa no se puede robar: hay que fusionar las cuentas.
+func linkIdentity(db *gorm.DB, userID uint, identity *models.UserIdentity) error {
+	existing, err := findIdentity(db, identity.Provider, identity.ProviderRef, identity.Subject)
+	if err != nil {
+		return err
+	}
+
+	if existing == nil {
+		identity.ID = 0
+		identity.UserID = userID
+		return db.Create(identity).Error
+	}
+
+	if existing.UserID != userID {
+		var owner int64
+		if err := db.Model(&models.User{}).Where("id = ?", existing.UserID).Count(&owner).Error; err != nil {
+			return err
+		}
+		if owner > 0 {
+			return utils.NewConflictError("this identity is already linked to another account")
+		}
+	}
+
+	// Ya era de este usuario, o su dueño anterior fue eliminado y pasa al usuario actual
+	return db.Model(existing).Updates(map[string]interface{}{
+		"user_id":        userID,
+		"email":          identity.Email,
+		"email_verified": identity.EmailVerified,
+		"last_used_at":   identity.LastUsedAt,
+	}).Error
+}
@@ -0,0 +1,296 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"megabaseGo/internal/app/dto"
+	"megabaseGo/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// Tamaño de página por defecto y máximo de los listados
+const (
+	defaultPerPage = 20
+	maxPerPage     = 100
+)
+
+// listFilter aplica un filtro del listado con el valor recibido en la query string
+type listFilter func(db *gorm.DB, value string) (*gorm.DB, error)
+
+// listSpec describe qué admite el listado de un recurso: los campos por los que se puede
+// ordenar (con la función que lee su valor de un registro, necesaria para el cursor), el
+// orden por defecto y los filtros. Las columnas se cualifican con table.
+type listSpec[T any] struct {
+	table       string
+	sorts       map[string]func(*T) any
+	defaultSort string
+	filters     map[string]listFilter
+}
+
+// sortTerm campo de ordenación ya validado
+type sortTerm[T any] struct {
+	field string
+	desc  bool
+	value func(*T) any
+}
+
+// listCursor contenido del cursor opaco: el orden con el que se generó y los valores
+// de los campos de ordenación del último elemento devuelto
+type listCursor struct {
+	Sort   string            `json:"s"`
+	Values []json.RawMessage `json:"v"`
+}
+
+// paginate aplica filtros, orden y paginación a query y carga una página de registros.
+// El total se cuenta con los filtros aplicados; preloads se cargan solo para la página.
+func paginate[T any](query *gorm.DB, spec listSpec[T], params *dto.ListQuery, preloads ...string) ([]T, *dto.PageMeta, error) {
+	perPage := params.PerPage
+	if perPage <= 0 {
+		perPage = defaultPerPage
+	}
+	if perPage > maxPerPage {
+		perPage = maxPerPage
+	}
+
+	sortParam := params.Sort
+	if sortParam == "" {
+		sortParam = spec.defaultSort
+	}
+	terms, err := spec.parseSort(sortParam)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, name := range sortedKeys(params.Filters) {
+		filter, ok := spec.filters[name]
+		if !ok || params.Filters[name] == "" {
+			continue
+		}
+		if query, err = filter(query, params.Filters[name]); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Model(new(T)).Count(&total).Error; err != nil {
+		return nil, nil, err
+	}
+
+	meta := &dto.PageMeta{Total: total, PerPage: perPage, Sort: sortParam}
+	meta.TotalPages = int((total + int64(perPage) - 1) / int64(perPage))
+
+	if params.Cursor != "" {
+		condition, args, err := keysetCondition(spec.table, terms, params.Cursor, sortParam)
+		if err != nil {
+			return nil, nil, err
+		}
+		query = query.Where(condition, args...)
+	} else {
+		meta.Page = params.Page
+		if meta.Page <= 0 {
+			meta.Page = 1
+		}
+		query = query.Offset((meta.Page - 1) * perPage)
+	}
+
+	for _, term := range terms {
+		direction := "ASC"
+		if term.desc {
+			direction = "DESC"
+		}
+		query = query.Order(spec.table + "." + term.field + " " + direction)
+	}
+	for _, preload := range preloads {
+		query = query.Preload(preload)
+	}
+
+	// Un registro de más indica si hay página siguiente
+	var records []T
+	if err := query.Limit(perPage + 1).Find(&records).Error; err != nil {
+		return nil, nil, err
+	}
+	if len(records) > perPage {
+		records = records[:perPage]
+		if meta.NextCursor, err = encodeCursor(terms, &records[len(records)-1], sortParam); err != nil {
+			return nil, nil, err
+		}
+	}
+	meta.Count = len(records)
+
+	return records, meta, nil
+}
+
+// parseSort valida los campos de ordenación y añade el ID como desempate para que el orden sea total
+func (spec listSpec[T]) parseSort(value string) ([]sortTerm[T], error) {
+	var terms []sortTerm[T]
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		desc := strings.HasPrefix(part, "-")
+		field := strings.TrimPrefix(part, "-")
+		read, ok := spec.sorts[field]
+		if !ok {
+			return nil, utils.NewBadRequestError(fmt.Sprintf("cannot sort by %q; allowed fields: %s", field, strings.Join(sortedKeys(spec.sorts), ", ")))
+		}
+		if seen[field] {
+			return nil, utils.NewBadRequestError(fmt.Sprintf("sort field %q is repeated", field))
+		}
+		seen[field] = true
+		terms = append(terms, sortTerm[T]{field: field, desc: desc, value: read})
+	}
+	if !seen["id"] {
+		terms = append(terms, sortTerm[T]{field: "id", value: spec.sorts["id"]})
+	}
+	return terms, nil
+}
+
+// encodeCursor genera el cursor que apunta a continuación de record
+func encodeCursor[T any](terms []sortTerm[T], record *T, sortParam string) (string, error) {
+	cursor := listCursor{Sort: sortParam}
+	for _, term := range terms {
+		value, err := json.Marshal(term.value(record))
+		if err != nil {
+			return "", err
+		}
+		cursor.Values = append(cursor.Values, value)
+	}
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// keysetCondition decodifica el cursor y construye la condición "posterior al cursor" para el
+// orden dado: (a > va) OR (a = va AND b > vb) OR ..., con < en los campos descendentes
+func keysetCondition[T any](table string, terms []sortTerm[T], encoded, sortParam string) (string, []any, error) {
+	invalid := utils.NewBadRequestError("invalid cursor")
+
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", nil, invalid
+	}
+	var cursor listCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || len(cursor.Values) != len(terms) {
+		return "", nil, invalid
+	}
+	if cursor.Sort != sortParam {
+		return "", nil, utils.NewBadRequestError("cursor was issued for a different sort order")
+	}
+
+	// Cada valor se decodifica al tipo del campo para compararlo en la BD con su tipo real
+	values := make([]any, len(terms))
+	for i, term := range terms {
+		target := reflect.New(reflect.TypeOf(term.value(new(T))))
+		if err := json.Unmarshal(cursor.Values[i], target.Interface()); err != nil {
+			return "", nil, invalid
+		}
+		values[i] = target.Elem().Interface()
+	}
+
+	// Los campos anulables (punteros) siguen el orden de Postgres: NULL al final en ASC y al
+	// principio en DESC. Nada va después de NULL en ASC, salvo los empates.
+	nullable := make([]bool, len(terms))
+	null := make([]bool, len(terms))
+	for i, term := range terms {
+		nullable[i] = reflect.TypeOf(term.value(new(T))).Kind() == reflect.Pointer
+		null[i] = nullable[i] && reflect.ValueOf(values[i]).IsNil()
+	}
+
+	var clauses []string
+	var args []any
+	for i, term := range terms {
+		column := table + "." + term.field
+		var after string
+		var afterArgs []any
+		switch {
+		case null[i] && term.desc:
+			after = column + " IS NOT NULL"
+		case null[i]:
+			continue
+		case term.desc:
+			after, afterArgs = column+" < ?", []any{values[i]}
+		case nullable[i]:
+			after, afterArgs = "("+column+" > ? OR "+column+" IS NULL)", []any{values[i]}
+		default:
+			after, afterArgs = column+" > ?", []any{values[i]}
+		}
+
+		var parts []string
+		for j := 0; j < i; j++ {
+			if null[j] {
+				parts = append(parts, table+"."+terms[j].field+" IS NULL")
+			} else {
+				parts = append(parts, table+"."+terms[j].field+" = ?")
+				args = append(args, values[j])
+			}
+		}
+		parts = append(parts, after)
+		args = append(args, afterArgs...)
+		clauses = append(clauses, "("+strings.Join(parts, " AND ")+")")
+	}
+	return "(" + strings.Join(clauses, " OR ") + ")", args, nil
+}
+
+// containsFilter filtra por subcadena sin distinguir mayúsculas en alguna de las columnas
+func containsFilter(columns ...string) listFilter {
+	return func(db *gorm.DB, value string) (*gorm.DB, error) {
+		pattern := "%" + escapeLike(strings.ToLower(value)) + "%"
+		conditions := make([]string, len(columns))
+		args := make([]any, len(columns))
+		for i, column := range columns {
+			conditions[i] = "LOWER(" + column + ") LIKE ?"
+			args[i] = pattern
+		}
+		return db.Where("("+strings.Join(conditions, " OR ")+")", args...), nil
+	}
+}
+
+// boolFilter filtra una columna booleana con "true" o "false"
+func boolFilter(column, name string) listFilter {
+	return func(db *gorm.DB, value string) (*gorm.DB, error) {
+		switch value {
+		case "true":
+			return db.Where(column+" = ?", true), nil
+		case "false":
+			return db.Where(column+" = ?", false), nil
+		default:
+			return nil, utils.NewBadRequestError(name + " must be 'true' or 'false'")
+		}
+	}
+}
+
+// timeFilter filtra una columna de fecha desde (>=) o hasta (<=) el instante indicado, en RFC 3339 o YYYY-MM-DD.
+// Una fecha sin hora como límite superior incluye el día completo.
+func timeFilter(column, operator, name string) listFilter {
+	return func(db *gorm.DB, value string) (*gorm.DB, error) {
+		if at, err := time.Parse(time.RFC3339, value); err == nil {
+			return db.Where(column+" "+operator+" ?", at), nil
+		}
+		day, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			return nil, utils.NewBadRequestError(fmt.Sprintf("invalid %s: expected RFC 3339 timestamp or YYYY-MM-DD date", name))
+		}
+		if operator == "<=" {
+			return db.Where(column+" < ?", day.AddDate(0, 0, 1)), nil
+		}
+		return db.Where(column+" "+operator+" ?", day), nil
+	}
+}
+
+// sortedKeys claves de un mapa en orden alfabético
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
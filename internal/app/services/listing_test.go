@@ -0,0 +1,153 @@
+package services
+
+import (
+	"encoding/base64"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type cursorTestRecord struct {
+	ID          uint
+	Name        string
+	LastLoginAt *time.Time
+}
+
+var cursorTestSpec = listSpec[cursorTestRecord]{
+	table: "records",
+	sorts: map[string]func(*cursorTestRecord) any{
+		"id":            func(r *cursorTestRecord) any { return r.ID },
+		"name":          func(r *cursorTestRecord) any { return r.Name },
+		"last_login_at": func(r *cursorTestRecord) any { return r.LastLoginAt },
+	},
+	defaultSort: "id",
+}
+
+func TestKeysetCondition(t *testing.T) {
+	loginAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		sort     string
+		record   cursorTestRecord
+		wantSQL  string
+		wantArgs []any
+	}{
+		{"id only", "id", cursorTestRecord{ID: 7},
+			"((records.id > ?))", []any{uint(7)}},
+		{"descending id", "-id", cursorTestRecord{ID: 7},
+			"((records.id < ?))", []any{uint(7)}},
+		{"id added as tiebreaker", "name", cursorTestRecord{ID: 7, Name: "bob"},
+			"((records.name > ?) OR (records.name = ? AND records.id > ?))", []any{"bob", "bob", uint(7)}},
+		{"mixed directions", "-name,id", cursorTestRecord{ID: 7, Name: "bob"},
+			"((records.name < ?) OR (records.name = ? AND records.id > ?))", []any{"bob", "bob", uint(7)}},
+		{"nullable ascending includes trailing nulls", "last_login_at", cursorTestRecord{ID: 7, LastLoginAt: &loginAt},
+			"(((records.last_login_at > ? OR records.last_login_at IS NULL)) OR (records.last_login_at = ? AND records.id > ?))",
+			[]any{&loginAt, &loginAt, uint(7)}},
+		{"null ascending only continues among nulls", "last_login_at", cursorTestRecord{ID: 7},
+			"((records.last_login_at IS NULL AND records.id > ?))", []any{uint(7)}},
+		{"nullable descending", "-last_login_at", cursorTestRecord{ID: 7, LastLoginAt: &loginAt},
+			"((records.last_login_at < ?) OR (records.last_login_at = ? AND records.id > ?))",
+			[]any{&loginAt, &loginAt, uint(7)}},
+		{"null descending continues with non-null values", "-last_login_at", cursorTestRecord{ID: 7},
+			"((records.last_login_at IS NOT NULL) OR (records.last_login_at IS NULL AND records.id > ?))", []any{uint(7)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			terms, err := cursorTestSpec.parseSort(tt.sort)
+			if err != nil {
+				t.Fatalf("parseSort(%q) error: %v", tt.sort, err)
+			}
+			cursor, err := encodeCursor(terms, &tt.record, tt.sort)
+			if err != nil {
+				t.Fatalf("encodeCursor error: %v", err)
+			}
+			sql, args, err := keysetCondition(cursorTestSpec.table, terms, cursor, tt.sort)
+			if err != nil {
+				t.Fatalf("keysetCondition error: %v", err)
+			}
+			if sql != tt.wantSQL {
+				t.Errorf("sql = %q, want %q", sql, tt.wantSQL)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("args = %#v, want %#v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestKeysetConditionErrors(t *testing.T) {
+	terms, err := cursorTestSpec.parseSort("name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid, err := encodeCursor(terms, &cursorTestRecord{ID: 7, Name: "bob"}, "name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	encode := func(raw string) string { return base64.RawURLEncoding.EncodeToString([]byte(raw)) }
+
+	tests := []struct {
+		name    string
+		cursor  string
+		sort    string
+		wantErr string
+	}{
+		{"not base64", "!!!", "name", "invalid cursor"},
+		{"not json", encode("nope"), "name", "invalid cursor"},
+		{"wrong number of values", encode(`{"s":"name","v":["bob"]}`), "name", "invalid cursor"},
+		{"value of the wrong type", encode(`{"s":"name","v":["bob","seven"]}`), "name", "invalid cursor"},
+		{"different sort", valid, "-name", "cursor was issued for a different sort order"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			terms, err := cursorTestSpec.parseSort(tt.sort)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, _, err = keysetCondition(cursorTestSpec.table, terms, tt.cursor, tt.sort)
+			assertAPIError(t, err, http.StatusBadRequest, tt.wantErr)
+		})
+	}
+}
+
+func TestParseSort(t *testing.T) {
+	tests := []struct {
+		sort    string
+		want    []string
+		wantErr string
+	}{
+		{"id", []string{"id"}, ""},
+		{"-name", []string{"-name", "id"}, ""},
+		{"name, -id", []string{"name", "-id"}, ""},
+		{"email", nil, `cannot sort by "email"; allowed fields: id, last_login_at, name`},
+		{"name,-name", nil, `sort field "name" is repeated`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sort, func(t *testing.T) {
+			terms, err := cursorTestSpec.parseSort(tt.sort)
+			if tt.wantErr != "" {
+				assertAPIError(t, err, http.StatusBadRequest, tt.wantErr)
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseSort error: %v", err)
+			}
+			var got []string
+			for _, term := range terms {
+				field := term.field
+				if term.desc {
+					field = "-" + field
+				}
+				got = append(got, field)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("terms = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"time"
+
+	"megabaseGo/internal/app/dto"
+	"megabaseGo/internal/database"
+	"megabaseGo/internal/models"
+	"megabaseGo/internal/utils"
+)
+
+type LoginHistoryService struct {
+	mailer utils.Mailer
+	tenant *Tenant
+}
+
+// NewLoginHistoryService crea una nueva instancia del servicio de historial de login
+func NewLoginHistoryService() *LoginHistoryService {
+	return &LoginHistoryService{
+		mailer: utils.NewMailer(),
+	}
+}
+
+// ForTenant devuelve una copia del servicio limitada a los usuarios de la organización
+func (s *LoginHistoryService) ForTenant(tenant *Tenant) *LoginHistoryService {
+	scoped := *s
+	scoped.tenant = tenant
+	return &scoped
+}
+
+// RecordSuccess registra un login exitoso y avisa por email si llega desde un dispositivo nuevo
+func (s *LoginHistoryService) RecordSuccess(user *models.User, method string, meta *dto.RequestMeta) {
+	attempt := s.newAttempt(method, meta)
+	attempt.UserID = &user.ID
+	attempt.Identifier = user.UserName
+	attempt.Success = true
+
+	db := database.GetDB()
+
+	// Dispositivo nuevo: el usuario ya había entrado antes, pero nunca con esta huella
+	var previousLogins, knownDevice int64
+	db.Model(&models.LoginAttempt{}).Where("user_id = ? AND success = ?", user.ID, true).Count(&previousLogins)
+	db.Model(&models.LoginAttempt{}).
+		Where("user_id = ? AND success = ? AND fingerprint = ?", user.ID, true, attempt.Fingerprint).
+		Count(&knownDevice)
+	attempt.NewDevice = previousLogins > 0 && knownDevice == 0
+
+	if err := db.Create(attempt).Error; err != nil {
+		log.Printf("Error registrando login de usuario %d: %v", user.ID, err)
+		return
+	}
+
+	if attempt.NewDevice {
+		go s.sendNewDeviceAlert(user, attempt)
+	}
+}
+
+// RecordFailure registra un intento de login fallido
+func (s *LoginHistoryService) RecordFailure(userID *uint, identifier, reason, method string, meta *dto.RequestMeta) {
+	attempt := s.newAttempt(method, meta)
+	attempt.UserID = userID
+	attempt.Identifier = identifier
+	attempt.FailureReason = reason
+
+	if err := database.GetDB().Create(attempt).Error; err != nil {
+		log.Printf("Error registrando intento de login fallido: %v", err)
+	}
+}
+
+// GetUserLogins obtiene el historial de login de un usuario, del más reciente al más antiguo.
+// Con tenant el usuario debe ser miembro de la organización.
+func (s *LoginHistoryService) GetUserLogins(userID uint, limit, offset int) ([]dto.LoginAttemptResponse, int64, error) {
+	db := database.GetDB()
+
+	if s.tenant != nil {
+		var members int64
+		if err := db.Model(&models.User{}).Scopes(tenantUsers(s.tenant)).Where("users.id = ?", userID).Count(&members).Error; err != nil {
+			return nil, 0, err
+		}
+		if members == 0 {
+			return nil, 0, utils.NewNotFoundError("User")
+		}
+	}
+
+	var total int64
+	query := db.Model(&models.LoginAttempt{}).Where("user_id = ?", userID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var attempts []models.LoginAttempt
+	if err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&attempts).Error; err != nil {
+		return nil, 0, err
+	}
+
+	responses := make([]dto.LoginAttemptResponse, 0, len(attempts))
+	for _, attempt := range attempts {
+		responses = append(responses, dto.LoginAttemptResponse{
+			ID:            attempt.ID,
+			UserID:        attempt.UserID,
+			Success:       attempt.Success,
+			FailureReason: attempt.FailureReason,
+			Method:        attempt.Method,
+			IPAddress:     attempt.IPAddress,
+			UserAgent:     attempt.UserAgent,
+			NewDevice:     attempt.NewDevice,
+			CreatedAt:     attempt.CreatedAt,
+		})
+	}
+
+	return responses, total, nil
+}
+
+func (s *LoginHistoryService) newAttempt(method string, meta *dto.RequestMeta) *models.LoginAttempt {
+	attempt := &models.LoginAttempt{Method: method}
+	if meta != nil {
+		attempt.IPAddress = meta.IPAddress
+		attempt.UserAgent = truncate(meta.UserAgent, 512)
+		attempt.Fingerprint = deviceFingerprint(meta.IPAddress, meta.UserAgent)
+	}
+	return attempt
+}
+
+func (s *LoginHistoryService) sendNewDeviceAlert(user *models.User, attempt *models.LoginAttempt) {
+	body := fmt.Sprintf(
+		"Hola %s,\n\nDetectamos un inicio de sesión en tu cuenta desde un dispositivo o ubicación nuevos:\n\n"+
+			"  Fecha: %s\n  IP: %s\n  Navegador: %s\n  Método: %s\n\n"+
+			"Si fuiste tú, no necesitas hacer nada. Si no reconoces este acceso, cambia tu contraseña inmediatamente.",
+		user.Name, attempt.CreatedAt.Format(time.RFC1123), attempt.IPAddress, attempt.UserAgent, attempt.Method,
+	)
+	if err := s.mailer.Send(user.Email, "Nuevo inicio de sesión en tu cuenta", body); err != nil {
+		log.Printf("Error enviando alerta de nuevo dispositivo a %s: %v", user.Email, err)
+	}
+}
+
+// deviceFingerprint combina el user agent con la red del cliente (/24 en IPv4, /64 en IPv6)
+// para que un cambio de IP dentro de la misma red no cuente como dispositivo nuevo
+func deviceFingerprint(ipAddress, userAgent string) string {
+	network := ipAddress
+	if ip := net.ParseIP(ipAddress); ip != nil {
+		if ipv4 := ip.To4(); ipv4 != nil {
+			network = ipv4.Mask(net.CIDRMask(24, 32)).String()
+		} else {
+			network = ip.Mask(net.CIDRMask(64, 128)).String()
+		}
+	}
+	return utils.HashToken(userAgent + "|" + network)
+}
+
+func truncate(value string, max int) string {
+	if len(value) > max {
+		return value[:max]
+	}
+	return value
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"time"
+
+	"megabaseGo/internal/app/dto"
+	"megabaseGo/internal/database"
+	"megabaseGo/internal/models"
+	"megabaseGo/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+type MagicLinkService struct {
+	authService *AuthService
+	jwtManager  *utils.JWTManager
+	mailer      utils.Mailer
+	linkURL     string
+	ttl         time.Duration
+	rateLimit   int
+	rateWindow  time.Duration
+}
+
+// NewMagicLinkService crea una nueva instancia del servicio de enlaces mágicos
+func NewMagicLinkService() *MagicLinkService {
+	return &MagicLinkService{
+		authService: NewAuthService(),
+		jwtManager:  utils.NewJWTManager(),
+		mailer:      utils.NewMailer(),
+		linkURL:     utils.GetEnv("MAGIC_LINK_URL", "http://localhost:3000/auth/magic-link"),
+		ttl:         time.Duration(utils.GetEnvInt("MAGIC_LINK_TTL_MINUTES", 15)) * time.Minute,
+		rateLimit:   utils.GetEnvInt("MAGIC_LINK_RATE_LIMIT", 3),
+		rateWindow:  time.Duration(utils.GetEnvInt("MAGIC_LINK_RATE_WINDOW_MINUTES", 15)) * time.Minute,
+	}
+}
+
+// RequestLink emite un enlace mágico y lo envía por email.
+// Para no revelar qué emails existen, un email desconocido no devuelve error.
+func (s *MagicLinkService) RequestLink(req *dto.MagicLinkRequest, requestIP string) error {
+	db := database.GetDB()
+	email := utils.NormalizeEmail(req.Email)
+
+	// Rate limit por email
+	var recent int64
+	if err := db.Model(&models.MagicLinkToken{}).
+		Where("email = ? AND created_at > ?", email, time.Now().Add(-s.rateWindow)).
+		Count(&recent).Error; err != nil {
+		return err
+	}
+	if recent >= int64(s.rateLimit) {
+		return utils.NewTooManyRequestsError("too many magic link requests, try again later")
+	}
+
+	var user models.User
+	var userID *uint
+	err := db.Scopes(byEmail(email)).First(&user).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if err == nil && user.IsActive {
+		userID = &user.ID
+	}
+
+	jti, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return errors.New("failed to generate magic link")
+	}
+
+	record := models.MagicLinkToken{
+		Email:     email,
+		UserID:    userID,
+		TokenHash: utils.HashToken(jti),
+		RequestIP: requestIP,
+		ExpiresAt: time.Now().Add(s.ttl),
+	}
+	if err := db.Create(&record).Error; err != nil {
+		return err
+	}
+
+	// El registro cuenta para el rate limit aunque el email no exista
+	if userID == nil {
+		return nil
+	}
+
+	token, err := s.jwtManager.GenerateMagicLinkToken(user.ID, user.Email, jti, s.ttl)
+	if err != nil {
+		return errors.New("failed to generate magic link")
+	}
+
+	link := s.linkURL + "?token=" + url.QueryEscape(token)
+	body := fmt.Sprintf(
+		"Hola %s,\n\nUsa el siguiente enlace para iniciar sesión. Caduca en %d minutos y solo puede usarse una vez:\n\n%s\n\nSi no solicitaste este enlace, ignora este correo.",
+		user.Name, int(s.ttl.Minutes()), link,
+	)
+
+	go func() {
+		if err := s.mailer.Send(user.Email, "Tu enlace de acceso", body); err != nil {
+			log.Printf("Error enviando enlace mágico a %s: %v", user.Email, err)
+		}
+	}()
+
+	return nil
+}
+
+// VerifyLink canjea un enlace mágico válido por el par de tokens estándar
+func (s *MagicLinkService) VerifyLink(req *dto.MagicLinkVerifyRequest, meta *dto.RequestMeta) (*dto.AuthResponse, error) {
+	claims, err := s.jwtManager.ValidateMagicLinkToken(req.Token)
+	if err != nil {
+		return nil, utils.NewUnauthorizedError("invalid or expired magic link")
+	}
+
+	db := database.GetDB()
+	now := time.Now()
+
+	// Marcar como usado de forma atómica: solo una petición puede ganar
+	result := db.Model(&models.MagicLinkToken{}).
+		Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", utils.HashToken(claims.ID), now).
+		Update("used_at", now)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected != 1 {
+		return nil, utils.NewUnauthorizedError("magic link has already been used or expired")
+	}
+
+	userID, err := strconv.Atoi(claims.Subject)
+	if err != nil {
+		return nil, utils.NewUnauthorizedError("invalid or expired magic link")
+	}
+
+	var user models.User
+	if err := db.Preload("Role").First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewUnauthorizedError("invalid or expired magic link")
+		}
+		return nil, err
+	}
+
+	// El enlace deja de ser válido si el email cambió después de emitirlo
+	if user.Email != claims.Email {
+		return nil, utils.NewUnauthorizedError("invalid or expired magic link")
+	}
+
+	if !user.IsActive {
+		return nil, utils.NewForbiddenError("user account is disabled")
+	}
+
+	user.LastLoginAt = now
+	db.Save(&user)
+	s.authService.recordLogin(&user, models.LoginMethodMagicLink, meta)
+
+	// Sin PasswordAuthAt: quien lee el buzón no ha demostrado la contraseña, así que la sesión
+	// no sirve para operaciones sensibles sin re-autenticarse
+	return s.authService.issueAuthResponse(&user, now, sessionOptions{Meta: meta})
+}
@@ -0,0 +1,367 @@
+package services
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"megabaseGo/internal/app/dto"
+	"megabaseGo/internal/database"
+	"megabaseGo/internal/models"
+	"megabaseGo/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// El slug empieza por letra para no confundirse con un ID en la cabecera X-Organization
+var organizationSlugPattern = regexp.MustCompile(`^[a-z][a-z0-9-]{0,99}$`)
+
+type OrganizationService struct{}
+
+// NewOrganizationService crea una nueva instancia del servicio de organizaciones
+func NewOrganizationService() *OrganizationService {
+	return &OrganizationService{}
+}
+
+// ResolveTenant determina la organización activa de una petición: la solicitada (slug o ID),
+// la del token o, si el usuario solo pertenece a una, esa. Devuelve nil si no hay ninguna.
+// El usuario debe ser miembro de la organización resuelta.
+func (s *OrganizationService) ResolveTenant(userID uint, requested string, tokenOrganizationID uint) (*Tenant, error) {
+	db := database.GetDB()
+
+	var membership *models.OrganizationMembership
+	var err error
+	switch {
+	case requested != "":
+		organization, findErr := findOrganization(db, requested)
+		if findErr != nil {
+			return nil, utils.NewForbiddenError("not a member of this organization")
+		}
+		membership, err = findMembership(db, organization.ID, userID)
+	case tokenOrganizationID != 0:
+		membership, err = findMembership(db, tokenOrganizationID, userID)
+	default:
+		membership, err = singleMembership(db, userID)
+		if err == nil && membership == nil {
+			return nil, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if membership == nil {
+		return nil, utils.NewForbiddenError("not a member of this organization")
+	}
+
+	return &Tenant{OrganizationID: membership.OrganizationID, RoleID: membership.RoleID}, nil
+}
+
+// ListUserOrganizations lista las organizaciones activas a las que pertenece un usuario
+func (s *OrganizationService) ListUserOrganizations(userID uint) ([]dto.MembershipResponse, error) {
+	var memberships []models.OrganizationMembership
+	err := database.GetDB().Preload("Organization").Preload("Role").
+		Joins("JOIN organizations ON organizations.id = organization_memberships.organization_id").
+		Where("organizations.is_active AND organizations.deleted_at IS NULL").
+		Where("organization_memberships.user_id = ?", userID).
+		Order("organizations.slug").
+		Find(&memberships).Error
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]dto.MembershipResponse, 0, len(memberships))
+	for i := range memberships {
+		responses = append(responses, *s.toMembershipResponse(&memberships[i], nil))
+	}
+	return responses, nil
+}
+
+// CreateOrganization crea una organización
+func (s *OrganizationService) CreateOrganization(req *dto.OrganizationRequest) (*dto.OrganizationResponse, error) {
+	db := database.GetDB()
+
+	organization := models.Organization{IsActive: true}
+	if err := s.applyOrganizationRequest(&organization, req); err != nil {
+		return nil, err
+	}
+
+	var existing models.Organization
+	if err := db.Unscoped().Where("slug = ?", organization.Slug).First(&existing).Error; err == nil {
+		return nil, utils.NewConflictError("organization with this slug already exists")
+	}
+
+	if err := db.Create(&organization).Error; err != nil {
+		return nil, err
+	}
+	if !organization.IsActive {
+		db.Model(&organization).Update("is_active", false)
+	}
+	return s.toOrganizationResponse(&organization), nil
+}
+
+// GetOrganizations lista las organizaciones
+func (s *OrganizationService) GetOrganizations() ([]dto.OrganizationResponse, error) {
+	var organizations []models.Organization
+	if err := database.GetDB().Order("slug").Find(&organizations).Error; err != nil {
+		return nil, err
+	}
+
+	responses := make([]dto.OrganizationResponse, 0, len(organizations))
+	for i := range organizations {
+		responses = append(responses, *s.toOrganizationResponse(&organizations[i]))
+	}
+	return responses, nil
+}
+
+// GetOrganization obtiene una organización por ID
+func (s *OrganizationService) GetOrganization(id uint) (*dto.OrganizationResponse, error) {
+	organization, err := s.findOrganizationByID(database.GetDB(), id)
+	if err != nil {
+		return nil, err
+	}
+	return s.toOrganizationResponse(organization), nil
+}
+
+// UpdateOrganization actualiza una organización
+func (s *OrganizationService) UpdateOrganization(id uint, req *dto.OrganizationRequest) (*dto.OrganizationResponse, error) {
+	db := database.GetDB()
+
+	organization, err := s.findOrganizationByID(db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.applyOrganizationRequest(organization, req); err != nil {
+		return nil, err
+	}
+
+	var existing models.Organization
+	if err := db.Unscoped().Where("slug = ? AND id <> ?", organization.Slug, id).First(&existing).Error; err == nil {
+		return nil, utils.NewConflictError("organization with this slug already exists")
+	}
+
+	if err := db.Omit("Memberships").Save(organization).Error; err != nil {
+		return nil, err
+	}
+	return s.toOrganizationResponse(organization), nil
+}
+
+// DeleteOrganization elimina una organización (soft delete) y sus membresías
+func (s *OrganizationService) DeleteOrganization(id uint) error {
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&models.Organization{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return utils.NewNotFoundError("Organization")
+		}
+		return tx.Where("organization_id = ?", id).Delete(&models.OrganizationMembership{}).Error
+	})
+}
+
+// ListMembers lista los miembros de una organización con su rol en ella
+func (s *OrganizationService) ListMembers(organizationID uint) ([]dto.MembershipResponse, error) {
+	db := database.GetDB()
+
+	organization, err := s.findOrganizationByID(db, organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	var memberships []models.OrganizationMembership
+	if err := db.Preload("Role").Where("organization_id = ?", organizationID).
+		Where("user_id IN (SELECT id FROM users WHERE deleted_at IS NULL)").Order("id").Find(&memberships).Error; err != nil {
+		return nil, err
+	}
+
+	users, err := s.membersByID(db, memberships)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]dto.MembershipResponse, 0, len(memberships))
+	for i := range memberships {
+		memberships[i].Organization = *organization
+		responses = append(responses, *s.toMembershipResponse(&memberships[i], users[memberships[i].UserID]))
+	}
+	return responses, nil
+}
+
+// AddMember añade un usuario a una organización con un rol de la organización o de sistema
+func (s *OrganizationService) AddMember(organizationID uint, req *dto.MembershipRequest) (*dto.MembershipResponse, error) {
+	db := database.GetDB()
+
+	organization, err := s.findOrganizationByID(db, organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	if err := db.First(&user, req.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("User")
+		}
+		return nil, err
+	}
+
+	role, err := s.findMembershipRole(db, organizationID, req.RoleID)
+	if err != nil {
+		return nil, err
+	}
+
+	var existing models.OrganizationMembership
+	if err := db.Where("organization_id = ? AND user_id = ?", organizationID, req.UserID).First(&existing).Error; err == nil {
+		return nil, utils.NewConflictError("user is already a member of this organization")
+	}
+
+	membership := models.OrganizationMembership{
+		OrganizationID: organizationID,
+		UserID:         req.UserID,
+		RoleID:         role.ID,
+	}
+	if err := db.Create(&membership).Error; err != nil {
+		return nil, err
+	}
+
+	membership.Organization = *organization
+	membership.Role = *role
+	return s.toMembershipResponse(&membership, &user), nil
+}
+
+// UpdateMember cambia el rol de un miembro de la organización
+func (s *OrganizationService) UpdateMember(organizationID, userID uint, req *dto.UpdateMembershipRequest) (*dto.MembershipResponse, error) {
+	db := database.GetDB()
+
+	organization, err := s.findOrganizationByID(db, organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	var membership models.OrganizationMembership
+	if err := db.Where("organization_id = ? AND user_id = ?", organizationID, userID).First(&membership).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("Membership")
+		}
+		return nil, err
+	}
+
+	role, err := s.findMembershipRole(db, organizationID, req.RoleID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&membership).Update("role_id", role.ID).Error; err != nil {
+			return err
+		}
+		// El rol de la membresía forma parte del usuario que ve la organización
+		return touchUser(tx, userID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	membership.Organization = *organization
+	membership.Role = *role
+	return s.toMembershipResponse(&membership, nil), nil
+}
+
+// RemoveMember saca a un usuario de la organización
+func (s *OrganizationService) RemoveMember(organizationID, userID uint) error {
+	result := database.GetDB().Where("organization_id = ? AND user_id = ?", organizationID, userID).
+		Delete(&models.OrganizationMembership{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return utils.NewNotFoundError("Membership")
+	}
+	return nil
+}
+
+func (s *OrganizationService) findOrganizationByID(db *gorm.DB, id uint) (*models.Organization, error) {
+	var organization models.Organization
+	if err := db.First(&organization, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("Organization")
+		}
+		return nil, err
+	}
+	return &organization, nil
+}
+
+// findMembershipRole valida que el rol sea de la organización o de sistema
+func (s *OrganizationService) findMembershipRole(db *gorm.DB, organizationID, roleID uint) (*models.Role, error) {
+	var role models.Role
+	if err := db.Scopes(tenantRoles(&Tenant{OrganizationID: organizationID})).First(&role, roleID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewBadRequestError("role not found")
+		}
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (s *OrganizationService) membersByID(db *gorm.DB, memberships []models.OrganizationMembership) (map[uint]*models.User, error) {
+	ids := make([]uint, 0, len(memberships))
+	for _, membership := range memberships {
+		ids = append(ids, membership.UserID)
+	}
+
+	users := map[uint]*models.User{}
+	if len(ids) == 0 {
+		return users, nil
+	}
+
+	var found []models.User
+	if err := db.Where("id IN ?", ids).Find(&found).Error; err != nil {
+		return nil, err
+	}
+	for i := range found {
+		users[found[i].ID] = &found[i]
+	}
+	return users, nil
+}
+
+func (s *OrganizationService) applyOrganizationRequest(organization *models.Organization, req *dto.OrganizationRequest) error {
+	slug := strings.ToLower(strings.TrimSpace(req.Slug))
+	if !organizationSlugPattern.MatchString(slug) {
+		return utils.NewValidationError("slug must start with a letter and contain only lowercase letters, digits and dashes")
+	}
+
+	organization.Slug = slug
+	organization.Name = req.Name
+	if req.IsActive != nil {
+		organization.IsActive = *req.IsActive
+	}
+	return nil
+}
+
+func (s *OrganizationService) toOrganizationResponse(organization *models.Organization) *dto.OrganizationResponse {
+	return &dto.OrganizationResponse{
+		ID:        organization.ID,
+		Slug:      organization.Slug,
+		Name:      organization.Name,
+		IsActive:  organization.IsActive,
+		CreatedAt: organization.CreatedAt,
+		UpdatedAt: organization.UpdatedAt,
+	}
+}
+
+func (s *OrganizationService) toMembershipResponse(membership *models.OrganizationMembership, user *models.User) *dto.MembershipResponse {
+	response := &dto.MembershipResponse{
+		OrganizationID:   membership.OrganizationID,
+		OrganizationSlug: membership.Organization.Slug,
+		OrganizationName: membership.Organization.Name,
+		UserID:           membership.UserID,
+		RoleID:           membership.RoleID,
+		RoleName:         membership.Role.Name,
+		CreatedAt:        membership.CreatedAt,
+	}
+	if user != nil {
+		response.UserName = user.UserName
+		response.Email = user.Email
+	}
+	return response
+}
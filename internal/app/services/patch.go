@@ -0,0 +1,295 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"megabaseGo/internal/app/dto"
+	"megabaseGo/internal/utils"
+)
+
+// errPatchTestFailed operación "test" de JSON Patch que no se cumple
+var errPatchTestFailed = errors.New("test failed")
+
+// jsonPatchOperation operación de JSON Patch (RFC 6902). Value queda a nil si no viene
+// y a "null" si viene como null, para distinguir ambos casos.
+type jsonPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// applyPatch aplica el parche al documento JSON de current y decodifica el resultado en patched,
+// rechazando campos desconocidos. La validación del resultado queda para quien llama.
+// Un parche mal formado es 400 y una operación "test" que no se cumple, 409.
+func applyPatch(current any, patch *dto.Patch, patched any) error {
+	data, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+	var document any
+	if err := json.Unmarshal(data, &document); err != nil {
+		return err
+	}
+
+	switch patch.ContentType {
+	case dto.MergePatchContentType:
+		var mergePatch any
+		if err := json.Unmarshal(patch.Body, &mergePatch); err != nil {
+			return utils.NewBadRequestError("invalid merge patch: " + err.Error())
+		}
+		if _, isObject := mergePatch.(map[string]any); !isObject {
+			return utils.NewBadRequestError("merge patch must be a JSON object")
+		}
+		document = mergePatchValue(document, mergePatch)
+	case dto.JSONPatchContentType:
+		var operations []jsonPatchOperation
+		if err := json.Unmarshal(patch.Body, &operations); err != nil {
+			return utils.NewBadRequestError("invalid JSON patch: expected an array of operations")
+		}
+		for i, operation := range operations {
+			document, err = operation.apply(document)
+			if errors.Is(err, errPatchTestFailed) {
+				return utils.NewConflictError(fmt.Sprintf("JSON patch operation %d: test failed at %q", i, operation.Path))
+			}
+			if err != nil {
+				return utils.NewBadRequestError(fmt.Sprintf("invalid JSON patch operation %d (%s %q): %v", i, operation.Op, operation.Path, err))
+			}
+		}
+	default:
+		return utils.NewUnsupportedMediaTypeError("PATCH requires " + dto.MergePatchContentType + " or " + dto.JSONPatchContentType)
+	}
+
+	result, err := json.Marshal(document)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(result))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(patched); err != nil {
+		return utils.NewBadRequestError("patched document is invalid: " + strings.TrimPrefix(err.Error(), "json: "))
+	}
+	return nil
+}
+
+// mergePatchValue aplica un merge patch (RFC 7396): los objetos se fusionan recursivamente,
+// null elimina el miembro y cualquier otro valor sustituye al actual
+func mergePatchValue(target, patch any) any {
+	patchObject, isObject := patch.(map[string]any)
+	if !isObject {
+		return patch
+	}
+	targetObject, isObject := target.(map[string]any)
+	if !isObject {
+		targetObject = make(map[string]any)
+	}
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+		} else {
+			targetObject[key] = mergePatchValue(targetObject[key], value)
+		}
+	}
+	return targetObject
+}
+
+// apply ejecuta la operación sobre el documento y devuelve el documento resultante
+func (o jsonPatchOperation) apply(document any) (any, error) {
+	path, err := parseJSONPointer(o.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch o.Op {
+	case "add", "replace", "test":
+		if o.Value == nil {
+			return nil, errors.New("value is required")
+		}
+		var value any
+		if err := json.Unmarshal(o.Value, &value); err != nil {
+			return nil, err
+		}
+		switch o.Op {
+		case "add":
+			return jsonPointerAdd(document, path, value)
+		case "replace":
+			if _, err := jsonPointerGet(document, path); err != nil {
+				return nil, err
+			}
+			if len(path) == 0 {
+				return value, nil
+			}
+			if document, err = jsonPointerRemove(document, path); err != nil {
+				return nil, err
+			}
+			return jsonPointerAdd(document, path, value)
+		default:
+			current, err := jsonPointerGet(document, path)
+			if err != nil {
+				return nil, err
+			}
+			if !reflect.DeepEqual(current, value) {
+				return nil, errPatchTestFailed
+			}
+			return document, nil
+		}
+	case "remove":
+		return jsonPointerRemove(document, path)
+	case "move", "copy":
+		from, err := parseJSONPointer(o.From)
+		if err != nil {
+			return nil, fmt.Errorf("from: %v", err)
+		}
+		value, err := jsonPointerGet(document, from)
+		if err != nil {
+			return nil, fmt.Errorf("from: %v", err)
+		}
+		if o.Op == "copy" {
+			// Copia profunda: el valor no puede compartir mapas ni slices con el original
+			data, _ := json.Marshal(value)
+			value = nil
+			if err := json.Unmarshal(data, &value); err != nil {
+				return nil, err
+			}
+			return jsonPointerAdd(document, path, value)
+		}
+		if strings.HasPrefix(o.Path+"/", o.From+"/") && o.Path != o.From {
+			return nil, errors.New("cannot move a value into one of its children")
+		}
+		if document, err = jsonPointerRemove(document, from); err != nil {
+			return nil, err
+		}
+		return jsonPointerAdd(document, path, value)
+	}
+	return nil, fmt.Errorf("unsupported op %q (add, remove, replace, move, copy or test)", o.Op)
+}
+
+// parseJSONPointer separa un JSON Pointer (RFC 6901) en sus segmentos; "" es el documento completo
+func parseJSONPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("path %q must start with '/'", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func jsonPointerGet(document any, path []string) (any, error) {
+	node := document
+	for _, token := range path {
+		switch container := node.(type) {
+		case map[string]any:
+			value, ok := container[token]
+			if !ok {
+				return nil, fmt.Errorf("member %q does not exist", token)
+			}
+			node = value
+		case []any:
+			index, err := jsonArrayIndex(container, token, false)
+			if err != nil {
+				return nil, err
+			}
+			node = container[index]
+		default:
+			return nil, fmt.Errorf("cannot traverse into %q", token)
+		}
+	}
+	return node, nil
+}
+
+func jsonPointerAdd(document any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return jsonPointerUpdate(document, path, func(container any, token string) (any, error) {
+		switch container := container.(type) {
+		case map[string]any:
+			container[token] = value
+			return container, nil
+		case []any:
+			index, err := jsonArrayIndex(container, token, true)
+			if err != nil {
+				return nil, err
+			}
+			container = append(container, nil)
+			copy(container[index+1:], container[index:])
+			container[index] = value
+			return container, nil
+		}
+		return nil, fmt.Errorf("cannot add %q to a scalar value", token)
+	})
+}
+
+func jsonPointerRemove(document any, path []string) (any, error) {
+	if len(path) == 0 {
+		return nil, errors.New("cannot remove the whole document")
+	}
+	return jsonPointerUpdate(document, path, func(container any, token string) (any, error) {
+		switch container := container.(type) {
+		case map[string]any:
+			if _, ok := container[token]; !ok {
+				return nil, fmt.Errorf("member %q does not exist", token)
+			}
+			delete(container, token)
+			return container, nil
+		case []any:
+			index, err := jsonArrayIndex(container, token, false)
+			if err != nil {
+				return nil, err
+			}
+			return append(container[:index], container[index+1:]...), nil
+		}
+		return nil, fmt.Errorf("cannot remove %q from a scalar value", token)
+	})
+}
+
+// jsonPointerUpdate aplica fn al contenedor padre del último segmento y vuelve a colocar cada
+// contenedor modificado en el suyo (los slices pueden cambiar de cabecera al insertar o borrar)
+func jsonPointerUpdate(node any, path []string, fn func(container any, token string) (any, error)) (any, error) {
+	if len(path) == 1 {
+		return fn(node, path[0])
+	}
+	child, err := jsonPointerGet(node, path[:1])
+	if err != nil {
+		return nil, err
+	}
+	updated, err := jsonPointerUpdate(child, path[1:], fn)
+	if err != nil {
+		return nil, err
+	}
+	switch container := node.(type) {
+	case map[string]any:
+		container[path[0]] = updated
+	case []any:
+		index, _ := jsonArrayIndex(container, path[0], false)
+		container[index] = updated
+	}
+	return node, nil
+}
+
+// jsonArrayIndex índice de un segmento sobre un array; "-" (final) y len solo valen al añadir.
+// RFC 6901 solo admite dígitos sin ceros a la izquierda: ni signo ni espacios.
+func jsonArrayIndex(array []any, token string, adding bool) (int, error) {
+	if token == "-" && adding {
+		return len(array), nil
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || strings.Trim(token, "0123456789") != "" || (token != "0" && strings.HasPrefix(token, "0")) {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	if index > len(array) || (index == len(array) && !adding) {
+		return 0, fmt.Errorf("array index %d is out of bounds", index)
+	}
+	return index, nil
+}
@@ -0,0 +1,219 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"megabaseGo/internal/app/dto"
+	"megabaseGo/internal/utils"
+)
+
+// Casos del apéndice A de RFC 6902 y algunos propios
+func TestApplyJSONPatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		patch    string
+		want     string
+	}{
+		{"A.1 add object member", `{"foo":"bar"}`,
+			`[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`},
+		{"A.2 add array element", `{"foo":["bar","baz"]}`,
+			`[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+		{"A.3 remove object member", `{"baz":"qux","foo":"bar"}`,
+			`[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+		{"A.4 remove array element", `{"foo":["bar","qux","baz"]}`,
+			`[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+		{"A.5 replace value", `{"baz":"qux","foo":"bar"}`,
+			`[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+		{"A.6 move value", `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`,
+			`[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			`{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{"A.7 move array element", `{"foo":["all","grass","cows","eat"]}`,
+			`[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`},
+		{"A.8 test success", `{"baz":"qux","foo":["a",2,"c"]}`,
+			`[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`,
+			`{"baz":"qux","foo":["a",2,"c"]}`},
+		{"A.10 add nested member", `{"foo":"bar"}`,
+			`[{"op":"add","path":"/child","value":{"grandchild":{}}}]`, `{"foo":"bar","child":{"grandchild":{}}}`},
+		{"A.11 ignore unrecognized elements", `{"foo":"bar"}`,
+			`[{"op":"add","path":"/baz","value":"qux","xyz":123}]`, `{"foo":"bar","baz":"qux"}`},
+		{"A.14 escape ordering", `{"/":9,"~1":10}`,
+			`[{"op":"test","path":"/~01","value":10}]`, `{"/":9,"~1":10}`},
+		{"A.16 add array value", `{"foo":["bar"]}`,
+			`[{"op":"add","path":"/foo/-","value":["abc","def"]}]`, `{"foo":["bar",["abc","def"]]}`},
+		{"append with index equal to length", `{"foo":["bar"]}`,
+			`[{"op":"add","path":"/foo/1","value":"baz"}]`, `{"foo":["bar","baz"]}`},
+		{"add null value", `{"foo":"bar"}`,
+			`[{"op":"add","path":"/baz","value":null}]`, `{"foo":"bar","baz":null}`},
+		{"copy is deep", `{"a":{"b":1}}`,
+			`[{"op":"copy","from":"/a","path":"/c"},{"op":"replace","path":"/c/b","value":2}]`,
+			`{"a":{"b":1},"c":{"b":2}}`},
+		{"move to sibling with common prefix", `{"a":1}`,
+			`[{"op":"move","from":"/a","path":"/ab"}]`, `{"ab":1}`},
+		{"replace whole document", `{"a":1}`,
+			`[{"op":"replace","path":"","value":{"b":2}}]`, `{"b":2}`},
+		{"operations apply in order", `{"a":[]}`,
+			`[{"op":"add","path":"/a/-","value":1},{"op":"add","path":"/a/0","value":0},{"op":"test","path":"/a","value":[0,1]}]`,
+			`{"a":[0,1]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyTestPatch(tt.document, dto.JSONPatchContentType, tt.patch)
+			if err != nil {
+				t.Fatalf("applyPatch error: %v", err)
+			}
+			assertJSONEqual(t, got, tt.want)
+		})
+	}
+}
+
+func TestApplyJSONPatchErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		document   string
+		patch      string
+		wantStatus int
+		wantErr    string
+	}{
+		{"A.9 test failure", `{"baz":"qux","foo":["a",2,"c"]}`,
+			`[{"op":"test","path":"/baz","value":"bar"}]`, http.StatusConflict, `JSON patch operation 0: test failed at "/baz"`},
+		{"A.12 add to nonexistent target", `{"foo":"bar"}`,
+			`[{"op":"add","path":"/baz/bat","value":"qux"}]`, http.StatusBadRequest, `member "baz" does not exist`},
+		{"A.15 string is not a number", `{"/":9,"~1":10}`,
+			`[{"op":"test","path":"/~01","value":"10"}]`, http.StatusConflict, "test failed"},
+		{"move into a child", `{"a":{"b":1}}`,
+			`[{"op":"move","from":"/a","path":"/a/b/c"}]`, http.StatusBadRequest, "cannot move a value into one of its children"},
+		{"dash only when adding", `{"foo":["bar"]}`,
+			`[{"op":"remove","path":"/foo/-"}]`, http.StatusBadRequest, `invalid array index "-"`},
+		{"leading zero index", `{"foo":["bar","baz"]}`,
+			`[{"op":"remove","path":"/foo/01"}]`, http.StatusBadRequest, `invalid array index "01"`},
+		{"signed index", `{"foo":["bar","baz"]}`,
+			`[{"op":"remove","path":"/foo/+1"}]`, http.StatusBadRequest, `invalid array index "+1"`},
+		{"index out of bounds", `{"foo":["bar"]}`,
+			`[{"op":"add","path":"/foo/2","value":"baz"}]`, http.StatusBadRequest, "array index 2 is out of bounds"},
+		{"replace missing member", `{"foo":"bar"}`,
+			`[{"op":"replace","path":"/baz","value":1}]`, http.StatusBadRequest, `member "baz" does not exist`},
+		{"missing value", `{"foo":"bar"}`,
+			`[{"op":"add","path":"/baz"}]`, http.StatusBadRequest, "value is required"},
+		{"pointer without slash", `{"foo":"bar"}`,
+			`[{"op":"remove","path":"foo"}]`, http.StatusBadRequest, `path "foo" must start with '/'`},
+		{"unknown op", `{"foo":"bar"}`,
+			`[{"op":"merge","path":"/foo"}]`, http.StatusBadRequest, `unsupported op "merge"`},
+		{"not an array", `{"foo":"bar"}`,
+			`{"op":"remove","path":"/foo"}`, http.StatusBadRequest, "invalid JSON patch: expected an array of operations"},
+		{"test after removing the member", `{"foo":"bar"}`,
+			`[{"op":"remove","path":"/foo"},{"op":"test","path":"/foo","value":"bar"}]`, http.StatusBadRequest, `member "foo" does not exist`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := applyTestPatch(tt.document, dto.JSONPatchContentType, tt.patch)
+			assertAPIError(t, err, tt.wantStatus, tt.wantErr)
+		})
+	}
+}
+
+// Casos de RFC 7396
+func TestApplyMergePatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		patch    string
+		want     string
+	}{
+		{"replace member", `{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{"add member", `{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{"null deletes member", `{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{"null on missing member", `{"a":"b"}`, `{"c":null}`, `{"a":"b"}`},
+		{"arrays are replaced", `{"a":["b"]}`, `{"a":["c","d"]}`, `{"a":["c","d"]}`},
+		{"nested merge", `{"a":{"b":"c","d":"e"}}`, `{"a":{"b":"x","d":null}}`, `{"a":{"b":"x"}}`},
+		{"object replaces scalar", `{"a":"b"}`, `{"a":{"c":null,"d":1}}`, `{"a":{"d":1}}`},
+		{"empty patch", `{"a":"b"}`, `{}`, `{"a":"b"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyTestPatch(tt.document, dto.MergePatchContentType, tt.patch)
+			if err != nil {
+				t.Fatalf("applyPatch error: %v", err)
+			}
+			assertJSONEqual(t, got, tt.want)
+		})
+	}
+}
+
+func TestApplyPatchErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		patch       string
+		wantStatus  int
+		wantErr     string
+	}{
+		{"merge patch must be an object", dto.MergePatchContentType, `["a"]`, http.StatusBadRequest, "merge patch must be a JSON object"},
+		{"invalid merge patch", dto.MergePatchContentType, `{"a":`, http.StatusBadRequest, "invalid merge patch"},
+		{"unsupported content type", "application/json", `{"a":"b"}`, http.StatusUnsupportedMediaType, "PATCH requires"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := applyTestPatch(`{"a":"b"}`, tt.contentType, tt.patch)
+			assertAPIError(t, err, tt.wantStatus, tt.wantErr)
+		})
+	}
+}
+
+func TestApplyPatchRejectsUnknownFields(t *testing.T) {
+	current := dto.RoleDocument{Name: "editor", DisplayName: "Editor"}
+	var patched dto.RoleDocument
+	err := applyPatch(current, &dto.Patch{ContentType: dto.MergePatchContentType, Body: []byte(`{"permissions":["all"]}`)}, &patched)
+	assertAPIError(t, err, http.StatusBadRequest, `patched document is invalid: unknown field "permissions"`)
+
+	err = applyPatch(current, &dto.Patch{ContentType: dto.MergePatchContentType, Body: []byte(`{"display_name":"Editors"}`)}, &patched)
+	if err != nil {
+		t.Fatalf("applyPatch error: %v", err)
+	}
+	if patched.Name != "editor" || patched.DisplayName != "Editors" {
+		t.Errorf("patched = %+v", patched)
+	}
+}
+
+func applyTestPatch(document, contentType, patch string) (any, error) {
+	var patched any
+	err := applyPatch(json.RawMessage(document), &dto.Patch{ContentType: contentType, Body: []byte(patch)}, &patched)
+	return patched, err
+}
+
+func assertJSONEqual(t *testing.T, got any, want string) {
+	t.Helper()
+	var expected any
+	if err := json.Unmarshal([]byte(want), &expected); err != nil {
+		t.Fatalf("invalid expected JSON %s: %v", want, err)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		encoded, _ := json.Marshal(got)
+		t.Errorf("got %s, want %s", encoded, want)
+	}
+}
+
+func assertAPIError(t *testing.T, err error, wantStatus int, wantErr string) {
+	t.Helper()
+	if err == nil {
+		t.Fatalf("error = nil, want %d %q", wantStatus, wantErr)
+	}
+	apiErr, ok := utils.IsAPIError(err)
+	if !ok {
+		t.Fatalf("error = %#v, want an APIError", err)
+	}
+	if apiErr.StatusCode != wantStatus {
+		t.Errorf("status = %d, want %d (%v)", apiErr.StatusCode, wantStatus, err)
+	}
+	if !strings.Contains(err.Error(), wantErr) {
+		t.Errorf("error = %q, want it to contain %q", err.Error(), wantErr)
+	}
+}
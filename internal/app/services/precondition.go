@@ -0,0 +1,65 @@
+package services
+
+import (
+	"time"
+
+	"megabaseGo/internal/models"
+	"megabaseGo/internal/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Precondition versiones aceptadas por la cabecera If-Match de la petición
+type Precondition struct {
+	Versions []uint
+}
+
+// check compara la versión actual del recurso con If-Match; sin precondición no hay nada que comprobar
+func (p *Precondition) check(version uint) error {
+	if p == nil {
+		return nil
+	}
+	for _, expected := range p.Versions {
+		if expected == version {
+			return nil
+		}
+	}
+	return utils.NewPreconditionFailedError("resource has been modified since it was read: ETag does not match")
+}
+
+// errConcurrentModification otra escritura cambió la fila entre la lectura y la actualización
+var errConcurrentModification = utils.NewPreconditionFailedError("resource was modified concurrently, reload it and retry")
+
+// updateVersioned guarda todos los campos de record solo si la fila sigue en la versión leída;
+// si otra escritura se adelantó devuelve 412 en vez de sobrescribirla. El trigger de la BD
+// incrementa la versión, así que basta con reflejar el incremento en memoria.
+func updateVersioned(tx *gorm.DB, record any, version *uint) error {
+	result := tx.Model(record).Where("version = ?", *version).Select("*").Omit(clause.Associations).Updates(record)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errConcurrentModification
+	}
+	*version++
+	return nil
+}
+
+// deleteVersioned hace el borrado lógico de record solo si la fila sigue en la versión leída
+func deleteVersioned(tx *gorm.DB, record any, version uint) error {
+	result := tx.Where("version = ?", version).Delete(record)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errConcurrentModification
+	}
+	return nil
+}
+
+// touchUser incrementa la versión del usuario (vía trigger) cuando cambia algo de su representación
+// que vive fuera de la fila, como el rol de su membresía, para que ETag e If-Match lo detecten
+func touchUser(tx *gorm.DB, userID uint) error {
+	return tx.Model(&models.User{}).Where("id = ?", userID).UpdateColumn("updated_at", time.Now()).Error
+}
@@ -0,0 +1,225 @@
+package services
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"time"
+
+	"megabaseGo/internal/app/dto"
+	"megabaseGo/internal/database"
+	"megabaseGo/internal/models"
+	"megabaseGo/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// dataExportProfile contenido de profile.json
+type dataExportProfile struct {
+	User        *dto.UserResponse            `json:"user"`
+	HasPassword bool                         `json:"has_password"`
+	ExternalID  string                       `json:"external_id,omitempty"`
+	Memberships []dto.MembershipResponse     `json:"memberships"`
+	Identities  []models.UserIdentity        `json:"identities"`
+	Erasure     []dto.ErasureRequestResponse `json:"erasure_requests"`
+}
+
+// roleHistoryEntry cambio del rol de plataforma del usuario según su historial de revisiones
+type roleHistoryEntry struct {
+	Revision uint        `json:"revision"`
+	RoleID   interface{} `json:"role_id"`
+	Since    time.Time   `json:"since"`
+}
+
+// generateExport genera el archivo de una exportación y actualiza su estado; se ejecuta en segundo plano
+func (s *PrivacyService) generateExport(id uint) {
+	db := database.GetDB()
+
+	var export models.DataExport
+	if err := db.First(&export, id).Error; err != nil {
+		log.Printf("Error cargando la exportación de datos %d: %v", id, err)
+		return
+	}
+
+	path, size, err := s.writeArchive(db, &export)
+	now := time.Now()
+	if err != nil {
+		log.Printf("Error generando la exportación de datos %d: %v", id, err)
+		export.Status = models.DataExportFailed
+		export.Error = "could not generate the export"
+	} else {
+		expiresAt := now.Add(s.exportTTL)
+		export.Status = models.DataExportReady
+		export.FilePath = path
+		export.Size = size
+		export.ExpiresAt = &expiresAt
+	}
+	export.CompletedAt = &now
+
+	if err := db.Save(&export).Error; err != nil {
+		log.Printf("Error guardando la exportación de datos %d: %v", id, err)
+		return
+	}
+	if export.Status == models.DataExportReady {
+		s.sendExportReady(db, &export)
+	}
+}
+
+// writeArchive escribe un zip con un JSON por categoría de datos del usuario
+func (s *PrivacyService) writeArchive(db *gorm.DB, export *models.DataExport) (string, int64, error) {
+	files, err := s.collectUserData(db, export.UserID)
+	if err != nil {
+		return "", 0, err
+	}
+
+	if err := os.MkdirAll(s.exportDir, 0o750); err != nil {
+		return "", 0, err
+	}
+	suffix, err := utils.GenerateRandomToken(12)
+	if err != nil {
+		return "", 0, err
+	}
+	path := filepath.Join(s.exportDir, fmt.Sprintf("%d-%d-%s.zip", export.UserID, export.ID, suffix))
+
+	// Se escribe en un temporal y se renombra para no servir nunca un zip a medias
+	tmp, err := os.CreateTemp(s.exportDir, "export-*.tmp")
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(tmp.Name())
+
+	archive := zip.NewWriter(tmp)
+	for _, name := range []string{"profile.json", "role_history.json", "revisions.json", "sessions.json", "logins.json", "audit.json"} {
+		writer, err := archive.Create(name)
+		if err != nil {
+			tmp.Close()
+			return "", 0, err
+		}
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(files[name]); err != nil {
+			tmp.Close()
+			return "", 0, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		tmp.Close()
+		return "", 0, err
+	}
+	info, err := tmp.Stat()
+	if err != nil {
+		tmp.Close()
+		return "", 0, err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", 0, err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", 0, err
+	}
+	return path, info.Size(), nil
+}
+
+// collectUserData reúne los datos del usuario indexados por el nombre del fichero del archivo
+func (s *PrivacyService) collectUserData(db *gorm.DB, userID uint) (map[string]interface{}, error) {
+	var user models.User
+	if err := db.Unscoped().Preload("Role").First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+
+	var memberships []models.OrganizationMembership
+	if err := db.Preload("Organization").Preload("Role").Where("user_id = ?", userID).Order("id").Find(&memberships).Error; err != nil {
+		return nil, err
+	}
+	organizations := NewOrganizationService()
+	membershipResponses := make([]dto.MembershipResponse, 0, len(memberships))
+	for i := range memberships {
+		membershipResponses = append(membershipResponses, *organizations.toMembershipResponse(&memberships[i], &user))
+	}
+
+	var identities []models.UserIdentity
+	if err := db.Where("user_id = ?", userID).Order("id").Find(&identities).Error; err != nil {
+		return nil, err
+	}
+
+	var erasures []models.ErasureRequest
+	if err := db.Where("user_id = ?", userID).Order("id").Find(&erasures).Error; err != nil {
+		return nil, err
+	}
+	erasureResponses := make([]dto.ErasureRequestResponse, 0, len(erasures))
+	for i := range erasures {
+		erasureResponses = append(erasureResponses, *s.toErasureResponse(&erasures[i]))
+	}
+
+	revisions, err := s.revisions.List(db, models.RevisionUser, userID, nil)
+	if err != nil {
+		return nil, err
+	}
+	roleHistory := []roleHistoryEntry{}
+	var previousRole interface{}
+	for i, revision := range revisions {
+		roleID := revision.Snapshot["role_id"]
+		if i == 0 || !reflect.DeepEqual(roleID, previousRole) {
+			roleHistory = append(roleHistory, roleHistoryEntry{Revision: revision.Revision, RoleID: roleID, Since: revision.CreatedAt})
+		}
+		previousRole = roleID
+	}
+
+	var sessions []models.Session
+	if err := db.Where("user_id = ?", userID).Order("created_at").Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+
+	var logins []models.LoginAttempt
+	if err := db.Where("user_id = ?", userID).Order("id").Find(&logins).Error; err != nil {
+		return nil, err
+	}
+
+	// Entradas en las que el usuario actúa o es el objetivo
+	var entries []models.AuditLog
+	if err := db.Where("actor_id = ? OR (target_type = ? AND target_id = ?)",
+		userID, models.AuditTargetUser, strconv.FormatUint(uint64(userID), 10)).Order("id").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	auditResponses, err := s.audit.toAuditLogResponses(entries)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"profile.json": dataExportProfile{
+			User:        NewUserService().toUserResponse(&user),
+			HasPassword: user.HasPassword,
+			ExternalID:  user.ExternalID,
+			Memberships: membershipResponses,
+			Identities:  identities,
+			Erasure:     erasureResponses,
+		},
+		"role_history.json": roleHistory,
+		"revisions.json":    revisions,
+		"sessions.json":     sessions,
+		"logins.json":       logins,
+		"audit.json":        auditResponses,
+	}, nil
+}
+
+// sendExportReady avisa al usuario de que puede descargar su exportación
+func (s *PrivacyService) sendExportReady(db *gorm.DB, export *models.DataExport) {
+	var user models.User
+	if err := db.Select("id", "name", "email").First(&user, export.UserID).Error; err != nil {
+		return
+	}
+	body := fmt.Sprintf(
+		"Hola %s,\n\nLa copia de tus datos que solicitaste está lista. Puedes descargarla hasta el %s en:\n\n%s\n\n"+
+			"Si no la has pedido tú, cambia tu contraseña inmediatamente.",
+		user.Name, export.ExpiresAt.Format(time.RFC1123), s.exportsURL,
+	)
+	if err := s.mailer.Send(user.Email, "Tu copia de datos está lista", body); err != nil {
+		log.Printf("Error enviando aviso de exportación a %s: %v", user.Email, err)
+	}
+}
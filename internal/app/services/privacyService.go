@@ -0,0 +1,565 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"megabaseGo/internal/app/dto"
+	"megabaseGo/internal/database"
+	"megabaseGo/internal/models"
+	"megabaseGo/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// Valor que sustituye a los datos personales de un usuario suprimido
+const erasedValue = "[erased]"
+
+// Campos con datos personales en los cambios y metadatos de las entradas de auditoría
+var personalAuditFields = []string{"name", "user_name", "email", "external_id", "identifier"}
+
+// PrivacyService atiende los derechos de acceso (exportación de datos) y de supresión de los usuarios
+type PrivacyService struct {
+	hasher     utils.PasswordHasher
+	audit      *AuditService
+	revisions  *RevisionService
+	mailer     utils.Mailer
+	exportDir  string
+	exportTTL  time.Duration
+	graceDays  int
+	exportsURL string
+}
+
+// NewPrivacyService crea una nueva instancia del servicio de privacidad
+func NewPrivacyService() *PrivacyService {
+	return &PrivacyService{
+		hasher:     utils.NewBcryptHasher(),
+		audit:      NewAuditService(),
+		revisions:  NewRevisionService(),
+		mailer:     utils.NewMailer(),
+		exportDir:  utils.GetEnv("DATA_EXPORT_DIR", "storage/exports"),
+		exportTTL:  time.Duration(utils.GetEnvInt("DATA_EXPORT_TTL_HOURS", 72)) * time.Hour,
+		graceDays:  utils.GetEnvInt("ERASURE_GRACE_DAYS", 7),
+		exportsURL: utils.GetEnv("DATA_EXPORT_URL", "http://localhost:3000/profile/exports"),
+	}
+}
+
+// ErasedUserName seudónimo que sustituye al username (y al nombre en la auditoría) de un usuario suprimido
+func ErasedUserName(userID uint) string {
+	return fmt.Sprintf("erased-%d", userID)
+}
+
+// RequestExport encola la generación del archivo con los datos del usuario.
+// Solo puede haber una exportación en curso por usuario.
+func (s *PrivacyService) RequestExport(userID uint, actor *Actor) (*dto.DataExportResponse, error) {
+	db := database.GetDB()
+
+	var pending int64
+	if err := db.Model(&models.DataExport{}).Where("user_id = ? AND status = ?", userID, models.DataExportPending).
+		Count(&pending).Error; err != nil {
+		return nil, err
+	}
+	if pending > 0 {
+		return nil, utils.NewConflictError("a data export is already being generated")
+	}
+
+	export := models.DataExport{UserID: userID, Status: models.DataExportPending}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&export).Error; err != nil {
+			return err
+		}
+		return s.audit.Record(tx, actor, auditEvent{
+			Action:     models.AuditDataExport,
+			TargetType: models.AuditTargetUser,
+			TargetID:   userID,
+			Metadata:   map[string]interface{}{"export_id": export.ID},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	go s.generateExport(export.ID)
+
+	return s.toDataExportResponse(&export), nil
+}
+
+// GetExports obtiene las exportaciones del usuario, de la más reciente a la más antigua
+func (s *PrivacyService) GetExports(userID uint) ([]dto.DataExportResponse, error) {
+	var exports []models.DataExport
+	if err := database.GetDB().Where("user_id = ?", userID).Order("id DESC").Find(&exports).Error; err != nil {
+		return nil, err
+	}
+
+	responses := make([]dto.DataExportResponse, 0, len(exports))
+	for i := range exports {
+		responses = append(responses, *s.toDataExportResponse(&exports[i]))
+	}
+	return responses, nil
+}
+
+// GetExport obtiene una exportación del usuario
+func (s *PrivacyService) GetExport(userID, id uint) (*dto.DataExportResponse, error) {
+	export, err := s.findExport(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.toDataExportResponse(export), nil
+}
+
+// ExportFile devuelve la ruta del archivo de una exportación lista y no caducada
+func (s *PrivacyService) ExportFile(userID, id uint) (string, error) {
+	export, err := s.findExport(userID, id)
+	if err != nil {
+		return "", err
+	}
+	if export.Status != models.DataExportReady {
+		return "", utils.NewConflictError("data export is not ready")
+	}
+	if export.ExpiresAt != nil && time.Now().After(*export.ExpiresAt) {
+		return "", utils.NewNotFoundError("Data export file")
+	}
+	return export.FilePath, nil
+}
+
+// RequestErasure registra la solicitud de supresión del propio usuario. Se ejecuta pasados
+// ERASURE_GRACE_DAYS días (ProcessDue) salvo que la cancele antes; con 0 días se ejecuta ya.
+func (s *PrivacyService) RequestErasure(userID uint, actor *Actor) (*dto.ErasureRequestResponse, error) {
+	db := database.GetDB()
+
+	if _, err := s.pendingErasure(db, userID); err == nil {
+		return nil, utils.NewConflictError("an erasure request is already pending")
+	} else if _, ok := utils.IsAPIError(err); !ok {
+		return nil, err
+	}
+
+	request := models.ErasureRequest{
+		UserID:       userID,
+		Status:       models.ErasurePending,
+		ScheduledFor: time.Now().AddDate(0, 0, s.graceDays),
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&request).Error; err != nil {
+			return err
+		}
+		return s.audit.Record(tx, actor, auditEvent{
+			Action:     models.AuditErasureRequest,
+			TargetType: models.AuditTargetUser,
+			TargetID:   userID,
+			Metadata: map[string]interface{}{
+				"erasure_request_id": request.ID,
+				"scheduled_for":      request.ScheduledFor.UTC().Format(time.RFC3339),
+			},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if s.graceDays <= 0 {
+		if err := s.erase(&request, actor, "user"); err != nil {
+			return nil, err
+		}
+	}
+	return s.toErasureResponse(&request), nil
+}
+
+// GetErasureRequest obtiene la solicitud de supresión pendiente del usuario
+func (s *PrivacyService) GetErasureRequest(userID uint) (*dto.ErasureRequestResponse, error) {
+	request, err := s.pendingErasure(database.GetDB(), userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.toErasureResponse(request), nil
+}
+
+// CancelErasure cancela la solicitud de supresión pendiente del usuario
+func (s *PrivacyService) CancelErasure(userID uint, actor *Actor) error {
+	db := database.GetDB()
+
+	request, err := s.pendingErasure(db, userID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	request.Status = models.ErasureCancelled
+	request.CancelledAt = &now
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(request).Error; err != nil {
+			return err
+		}
+		return s.audit.Record(tx, actor, auditEvent{
+			Action:     models.AuditErasureCancel,
+			TargetType: models.AuditTargetUser,
+			TargetID:   userID,
+			Metadata:   map[string]interface{}{"erasure_request_id": request.ID},
+		})
+	})
+}
+
+// EraseUser suprime ya los datos de un usuario por orden de un admin, sin plazo de gracia.
+// Una solicitud pendiente del propio usuario se completa con esta.
+func (s *PrivacyService) EraseUser(userID uint, actor *Actor) (*dto.ErasureRequestResponse, error) {
+	db := database.GetDB()
+
+	var user models.User
+	if err := db.Unscoped().Select("id").First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("User")
+		}
+		return nil, err
+	}
+
+	var completed int64
+	if err := db.Model(&models.ErasureRequest{}).Where("user_id = ? AND status = ?", userID, models.ErasureCompleted).
+		Count(&completed).Error; err != nil {
+		return nil, err
+	}
+	if completed > 0 {
+		return nil, utils.NewConflictError("user data has already been erased")
+	}
+
+	request, err := s.pendingErasure(db, userID)
+	if err != nil {
+		if _, ok := utils.IsAPIError(err); !ok {
+			return nil, err
+		}
+		request = &models.ErasureRequest{
+			UserID:       userID,
+			Status:       models.ErasurePending,
+			ScheduledFor: time.Now(),
+		}
+		if actor != nil && actor.UserID != 0 {
+			request.RequestedByID = &actor.UserID
+		}
+		if err := db.Create(request).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.erase(request, actor, "admin"); err != nil {
+		return nil, err
+	}
+	return s.toErasureResponse(request), nil
+}
+
+// ProcessDue ejecuta las solicitudes de supresión cuyo plazo de gracia ha vencido y borra las
+// exportaciones caducadas. Devuelve cuántos usuarios suprimió.
+func (s *PrivacyService) ProcessDue() (int, error) {
+	db := database.GetDB()
+
+	var requests []models.ErasureRequest
+	if err := db.Where("status = ? AND scheduled_for <= ?", models.ErasurePending, time.Now()).
+		Order("id").Find(&requests).Error; err != nil {
+		return 0, err
+	}
+
+	erased := 0
+	for i := range requests {
+		if err := s.erase(&requests[i], nil, "user"); err != nil {
+			return erased, err
+		}
+		erased++
+	}
+
+	return erased, s.removeExpiredExports(db)
+}
+
+// RunProcessing ejecuta ProcessDue cada interval mientras el proceso esté vivo
+func (s *PrivacyService) RunProcessing(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		erased, err := s.ProcessDue()
+		if err != nil {
+			log.Printf("Error procesando solicitudes de supresión: %v", err)
+			continue
+		}
+		if erased > 0 {
+			log.Printf("Usuarios suprimidos: %d", erased)
+		}
+	}
+}
+
+// erase anonimiza el usuario y borra o desvincula sus datos personales. La fila del usuario se
+// conserva (inactiva y con seudónimo) para que lo que la referencia siga siendo coherente.
+// El log de auditoría no se reescribe (es de solo inserción y está encadenado): la solicitud
+// completada hace que sus entradas se seudonimicen al leerlas. requestedBy ("user" o "admin")
+// queda en los metadatos del evento.
+func (s *PrivacyService) erase(request *models.ErasureRequest, actor *Actor, requestedBy string) error {
+	randomPassword, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return err
+	}
+	hashedPassword, err := s.hasher.HashPassword(randomPassword)
+	if err != nil {
+		return errors.New("failed to hash password")
+	}
+
+	var exportFiles []string
+	var avatarKey string
+	err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Unscoped().First(&user, request.UserID).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Unscoped().Where("user_id = ? OR LOWER(email) = ?", user.ID, utils.NormalizeEmail(user.Email)).
+			Delete(&models.MagicLinkToken{}).Error; err != nil {
+			return err
+		}
+		for _, dependent := range []interface{}{
+			&models.OrganizationMembership{},
+			&models.Session{},
+			&models.UserIdentity{},
+			&models.DeviceAuthorization{},
+			&models.EmailChangeRequest{},
+		} {
+			if err := tx.Unscoped().Where("user_id = ?", user.ID).Delete(dependent).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&models.LoginAttempt{}).Where("user_id = ?", user.ID).Updates(map[string]interface{}{
+			"identifier":  "",
+			"ip_address":  "",
+			"user_agent":  "",
+			"fingerprint": "",
+		}).Error; err != nil {
+			return err
+		}
+
+		var exports []models.DataExport
+		if err := tx.Where("user_id = ?", user.ID).Find(&exports).Error; err != nil {
+			return err
+		}
+		for _, export := range exports {
+			if export.FilePath != "" {
+				exportFiles = append(exportFiles, export.FilePath)
+			}
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.DataExport{}).Error; err != nil {
+			return err
+		}
+
+		// El historial guarda los valores anteriores: se borra antes de anonimizar, y el
+		// guardado deja una única revisión con el estado anonimizado
+		if err := tx.Where("resource_type = ? AND resource_id = ?", models.RevisionUser, user.ID).
+			Delete(&models.Revision{}).Error; err != nil {
+			return err
+		}
+
+		pseudonym := ErasedUserName(user.ID)
+		user.Name = "Erased user"
+		user.UserName = pseudonym
+		user.Email = pseudonym + "@erased.invalid"
+		user.Password = hashedPassword
+		user.HasPassword = false
+		user.IsActive = false
+		user.ExternalID = ""
+		user.LastLoginAt = time.Time{}
+		avatarKey = user.AvatarKey
+		user.AvatarURL = ""
+		user.AvatarKey = ""
+		if err := tx.Unscoped().Save(&user).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		request.Status = models.ErasureCompleted
+		request.CompletedAt = &now
+		if err := tx.Save(request).Error; err != nil {
+			return err
+		}
+
+		// Sin cambios: el estado anterior son justo los datos que se suprimen
+		return s.audit.Record(tx, actor, auditEvent{
+			Action:     models.AuditErasureComplete,
+			TargetType: models.AuditTargetUser,
+			TargetID:   user.ID,
+			Metadata: map[string]interface{}{
+				"erasure_request_id": request.ID,
+				"requested_by":       requestedBy,
+			},
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, file := range exportFiles {
+		if err := os.Remove(file); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("Error borrando exportación de un usuario suprimido: %v", err)
+		}
+	}
+	deleteAvatar(avatarKey)
+	return nil
+}
+
+func (s *PrivacyService) removeExpiredExports(db *gorm.DB) error {
+	var exports []models.DataExport
+	if err := db.Where("expires_at < ?", time.Now()).Find(&exports).Error; err != nil {
+		return err
+	}
+	for _, export := range exports {
+		if export.FilePath != "" {
+			if err := os.Remove(export.FilePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+		}
+		if err := db.Delete(&export).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *PrivacyService) findExport(userID, id uint) (*models.DataExport, error) {
+	var export models.DataExport
+	if err := database.GetDB().Where("user_id = ?", userID).First(&export, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("Data export")
+		}
+		return nil, err
+	}
+	return &export, nil
+}
+
+func (s *PrivacyService) pendingErasure(db *gorm.DB, userID uint) (*models.ErasureRequest, error) {
+	var request models.ErasureRequest
+	if err := db.Where("user_id = ? AND status = ?", userID, models.ErasurePending).First(&request).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("Erasure request")
+		}
+		return nil, err
+	}
+	return &request, nil
+}
+
+func (s *PrivacyService) toDataExportResponse(export *models.DataExport) *dto.DataExportResponse {
+	response := &dto.DataExportResponse{
+		ID:          export.ID,
+		Status:      export.Status,
+		Size:        export.Size,
+		Error:       export.Error,
+		CompletedAt: export.CompletedAt,
+		ExpiresAt:   export.ExpiresAt,
+		CreatedAt:   export.CreatedAt,
+	}
+	if export.Status == models.DataExportReady {
+		response.DownloadURL = fmt.Sprintf("/api/v1/profile/exports/%d/download", export.ID)
+	}
+	return response
+}
+
+func (s *PrivacyService) toErasureResponse(request *models.ErasureRequest) *dto.ErasureRequestResponse {
+	return &dto.ErasureRequestResponse{
+		ID:            request.ID,
+		UserID:        request.UserID,
+		Status:        request.Status,
+		RequestedByID: request.RequestedByID,
+		ScheduledFor:  request.ScheduledFor,
+		CompletedAt:   request.CompletedAt,
+		CancelledAt:   request.CancelledAt,
+		CreatedAt:     request.CreatedAt,
+	}
+}
+
+// pseudonymizeAuditEntries sustituye, en las entradas que leen la API y las exportaciones, los
+// datos personales de los usuarios suprimidos: nombre del actor, IP, user agent y los campos
+// personales de cambios y metadatos. El ID del usuario se mantiene como seudónimo.
+func pseudonymizeAuditEntries(db *gorm.DB, entries []dto.AuditLogResponse) error {
+	ids := map[uint]bool{}
+	for i := range entries {
+		if entries[i].ActorID != nil {
+			ids[*entries[i].ActorID] = true
+		}
+		if id, ok := auditTargetUser(&entries[i]); ok {
+			ids[id] = true
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	candidates := make([]uint, 0, len(ids))
+	for id := range ids {
+		candidates = append(candidates, id)
+	}
+	var erasedIDs []uint
+	if err := db.Model(&models.ErasureRequest{}).Where("status = ? AND user_id IN ?", models.ErasureCompleted, candidates).
+		Pluck("user_id", &erasedIDs).Error; err != nil {
+		return err
+	}
+	if len(erasedIDs) == 0 {
+		return nil
+	}
+	erased := make(map[uint]bool, len(erasedIDs))
+	for _, id := range erasedIDs {
+		erased[id] = true
+	}
+
+	for i := range entries {
+		entry := &entries[i]
+		if entry.ActorID != nil && erased[*entry.ActorID] {
+			entry.ActorName = ErasedUserName(*entry.ActorID)
+			entry.IPAddress = ""
+			entry.UserAgent = ""
+		}
+		if id, ok := auditTargetUser(entry); ok && erased[id] {
+			entry.Changes = redactPersonalFields(entry.Changes)
+			entry.Metadata = redactPersonalFields(entry.Metadata)
+			// Sin actor (p. ej. un login fallido) la IP y el navegador son los del propio usuario
+			if entry.ActorID == nil {
+				entry.IPAddress = ""
+				entry.UserAgent = ""
+			}
+		}
+	}
+	return nil
+}
+
+func auditTargetUser(entry *dto.AuditLogResponse) (uint, bool) {
+	if entry.TargetType != models.AuditTargetUser || entry.TargetID == "" {
+		return 0, false
+	}
+	id, err := strconv.ParseUint(entry.TargetID, 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// redactPersonalFields sustituye los campos personales de un objeto JSON; en los cambios
+// ({"old": ..., "new": ...}) se sustituyen ambos valores
+func redactPersonalFields(raw json.RawMessage) json.RawMessage {
+	if len(raw) == 0 {
+		return raw
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return raw
+	}
+	for _, field := range personalAuditFields {
+		value, exists := fields[field]
+		if !exists {
+			continue
+		}
+		if _, isChange := value.(map[string]interface{}); isChange {
+			fields[field] = auditChange{Old: erasedValue, New: erasedValue}
+		} else {
+			fields[field] = erasedValue
+		}
+	}
+	redacted, err := json.Marshal(fields)
+	if err != nil {
+		return raw
+	}
+	return redacted
+}
@@ -0,0 +1,556 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"megabaseGo/internal/app/dto"
+	"megabaseGo/internal/database"
+	"megabaseGo/internal/models"
+	"megabaseGo/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// ErrReauthenticationRequired la petición cambia el email sin una comprobación de contraseña reciente
+var ErrReauthenticationRequired = utils.NewForbiddenError("Recent authentication required")
+
+// ProfileService autoservicio de la cuenta: edición del propio perfil, cambio de email con
+// confirmación y baja de la cuenta con un plazo para reactivarla
+type ProfileService struct {
+	hasher            utils.PasswordHasher
+	audit             *AuditService
+	userService       *UserService
+	mailer            utils.Mailer
+	emailChangeURL    string
+	emailChangeTTL    time.Duration
+	reactivateURL     string
+	deletionGraceDays int
+}
+
+// NewProfileService crea una nueva instancia del servicio de perfil
+func NewProfileService() *ProfileService {
+	return &ProfileService{
+		hasher:            utils.NewBcryptHasher(),
+		audit:             NewAuditService(),
+		userService:       NewUserService(),
+		mailer:            utils.NewMailer(),
+		emailChangeURL:    utils.GetEnv("EMAIL_CHANGE_URL", "http://localhost:3000/profile/email/confirm"),
+		emailChangeTTL:    time.Duration(utils.GetEnvInt("EMAIL_CHANGE_TTL_HOURS", 24)) * time.Hour,
+		reactivateURL:     utils.GetEnv("ACCOUNT_REACTIVATION_URL", "http://localhost:3000/account/reactivate"),
+		deletionGraceDays: utils.GetEnvInt("ACCOUNT_DELETION_GRACE_DAYS", 30),
+	}
+}
+
+// GetProfile obtiene el perfil del usuario con el cambio de email pendiente, si lo hay
+func (s *ProfileService) GetProfile(userID uint) (*dto.ProfileResponse, error) {
+	user, err := s.userService.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	response := &dto.ProfileResponse{User: user}
+
+	pending, err := s.pendingEmailChange(database.GetDB(), userID)
+	if err != nil {
+		return nil, err
+	}
+	if pending != nil {
+		response.PendingEmail = pending.NewEmail
+	}
+	return response, nil
+}
+
+// UpdateProfile aplica los cambios del propio usuario en su nombre, username y avatar. Un email
+// distinto del actual no se cambia todavía: se envía un enlace de confirmación a la nueva dirección,
+// y solo si recentAuth indica que el usuario se ha re-autenticado hace poco.
+func (s *ProfileService) UpdateProfile(userID uint, req *dto.UpdateProfileRequest, recentAuth bool, actor *Actor) (*dto.ProfileResponse, error) {
+	db := database.GetDB()
+
+	var user models.User
+	if err := db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("User")
+		}
+		return nil, err
+	}
+	before := profileAuditState(&user)
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			return nil, utils.NewBadRequestError("name cannot be empty")
+		}
+		user.Name = name
+	}
+
+	if req.UserName != nil {
+		userName := utils.NormalizeUserName(*req.UserName)
+		if userName != user.UserName {
+			if err := validateUserName(userName); err != nil {
+				return nil, err
+			}
+			var existing models.User
+			if err := db.Scopes(byUserName(userName)).Where("id != ?", user.ID).First(&existing).Error; err == nil {
+				return nil, utils.NewConflictError("username already exists")
+			}
+			user.UserName = userName
+		}
+	}
+
+	var previousAvatarKey string
+	if req.AvatarURL != nil {
+		avatarURL := strings.TrimSpace(*req.AvatarURL)
+		if avatarURL != "" {
+			if err := validateAvatarURL(avatarURL); err != nil {
+				return nil, err
+			}
+		}
+		user.AvatarURL = avatarURL
+		// Una URL externa sustituye a la imagen subida
+		previousAvatarKey = user.AvatarKey
+		user.AvatarKey = ""
+	}
+
+	var newEmail string
+	if req.Email != nil {
+		if email := utils.NormalizeEmail(*req.Email); email != utils.NormalizeEmail(user.Email) {
+			if !recentAuth {
+				return nil, ErrReauthenticationRequired
+			}
+			if err := s.ensureEmailAvailable(db, email, user.ID); err != nil {
+				return nil, err
+			}
+			newEmail = email
+		}
+	}
+
+	changes := auditDiff(before, profileAuditState(&user))
+	var token string
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if len(changes) > 0 {
+			if err := tx.Model(&user).Select("name", "user_name", "avatar_url", "avatar_key").Updates(&user).Error; err != nil {
+				return err
+			}
+			if err := s.audit.Record(tx, actor, auditEvent{
+				Action:     models.AuditProfileUpdate,
+				TargetType: models.AuditTargetUser,
+				TargetID:   user.ID,
+				Changes:    changes,
+			}); err != nil {
+				return err
+			}
+		}
+		if newEmail == "" {
+			return nil
+		}
+
+		var err error
+		token, err = s.createEmailChange(tx, &user, newEmail)
+		if err != nil {
+			return err
+		}
+		return s.audit.Record(tx, actor, auditEvent{
+			Action:     models.AuditEmailChangeRequest,
+			TargetType: models.AuditTargetUser,
+			TargetID:   user.ID,
+			Changes:    auditChanges{"email": {Old: user.Email, New: newEmail}},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	deleteAvatar(previousAvatarKey)
+	if token != "" {
+		s.sendEmailChangeConfirmation(&user, newEmail, token)
+	}
+	return s.GetProfile(user.ID)
+}
+
+// UploadAvatar sustituye el avatar del usuario por la imagen subida
+func (s *ProfileService) UploadAvatar(userID uint, file io.Reader, actor *Actor) (*dto.ProfileResponse, error) {
+	if _, err := s.userService.ForActor(actor).UpdateAvatar(userID, file); err != nil {
+		return nil, err
+	}
+	return s.GetProfile(userID)
+}
+
+// RemoveAvatar quita el avatar del usuario, subido o externo
+func (s *ProfileService) RemoveAvatar(userID uint, actor *Actor) (*dto.ProfileResponse, error) {
+	if _, err := s.userService.ForActor(actor).RemoveAvatar(userID); err != nil {
+		return nil, err
+	}
+	return s.GetProfile(userID)
+}
+
+// ConfirmEmailChange aplica el cambio de email del enlace. El token es de un solo uso y caduca
+// a las EMAIL_CHANGE_TTL_HOURS horas; se avisa a la dirección anterior del cambio.
+func (s *ProfileService) ConfirmEmailChange(token string, meta *dto.RequestMeta) (*dto.ProfileResponse, error) {
+	db := database.GetDB()
+	invalid := utils.NewBadRequestError("invalid or expired email confirmation link")
+
+	var user models.User
+	var previousEmail string
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var request models.EmailChangeRequest
+		if err := tx.Where("token_hash = ? AND confirmed_at IS NULL AND expires_at > ?", utils.HashToken(token), time.Now()).
+			First(&request).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return invalid
+			}
+			return err
+		}
+
+		// Marcar como usado de forma atómica: solo una petición puede ganar
+		now := time.Now()
+		result := tx.Model(&models.EmailChangeRequest{}).Where("id = ? AND confirmed_at IS NULL", request.ID).Update("confirmed_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != 1 {
+			return invalid
+		}
+
+		if err := tx.First(&user, request.UserID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return invalid
+			}
+			return err
+		}
+		// El email pudo ocuparse entre la solicitud y la confirmación
+		if err := s.ensureEmailAvailable(tx, request.NewEmail, user.ID); err != nil {
+			return err
+		}
+
+		previousEmail = user.Email
+		user.Email = request.NewEmail
+		if err := tx.Model(&user).Select("email", "email_bidx").Updates(&user).Error; err != nil {
+			return err
+		}
+		return s.audit.Record(tx, actorFor(&user, meta), auditEvent{
+			Action:     models.AuditEmailChange,
+			TargetType: models.AuditTargetUser,
+			TargetID:   user.ID,
+			Changes:    auditChanges{"email": {Old: previousEmail, New: user.Email}},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.sendEmailChanged(&user, previousEmail)
+	return s.GetProfile(user.ID)
+}
+
+// DeleteAccount da de baja la cuenta del propio usuario tras confirmar su contraseña: la
+// desactiva, cierra sus sesiones y programa el paso a la papelera dentro de
+// ACCOUNT_DELETION_GRACE_DAYS días. Hasta entonces el enlace enviado por email la reactiva.
+func (s *ProfileService) DeleteAccount(userID uint, req *dto.DeleteProfileRequest, actor *Actor) (*dto.AccountDeletionResponse, error) {
+	db := database.GetDB()
+
+	var user models.User
+	if err := db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("User")
+		}
+		return nil, err
+	}
+	if !user.HasPassword {
+		return nil, utils.NewBadRequestError("account has no password to confirm the deletion; set one first")
+	}
+	if err := s.hasher.ComparePassword(user.Password, req.Password); err != nil {
+		return nil, utils.NewUnauthorizedError("password is incorrect")
+	}
+
+	var pending int64
+	if err := db.Model(&models.AccountDeletion{}).Where("user_id = ? AND status = ?", user.ID, models.AccountDeletionPending).
+		Count(&pending).Error; err != nil {
+		return nil, err
+	}
+	if pending > 0 {
+		return nil, utils.NewConflictError("account deletion is already pending")
+	}
+
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, errors.New("failed to generate reactivation token")
+	}
+	deletion := models.AccountDeletion{
+		UserID:       user.ID,
+		Status:       models.AccountDeletionPending,
+		TokenHash:    utils.HashToken(token),
+		ScheduledFor: time.Now().AddDate(0, 0, s.deletionGraceDays),
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&deletion).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&user).Update("is_active", false).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Session{}).Where("user_id = ? AND revoked_at IS NULL", user.ID).
+			Update("revoked_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return s.audit.Record(tx, actor, auditEvent{
+			Action:     models.AuditAccountDeletionRequest,
+			TargetType: models.AuditTargetUser,
+			TargetID:   user.ID,
+			Changes:    auditChanges{"is_active": {Old: true, New: false}},
+			Metadata: map[string]interface{}{
+				"account_deletion_id": deletion.ID,
+				"scheduled_for":       deletion.ScheduledFor.UTC().Format(time.RFC3339),
+			},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if s.deletionGraceDays <= 0 {
+		if err := s.completeDeletion(&deletion); err != nil {
+			return nil, err
+		}
+	} else {
+		s.sendReactivationLink(&user, &deletion, token)
+	}
+	return s.toDeletionResponse(&deletion), nil
+}
+
+// ReactivateAccount cancela una baja pendiente con el token del enlace y reactiva la cuenta
+func (s *ProfileService) ReactivateAccount(token string, meta *dto.RequestMeta) error {
+	db := database.GetDB()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var deletion models.AccountDeletion
+		if err := tx.Where("token_hash = ? AND status = ? AND scheduled_for > ?",
+			utils.HashToken(token), models.AccountDeletionPending, time.Now()).First(&deletion).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return utils.NewBadRequestError("invalid or expired reactivation link")
+			}
+			return err
+		}
+
+		var user models.User
+		if err := tx.Select("id", "user_name").First(&user, deletion.UserID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return utils.NewBadRequestError("invalid or expired reactivation link")
+			}
+			return err
+		}
+
+		now := time.Now()
+		if err := tx.Model(&deletion).Updates(map[string]interface{}{
+			"status":       models.AccountDeletionCancelled,
+			"cancelled_at": now,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&user).Update("is_active", true).Error; err != nil {
+			return err
+		}
+		return s.audit.Record(tx, actorFor(&user, meta), auditEvent{
+			Action:     models.AuditAccountReactivate,
+			TargetType: models.AuditTargetUser,
+			TargetID:   user.ID,
+			Changes:    auditChanges{"is_active": {Old: false, New: true}},
+			Metadata:   map[string]interface{}{"account_deletion_id": deletion.ID},
+		})
+	})
+}
+
+// ProcessDueDeletions envía a la papelera las cuentas cuyo plazo de reactivación ha vencido.
+// Si un admin reactivó la cuenta entretanto, la baja se cancela. Devuelve cuántas completó.
+func (s *ProfileService) ProcessDueDeletions() (int, error) {
+	var deletions []models.AccountDeletion
+	if err := database.GetDB().Where("status = ? AND scheduled_for <= ?", models.AccountDeletionPending, time.Now()).
+		Order("id").Find(&deletions).Error; err != nil {
+		return 0, err
+	}
+
+	completed := 0
+	for i := range deletions {
+		if err := s.completeDeletion(&deletions[i]); err != nil {
+			return completed, err
+		}
+		if deletions[i].Status == models.AccountDeletionCompleted {
+			completed++
+		}
+	}
+	return completed, nil
+}
+
+// RunProcessing ejecuta ProcessDueDeletions cada interval mientras el proceso esté vivo
+func (s *ProfileService) RunProcessing(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		completed, err := s.ProcessDueDeletions()
+		if err != nil {
+			log.Printf("Error procesando bajas de cuenta: %v", err)
+			continue
+		}
+		if completed > 0 {
+			log.Printf("Bajas de cuenta completadas: %d", completed)
+		}
+	}
+}
+
+// completeDeletion envía el usuario a la papelera, donde TRASH_RETENTION_DAYS decide cuándo se
+// purga; un usuario que vuelve a estar activo o que ya no existe cancela la baja
+func (s *ProfileService) completeDeletion(deletion *models.AccountDeletion) error {
+	db := database.GetDB()
+
+	var user models.User
+	err := db.Select("id", "is_active").First(&user, deletion.UserID).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	now := time.Now()
+	if err != nil || user.IsActive {
+		deletion.Status = models.AccountDeletionCancelled
+		deletion.CancelledAt = &now
+		return db.Model(deletion).Updates(map[string]interface{}{
+			"status":       deletion.Status,
+			"cancelled_at": now,
+		}).Error
+	}
+
+	if err := s.userService.DeleteUser(user.ID); err != nil {
+		return err
+	}
+	deletion.Status = models.AccountDeletionCompleted
+	deletion.CompletedAt = &now
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(deletion).Updates(map[string]interface{}{
+			"status":       deletion.Status,
+			"completed_at": now,
+		}).Error; err != nil {
+			return err
+		}
+		return s.audit.Record(tx, nil, auditEvent{
+			Action:     models.AuditAccountDeletionComplete,
+			TargetType: models.AuditTargetUser,
+			TargetID:   user.ID,
+			Metadata:   map[string]interface{}{"account_deletion_id": deletion.ID},
+		})
+	})
+}
+
+// createEmailChange sustituye las solicitudes de cambio de email pendientes por una nueva y devuelve su token
+func (s *ProfileService) createEmailChange(tx *gorm.DB, user *models.User, email string) (string, error) {
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", errors.New("failed to generate email confirmation token")
+	}
+	if err := tx.Where("user_id = ? AND confirmed_at IS NULL", user.ID).Delete(&models.EmailChangeRequest{}).Error; err != nil {
+		return "", err
+	}
+	return token, tx.Create(&models.EmailChangeRequest{
+		UserID:    user.ID,
+		NewEmail:  email,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().Add(s.emailChangeTTL),
+	}).Error
+}
+
+// ensureEmailAvailable comprueba que ningún otro usuario tenga ya el email
+func (s *ProfileService) ensureEmailAvailable(db *gorm.DB, email string, userID uint) error {
+	var existing models.User
+	err := db.Scopes(byEmail(email)).Where("id != ?", userID).Select("id").First(&existing).Error
+	if err == nil {
+		return utils.NewConflictError("email already exists")
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	return nil
+}
+
+func (s *ProfileService) pendingEmailChange(db *gorm.DB, userID uint) (*models.EmailChangeRequest, error) {
+	var request models.EmailChangeRequest
+	err := db.Where("user_id = ? AND confirmed_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("id DESC").First(&request).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+func (s *ProfileService) sendEmailChangeConfirmation(user *models.User, email, token string) {
+	link := s.emailChangeURL + "?token=" + url.QueryEscape(token)
+	body := fmt.Sprintf(
+		"Hola %s,\n\nConfirma que quieres usar esta dirección en tu cuenta con el siguiente enlace. Caduca en %d horas:\n\n%s\n\n"+
+			"Si no has pedido este cambio, ignora este correo.",
+		user.Name, int(s.emailChangeTTL.Hours()), link,
+	)
+	go func() {
+		if err := s.mailer.Send(email, "Confirma tu nuevo email", body); err != nil {
+			log.Printf("Error enviando confirmación de cambio de email a %s: %v", email, err)
+		}
+	}()
+}
+
+func (s *ProfileService) sendEmailChanged(user *models.User, previousEmail string) {
+	body := fmt.Sprintf(
+		"Hola %s,\n\nEl email de tu cuenta ha cambiado a %s.\n\nSi no has sido tú, contacta con el soporte inmediatamente.",
+		user.Name, user.Email,
+	)
+	go func() {
+		if err := s.mailer.Send(previousEmail, "El email de tu cuenta ha cambiado", body); err != nil {
+			log.Printf("Error enviando aviso de cambio de email a %s: %v", previousEmail, err)
+		}
+	}()
+}
+
+func (s *ProfileService) sendReactivationLink(user *models.User, deletion *models.AccountDeletion, token string) {
+	link := s.reactivateURL + "?token=" + url.QueryEscape(token)
+	body := fmt.Sprintf(
+		"Hola %s,\n\nHemos dado de baja tu cuenta. Se eliminará el %s; hasta entonces puedes reactivarla con este enlace:\n\n%s",
+		user.Name, deletion.ScheduledFor.Format(time.RFC1123), link,
+	)
+	go func() {
+		if err := s.mailer.Send(user.Email, "Tu cuenta ha sido dada de baja", body); err != nil {
+			log.Printf("Error enviando enlace de reactivación a %s: %v", user.Email, err)
+		}
+	}()
+}
+
+func (s *ProfileService) toDeletionResponse(deletion *models.AccountDeletion) *dto.AccountDeletionResponse {
+	return &dto.AccountDeletionResponse{
+		ID:           deletion.ID,
+		Status:       deletion.Status,
+		ScheduledFor: deletion.ScheduledFor,
+		CreatedAt:    deletion.CreatedAt,
+	}
+}
+
+// profileAuditState campos del perfil que puede editar el propio usuario
+func profileAuditState(user *models.User) map[string]interface{} {
+	return map[string]interface{}{
+		"name":       user.Name,
+		"user_name":  user.UserName,
+		"avatar_url": user.AvatarURL,
+		"avatar_key": user.AvatarKey,
+	}
+}
+
+// validateAvatarURL admite solo URLs https absolutas
+func validateAvatarURL(value string) error {
+	parsed, err := url.Parse(value)
+	if err != nil || parsed.Scheme != "https" || parsed.Host == "" {
+		return utils.NewBadRequestError("avatar_url must be an absolute https URL")
+	}
+	return nil
+}
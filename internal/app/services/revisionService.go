@@ -0,0 +1,143 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"megabaseGo/internal/app/dto"
+	"megabaseGo/internal/models"
+	"megabaseGo/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// RevisionService lee el historial de revisiones que crean los hooks AfterSave de los modelos.
+// Recibe la conexión (o la transacción) del servicio que lo usa.
+type RevisionService struct{}
+
+// NewRevisionService crea una nueva instancia del servicio de revisiones
+func NewRevisionService() *RevisionService {
+	return &RevisionService{}
+}
+
+// List devuelve las revisiones de un recurso en orden, cada una con sus cambios respecto a la
+// anterior. Con at devuelve solo la revisión vigente en ese momento.
+func (s *RevisionService) List(db *gorm.DB, resourceType string, resourceID uint, at *time.Time) ([]dto.RevisionResponse, error) {
+	query := db.Where("resource_type = ? AND resource_id = ?", resourceType, resourceID)
+	if at != nil {
+		query = query.Where("created_at <= ?", *at)
+	}
+
+	var revisions []models.Revision
+	if err := query.Order("number").Find(&revisions).Error; err != nil {
+		return nil, err
+	}
+
+	responses := make([]dto.RevisionResponse, 0, len(revisions))
+	var previous map[string]interface{}
+	for i := range revisions {
+		snapshot, err := models.OpenRevisionSnapshot(resourceType, revisions[i].Snapshot)
+		if err != nil {
+			return nil, err
+		}
+		responses = append(responses, dto.RevisionResponse{
+			Revision:  revisions[i].Number,
+			Snapshot:  snapshot,
+			Changes:   fieldChanges(auditDiff(previous, snapshot)),
+			CreatedAt: revisions[i].CreatedAt,
+		})
+		previous = snapshot
+	}
+
+	if at != nil && len(responses) > 0 {
+		return responses[len(responses)-1:], nil
+	}
+	return responses, nil
+}
+
+// Get devuelve una revisión con sus cambios respecto a la anterior y respecto al estado actual
+func (s *RevisionService) Get(db *gorm.DB, resourceType string, resourceID, number uint) (*dto.RevisionResponse, error) {
+	revision, err := s.find(db, resourceType, resourceID, number)
+	if err != nil {
+		return nil, err
+	}
+	snapshot, err := models.OpenRevisionSnapshot(resourceType, revision.Snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	var previous map[string]interface{}
+	if number > 1 {
+		if prior, err := s.find(db, resourceType, resourceID, number-1); err == nil {
+			if previous, err = models.OpenRevisionSnapshot(resourceType, prior.Snapshot); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var latest models.Revision
+	if err := db.Where("resource_type = ? AND resource_id = ?", resourceType, resourceID).
+		Order("number DESC").Take(&latest).Error; err != nil {
+		return nil, err
+	}
+	current, err := models.OpenRevisionSnapshot(resourceType, latest.Snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.RevisionResponse{
+		Revision:      revision.Number,
+		Snapshot:      snapshot,
+		Changes:       fieldChanges(auditDiff(previous, snapshot)),
+		DiffToCurrent: fieldChanges(auditDiff(current, snapshot)),
+		CreatedAt:     revision.CreatedAt,
+	}, nil
+}
+
+// Snapshot devuelve los campos guardados en una revisión
+func (s *RevisionService) Snapshot(db *gorm.DB, resourceType string, resourceID, number uint) (map[string]interface{}, error) {
+	revision, err := s.find(db, resourceType, resourceID, number)
+	if err != nil {
+		return nil, err
+	}
+	return models.OpenRevisionSnapshot(resourceType, revision.Snapshot)
+}
+
+func (s *RevisionService) find(db *gorm.DB, resourceType string, resourceID, number uint) (*models.Revision, error) {
+	var revision models.Revision
+	err := db.Where("resource_type = ? AND resource_id = ? AND number = ?", resourceType, resourceID, number).
+		Take(&revision).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, utils.NewNotFoundError("Revision")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+func fieldChanges(changes auditChanges) map[string]dto.FieldChange {
+	result := make(map[string]dto.FieldChange, len(changes))
+	for field, change := range changes {
+		result[field] = dto.FieldChange{Old: change.Old, New: change.New}
+	}
+	return result
+}
+
+// snapshotString lee un campo de texto de una instantánea
+func snapshotString(snapshot map[string]interface{}, field string) string {
+	value, _ := snapshot[field].(string)
+	return value
+}
+
+// snapshotUint lee un campo numérico de una instantánea (JSON decodifica los números como float64)
+func snapshotUint(snapshot map[string]interface{}, field string) uint {
+	value, _ := snapshot[field].(float64)
+	return uint(value)
+}
+
+// snapshotBool lee un campo booleano de una instantánea
+func snapshotBool(snapshot map[string]interface{}, field string) bool {
+	value, _ := snapshot[field].(bool)
+	return value
+}
@@ -5,24 +5,70 @@ import (
 	"megabaseGo/internal/app/dto"
 	"megabaseGo/internal/database"
 	"megabaseGo/internal/models"
+	"megabaseGo/internal/utils"
+	"time"
 
 	"gorm.io/gorm"
 )
 
-type RoleService struct{}
+type RoleService struct {
+	audit     *AuditService
+	revisions *RevisionService
+	tenant    *Tenant
+	actor     *Actor
+	// precondition If-Match de la petición: las modificaciones exigen que la versión coincida
+	precondition *Precondition
+}
 
 // NewRoleService crea una nueva instancia del servicio de roles
 func NewRoleService() *RoleService {
-	return &RoleService{}
+	return &RoleService{
+		audit:     NewAuditService(),
+		revisions: NewRevisionService(),
+	}
+}
+
+// ForTenant devuelve una copia del servicio limitada a la organización del tenant:
+// ve sus roles y los de sistema, pero solo puede crear y modificar los suyos.
+// Sin tenant el servicio gestiona los roles de sistema.
+func (s *RoleService) ForTenant(tenant *Tenant) *RoleService {
+	scoped := *s
+	scoped.tenant = tenant
+	return &scoped
+}
+
+// ForActor devuelve una copia del servicio que atribuye sus cambios al actor en la auditoría
+func (s *RoleService) ForActor(actor *Actor) *RoleService {
+	scoped := *s
+	scoped.actor = actor
+	return &scoped
+}
+
+// IfMatch devuelve una copia del servicio que solo modifica o elimina roles en una de las
+// versiones indicadas; nil no impone ninguna
+func (s *RoleService) IfMatch(precondition *Precondition) *RoleService {
+	scoped := *s
+	scoped.precondition = precondition
+	return &scoped
+}
+
+// roles consulta los roles visibles aplicando siempre el scope del tenant
+func (s *RoleService) roles() *gorm.DB {
+	return database.GetDB().Scopes(tenantRoles(s.tenant))
+}
+
+// ownedRoles consulta los roles que el tenant puede modificar
+func (s *RoleService) ownedRoles() *gorm.DB {
+	return database.GetDB().Scopes(tenantOwnedRoles(s.tenant))
 }
 
 // CreateRole crea un nuevo rol
 func (s *RoleService) CreateRole(req *dto.CreateRoleRequest) (*dto.RoleResponse, error) {
 	db := database.GetDB()
 
-	// Verificar nombre único
+	// Verificar nombre único entre los roles visibles
 	var existingRole models.Role
-	if err := db.Where("name = ?", req.Name).First(&existingRole).Error; err == nil {
+	if err := s.roles().Where("name = ?", req.Name).First(&existingRole).Error; err == nil {
 		return nil, errors.New("role with this name already exists")
 	}
 
@@ -39,43 +85,89 @@ func (s *RoleService) CreateRole(req *dto.CreateRoleRequest) (*dto.RoleResponse,
 		Description: req.Description,
 		IsActive:    isActive,
 	}
+	if s.tenant != nil {
+		role.OrganizationID = &s.tenant.OrganizationID
+	}
 
 	// Guardar en BD
-	if err := db.Create(&role).Error; err != nil {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&role).Error; err != nil {
+			return err
+		}
+		return s.audit.Record(tx, s.actor, s.auditEvent(models.AuditRoleCreate, &role, auditDiff(nil, roleAuditState(&role))))
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return s.toRoleResponse(&role), nil
 }
 
-// GetRoles obtiene todos los roles con filtros opcionales
-func (s *RoleService) GetRoles(includeInactive bool) ([]dto.RoleResponse, error) {
-	db := database.GetDB()
-	var roles []models.Role
+// GetRoles obtiene una página de roles con los filtros y el orden de params (ver roleListSpec).
+// Sin include_inactive ni filtro is_active solo devuelve los activos.
+// trashed ("with" u "only") incluye los roles en la papelera.
+func (s *RoleService) GetRoles(params *dto.ListQuery, includeInactive bool, trashed string) ([]dto.RoleResponse, *dto.PageMeta, error) {
+	trashedFilter, err := trashedScope("roles", trashed)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	query := db
-	if !includeInactive {
-		query = query.Where("is_active = ?", true)
+	query := s.roles().Scopes(trashedFilter)
+	if !includeInactive && params.Filters["is_active"] == "" {
+		query = query.Where("roles.is_active = ?", true)
 	}
 
-	if err := query.Find(&roles).Error; err != nil {
-		return nil, err
+	roles, meta, err := paginate(query, roleListSpec, params)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	var responses []dto.RoleResponse
+	responses := make([]dto.RoleResponse, 0, len(roles))
 	for _, role := range roles {
 		responses = append(responses, *s.toRoleResponse(&role))
 	}
 
-	return responses, nil
+	return responses, meta, nil
+}
+
+// roleListSpec campos de orden y filtros del listado de roles; name busca también en display_name
+var roleListSpec = listSpec[models.Role]{
+	table: "roles",
+	sorts: map[string]func(*models.Role) any{
+		"id":           func(r *models.Role) any { return r.ID },
+		"name":         func(r *models.Role) any { return r.Name },
+		"display_name": func(r *models.Role) any { return r.DisplayName },
+		"is_active":    func(r *models.Role) any { return r.IsActive },
+		"created_at":   func(r *models.Role) any { return r.CreatedAt },
+		"updated_at":   func(r *models.Role) any { return r.UpdatedAt },
+	},
+	defaultSort: "id",
+	filters: map[string]listFilter{
+		"name":         containsFilter("roles.name", "roles.display_name"),
+		"is_active":    boolFilter("roles.is_active", "is_active"),
+		"created_from": timeFilter("roles.created_at", ">=", "created_from"),
+		"created_to":   timeFilter("roles.created_at", "<=", "created_to"),
+		"filter":       rsqlFilter(roleFilterFields),
+	},
+}
+
+// roleFilterFields campos admitidos en ?filter= para roles; organization_id=null=true son los de sistema
+var roleFilterFields = map[string]rsqlField{
+	"id":              {Column: "roles.id", Kind: "id"},
+	"name":            {Column: "roles.name", Kind: "string"},
+	"display_name":    {Column: "roles.display_name", Kind: "string"},
+	"description":     {Column: "roles.description", Kind: "string"},
+	"is_active":       {Column: "roles.is_active", Kind: "bool"},
+	"organization_id": {Column: "roles.organization_id", Kind: "id", Nullable: true},
+	"created_at":      {Column: "roles.created_at", Kind: "date"},
+	"updated_at":      {Column: "roles.updated_at", Kind: "date"},
 }
 
 // GetRoleByID obtiene un rol por ID
 func (s *RoleService) GetRoleByID(id uint) (*dto.RoleResponse, error) {
-	db := database.GetDB()
 	var role models.Role
 
-	if err := db.First(&role, id).Error; err != nil {
+	if err := s.roles().First(&role, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("role not found")
 		}
@@ -87,41 +179,99 @@ func (s *RoleService) GetRoleByID(id uint) (*dto.RoleResponse, error) {
 
 // UpdateRole actualiza un rol existente
 func (s *RoleService) UpdateRole(id uint, req *dto.UpdateRoleRequest) (*dto.RoleResponse, error) {
+	return s.saveRole(id, func(role *models.Role) error {
+		if req.Name != "" {
+			role.Name = req.Name
+		}
+		if req.DisplayName != "" {
+			role.DisplayName = req.DisplayName
+		}
+		if req.Description != "" {
+			role.Description = req.Description
+		}
+		if req.IsActive != nil {
+			role.IsActive = *req.IsActive
+		}
+		return nil
+	})
+}
+
+// PatchRole aplica un merge patch o JSON Patch a la representación editable del rol. A diferencia
+// de PUT, un campo vacío o eliminado se guarda vacío: así se puede borrar la descripción.
+func (s *RoleService) PatchRole(id uint, patch *dto.Patch) (*dto.RoleResponse, error) {
+	return s.saveRole(id, func(role *models.Role) error {
+		isActive := role.IsActive
+		document := dto.RoleDocument{
+			Name:        role.Name,
+			DisplayName: role.DisplayName,
+			Description: role.Description,
+			IsActive:    &isActive,
+		}
+		var patched dto.RoleDocument
+		if err := applyPatch(document, patch, &patched); err != nil {
+			return err
+		}
+
+		if patched.Name == "" {
+			return utils.NewBadRequestError("name is required")
+		}
+		if patched.DisplayName == "" {
+			return utils.NewBadRequestError("display_name is required")
+		}
+		if patched.IsActive == nil {
+			return utils.NewBadRequestError("is_active is required")
+		}
+
+		role.Name = patched.Name
+		role.DisplayName = patched.DisplayName
+		role.Description = patched.Description
+		role.IsActive = *patched.IsActive
+		return nil
+	})
+}
+
+// saveRole carga un rol propio del tenant, le aplica apply y guarda y audita el resultado
+func (s *RoleService) saveRole(id uint, apply func(role *models.Role) error) (*dto.RoleResponse, error) {
 	db := database.GetDB()
 	var role models.Role
 
-	// Obtener rol existente
-	if err := db.First(&role, id).Error; err != nil {
+	// Obtener rol existente (los roles de sistema no se modifican desde una organización)
+	if err := s.ownedRoles().First(&role, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("role not found")
 		}
 		return nil, err
 	}
 
+	if err := s.precondition.check(role.Version); err != nil {
+		return nil, err
+	}
+
+	before := roleAuditState(&role)
+	if err := apply(&role); err != nil {
+		return nil, err
+	}
+
 	// Verificar nombre único si se está cambiando
-	if req.Name != "" && req.Name != role.Name {
+	if role.Name != before["name"] {
 		var existing models.Role
-		if err := db.Where("name = ? AND id != ?", req.Name, id).First(&existing).Error; err == nil {
+		if err := s.roles().Where("name = ? AND id != ?", role.Name, id).First(&existing).Error; err == nil {
 			return nil, errors.New("role with this name already exists")
 		}
 	}
 
-	// Actualizar campos
-	if req.Name != "" {
-		role.Name = req.Name
-	}
-	if req.DisplayName != "" {
-		role.DisplayName = req.DisplayName
-	}
-	if req.Description != "" {
-		role.Description = req.Description
-	}
-	if req.IsActive != nil {
-		role.IsActive = *req.IsActive
-	}
-
 	// Guardar cambios
-	if err := db.Save(&role).Error; err != nil {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := updateVersioned(tx, &role, &role.Version); err != nil {
+			return err
+		}
+		changes := auditDiff(before, roleAuditState(&role))
+		if len(changes) == 0 {
+			return nil
+		}
+		return s.audit.Record(tx, s.actor, s.auditEvent(models.AuditRoleUpdate, &role, changes))
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -132,15 +282,19 @@ func (s *RoleService) UpdateRole(id uint, req *dto.UpdateRoleRequest) (*dto.Role
 func (s *RoleService) DeleteRole(id uint) error {
 	db := database.GetDB()
 
-	// Verificar que el rol existe
+	// Verificar que el rol existe (los roles de sistema no se eliminan desde una organización)
 	var role models.Role
-	if err := db.First(&role, id).Error; err != nil {
+	if err := s.ownedRoles().First(&role, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return errors.New("role not found")
 		}
 		return err
 	}
 
+	if err := s.precondition.check(role.Version); err != nil {
+		return err
+	}
+
 	// Verificar que no hay usuarios usando este rol
 	var userCount int64
 	if err := db.Model(&models.User{}).Where("role_id = ?", id).Count(&userCount).Error; err != nil {
@@ -151,8 +305,179 @@ func (s *RoleService) DeleteRole(id uint) error {
 		return errors.New("cannot delete role: it is assigned to users")
 	}
 
-	// Soft delete
-	return db.Delete(&role).Error
+	// ...ni como rol de alguna membresía
+	if err := db.Model(&models.OrganizationMembership{}).Where("role_id = ?", id).Count(&userCount).Error; err != nil {
+		return err
+	}
+
+	if userCount > 0 {
+		return errors.New("cannot delete role: it is assigned to users")
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		// Soft delete
+		if err := deleteVersioned(tx, &role, role.Version); err != nil {
+			return err
+		}
+		return s.audit.Record(tx, s.actor, s.auditEvent(models.AuditRoleDelete, &role, auditDiff(roleAuditState(&role), nil)))
+	})
+}
+
+// RestoreRole saca un rol propio de la papelera si su nombre sigue libre
+func (s *RoleService) RestoreRole(id uint) (*dto.RoleResponse, error) {
+	var role models.Role
+	if err := s.ownedRoles().Unscoped().Where("roles.deleted_at IS NOT NULL").First(&role, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("Deleted role")
+		}
+		return nil, err
+	}
+
+	var existing models.Role
+	if err := s.roles().Where("name = ?", role.Name).First(&existing).Error; err == nil {
+		return nil, utils.NewConflictError("role with this name already exists")
+	}
+
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&role).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		return s.audit.Record(tx, s.actor, s.auditEvent(models.AuditRoleRestore, &role, auditDiff(nil, roleAuditState(&role))))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	role.DeletedAt = gorm.DeletedAt{}
+	return s.toRoleResponse(&role), nil
+}
+
+// PurgeRole elimina definitivamente un rol propio, esté o no en la papelera, junto con sus
+// revisiones. Falla si algún usuario, incluidos los de la papelera, o alguna membresía lo usa.
+func (s *RoleService) PurgeRole(id uint) error {
+	db := database.GetDB()
+
+	var role models.Role
+	if err := s.ownedRoles().Unscoped().First(&role, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.NewNotFoundError("Role")
+		}
+		return err
+	}
+
+	var userCount int64
+	if err := db.Unscoped().Model(&models.User{}).Where("role_id = ?", id).Count(&userCount).Error; err != nil {
+		return err
+	}
+	if userCount == 0 {
+		if err := db.Model(&models.OrganizationMembership{}).Where("role_id = ?", id).Count(&userCount).Error; err != nil {
+			return err
+		}
+	}
+	if userCount > 0 {
+		return utils.NewConflictError("cannot purge role: it is assigned to users")
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("resource_type = ? AND resource_id = ?", models.RevisionRole, role.ID).
+			Delete(&models.Revision{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Delete(&role).Error; err != nil {
+			return err
+		}
+		return s.audit.Record(tx, s.actor, s.auditEvent(models.AuditRolePurge, &role, auditDiff(roleAuditState(&role), nil)))
+	})
+}
+
+// GetRevisions obtiene el historial de revisiones de un rol; con at, solo la vigente en ese momento
+func (s *RoleService) GetRevisions(id uint, at *time.Time) ([]dto.RevisionResponse, error) {
+	if err := s.ensureVisible(id); err != nil {
+		return nil, err
+	}
+	return s.revisions.List(database.GetDB(), models.RevisionRole, id, at)
+}
+
+// GetRevision obtiene una revisión de un rol con sus diferencias
+func (s *RoleService) GetRevision(id, number uint) (*dto.RevisionResponse, error) {
+	if err := s.ensureVisible(id); err != nil {
+		return nil, err
+	}
+	return s.revisions.Get(database.GetDB(), models.RevisionRole, id, number)
+}
+
+// RestoreRevision devuelve un rol propio al estado de una revisión anterior; el guardado
+// crea a su vez una revisión nueva. La organización dueña del rol no cambia.
+func (s *RoleService) RestoreRevision(id, number uint) (*dto.RoleResponse, error) {
+	var role models.Role
+	if err := s.ownedRoles().First(&role, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("Role")
+		}
+		return nil, err
+	}
+
+	snapshot, err := s.revisions.Snapshot(database.GetDB(), models.RevisionRole, id, number)
+	if err != nil {
+		return nil, err
+	}
+
+	before := roleAuditState(&role)
+	role.Name = snapshotString(snapshot, "name")
+	role.DisplayName = snapshotString(snapshot, "display_name")
+	role.Description = snapshotString(snapshot, "description")
+	role.IsActive = snapshotBool(snapshot, "is_active")
+
+	if role.Name != before["name"] {
+		var existing models.Role
+		if err := s.roles().Where("name = ? AND id != ?", role.Name, id).First(&existing).Error; err == nil {
+			return nil, utils.NewConflictError("role with this name already exists")
+		}
+	}
+
+	err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&role).Error; err != nil {
+			return err
+		}
+		changes := auditDiff(before, roleAuditState(&role))
+		if len(changes) == 0 {
+			return nil
+		}
+		event := s.auditEvent(models.AuditRoleUpdate, &role, changes)
+		event.Metadata = map[string]interface{}{"restored_revision": number}
+		return s.audit.Record(tx, s.actor, event)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.toRoleResponse(&role), nil
+}
+
+// ensureVisible comprueba que el rol existe y es visible para el tenant
+func (s *RoleService) ensureVisible(id uint) error {
+	var role models.Role
+	if err := s.roles().Select("roles.id").First(&role, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.NewNotFoundError("Role")
+		}
+		return err
+	}
+	return nil
+}
+
+// auditEvent evento de auditoría sobre un rol en la organización del tenant
+func (s *RoleService) auditEvent(action string, role *models.Role, changes auditChanges) auditEvent {
+	event := auditEvent{
+		Action:     action,
+		TargetType: models.AuditTargetRole,
+		TargetID:   role.ID,
+		Changes:    changes,
+	}
+	if s.tenant != nil {
+		event.OrganizationID = &s.tenant.OrganizationID
+	}
+	return event
 }
 
 // toRoleResponse convierte un modelo Role a RoleResponse
@@ -163,7 +488,10 @@ func (s *RoleService) toRoleResponse(role *models.Role) *dto.RoleResponse {
 		DisplayName: role.DisplayName,
 		Description: role.Description,
 		IsActive:    role.IsActive,
+		OrganizationID: role.OrganizationID,
+		Version:     role.Version,
 		CreatedAt:   role.CreatedAt,
 		UpdatedAt:   role.UpdatedAt,
+		DeletedAt:   deletedAt(role.DeletedAt),
 	}
 }
\ No newline at end of file
@@ -0,0 +1,407 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"megabaseGo/internal/database/encryption"
+	"megabaseGo/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// Longitud máxima y anidamiento máximo de una expresión ?filter=
+const (
+	maxRSQLLength = 2000
+	maxRSQLDepth  = 16
+)
+
+// rsqlField describe cómo se traduce un campo del filtro a SQL
+type rsqlField struct {
+	Column    string // expresión SQL (solo procede de la lista blanca)
+	Kind      string // "string", "bool", "id" o "date"
+	CaseExact bool   // si false, las comparaciones de texto ignoran mayúsculas
+	Nullable  bool   // admite =null=
+	// Subquery envuelve la condición sobre Column cuando el campo es de otra tabla:
+	// "users.role_id IN (SELECT roles.id FROM roles WHERE {cond})"
+	Subquery     string
+	SubqueryArgs []any
+	// Encrypted columna cifrada con el cifrado de campos activo: solo admite igualdad y
+	// pertenencia si Match la resuelve (p.ej. por índice ciego); sin Match no se puede filtrar
+	Encrypted bool
+	Match     func(value string) (string, []any)
+}
+
+// Operadores admitidos: los de FIQL/RSQL más =like= (comodín *) y =null=
+var rsqlOperators = []string{"==", "!=", "=lt=", "=le=", "=gt=", "=ge=", "=in=", "=out=", "=like=", "=null=", "<=", ">=", "<", ">"}
+
+// Alias de los operadores abreviados
+var rsqlOperatorAliases = map[string]string{"<": "=lt=", "<=": "=le=", ">": "=gt=", ">=": "=ge="}
+
+// rsqlFilter filtro del listado que compila la expresión RSQL de ?filter= contra los campos permitidos
+func rsqlFilter(fields map[string]rsqlField) listFilter {
+	return func(db *gorm.DB, value string) (*gorm.DB, error) {
+		condition, args, err := compileRSQL(value, fields)
+		if err != nil {
+			return nil, err
+		}
+		return db.Where(condition, args...), nil
+	}
+}
+
+// compileRSQL compila una expresión RSQL (";" = AND, "," = OR, paréntesis para agrupar) a una
+// condición SQL parametrizada. Los errores indican la posición (1-based) del problema.
+func compileRSQL(input string, fields map[string]rsqlField) (string, []any, error) {
+	if len([]rune(input)) > maxRSQLLength {
+		return "", nil, utils.NewBadRequestError(fmt.Sprintf("invalid filter: expression exceeds %d characters", maxRSQLLength))
+	}
+	parser := &rsqlParser{input: []rune(input), fields: fields}
+	filter, err := parser.parseOr()
+	if err == nil {
+		parser.skipSpaces()
+		if parser.pos < len(parser.input) {
+			err = parser.errorf("unexpected %q", string(parser.input[parser.pos]))
+		}
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	return filter.SQL, filter.Args, nil
+}
+
+type rsqlParser struct {
+	input  []rune
+	pos    int
+	depth  int
+	fields map[string]rsqlField
+}
+
+// errorf error 400 con la posición actual
+func (p *rsqlParser) errorf(format string, args ...any) error {
+	return utils.NewBadRequestError(fmt.Sprintf("invalid filter at position %d: %s", p.pos+1, fmt.Sprintf(format, args...)))
+}
+
+func (p *rsqlParser) skipSpaces() {
+	for p.pos < len(p.input) && unicode.IsSpace(p.input[p.pos]) {
+		p.pos++
+	}
+}
+
+// consume avanza si el siguiente carácter (sin contar espacios) es r
+func (p *rsqlParser) consume(r rune) bool {
+	p.skipSpaces()
+	if p.pos < len(p.input) && p.input[p.pos] == r {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *rsqlParser) parseOr() (*scimFilter, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.consume(',') {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &scimFilter{SQL: "(" + left.SQL + " OR " + right.SQL + ")", Args: append(left.Args, right.Args...)}
+	}
+	return left, nil
+}
+
+func (p *rsqlParser) parseAnd() (*scimFilter, error) {
+	left, err := p.parseConstraint()
+	if err != nil {
+		return nil, err
+	}
+	for p.consume(';') {
+		right, err := p.parseConstraint()
+		if err != nil {
+			return nil, err
+		}
+		left = &scimFilter{SQL: "(" + left.SQL + " AND " + right.SQL + ")", Args: append(left.Args, right.Args...)}
+	}
+	return left, nil
+}
+
+func (p *rsqlParser) parseConstraint() (*scimFilter, error) {
+	p.skipSpaces()
+	if p.pos >= len(p.input) {
+		return nil, p.errorf("unexpected end of filter, expected a comparison")
+	}
+	if !p.consume('(') {
+		return p.parseComparison()
+	}
+
+	p.depth++
+	if p.depth > maxRSQLDepth {
+		p.pos-- // el error señala el paréntesis que excede el límite
+		return nil, p.errorf("groups are nested more than %d levels deep", maxRSQLDepth)
+	}
+	inner, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.consume(')') {
+		return nil, p.errorf("expected ')'")
+	}
+	p.depth--
+	return &scimFilter{SQL: "(" + inner.SQL + ")", Args: inner.Args}, nil
+}
+
+func (p *rsqlParser) parseComparison() (*scimFilter, error) {
+	start := p.pos
+	for p.pos < len(p.input) && (unicode.IsLetter(p.input[p.pos]) || unicode.IsDigit(p.input[p.pos]) || strings.ContainsRune("_.", p.input[p.pos])) {
+		p.pos++
+	}
+	selector := string(p.input[start:p.pos])
+	if selector == "" {
+		return nil, p.errorf("expected field name, got %q", string(p.input[p.pos]))
+	}
+	field, ok := p.fields[selector]
+	if !ok {
+		p.pos = start
+		return nil, p.errorf("unknown field %q; allowed fields: %s", selector, strings.Join(sortedKeys(p.fields), ", "))
+	}
+
+	p.skipSpaces()
+	operator, err := p.parseOperator(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := p.parseArguments(operator)
+	if err != nil {
+		return nil, err
+	}
+
+	condition, err := compileRSQLComparison(selector, field, operator, values)
+	if err != nil {
+		p.pos = start
+		return nil, p.errorf("%v", err)
+	}
+
+	if field.Subquery != "" {
+		condition = &scimFilter{
+			SQL:  strings.Replace(field.Subquery, "{cond}", condition.SQL, 1),
+			Args: append(append([]any(nil), field.SubqueryArgs...), condition.Args...),
+		}
+	}
+	return condition, nil
+}
+
+// parseOperator lee el operador más largo que coincida y devuelve su forma canónica (=lt= en vez de <)
+func (p *rsqlParser) parseOperator(selector string) (string, error) {
+	rest := string(p.input[p.pos:])
+	best := ""
+	for _, operator := range rsqlOperators {
+		if strings.HasPrefix(rest, operator) && len(operator) > len(best) {
+			best = operator
+		}
+	}
+	if best == "" {
+		if strings.HasPrefix(rest, "=") {
+			if end := strings.Index(rest[1:], "="); end > 0 {
+				return "", p.errorf("unknown operator %q (one of %s)", rest[:end+2], strings.Join(rsqlOperators, " "))
+			}
+		}
+		return "", p.errorf("expected comparison operator after %q (one of %s)", selector, strings.Join(rsqlOperators, " "))
+	}
+
+	p.pos += len([]rune(best))
+	if alias, ok := rsqlOperatorAliases[best]; ok {
+		return alias, nil
+	}
+	return best, nil
+}
+
+// parseArguments lee el argumento: un valor o, con =in= y =out=, una lista "(a,b,c)"
+func (p *rsqlParser) parseArguments(operator string) ([]string, error) {
+	if operator != "=in=" && operator != "=out=" {
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		return []string{value}, nil
+	}
+
+	if !p.consume('(') {
+		return nil, p.errorf("operator %s expects a list of values like (a,b)", operator)
+	}
+	var values []string
+	for {
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+		if p.consume(')') {
+			return values, nil
+		}
+		if !p.consume(',') {
+			return nil, p.errorf("expected ',' or ')' in value list")
+		}
+	}
+}
+
+// parseValue lee un valor sin comillas (hasta un separador) o entre comillas simples o dobles con escapes \
+func (p *rsqlParser) parseValue() (string, error) {
+	p.skipSpaces()
+	if p.pos >= len(p.input) {
+		return "", p.errorf("unexpected end of filter, expected a value")
+	}
+
+	if quote := p.input[p.pos]; quote == '\'' || quote == '"' {
+		start := p.pos
+		p.pos++
+		var value strings.Builder
+		for p.pos < len(p.input) && p.input[p.pos] != quote {
+			if p.input[p.pos] == '\\' && p.pos+1 < len(p.input) {
+				p.pos++
+			}
+			value.WriteRune(p.input[p.pos])
+			p.pos++
+		}
+		if p.pos >= len(p.input) {
+			p.pos = start
+			return "", p.errorf("unterminated quoted value")
+		}
+		p.pos++
+		return value.String(), nil
+	}
+
+	start := p.pos
+	for p.pos < len(p.input) && !unicode.IsSpace(p.input[p.pos]) && !strings.ContainsRune(`;,()'"`, p.input[p.pos]) {
+		p.pos++
+	}
+	if p.pos == start {
+		return "", p.errorf("expected a value, got %q", string(p.input[p.pos]))
+	}
+	return string(p.input[start:p.pos]), nil
+}
+
+// compileRSQLComparison traduce una comparación ya leída a SQL sobre la columna del campo
+func compileRSQLComparison(selector string, field rsqlField, operator string, raw []string) (*scimFilter, error) {
+	if operator == "=null=" {
+		if !field.Nullable {
+			return nil, fmt.Errorf("field %q is never null", selector)
+		}
+		switch raw[0] {
+		case "true":
+			return &scimFilter{SQL: field.Column + " IS NULL"}, nil
+		case "false":
+			return &scimFilter{SQL: field.Column + " IS NOT NULL"}, nil
+		}
+		return nil, fmt.Errorf("=null= expects true or false")
+	}
+
+	if field.Encrypted && encryption.Enabled() {
+		return compileEncryptedRSQL(selector, field, operator, raw)
+	}
+
+	column := field.Column
+	textual := field.Kind == "string" && !field.CaseExact
+	if textual {
+		column = "LOWER(" + column + ")"
+	}
+
+	if operator == "=like=" {
+		if field.Kind != "string" {
+			return nil, fmt.Errorf("operator =like= requires a text field, %q is %s", selector, field.Kind)
+		}
+		pattern := strings.ReplaceAll(escapeLike(raw[0]), "*", "%")
+		if textual {
+			pattern = strings.ToLower(pattern)
+		}
+		return &scimFilter{SQL: column + " LIKE ?", Args: []any{pattern}}, nil
+	}
+
+	values := make([]any, len(raw))
+	for i, value := range raw {
+		parsed, err := rsqlValue(field, value)
+		if err != nil {
+			return nil, fmt.Errorf("field %q: %v", selector, err)
+		}
+		if str, isString := parsed.(string); isString && textual {
+			parsed = strings.ToLower(str)
+		}
+		values[i] = parsed
+	}
+
+	switch operator {
+	case "==":
+		return &scimFilter{SQL: column + " = ?", Args: values}, nil
+	case "!=":
+		return &scimFilter{SQL: column + " IS DISTINCT FROM ?", Args: values}, nil
+	case "=in=":
+		return &scimFilter{SQL: column + " IN ?", Args: []any{values}}, nil
+	case "=out=":
+		return &scimFilter{SQL: "(" + column + " IS NULL OR " + column + " NOT IN ?)", Args: []any{values}}, nil
+	case "=lt=", "=le=", "=gt=", "=ge=":
+		if field.Kind == "bool" {
+			return nil, fmt.Errorf("operator %s is not valid for boolean field %q", operator, selector)
+		}
+		sqlOperator := map[string]string{"=lt=": "<", "=le=": "<=", "=gt=": ">", "=ge=": ">="}[operator]
+		return &scimFilter{SQL: column + " " + sqlOperator + " ?", Args: values}, nil
+	}
+	return nil, fmt.Errorf("unsupported operator %s", operator)
+}
+
+// compileEncryptedRSQL resuelve igualdad y pertenencia sobre un campo cifrado con su Match
+func compileEncryptedRSQL(selector string, field rsqlField, operator string, raw []string) (*scimFilter, error) {
+	if field.Match == nil || (operator != "==" && operator != "!=" && operator != "=in=" && operator != "=out=") {
+		if field.Match == nil {
+			return nil, fmt.Errorf("field %q is encrypted and cannot be filtered", selector)
+		}
+		return nil, fmt.Errorf("field %q is encrypted and only supports ==, !=, =in= and =out=", selector)
+	}
+
+	conditions := make([]string, len(raw))
+	var args []any
+	for i, value := range raw {
+		condition, conditionArgs := field.Match(value)
+		conditions[i] = condition
+		args = append(args, conditionArgs...)
+	}
+	sql := "(" + strings.Join(conditions, " OR ") + ")"
+	if operator == "!=" || operator == "=out=" {
+		sql = "NOT " + sql
+	}
+	return &scimFilter{SQL: sql, Args: args}, nil
+}
+
+// rsqlValue convierte el valor al tipo del campo; las fechas admiten RFC 3339 o YYYY-MM-DD (medianoche UTC)
+func rsqlValue(field rsqlField, value string) (any, error) {
+	switch field.Kind {
+	case "bool":
+		switch value {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+		return nil, fmt.Errorf("invalid boolean value %q, expected true or false", value)
+	case "id":
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid id value %q", value)
+		}
+		return uint(id), nil
+	case "date":
+		if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+			return parsed, nil
+		}
+		parsed, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date value %q, expected RFC 3339 timestamp or YYYY-MM-DD", value)
+		}
+		return parsed, nil
+	}
+	return value, nil
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"megabaseGo/internal/utils"
+)
+
+var testRSQLFields = map[string]rsqlField{
+	"name":       {Column: "name", Kind: "string"},
+	"user_name":  {Column: "user_name", Kind: "string", CaseExact: true},
+	"is_active":  {Column: "is_active", Kind: "bool"},
+	"role_id":    {Column: "role_id", Kind: "id", Nullable: true},
+	"created_at": {Column: "created_at", Kind: "date"},
+}
+
+func TestCompileRSQL(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		wantSQL  string
+		wantArgs []any
+	}{
+		{"equality ignores case", "name==Alice", "LOWER(name) = ?", []any{"alice"}},
+		{"case exact field", "user_name==Alice", "user_name = ?", []any{"Alice"}},
+		{"not equal", "user_name!=bob", "user_name IS DISTINCT FROM ?", []any{"bob"}},
+		{"and binds tighter than or", "name==a;user_name==b,is_active==true",
+			"((LOWER(name) = ? AND user_name = ?) OR is_active = ?)", []any{"a", "b", true}},
+		{"or then and", "name==a,user_name==b;is_active==true",
+			"(LOWER(name) = ? OR (user_name = ? AND is_active = ?))", []any{"a", "b", true}},
+		{"groups override precedence", "(name==a,name==b);is_active==false",
+			"(((LOWER(name) = ? OR LOWER(name) = ?)) AND is_active = ?)", []any{"a", "b", false}},
+		{"spaces around separators", " name==a ; user_name==b ",
+			"(LOWER(name) = ? AND user_name = ?)", []any{"a", "b"}},
+		{"single quotes keep separators", "user_name=='x;y,(z) w'", "user_name = ?", []any{"x;y,(z) w"}},
+		{"double quotes with escaped quote", `user_name=="say \"hi\""`, "user_name = ?", []any{`say "hi"`}},
+		{"escaped backslash", `user_name=='a\\b'`, "user_name = ?", []any{`a\b`}},
+		{"non-ascii value", "name=='Ana María'", "LOWER(name) = ?", []any{"ana maría"}},
+		{"in list", "role_id=in=(1, 2,3)", "role_id IN ?", []any{[]any{uint(1), uint(2), uint(3)}}},
+		{"out list with quoted value", "user_name=out=(a,'b c')",
+			"(user_name IS NULL OR user_name NOT IN ?)", []any{[]any{"a", "b c"}}},
+		{"like wildcard", "name=like=Ab*", "LOWER(name) LIKE ?", []any{"ab%"}},
+		{"like escapes sql wildcards", "user_name=like=*50%_off*", "user_name LIKE ?", []any{`%50\%\_off%`}},
+		{"like escapes backslash", `user_name=like='a\\b*'`, "user_name LIKE ?", []any{`a\\b%`}},
+		{"short alias", "role_id<5", "role_id < ?", []any{uint(5)}},
+		{"longest operator wins", "created_at>=2024-01-02", "created_at >= ?",
+			[]any{time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)}},
+		{"rfc 3339 date", "created_at=lt=2024-01-02T10:00:00Z", "created_at < ?",
+			[]any{time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)}},
+		{"null", "role_id=null=true", "role_id IS NULL", nil},
+		{"not null", "role_id=null=false", "role_id IS NOT NULL", nil},
+		{"nesting at the limit", strings.Repeat("(", maxRSQLDepth) + "name==a" + strings.Repeat(")", maxRSQLDepth),
+			strings.Repeat("(", maxRSQLDepth) + "LOWER(name) = ?" + strings.Repeat(")", maxRSQLDepth), []any{"a"}},
+		{"length at the limit", "user_name==" + strings.Repeat("a", maxRSQLLength-len("user_name==")),
+			"user_name = ?", []any{strings.Repeat("a", maxRSQLLength-len("user_name=="))}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, args, err := compileRSQL(tt.input, testRSQLFields)
+			if err != nil {
+				t.Fatalf("compileRSQL(%q) error: %v", tt.input, err)
+			}
+			if sql != tt.wantSQL {
+				t.Errorf("sql = %q, want %q", sql, tt.wantSQL)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("args = %#v, want %#v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestCompileRSQLErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{"unknown field", "bogus==1", `invalid filter at position 1: unknown field "bogus"`},
+		{"missing field name", "==1", `invalid filter at position 1: expected field name`},
+		{"trailing separator", "name==a;", "invalid filter at position 9: unexpected end of filter, expected a comparison"},
+		{"unknown operator", "name=foo=x", `invalid filter at position 5: unknown operator "=foo="`},
+		{"missing operator", "name", `invalid filter at position 5: expected comparison operator after "name"`},
+		{"missing value", "name==", "invalid filter at position 7: unexpected end of filter, expected a value"},
+		{"unterminated quote", "name=='abc", "invalid filter at position 7: unterminated quoted value"},
+		{"unbalanced close", "name==a)", `invalid filter at position 8: unexpected ")"`},
+		{"unclosed group", "(name==a", "invalid filter at position 9: expected ')'"},
+		{"in without list", "role_id=in=1", "invalid filter at position 12: operator =in= expects a list of values like (a,b)"},
+		{"unterminated list", "role_id=in=(1,2", "invalid filter at position 16: expected ',' or ')' in value list"},
+		{"invalid id", "is_active==true;role_id==abc", `invalid filter at position 17: field "role_id": invalid id value "abc"`},
+		{"invalid bool", "is_active==yes", `invalid filter at position 1: field "is_active": invalid boolean value "yes"`},
+		{"invalid date", "created_at>yesterday", `invalid filter at position 1: field "created_at": invalid date value "yesterday"`},
+		{"ordering on bool", "is_active=lt=true", "invalid filter at position 1: operator =lt= is not valid for boolean field"},
+		{"like on non-text", "role_id=like=1*", "invalid filter at position 1: operator =like= requires a text field"},
+		{"null on non-nullable", "name=null=true", `invalid filter at position 1: field "name" is never null`},
+		{"null expects bool", "role_id=null=maybe", "invalid filter at position 1: =null= expects true or false"},
+		{"too deep", strings.Repeat("(", maxRSQLDepth+1) + "name==a" + strings.Repeat(")", maxRSQLDepth+1),
+			"invalid filter at position 17: groups are nested more than 16 levels deep"},
+		{"too long", "user_name==" + strings.Repeat("a", maxRSQLLength),
+			"invalid filter: expression exceeds 2000 characters"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := compileRSQL(tt.input, testRSQLFields)
+			if err == nil {
+				t.Fatalf("compileRSQL(%q) = nil error, want %q", tt.input, tt.wantErr)
+			}
+			apiErr, ok := utils.IsAPIError(err)
+			if !ok || apiErr.StatusCode != http.StatusBadRequest {
+				t.Fatalf("error = %#v, want a 400 APIError", err)
+			}
+			if !strings.HasPrefix(err.Error(), tt.wantErr) {
+				t.Errorf("error = %q, want prefix %q", err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
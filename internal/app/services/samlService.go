@@ -0,0 +1,959 @@
+package services
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"megabaseGo/internal/app/dto"
+	"megabaseGo/internal/database"
+	"megabaseGo/internal/models"
+	"megabaseGo/internal/utils"
+
+	"github.com/crewjam/saml"
+	dsig "github.com/russellhaering/goxmldsig"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var samlSlugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,99}$`)
+
+// Nombres de atributo habituales (Azure AD, Okta, ADFS, Google, Shibboleth) cuando el IdP no tiene mapeo propio
+var defaultSamlAttributes = map[string][]string{
+	"email": {
+		"email", "mail", "emailaddress",
+		"http://schemas.xmlsoap.org/ws/2005/05/identity/claims/emailaddress",
+		"urn:oid:0.9.2342.19200300.100.1.3",
+	},
+	"user_name": {
+		"username", "uid", "preferred_username",
+		"urn:oid:0.9.2342.19200300.100.1.1",
+	},
+	"name": {
+		"displayname", "name", "cn",
+		"http://schemas.xmlsoap.org/ws/2005/05/identity/claims/name",
+		"urn:oid:2.16.840.1.113730.3.1.241",
+	},
+}
+
+type SamlService struct {
+	authService *AuthService
+	audit       *AuditService
+	hasher      utils.PasswordHasher
+	baseURL     string
+	successURL  string
+	defaultRole string
+	requestTTL  time.Duration
+	key         *rsa.PrivateKey
+	cert        *x509.Certificate
+}
+
+// NewSamlService crea una nueva instancia del servicio SAML (Service Provider)
+func NewSamlService() *SamlService {
+	service := &SamlService{
+		authService: NewAuthService(),
+		audit:       NewAuditService(),
+		hasher:      utils.NewBcryptHasher(),
+		baseURL:     strings.TrimRight(utils.GetEnv("SAML_SP_BASE_URL", "http://localhost:8080/api/v1/auth/saml"), "/"),
+		successURL:  utils.GetEnv("SAML_SUCCESS_URL", "http://localhost:3000/"),
+		defaultRole: utils.GetEnv("SAML_DEFAULT_ROLE", "user"),
+		requestTTL:  time.Duration(utils.GetEnvInt("SAML_REQUEST_TTL_MINUTES", 10)) * time.Minute,
+	}
+
+	// El par de claves del SP es opcional: sin él no se firman los AuthnRequest
+	// ni se pueden recibir aserciones cifradas
+	keyFile, certFile := utils.GetEnv("SAML_SP_KEY_FILE", ""), utils.GetEnv("SAML_SP_CERT_FILE", "")
+	if keyFile != "" && certFile != "" {
+		key, cert, err := utils.LoadKeyPair(keyFile, certFile)
+		if err != nil {
+			log.Printf("Error cargando el par de claves SAML del SP: %v", err)
+		} else {
+			service.key = key
+			service.cert = cert
+		}
+	}
+
+	return service
+}
+
+// ---------------------------------------------------------------------------
+// Flujo de login
+// ---------------------------------------------------------------------------
+
+// Metadata genera los metadatos XML del SP para un IdP concreto
+func (s *SamlService) Metadata(slug string) ([]byte, error) {
+	provider, err := s.findActiveProvider(slug)
+	if err != nil {
+		return nil, err
+	}
+	sp, err := s.serviceProvider(provider)
+	if err != nil {
+		return nil, err
+	}
+	return xml.MarshalIndent(sp.Metadata(), "", "  ")
+}
+
+// StartLogin inicia un login iniciado por el SP y devuelve la URL del IdP a la que redirigir
+func (s *SamlService) StartLogin(slug, redirectTo string) (string, error) {
+	return s.startAuthn(slug, models.SamlRequest{RedirectTo: redirectTo})
+}
+
+// StartLink inicia una autenticación en el IdP para vincular la identidad resultante a userID
+func (s *SamlService) StartLink(slug string, userID uint, redirectTo string) (string, error) {
+	return s.startAuthn(slug, models.SamlRequest{RedirectTo: redirectTo, LinkUserID: &userID})
+}
+
+// StartReauthentication inicia una re-autenticación (step-up) en el IdP para las cuentas sin
+// contraseña. Se pide ForceAuthn y la aserción debe ser de una identidad ya vinculada a userID;
+// el ACS responde con un token elevado de la sesión sessionID.
+func (s *SamlService) StartReauthentication(slug string, userID uint, sessionID, redirectTo string) (string, error) {
+	return s.startAuthn(slug, models.SamlRequest{RedirectTo: redirectTo, ReauthUserID: &userID, ReauthSession: sessionID})
+}
+
+// startAuthn emite el AuthnRequest y guarda pending (destino, vinculación o step-up) para el ACS
+func (s *SamlService) startAuthn(slug string, pending models.SamlRequest) (string, error) {
+	provider, err := s.findActiveProvider(slug)
+	if err != nil {
+		return "", err
+	}
+	sp, err := s.serviceProvider(provider)
+	if err != nil {
+		return "", err
+	}
+
+	ssoURL := sp.GetSSOBindingLocation(saml.HTTPRedirectBinding)
+	if ssoURL == "" {
+		return "", utils.NewBadRequestError("identity provider does not support the HTTP-Redirect binding")
+	}
+
+	authnRequest, err := sp.MakeAuthenticationRequest(ssoURL, saml.HTTPRedirectBinding, saml.HTTPPostBinding)
+	if err != nil {
+		return "", err
+	}
+	// Un step-up no puede resolverse con la sesión que el usuario ya tenga abierta en el IdP
+	if pending.ReauthUserID != nil {
+		forceAuthn := true
+		authnRequest.ForceAuthn = &forceAuthn
+	}
+
+	relayState, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	db := database.GetDB()
+	now := time.Now()
+	db.Where("expires_at < ?", now).Delete(&models.SamlRequest{})
+
+	pending.ID = authnRequest.ID
+	pending.ProviderID = provider.ID
+	pending.RelayStateHash = utils.HashToken(relayState)
+	pending.RedirectTo = s.safeRedirect(pending.RedirectTo)
+	pending.ExpiresAt = now.Add(s.requestTTL)
+	if err := db.Create(&pending).Error; err != nil {
+		return "", err
+	}
+
+	redirectURL, err := authnRequest.Redirect(relayState, sp)
+	if err != nil {
+		return "", err
+	}
+	return redirectURL.String(), nil
+}
+
+// SamlOutcome resultado de una respuesta del IdP y URL a la que debe volver el navegador.
+// Un login trae Auth, una re-autenticación Reauth y una vinculación de identidad ninguno.
+type SamlOutcome struct {
+	Auth       *dto.AuthResponse
+	Reauth     *dto.ReauthenticateResponse
+	RedirectTo string
+}
+
+// ConsumeResponse valida la respuesta del IdP en el ACS y completa el login (par de tokens
+// estándar), la vinculación de identidad o la re-autenticación que la inició.
+func (s *SamlService) ConsumeResponse(slug string, r *http.Request, meta *dto.RequestMeta) (*SamlOutcome, error) {
+	provider, err := s.findActiveProvider(slug)
+	if err != nil {
+		return nil, err
+	}
+	sp, err := s.serviceProvider(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return nil, utils.NewBadRequestError("invalid SAML response form")
+	}
+
+	db := database.GetDB()
+	now := time.Now()
+	redirectTo := s.successURL
+
+	// Login iniciado por el SP: el RelayState identifica el AuthnRequest y se consume una sola vez
+	var possibleRequestIDs []string
+	var request models.SamlRequest
+	relayState := r.PostForm.Get("RelayState")
+	if relayState != "" {
+		var pending models.SamlRequest
+		err := db.Where("relay_state_hash = ? AND provider_id = ? AND expires_at > ?", utils.HashToken(relayState), provider.ID, now).
+			First(&pending).Error
+		if err == nil {
+			if result := db.Delete(&models.SamlRequest{}, "id = ?", pending.ID); result.Error == nil && result.RowsAffected == 1 {
+				possibleRequestIDs = []string{pending.ID}
+				redirectTo = pending.RedirectTo
+				request = pending
+			}
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+
+	// Sin AuthnRequest asociado solo se aceptan respuestas no solicitadas si el IdP lo permite.
+	// crewjam/saml deja de comprobar InResponseTo con AllowIDPInitiated, así que solo se activa aquí.
+	if possibleRequestIDs == nil {
+		if !provider.AllowIdPInitiated {
+			s.authService.recordLoginFailure(nil, provider.Slug, "unsolicited_saml_response", models.LoginMethodSAML, meta)
+			return nil, utils.NewUnauthorizedError("unsolicited SAML responses are not allowed for this identity provider")
+		}
+		sp.AllowIDPInitiated = true
+		if relayState != "" {
+			redirectTo = s.safeRedirect(relayState)
+		}
+	}
+
+	assertion, err := sp.ParseResponse(r, possibleRequestIDs)
+	if err != nil {
+		var invalid *saml.InvalidResponseError
+		if errors.As(err, &invalid) {
+			log.Printf("Respuesta SAML inválida de %s: %v", provider.Slug, invalid.PrivateErr)
+		}
+		s.authService.recordLoginFailure(nil, provider.Slug, "invalid_saml_response", models.LoginMethodSAML, meta)
+		return nil, utils.NewUnauthorizedError("invalid SAML response")
+	}
+
+	// Protección contra reenvío: cada aserción se acepta una sola vez mientras sea válida
+	expiresAt := now.Add(saml.MaxIssueDelay)
+	if assertion.Conditions != nil && !assertion.Conditions.NotOnOrAfter.IsZero() {
+		expiresAt = assertion.Conditions.NotOnOrAfter.Add(saml.MaxClockSkew)
+	}
+	db.Where("expires_at < ?", now).Delete(&models.SamlAssertion{})
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.SamlAssertion{
+		ProviderID:  provider.ID,
+		AssertionID: assertion.ID,
+		ExpiresAt:   expiresAt,
+	})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		s.authService.recordLoginFailure(nil, provider.Slug, "saml_assertion_replay", models.LoginMethodSAML, meta)
+		return nil, utils.NewUnauthorizedError("SAML assertion has already been used")
+	}
+
+	attributes := samlAttributes(assertion)
+	identity := s.samlIdentity(provider, assertion, attributes)
+	if identity.Subject == "" {
+		return nil, utils.NewUnauthorizedError("SAML assertion does not include a usable subject or email address")
+	}
+
+	if request.LinkUserID != nil {
+		if err := linkIdentity(db, *request.LinkUserID, identity); err != nil {
+			return nil, err
+		}
+		return &SamlOutcome{RedirectTo: redirectTo}, nil
+	}
+	if request.ReauthUserID != nil {
+		reauth, err := s.reauthenticate(provider, &request, assertion, identity, meta)
+		if err != nil {
+			return nil, err
+		}
+		return &SamlOutcome{Reauth: reauth, RedirectTo: redirectTo}, nil
+	}
+
+	user, err := s.resolveUser(provider, attributes, identity)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.IsActive {
+		s.authService.recordLoginFailure(&user.ID, user.UserName, "user_disabled", models.LoginMethodSAML, meta)
+		return nil, utils.NewForbiddenError("user account is disabled")
+	}
+
+	user.LastLoginAt = now
+	db.Omit("Role").Save(user)
+	s.authService.recordLogin(user, models.LoginMethodSAML, meta)
+
+	// Sin PasswordAuthAt: un login SSO normal no vale como re-autenticación (ver reauthenticate)
+	authResponse, err := s.authService.issueAuthResponse(user, now, sessionOptions{Meta: meta})
+	if err != nil {
+		return nil, err
+	}
+	return &SamlOutcome{Auth: authResponse, RedirectTo: redirectTo}, nil
+}
+
+// reauthenticate completa un step-up: la identidad debe estar vinculada al usuario que lo
+// pidió y el IdP debe haberle autenticado después de emitir el AuthnRequest (ForceAuthn)
+func (s *SamlService) reauthenticate(provider *models.SamlProvider, request *models.SamlRequest, assertion *saml.Assertion, identity *models.UserIdentity, meta *dto.RequestMeta) (*dto.ReauthenticateResponse, error) {
+	db := database.GetDB()
+
+	var user models.User
+	if err := db.Preload("Role").First(&user, *request.ReauthUserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("User")
+		}
+		return nil, err
+	}
+	if !user.IsActive {
+		return nil, utils.NewForbiddenError("user account is disabled")
+	}
+
+	fail := func(reason, message string) error {
+		s.authService.audit.Log(actorFor(&user, meta), s.authService.authEvent(models.AuditReauthenticateFailed, &user,
+			map[string]interface{}{"reason": reason, "method": models.LoginMethodSAML, "provider": provider.Slug}))
+		return utils.NewForbiddenError(message)
+	}
+
+	linked, err := findIdentity(db, identity.Provider, identity.ProviderRef, identity.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if linked == nil || linked.UserID != user.ID {
+		return nil, fail("identity_not_linked", "this identity is not linked to your account")
+	}
+
+	authenticatedAt := time.Time{}
+	for _, statement := range assertion.AuthnStatements {
+		if statement.AuthnInstant.After(authenticatedAt) {
+			authenticatedAt = statement.AuthnInstant
+		}
+	}
+	if authenticatedAt.Before(request.CreatedAt.Add(-saml.MaxClockSkew)) {
+		return nil, fail("stale_authentication", "the identity provider did not re-authenticate the user")
+	}
+
+	// La organización del token elevado es la de la sesión, que debe seguir abierta
+	var organizationID uint
+	if request.ReauthSession != "" {
+		var session models.Session
+		if err := db.Where("id = ? AND user_id = ? AND revoked_at IS NULL", request.ReauthSession, user.ID).First(&session).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, utils.NewUnauthorizedError("session is no longer active")
+			}
+			return nil, err
+		}
+		if session.OrganizationID != nil {
+			organizationID = *session.OrganizationID
+		}
+	}
+
+	return s.authService.elevate(&user, request.ReauthSession, organizationID, models.LoginMethodSAML, meta)
+}
+
+// resolveUser localiza al usuario por su identidad vinculada o, en su defecto, por email si el IdP
+// es autoridad del dominio del email (allowed_domains); si no existe y el IdP lo permite, lo aprovisiona.
+// La identidad queda vinculada en todos los casos. Una cuenta existente de otro dominio no se
+// vincula sola: su titular debe hacerlo desde /profile/identities.
+// El rol se sincroniza con los grupos de la aserción cuando alguno está mapeado.
+func (s *SamlService) resolveUser(provider *models.SamlProvider, attributes map[string][]string, identity *models.UserIdentity) (*models.User, error) {
+	db := database.GetDB()
+	email := identity.Email
+
+	roleName := s.mappedRole(provider, attributes)
+
+	var user models.User
+	linked, err := findIdentity(db, identity.Provider, identity.ProviderRef, identity.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if linked != nil {
+		err = db.Preload("Role").First(&user, linked.UserID).Error
+	} else if email != "" {
+		err = db.Scopes(byEmail(email)).Preload("Role").First(&user).Error
+	} else {
+		err = gorm.ErrRecordNotFound
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if !provider.AutoProvision || email == "" {
+			return nil, utils.NewForbiddenError("no account exists for this SAML identity")
+		}
+		if roleName == "" {
+			roleName = provider.DefaultRole
+		}
+		if roleName == "" {
+			roleName = s.defaultRole
+		}
+		provisioned, err := s.provisionUser(provider, attributes, email, roleName)
+		if err != nil {
+			return nil, err
+		}
+		if err := linkIdentity(db, provisioned.ID, identity); err != nil {
+			return nil, err
+		}
+		return provisioned, nil
+	}
+
+	if linked == nil && !trustsEmailDomain(provider, email) {
+		return nil, utils.NewForbiddenError("an account with this email already exists; sign in and link this identity from your profile")
+	}
+
+	if err := linkIdentity(db, user.ID, identity); err != nil {
+		return nil, err
+	}
+
+	if roleName != "" && roleName != user.Role.Name {
+		var role models.Role
+		if err := db.Scopes(systemRoles).Where("name = ?", roleName).First(&role).Error; err != nil {
+			log.Printf("Rol %q mapeado por el IdP %s no existe: %v", roleName, provider.Slug, err)
+		} else {
+			user.RoleID = role.ID
+			user.Role = role
+		}
+	}
+	return &user, nil
+}
+
+// trustsEmailDomain indica si el IdP es autoridad del dominio de email según sus allowed_domains
+func trustsEmailDomain(provider *models.SamlProvider, email string) bool {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := strings.ToLower(email[at+1:])
+	for _, allowed := range provider.AllowedDomains {
+		if domain == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *SamlService) provisionUser(provider *models.SamlProvider, attributes map[string][]string, email, roleName string) (*models.User, error) {
+	db := database.GetDB()
+
+	var role models.Role
+	if err := db.Scopes(systemRoles).Where("name = ?", roleName).First(&role).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewInternalServerError("role for provisioned SAML users does not exist")
+		}
+		return nil, err
+	}
+
+	userName := s.mappedValue(provider, attributes, "user_name")
+	if userName == "" {
+		userName = strings.SplitN(email, "@", 2)[0]
+	}
+	userName, err := s.uniqueUserName(db, userName)
+	if err != nil {
+		return nil, err
+	}
+
+	name := s.mappedValue(provider, attributes, "name")
+	if name == "" {
+		name = userName
+	}
+
+	// Las cuentas creadas por SSO no tienen contraseña utilizable
+	randomPassword, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	hashedPassword, err := s.hasher.HashPassword(randomPassword)
+	if err != nil {
+		return nil, errors.New("failed to hash password")
+	}
+
+	user := models.User{
+		Name:     name,
+		UserName: userName,
+		Email:    email,
+		Password: hashedPassword,
+		RoleID:   role.ID,
+		IsActive: true,
+	}
+	if err := db.Create(&user).Error; err != nil {
+		return nil, err
+	}
+	// GORM omite el false frente a default:true, así que se persiste aparte
+	if err := db.Model(&user).Update("has_password", false).Error; err != nil {
+		return nil, err
+	}
+	user.HasPassword = false
+	if err := joinDefaultOrganization(db, user.ID, role.ID); err != nil {
+		return nil, err
+	}
+	user.Role = role
+	return &user, nil
+}
+
+func (s *SamlService) uniqueUserName(db *gorm.DB, candidate string) (string, error) {
+	base := utils.NormalizeUserName(strings.ReplaceAll(candidate, "@", "."))
+	if base == "" {
+		base = "user"
+	}
+	userName := base
+	for i := 2; ; i++ {
+		var count int64
+		if err := db.Model(&models.User{}).Scopes(byUserName(userName)).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return userName, nil
+		}
+		userName = fmt.Sprintf("%s-%d", base, i)
+	}
+}
+
+// samlIdentity construye la identidad externa de la aserción. El NameID es el sujeto estable;
+// si es transitorio (cambia en cada login) se usa el email como sujeto.
+func (s *SamlService) samlIdentity(provider *models.SamlProvider, assertion *saml.Assertion, attributes map[string][]string) *models.UserIdentity {
+	var nameID *saml.NameID
+	if assertion.Subject != nil {
+		nameID = assertion.Subject.NameID
+	}
+
+	email := s.mappedValue(provider, attributes, "email")
+	if email == "" && nameID != nil && utils.IsEmailIdentifier(nameID.Value) {
+		email = nameID.Value
+	}
+	email = utils.NormalizeEmail(email)
+
+	subject := email
+	if nameID != nil && nameID.Value != "" && nameID.Format != string(saml.TransientNameIDFormat) {
+		subject = strings.TrimSpace(nameID.Value)
+	}
+
+	now := time.Now()
+	return &models.UserIdentity{
+		Provider:    models.IdentityProviderSAML,
+		ProviderRef: provider.Slug,
+		Subject:     subject,
+		Email:       email,
+		// El email lo afirma un IdP configurado por un administrador
+		EmailVerified: email != "",
+		LastUsedAt:    &now,
+	}
+}
+
+// mappedValue obtiene el primer valor del atributo configurado para un campo del usuario
+func (s *SamlService) mappedValue(provider *models.SamlProvider, attributes map[string][]string, field string) string {
+	candidates := defaultSamlAttributes[field]
+	if mapped := provider.AttributeMapping[field]; mapped != "" {
+		candidates = []string{mapped}
+	}
+	for _, candidate := range candidates {
+		if values := attributes[strings.ToLower(candidate)]; len(values) > 0 {
+			return strings.TrimSpace(values[0])
+		}
+	}
+	return ""
+}
+
+// mappedRole devuelve el rol del primer grupo (en orden alfabético) que tenga mapeo
+func (s *SamlService) mappedRole(provider *models.SamlProvider, attributes map[string][]string) string {
+	if len(provider.GroupRoleMapping) == 0 {
+		return ""
+	}
+	groupAttribute := provider.GroupAttribute
+	if groupAttribute == "" {
+		groupAttribute = "groups"
+	}
+
+	groups := append([]string(nil), attributes[strings.ToLower(groupAttribute)]...)
+	sort.Strings(groups)
+	for _, group := range groups {
+		if roleName, ok := provider.GroupRoleMapping[group]; ok {
+			return roleName
+		}
+	}
+	return ""
+}
+
+// samlAttributes indexa los atributos de la aserción por Name y FriendlyName en minúsculas
+func samlAttributes(assertion *saml.Assertion) map[string][]string {
+	attributes := map[string][]string{}
+	for _, statement := range assertion.AttributeStatements {
+		for _, attribute := range statement.Attributes {
+			var values []string
+			for _, value := range attribute.Values {
+				values = append(values, value.Value)
+			}
+			for _, key := range []string{attribute.Name, attribute.FriendlyName} {
+				if key != "" {
+					attributes[strings.ToLower(key)] = append(attributes[strings.ToLower(key)], values...)
+				}
+			}
+		}
+	}
+	return attributes
+}
+
+// safeRedirect solo admite rutas relativas o URLs del mismo origen que SAML_SUCCESS_URL
+func (s *SamlService) safeRedirect(target string) string {
+	if target == "" {
+		return s.successURL
+	}
+	success, err := url.Parse(s.successURL)
+	if err != nil {
+		return s.successURL
+	}
+	parsed, err := url.Parse(target)
+	if err != nil {
+		return s.successURL
+	}
+	if !parsed.IsAbs() && strings.HasPrefix(target, "/") && !strings.HasPrefix(target, "//") {
+		return success.ResolveReference(parsed).String()
+	}
+	if parsed.Scheme == success.Scheme && parsed.Host == success.Host {
+		return parsed.String()
+	}
+	return s.successURL
+}
+
+// serviceProvider construye el SP de crewjam/saml para un IdP concreto.
+// Cada IdP tiene su propio entity ID y ACS para que las aserciones no sirvan entre IdPs.
+func (s *SamlService) serviceProvider(provider *models.SamlProvider) (*saml.ServiceProvider, error) {
+	idpMetadata, err := samlIdPMetadata(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	metadataURL, err := url.Parse(s.baseURL + "/" + provider.Slug + "/metadata")
+	if err != nil {
+		return nil, err
+	}
+	acsURL, err := url.Parse(s.baseURL + "/" + provider.Slug + "/acs")
+	if err != nil {
+		return nil, err
+	}
+
+	sp := &saml.ServiceProvider{
+		EntityID:          metadataURL.String(),
+		Key:               s.key,
+		Certificate:       s.cert,
+		MetadataURL:       *metadataURL,
+		AcsURL:            *acsURL,
+		IDPMetadata:       idpMetadata,
+		AuthnNameIDFormat: saml.UnspecifiedNameIDFormat,
+	}
+	if s.key != nil && s.cert != nil {
+		sp.SignatureMethod = dsig.RSASHA256SignatureMethod
+	}
+	return sp, nil
+}
+
+// samlIdPMetadata usa el XML de metadatos del IdP si existe; si no, lo construye a partir de los campos
+func samlIdPMetadata(provider *models.SamlProvider) (*saml.EntityDescriptor, error) {
+	if strings.TrimSpace(provider.MetadataXML) != "" {
+		var descriptor saml.EntityDescriptor
+		if err := xml.Unmarshal([]byte(provider.MetadataXML), &descriptor); err != nil {
+			return nil, utils.NewBadRequestError("invalid IdP metadata XML: " + err.Error())
+		}
+		if descriptor.EntityID == "" || len(descriptor.IDPSSODescriptors) == 0 {
+			return nil, utils.NewBadRequestError("IdP metadata must contain an EntityDescriptor with an IDPSSODescriptor")
+		}
+		return &descriptor, nil
+	}
+
+	if provider.IdPEntityID == "" || provider.SSOURL == "" || provider.Certificate == "" {
+		return nil, utils.NewBadRequestError("idp_entity_id, sso_url and certificate are required when metadata_xml is not provided")
+	}
+	cert, err := utils.ParseCertificate(provider.Certificate)
+	if err != nil {
+		return nil, utils.NewBadRequestError("invalid IdP certificate: " + err.Error())
+	}
+
+	return &saml.EntityDescriptor{
+		EntityID: provider.IdPEntityID,
+		IDPSSODescriptors: []saml.IDPSSODescriptor{{
+			SSODescriptor: saml.SSODescriptor{
+				RoleDescriptor: saml.RoleDescriptor{
+					ProtocolSupportEnumeration: "urn:oasis:names:tc:SAML:2.0:protocol",
+					KeyDescriptors: []saml.KeyDescriptor{{
+						Use: "signing",
+						KeyInfo: saml.KeyInfo{
+							X509Data: saml.X509Data{
+								X509Certificates: []saml.X509Certificate{{Data: base64.StdEncoding.EncodeToString(cert.Raw)}},
+							},
+						},
+					}},
+				},
+			},
+			SingleSignOnServices: []saml.Endpoint{
+				{Binding: saml.HTTPRedirectBinding, Location: provider.SSOURL},
+			},
+		}},
+	}, nil
+}
+
+// ---------------------------------------------------------------------------
+// Administración de IdPs
+// ---------------------------------------------------------------------------
+
+// CreateProvider registra un nuevo IdP SAML
+func (s *SamlService) CreateProvider(req *dto.SamlProviderRequest, actor *Actor) (*dto.SamlProviderResponse, error) {
+	db := database.GetDB()
+
+	provider := models.SamlProvider{IsActive: true}
+	if err := s.applyProviderRequest(&provider, req); err != nil {
+		return nil, err
+	}
+
+	var existing models.SamlProvider
+	if err := db.Where("slug = ?", provider.Slug).First(&existing).Error; err == nil {
+		return nil, utils.NewConflictError("saml provider with this slug already exists")
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&provider).Error; err != nil {
+			return err
+		}
+		// GORM omite el false frente a default:true, así que se persiste aparte
+		if !provider.IsActive {
+			if err := tx.Model(&provider).Update("is_active", false).Error; err != nil {
+				return err
+			}
+		}
+		return s.audit.Record(tx, actor, auditEvent{
+			Action:     models.AuditSamlProviderCreate,
+			TargetType: models.AuditTargetSamlProvider,
+			TargetID:   provider.ID,
+			Changes:    auditDiff(nil, samlProviderAuditState(&provider)),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.toProviderResponse(&provider), nil
+}
+
+// GetProviders lista los IdPs SAML configurados
+func (s *SamlService) GetProviders() ([]dto.SamlProviderResponse, error) {
+	var providers []models.SamlProvider
+	if err := database.GetDB().Order("slug").Find(&providers).Error; err != nil {
+		return nil, err
+	}
+
+	responses := make([]dto.SamlProviderResponse, 0, len(providers))
+	for i := range providers {
+		responses = append(responses, *s.toProviderResponse(&providers[i]))
+	}
+	return responses, nil
+}
+
+// GetProvider obtiene un IdP SAML por ID
+func (s *SamlService) GetProvider(id uint) (*dto.SamlProviderResponse, error) {
+	var provider models.SamlProvider
+	if err := database.GetDB().First(&provider, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("SAML provider")
+		}
+		return nil, err
+	}
+	return s.toProviderResponse(&provider), nil
+}
+
+// UpdateProvider reemplaza la configuración de un IdP SAML
+func (s *SamlService) UpdateProvider(id uint, req *dto.SamlProviderRequest, actor *Actor) (*dto.SamlProviderResponse, error) {
+	db := database.GetDB()
+
+	var provider models.SamlProvider
+	if err := db.First(&provider, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("SAML provider")
+		}
+		return nil, err
+	}
+
+	before := samlProviderAuditState(&provider)
+	if err := s.applyProviderRequest(&provider, req); err != nil {
+		return nil, err
+	}
+
+	var existing models.SamlProvider
+	if err := db.Where("slug = ? AND id <> ?", provider.Slug, id).First(&existing).Error; err == nil {
+		return nil, utils.NewConflictError("saml provider with this slug already exists")
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&provider).Error; err != nil {
+			return err
+		}
+		return s.audit.Record(tx, actor, auditEvent{
+			Action:     models.AuditSamlProviderUpdate,
+			TargetType: models.AuditTargetSamlProvider,
+			TargetID:   provider.ID,
+			Changes:    auditDiff(before, samlProviderAuditState(&provider)),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.toProviderResponse(&provider), nil
+}
+
+// DeleteProvider elimina un IdP SAML y sus peticiones pendientes
+func (s *SamlService) DeleteProvider(id uint, actor *Actor) error {
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var provider models.SamlProvider
+		if err := tx.First(&provider, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return utils.NewNotFoundError("SAML provider")
+			}
+			return err
+		}
+		if err := tx.Delete(&provider).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("provider_id = ?", id).Delete(&models.SamlRequest{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("provider_id = ?", id).Delete(&models.SamlAssertion{}).Error; err != nil {
+			return err
+		}
+		return s.audit.Record(tx, actor, auditEvent{
+			Action:     models.AuditSamlProviderDelete,
+			TargetType: models.AuditTargetSamlProvider,
+			TargetID:   provider.ID,
+			Changes:    auditDiff(samlProviderAuditState(&provider), nil),
+		})
+	})
+}
+
+// samlProviderAuditState campos auditados de un IdP SAML; del certificado y los metadatos solo
+// consta una huella, para detectar su cambio sin copiar el documento en cada entrada
+func samlProviderAuditState(provider *models.SamlProvider) map[string]interface{} {
+	fingerprint := func(document string) string {
+		if document == "" {
+			return ""
+		}
+		return utils.HashToken(document)
+	}
+	return map[string]interface{}{
+		"slug":                provider.Slug,
+		"name":                provider.Name,
+		"idp_entity_id":       provider.IdPEntityID,
+		"sso_url":             provider.SSOURL,
+		"certificate":         fingerprint(provider.Certificate),
+		"metadata_xml":        fingerprint(provider.MetadataXML),
+		"attribute_mapping":   provider.AttributeMapping,
+		"group_attribute":     provider.GroupAttribute,
+		"group_role_mapping":  provider.GroupRoleMapping,
+		"default_role":        provider.DefaultRole,
+		"auto_provision":      provider.AutoProvision,
+		"allowed_domains":     provider.AllowedDomains,
+		"allow_idp_initiated": provider.AllowIdPInitiated,
+		"is_active":           provider.IsActive,
+	}
+}
+
+func (s *SamlService) applyProviderRequest(provider *models.SamlProvider, req *dto.SamlProviderRequest) error {
+	slug := strings.ToLower(strings.TrimSpace(req.Slug))
+	if !samlSlugPattern.MatchString(slug) {
+		return utils.NewValidationError("slug must contain only lowercase letters, digits and dashes")
+	}
+
+	provider.Slug = slug
+	provider.Name = req.Name
+	provider.IdPEntityID = req.IdPEntityID
+	provider.SSOURL = req.SSOURL
+	provider.Certificate = req.Certificate
+	provider.MetadataXML = req.MetadataXML
+	provider.AttributeMapping = req.AttributeMapping
+	provider.GroupAttribute = req.GroupAttribute
+	provider.GroupRoleMapping = req.GroupRoleMapping
+	provider.DefaultRole = req.DefaultRole
+	provider.AutoProvision = req.AutoProvision
+	provider.AllowedDomains = nil
+	for _, domain := range req.AllowedDomains {
+		domain = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(domain), "@"))
+		if domain == "" || strings.ContainsAny(domain, "@ ") {
+			return utils.NewValidationError("allowed_domains must contain email domains like example.com")
+		}
+		provider.AllowedDomains = append(provider.AllowedDomains, domain)
+	}
+	provider.AllowIdPInitiated = req.AllowIdPInitiated
+	if req.IsActive != nil {
+		provider.IsActive = *req.IsActive
+	}
+
+	// Validar la configuración del IdP construyendo sus metadatos
+	idpMetadata, err := samlIdPMetadata(provider)
+	if err != nil {
+		return err
+	}
+	provider.IdPEntityID = idpMetadata.EntityID
+	if len(idpMetadata.IDPSSODescriptors) > 0 {
+		for _, endpoint := range idpMetadata.IDPSSODescriptors[0].SingleSignOnServices {
+			if endpoint.Binding == saml.HTTPRedirectBinding {
+				provider.SSOURL = endpoint.Location
+			}
+		}
+	}
+
+	// Los roles mapeados deben existir
+	db := database.GetDB()
+	roleNames := []string{}
+	if provider.DefaultRole != "" {
+		roleNames = append(roleNames, provider.DefaultRole)
+	}
+	for _, roleName := range provider.GroupRoleMapping {
+		roleNames = append(roleNames, roleName)
+	}
+	for _, roleName := range roleNames {
+		var role models.Role
+		if err := db.Scopes(systemRoles).Where("name = ?", roleName).First(&role).Error; err != nil {
+			return utils.NewValidationError("role " + roleName + " does not exist")
+		}
+	}
+	return nil
+}
+
+func (s *SamlService) findActiveProvider(slug string) (*models.SamlProvider, error) {
+	var provider models.SamlProvider
+	if err := database.GetDB().Where("slug = ? AND is_active = ?", slug, true).First(&provider).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("SAML provider")
+		}
+		return nil, err
+	}
+	return &provider, nil
+}
+
+func (s *SamlService) toProviderResponse(provider *models.SamlProvider) *dto.SamlProviderResponse {
+	return &dto.SamlProviderResponse{
+		ID:                provider.ID,
+		Slug:              provider.Slug,
+		Name:              provider.Name,
+		IdPEntityID:       provider.IdPEntityID,
+		SSOURL:            provider.SSOURL,
+		HasMetadataXML:    provider.MetadataXML != "",
+		AttributeMapping:  provider.AttributeMapping,
+		GroupAttribute:    provider.GroupAttribute,
+		GroupRoleMapping:  provider.GroupRoleMapping,
+		DefaultRole:       provider.DefaultRole,
+		AutoProvision:     provider.AutoProvision,
+		AllowedDomains:    provider.AllowedDomains,
+		AllowIdPInitiated: provider.AllowIdPInitiated,
+		IsActive:          provider.IsActive,
+		SPEntityID:        s.baseURL + "/" + provider.Slug + "/metadata",
+		ACSURL:            s.baseURL + "/" + provider.Slug + "/acs",
+		LoginURL:          s.baseURL + "/" + provider.Slug + "/login",
+		CreatedAt:         provider.CreatedAt,
+		UpdatedAt:         provider.UpdatedAt,
+	}
+}
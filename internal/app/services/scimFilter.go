@@ -0,0 +1,359 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"megabaseGo/internal/database/encryption"
+)
+
+// scimAttribute describe cómo se traduce un atributo SCIM a una expresión SQL
+type scimAttribute struct {
+	Column    string // expresión SQL (solo procede de la lista blanca)
+	CaseExact bool   // si false, las comparaciones de texto ignoran mayúsculas
+	Kind      string // "string", "bool", "id" o "date"
+	// Encrypted columna cifrada con el cifrado de campos activo: solo admite pr y, si tiene
+	// BlindIndex, eq/ne sobre la columna del índice ciego
+	Encrypted  bool
+	BlindIndex *scimBlindIndex
+}
+
+// scimBlindIndex columna con el índice ciego de un atributo cifrado y cómo se calcula
+type scimBlindIndex struct {
+	Column string
+	Index  func(value string) string
+}
+
+// scimFilter representa una expresión de filtro SCIM ya compilada a SQL parametrizado
+type scimFilter struct {
+	SQL  string
+	Args []interface{}
+}
+
+type scimToken struct {
+	kind  string // "word", "string", "lparen", "rparen", "lbracket", "rbracket"
+	value string
+}
+
+// compileScimFilter compila un filtro SCIM (RFC 7644 §3.4.2.2) a una condición SQL
+// usando solo los atributos de la lista blanca
+func compileScimFilter(filter string, attributes map[string]scimAttribute) (*scimFilter, error) {
+	tokens, err := tokenizeScimFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := &scimFilterParser{tokens: tokens, attributes: attributes}
+	result, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if parser.pos < len(parser.tokens) {
+		return nil, fmt.Errorf("unexpected token %q", parser.tokens[parser.pos].value)
+	}
+	return result, nil
+}
+
+func tokenizeScimFilter(input string) ([]scimToken, error) {
+	var tokens []scimToken
+	runes := []rune(input)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, scimToken{kind: "lparen", value: "("})
+			i++
+		case r == ')':
+			tokens = append(tokens, scimToken{kind: "rparen", value: ")"})
+			i++
+		case r == '[':
+			tokens = append(tokens, scimToken{kind: "lbracket", value: "["})
+			i++
+		case r == ']':
+			tokens = append(tokens, scimToken{kind: "rbracket", value: "]"})
+			i++
+		case r == '"':
+			// Cadena JSON: se respeta el escapado estándar
+			j := i + 1
+			for j < len(runes) && runes[j] != '"' {
+				if runes[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(runes) {
+				return nil, fmt.Errorf("unterminated string in filter")
+			}
+			var value string
+			if err := json.Unmarshal([]byte(string(runes[i:j+1])), &value); err != nil {
+				return nil, fmt.Errorf("invalid string literal in filter")
+			}
+			tokens = append(tokens, scimToken{kind: "string", value: value})
+			i = j + 1
+		default:
+			j := i
+			for j < len(runes) && !unicode.IsSpace(runes[j]) && !strings.ContainsRune("()[]\"", runes[j]) {
+				j++
+			}
+			tokens = append(tokens, scimToken{kind: "word", value: string(runes[i:j])})
+			i = j
+		}
+	}
+	return tokens, nil
+}
+
+type scimFilterParser struct {
+	tokens     []scimToken
+	pos        int
+	attributes map[string]scimAttribute
+}
+
+func (p *scimFilterParser) peekWord(word string) bool {
+	return p.pos < len(p.tokens) && p.tokens[p.pos].kind == "word" && strings.EqualFold(p.tokens[p.pos].value, word)
+}
+
+func (p *scimFilterParser) parseOr() (*scimFilter, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekWord("or") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &scimFilter{SQL: "(" + left.SQL + " OR " + right.SQL + ")", Args: append(left.Args, right.Args...)}
+	}
+	return left, nil
+}
+
+func (p *scimFilterParser) parseAnd() (*scimFilter, error) {
+	left, err := p.parseFactor()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekWord("and") {
+		p.pos++
+		right, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		left = &scimFilter{SQL: "(" + left.SQL + " AND " + right.SQL + ")", Args: append(left.Args, right.Args...)}
+	}
+	return left, nil
+}
+
+func (p *scimFilterParser) parseFactor() (*scimFilter, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("unexpected end of filter")
+	}
+
+	if p.peekWord("not") {
+		p.pos++
+		inner, err := p.parseGroup()
+		if err != nil {
+			return nil, err
+		}
+		return &scimFilter{SQL: "NOT " + inner.SQL, Args: inner.Args}, nil
+	}
+
+	if p.tokens[p.pos].kind == "lparen" {
+		return p.parseGroup()
+	}
+
+	return p.parseComparison()
+}
+
+func (p *scimFilterParser) parseGroup() (*scimFilter, error) {
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != "lparen" {
+		return nil, fmt.Errorf("expected '('")
+	}
+	p.pos++
+	inner, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != "rparen" {
+		return nil, fmt.Errorf("expected ')'")
+	}
+	p.pos++
+	return &scimFilter{SQL: "(" + inner.SQL + ")", Args: inner.Args}, nil
+}
+
+func (p *scimFilterParser) parseComparison() (*scimFilter, error) {
+	token := p.tokens[p.pos]
+	if token.kind != "word" {
+		return nil, fmt.Errorf("expected attribute name, got %q", token.value)
+	}
+	p.pos++
+
+	path := token.value
+	// Filtro de valor: emails[type eq "work"].value se reduce al atributo emails.value
+	if p.pos < len(p.tokens) && p.tokens[p.pos].kind == "lbracket" {
+		depth := 0
+		for p.pos < len(p.tokens) {
+			if p.tokens[p.pos].kind == "lbracket" {
+				depth++
+			} else if p.tokens[p.pos].kind == "rbracket" {
+				depth--
+				if depth == 0 {
+					p.pos++
+					break
+				}
+			}
+			p.pos++
+		}
+		if p.pos < len(p.tokens) && p.tokens[p.pos].kind == "word" && strings.HasPrefix(p.tokens[p.pos].value, ".") {
+			path += p.tokens[p.pos].value
+			p.pos++
+		}
+	}
+
+	attribute, ok := lookupScimAttribute(p.attributes, path)
+	if !ok {
+		return nil, fmt.Errorf("unsupported filter attribute %q", path)
+	}
+
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != "word" {
+		return nil, fmt.Errorf("expected operator after %q", path)
+	}
+	operator := strings.ToLower(p.tokens[p.pos].value)
+	p.pos++
+
+	if operator == "pr" {
+		if attribute.Kind == "string" {
+			return &scimFilter{SQL: "(" + attribute.Column + " IS NOT NULL AND " + attribute.Column + " <> '')"}, nil
+		}
+		return &scimFilter{SQL: attribute.Column + " IS NOT NULL"}, nil
+	}
+
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("expected value after %q", operator)
+	}
+	valueToken := p.tokens[p.pos]
+	p.pos++
+
+	value, err := scimFilterValue(attribute, valueToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if attribute.Encrypted && encryption.Enabled() {
+		return compileEncryptedComparison(path, attribute, operator, value)
+	}
+
+	column := attribute.Column
+	if str, isString := value.(string); isString && attribute.Kind == "string" && !attribute.CaseExact {
+		column = "LOWER(" + column + ")"
+		value = strings.ToLower(str)
+	}
+
+	switch operator {
+	case "eq":
+		if value == nil {
+			return &scimFilter{SQL: attribute.Column + " IS NULL"}, nil
+		}
+		return &scimFilter{SQL: column + " = ?", Args: []interface{}{value}}, nil
+	case "ne":
+		if value == nil {
+			return &scimFilter{SQL: attribute.Column + " IS NOT NULL"}, nil
+		}
+		return &scimFilter{SQL: column + " <> ?", Args: []interface{}{value}}, nil
+	case "co", "sw", "ew":
+		str, isString := value.(string)
+		if !isString || attribute.Kind != "string" {
+			return nil, fmt.Errorf("operator %q requires a string attribute", operator)
+		}
+		pattern := escapeLike(str)
+		switch operator {
+		case "co":
+			pattern = "%" + pattern + "%"
+		case "sw":
+			pattern = pattern + "%"
+		case "ew":
+			pattern = "%" + pattern
+		}
+		return &scimFilter{SQL: column + " LIKE ?", Args: []interface{}{pattern}}, nil
+	case "gt", "ge", "lt", "le":
+		if value == nil || attribute.Kind == "bool" {
+			return nil, fmt.Errorf("operator %q is not valid for %q", operator, path)
+		}
+		sqlOperator := map[string]string{"gt": ">", "ge": ">=", "lt": "<", "le": "<="}[operator]
+		return &scimFilter{SQL: column + " " + sqlOperator + " ?", Args: []interface{}{value}}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported filter operator %q", operator)
+}
+
+func lookupScimAttribute(attributes map[string]scimAttribute, path string) (scimAttribute, bool) {
+	path = strings.ToLower(path)
+	// Los atributos pueden venir con el URN del esquema como prefijo
+	if idx := strings.LastIndex(path, ":"); idx >= 0 {
+		path = path[idx+1:]
+	}
+	attribute, ok := attributes[path]
+	return attribute, ok
+}
+
+func scimFilterValue(attribute scimAttribute, token scimToken) (interface{}, error) {
+	if token.kind != "string" && token.kind != "word" {
+		return nil, fmt.Errorf("expected comparison value, got %q", token.value)
+	}
+
+	if token.kind == "word" && strings.EqualFold(token.value, "null") {
+		return nil, nil
+	}
+
+	switch attribute.Kind {
+	case "bool":
+		if token.kind != "word" || (!strings.EqualFold(token.value, "true") && !strings.EqualFold(token.value, "false")) {
+			return nil, fmt.Errorf("invalid boolean value %q", token.value)
+		}
+		return strings.EqualFold(token.value, "true"), nil
+	case "id":
+		id, err := strconv.ParseUint(token.value, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid id value %q", token.value)
+		}
+		return uint(id), nil
+	case "date":
+		parsed, err := time.Parse(time.RFC3339, token.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid dateTime value %q", token.value)
+		}
+		return parsed, nil
+	}
+
+	if token.kind != "string" {
+		return nil, fmt.Errorf("string values must be quoted: %s", token.value)
+	}
+	return token.value, nil
+}
+
+// compileEncryptedComparison compara un atributo cifrado por su índice ciego. Las filas aún sin
+// índice (en claro, anteriores a activar el cifrado) se comparan por la columna.
+func compileEncryptedComparison(path string, attribute scimAttribute, operator string, value interface{}) (*scimFilter, error) {
+	str, isString := value.(string)
+	if attribute.BlindIndex == nil || !isString || (operator != "eq" && operator != "ne") {
+		return nil, fmt.Errorf("attribute %q is encrypted and only supports pr, eq and ne", path)
+	}
+
+	index := attribute.BlindIndex
+	sql := "(" + index.Column + " = ? OR (" + index.Column + " = '' AND LOWER(" + attribute.Column + ") = ?))"
+	if operator == "ne" {
+		sql = "NOT " + sql
+	}
+	return &scimFilter{SQL: sql, Args: []interface{}{index.Index(str), strings.ToLower(str)}}, nil
+}
+
+// escapeLike escapa los comodines de LIKE en un valor literal
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompileScimFilter(t *testing.T) {
+	tests := []struct {
+		name     string
+		filter   string
+		wantSQL  string
+		wantArgs []interface{}
+	}{
+		{"eq ignores case", `userName eq "Bjensen"`, "LOWER(user_name) = ?", []interface{}{"bjensen"}},
+		{"case exact attribute", `externalId eq "AbC"`, "external_id = ?", []interface{}{"AbC"}},
+		{"operator and attribute are case-insensitive", `USERNAME EQ "x"`, "LOWER(user_name) = ?", []interface{}{"x"}},
+		{"schema urn prefix", `urn:ietf:params:scim:schemas:core:2.0:User:userName eq "x"`,
+			"LOWER(user_name) = ?", []interface{}{"x"}},
+		{"ne", `userName ne "x"`, "LOWER(user_name) <> ?", []interface{}{"x"}},
+		{"eq null", `externalId eq null`, "external_id IS NULL", nil},
+		{"ne null", `externalId ne null`, "external_id IS NOT NULL", nil},
+		{"present string", `externalId pr`, "(external_id IS NOT NULL AND external_id <> '')", nil},
+		{"present non-string", `meta.created pr`, "created_at IS NOT NULL", nil},
+		{"contains escapes wildcards", `userName co "50%_a\\b"`, "LOWER(user_name) LIKE ?", []interface{}{`%50\%\_a\\b%`}},
+		{"starts with", `userName sw "J"`, "LOWER(user_name) LIKE ?", []interface{}{"j%"}},
+		{"ends with", `externalId ew "Z"`, "external_id LIKE ?", []interface{}{"%Z"}},
+		{"json escapes in strings", `userName eq "say \"hi\" é"`, "LOWER(user_name) = ?", []interface{}{`say "hi" é`}},
+		{"bool", `active eq true`, "is_active = ?", []interface{}{true}},
+		{"id quoted or bare", `id eq "5" or id eq 6`, "(id = ? OR id = ?)", []interface{}{uint(5), uint(6)}},
+		{"date comparison", `meta.lastModified gt "2011-05-13T04:42:34Z"`, "updated_at > ?",
+			[]interface{}{time.Date(2011, 5, 13, 4, 42, 34, 0, time.UTC)}},
+		{"and binds tighter than or", `userName eq "a" or userName eq "b" and active eq false`,
+			"(LOWER(user_name) = ? OR (LOWER(user_name) = ? AND is_active = ?))", []interface{}{"a", "b", false}},
+		{"groups", `(userName eq "a" or userName eq "b") and active eq false`,
+			"(((LOWER(user_name) = ? OR LOWER(user_name) = ?)) AND is_active = ?)", []interface{}{"a", "b", false}},
+		{"not", `not (active eq true)`, "NOT (is_active = ?)", []interface{}{true}},
+		{"value filter reduces to sub-attribute", `emails[type eq "work"].value eq "A@example.com"`,
+			"LOWER(email) = ?", []interface{}{"a@example.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := compileScimFilter(tt.filter, scimUserAttributes)
+			if err != nil {
+				t.Fatalf("compileScimFilter(%q) error: %v", tt.filter, err)
+			}
+			if filter.SQL != tt.wantSQL {
+				t.Errorf("sql = %q, want %q", filter.SQL, tt.wantSQL)
+			}
+			if !reflect.DeepEqual(filter.Args, tt.wantArgs) {
+				t.Errorf("args = %#v, want %#v", filter.Args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestCompileScimFilterErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  string
+		wantErr string
+	}{
+		{"empty", ``, "unexpected end of filter"},
+		{"unknown attribute", `password eq "x"`, `unsupported filter attribute "password"`},
+		{"unknown operator", `userName like "x"`, `unsupported filter operator "like"`},
+		{"missing operator", `userName`, `expected operator after "userName"`},
+		{"missing value", `userName eq`, `expected value after "eq"`},
+		{"unquoted string", `userName eq bjensen`, "string values must be quoted: bjensen"},
+		{"unterminated string", `userName eq "bjensen`, "unterminated string in filter"},
+		{"invalid escape", `userName eq "\x"`, "invalid string literal in filter"},
+		{"invalid bool", `active eq "true"`, `invalid boolean value "true"`},
+		{"invalid id", `id eq "abc"`, `invalid id value "abc"`},
+		{"invalid date", `meta.created gt "yesterday"`, `invalid dateTime value "yesterday"`},
+		{"ordering on bool", `active gt true`, `operator "gt" is not valid for "active"`},
+		{"ordering with null", `meta.created lt null`, `operator "lt" is not valid for "meta.created"`},
+		{"contains on non-string", `id co "1"`, `operator "co" requires a string attribute`},
+		{"not without group", `not active eq true`, "expected '('"},
+		{"unclosed group", `(userName eq "a"`, "expected ')'"},
+		{"trailing token", `userName eq "a" )`, `unexpected token ")"`},
+		{"dangling and", `userName eq "a" and`, "unexpected end of filter"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := compileScimFilter(tt.filter, scimUserAttributes)
+			if err == nil {
+				t.Fatalf("compileScimFilter(%q) = nil error, want %q", tt.filter, tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %q, want it to contain %q", err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
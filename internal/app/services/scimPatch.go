@@ -0,0 +1,200 @@
+package services
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"megabaseGo/internal/app/dto"
+)
+
+// scimPath ruta de un PATCH SCIM: attr[filtroAttr eq "valor"].subAttr
+type scimPath struct {
+	Attribute   string
+	FilterAttr  string
+	FilterValue string
+	SubAttr     string
+}
+
+// parseScimPath interpreta una ruta de PATCH. Solo se admiten filtros de igualdad
+// dentro de los corchetes, que es lo que envían los IdP habituales.
+func parseScimPath(path string) (*scimPath, error) {
+	path = strings.TrimSpace(path)
+
+	// Quitar el URN del esquema si viene como prefijo
+	if strings.HasPrefix(strings.ToLower(path), "urn:") {
+		bracket := strings.Index(path, "[")
+		head := path
+		if bracket >= 0 {
+			head = path[:bracket]
+		}
+		if idx := strings.LastIndex(head, ":"); idx >= 0 {
+			path = path[idx+1:]
+		}
+	}
+
+	result := &scimPath{}
+	if open := strings.Index(path, "["); open >= 0 {
+		closing := strings.Index(path, "]")
+		if closing < open {
+			return nil, scimInvalidPath(path)
+		}
+		result.Attribute = path[:open]
+
+		tokens, err := tokenizeScimFilter(path[open+1 : closing])
+		if err != nil || len(tokens) != 3 || !strings.EqualFold(tokens[1].value, "eq") {
+			return nil, scimInvalidPath(path)
+		}
+		result.FilterAttr = tokens[0].value
+		result.FilterValue = tokens[2].value
+
+		rest := path[closing+1:]
+		if strings.HasPrefix(rest, ".") {
+			result.SubAttr = rest[1:]
+		} else if rest != "" {
+			return nil, scimInvalidPath(path)
+		}
+		return result, nil
+	}
+
+	if dot := strings.Index(path, "."); dot >= 0 {
+		result.Attribute = path[:dot]
+		result.SubAttr = path[dot+1:]
+	} else {
+		result.Attribute = path
+	}
+	if result.Attribute == "" {
+		return nil, scimInvalidPath(path)
+	}
+	return result, nil
+}
+
+// applyScimPatch aplica las operaciones sobre la representación JSON genérica del recurso
+func applyScimPatch(resource map[string]interface{}, operations []dto.ScimPatchOperation) error {
+	for _, operation := range operations {
+		op := strings.ToLower(operation.Op)
+		if op != "add" && op != "replace" && op != "remove" {
+			return &ScimError{Status: http.StatusBadRequest, ScimType: "invalidSyntax", Detail: fmt.Sprintf("unsupported patch op %q", operation.Op)}
+		}
+
+		// Sin path: el valor es un objeto con los atributos a añadir o reemplazar
+		if operation.Path == "" {
+			if op == "remove" {
+				return &ScimError{Status: http.StatusBadRequest, ScimType: "noTarget", Detail: "remove operations require a path"}
+			}
+			values, ok := operation.Value.(map[string]interface{})
+			if !ok {
+				return &ScimError{Status: http.StatusBadRequest, ScimType: "invalidValue", Detail: "patch value must be an object when path is omitted"}
+			}
+			for key, value := range values {
+				path, err := parseScimPath(key)
+				if err != nil {
+					return err
+				}
+				if err := applyScimPatchPath(resource, op, path, value); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		path, err := parseScimPath(operation.Path)
+		if err != nil {
+			return err
+		}
+		if err := applyScimPatchPath(resource, op, path, operation.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func applyScimPatchPath(resource map[string]interface{}, op string, path *scimPath, value interface{}) error {
+	key := resourceKey(resource, path.Attribute)
+
+	// Atributos multivaluados con filtro: emails[type eq "work"].value, members[value eq "5"]
+	if path.FilterAttr != "" {
+		items, _ := resource[key].([]interface{})
+		matched := false
+		var kept []interface{}
+		for _, item := range items {
+			element, ok := item.(map[string]interface{})
+			if !ok || !strings.EqualFold(fmt.Sprint(element[resourceKey(element, path.FilterAttr)]), path.FilterValue) {
+				kept = append(kept, item)
+				continue
+			}
+			matched = true
+			if op == "remove" {
+				if path.SubAttr != "" {
+					delete(element, resourceKey(element, path.SubAttr))
+					kept = append(kept, element)
+				}
+				continue
+			}
+			if path.SubAttr != "" {
+				element[resourceKey(element, path.SubAttr)] = value
+			} else if replacement, ok := value.(map[string]interface{}); ok {
+				for k, v := range replacement {
+					element[k] = v
+				}
+			}
+			kept = append(kept, element)
+		}
+		if !matched && op != "remove" {
+			element := map[string]interface{}{path.FilterAttr: path.FilterValue}
+			if path.SubAttr != "" {
+				element[path.SubAttr] = value
+			}
+			kept = append(kept, element)
+		}
+		resource[key] = kept
+		return nil
+	}
+
+	// Sub-atributo de un atributo complejo: name.givenName
+	if path.SubAttr != "" {
+		complexValue, _ := resource[key].(map[string]interface{})
+		if complexValue == nil {
+			complexValue = map[string]interface{}{}
+		}
+		subKey := resourceKey(complexValue, path.SubAttr)
+		if op == "remove" {
+			delete(complexValue, subKey)
+		} else {
+			complexValue[subKey] = value
+		}
+		resource[key] = complexValue
+		return nil
+	}
+
+	switch op {
+	case "remove":
+		delete(resource, key)
+	case "add":
+		// En atributos multivaluados "add" añade elementos en lugar de reemplazar
+		if existing, ok := resource[key].([]interface{}); ok {
+			if additions, ok := value.([]interface{}); ok {
+				resource[key] = append(existing, additions...)
+				return nil
+			}
+		}
+		resource[key] = value
+	case "replace":
+		resource[key] = value
+	}
+	return nil
+}
+
+// resourceKey busca la clave existente sin distinguir mayúsculas (los atributos SCIM no las distinguen)
+func resourceKey(resource map[string]interface{}, attribute string) string {
+	for key := range resource {
+		if strings.EqualFold(key, attribute) {
+			return key
+		}
+	}
+	return attribute
+}
+
+func scimInvalidPath(path string) *ScimError {
+	return &ScimError{Status: http.StatusBadRequest, ScimType: "invalidPath", Detail: fmt.Sprintf("invalid path %q", path)}
+}
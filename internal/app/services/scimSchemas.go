@@ -0,0 +1,165 @@
+package services
+
+import (
+	"megabaseGo/internal/app/dto"
+)
+
+// Documentos de descubrimiento SCIM (RFC 7643 §5-7). Son estáticos salvo las URLs.
+
+// ServiceProviderConfig describe las capacidades soportadas por este servidor SCIM
+func (s *ScimService) ServiceProviderConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"schemas":          []string{dto.ScimSPConfigSchema},
+		"documentationUri": s.baseURL,
+		"patch":            map[string]interface{}{"supported": true},
+		"bulk":             map[string]interface{}{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":           map[string]interface{}{"supported": true, "maxResults": s.maxResults},
+		"changePassword":   map[string]interface{}{"supported": true},
+		"sort":             map[string]interface{}{"supported": false},
+		"etag":             map[string]interface{}{"supported": false},
+		"authenticationSchemes": []map[string]interface{}{{
+			"type":        "oauthbearertoken",
+			"name":        "Bearer Token",
+			"description": "Token de cliente de aprovisionamiento en la cabecera Authorization",
+			"primary":     true,
+		}},
+		"meta": map[string]interface{}{
+			"resourceType": "ServiceProviderConfig",
+			"location":     s.baseURL + "/ServiceProviderConfig",
+		},
+	}
+}
+
+// ResourceTypes lista los tipos de recurso expuestos
+func (s *ScimService) ResourceTypes() []map[string]interface{} {
+	return []map[string]interface{}{
+		s.resourceType("User", "/Users", dto.ScimUserSchema),
+		s.resourceType("Group", "/Groups", dto.ScimGroupSchema),
+	}
+}
+
+// ResourceType obtiene un tipo de recurso por nombre
+func (s *ScimService) ResourceType(name string) (map[string]interface{}, bool) {
+	for _, resourceType := range s.ResourceTypes() {
+		if resourceType["id"] == name {
+			return resourceType, true
+		}
+	}
+	return nil, false
+}
+
+// Schemas lista los esquemas de los recursos soportados
+func (s *ScimService) Schemas() []map[string]interface{} {
+	return []map[string]interface{}{s.userSchema(), s.groupSchema()}
+}
+
+// Schema obtiene un esquema por su URN
+func (s *ScimService) Schema(id string) (map[string]interface{}, bool) {
+	for _, schema := range s.Schemas() {
+		if schema["id"] == id {
+			return schema, true
+		}
+	}
+	return nil, false
+}
+
+func (s *ScimService) resourceType(name, endpoint, schema string) map[string]interface{} {
+	return map[string]interface{}{
+		"schemas":     []string{dto.ScimResourceTypeSchema},
+		"id":          name,
+		"name":        name,
+		"endpoint":    endpoint,
+		"description": name,
+		"schema":      schema,
+		"meta": map[string]interface{}{
+			"resourceType": "ResourceType",
+			"location":     s.baseURL + "/ResourceTypes/" + name,
+		},
+	}
+}
+
+func (s *ScimService) userSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"schemas":     []string{dto.ScimSchemaSchema},
+		"id":          dto.ScimUserSchema,
+		"name":        "User",
+		"description": "User Account",
+		"attributes": []map[string]interface{}{
+			scimSchemaAttribute("userName", "string", true, "readWrite", "server"),
+			scimSchemaAttribute("externalId", "string", false, "readWrite", "none"),
+			scimSchemaAttribute("displayName", "string", false, "readWrite", "none"),
+			scimComplexAttribute("name", false, []map[string]interface{}{
+				scimSchemaAttribute("formatted", "string", false, "readWrite", "none"),
+				scimSchemaAttribute("givenName", "string", false, "readWrite", "none"),
+				scimSchemaAttribute("familyName", "string", false, "readWrite", "none"),
+			}),
+			scimComplexAttribute("emails", true, []map[string]interface{}{
+				scimSchemaAttribute("value", "string", true, "readWrite", "server"),
+				scimSchemaAttribute("type", "string", false, "readWrite", "none"),
+				scimSchemaAttribute("primary", "boolean", false, "readWrite", "none"),
+			}),
+			scimSchemaAttribute("active", "boolean", false, "readWrite", "none"),
+			scimSchemaAttribute("password", "string", false, "writeOnly", "none"),
+			scimComplexAttribute("groups", true, []map[string]interface{}{
+				scimSchemaAttribute("value", "string", false, "readOnly", "none"),
+				scimSchemaAttribute("display", "string", false, "readOnly", "none"),
+				scimSchemaAttribute("$ref", "reference", false, "readOnly", "none"),
+			}),
+		},
+		"meta": map[string]interface{}{
+			"resourceType": "Schema",
+			"location":     s.baseURL + "/Schemas/" + dto.ScimUserSchema,
+		},
+	}
+}
+
+func (s *ScimService) groupSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"schemas":     []string{dto.ScimSchemaSchema},
+		"id":          dto.ScimGroupSchema,
+		"name":        "Group",
+		"description": "Group (mapeado sobre roles; cada usuario pertenece a un único grupo)",
+		"attributes": []map[string]interface{}{
+			scimSchemaAttribute("displayName", "string", true, "readWrite", "none"),
+			scimSchemaAttribute("externalId", "string", false, "readWrite", "none"),
+			scimComplexAttribute("members", true, []map[string]interface{}{
+				scimSchemaAttribute("value", "string", false, "immutable", "none"),
+				scimSchemaAttribute("display", "string", false, "readOnly", "none"),
+				scimSchemaAttribute("$ref", "reference", false, "immutable", "none"),
+			}),
+		},
+		"meta": map[string]interface{}{
+			"resourceType": "Schema",
+			"location":     s.baseURL + "/Schemas/" + dto.ScimGroupSchema,
+		},
+	}
+}
+
+func scimSchemaAttribute(name, kind string, required bool, mutability, uniqueness string) map[string]interface{} {
+	returned := "default"
+	if mutability == "writeOnly" {
+		returned = "never"
+	}
+	return map[string]interface{}{
+		"name":        name,
+		"type":        kind,
+		"multiValued": false,
+		"required":    required,
+		"caseExact":   false,
+		"mutability":  mutability,
+		"returned":    returned,
+		"uniqueness":  uniqueness,
+	}
+}
+
+func scimComplexAttribute(name string, multiValued bool, subAttributes []map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"name":          name,
+		"type":          "complex",
+		"multiValued":   multiValued,
+		"required":      false,
+		"mutability":    "readWrite",
+		"returned":      "default",
+		"subAttributes": subAttributes,
+	}
+}
@@ -0,0 +1,678 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"megabaseGo/internal/app/dto"
+	"megabaseGo/internal/database"
+	"megabaseGo/internal/models"
+	"megabaseGo/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// ScimError error con el formato de RFC 7644 §3.12
+type ScimError struct {
+	Status   int
+	ScimType string
+	Detail   string
+}
+
+// Error implementa la interfaz error
+func (e *ScimError) Error() string {
+	return e.Detail
+}
+
+var scimEmailIndex = &scimBlindIndex{Column: "email_bidx", Index: models.EmailBlindIndex}
+
+var scimUserAttributes = map[string]scimAttribute{
+	"id":                {Column: "id", Kind: "id"},
+	"username":          {Column: "user_name", Kind: "string"},
+	"externalid":        {Column: "external_id", Kind: "string", CaseExact: true},
+	"emails":            {Column: "email", Kind: "string", Encrypted: true, BlindIndex: scimEmailIndex},
+	"emails.value":      {Column: "email", Kind: "string", Encrypted: true, BlindIndex: scimEmailIndex},
+	"displayname":       {Column: "name", Kind: "string", Encrypted: true},
+	"name.formatted":    {Column: "name", Kind: "string", Encrypted: true},
+	"active":            {Column: "is_active", Kind: "bool"},
+	"meta.created":      {Column: "created_at", Kind: "date"},
+	"meta.lastmodified": {Column: "updated_at", Kind: "date"},
+}
+
+var scimGroupAttributes = map[string]scimAttribute{
+	"id":                {Column: "id", Kind: "id"},
+	"displayname":       {Column: "display_name", Kind: "string"},
+	"externalid":        {Column: "external_id", Kind: "string", CaseExact: true},
+	"meta.created":      {Column: "created_at", Kind: "date"},
+	"meta.lastmodified": {Column: "updated_at", Kind: "date"},
+}
+
+var roleNameSanitizer = regexp.MustCompile(`[^a-z0-9]+`)
+
+type ScimService struct {
+	userService *UserService
+	hasher      utils.PasswordHasher
+	baseURL     string
+	defaultRole string
+	maxResults  int
+}
+
+// NewScimService crea una nueva instancia del servicio de aprovisionamiento SCIM
+func NewScimService() *ScimService {
+	return &ScimService{
+		userService: NewUserService(),
+		hasher:      utils.NewBcryptHasher(),
+		baseURL:     strings.TrimRight(utils.GetEnv("SCIM_BASE_URL", "http://localhost:8080/scim/v2"), "/"),
+		defaultRole: utils.GetEnv("SCIM_DEFAULT_ROLE", "user"),
+		maxResults:  utils.GetEnvInt("SCIM_MAX_RESULTS", 200),
+	}
+}
+
+// BaseURL retorna la URL base publicada en los metadatos SCIM
+func (s *ScimService) BaseURL() string {
+	return s.baseURL
+}
+
+// MaxResults retorna el tamaño máximo de página
+func (s *ScimService) MaxResults() int {
+	return s.maxResults
+}
+
+// ---------------------------------------------------------------------------
+// Clientes de aprovisionamiento
+// ---------------------------------------------------------------------------
+
+// CreateClient registra un cliente de aprovisionamiento y devuelve su token (solo se muestra una vez)
+func (s *ScimService) CreateClient(name string) (string, error) {
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	client := models.ScimClient{
+		Name:      name,
+		TokenHash: utils.HashToken(token),
+		IsActive:  true,
+	}
+	if err := database.GetDB().Create(&client).Error; err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// ListClients lista los clientes de aprovisionamiento
+func (s *ScimService) ListClients() ([]models.ScimClient, error) {
+	var clients []models.ScimClient
+	err := database.GetDB().Order("name").Find(&clients).Error
+	return clients, err
+}
+
+// RevokeClient desactiva un cliente de aprovisionamiento
+func (s *ScimService) RevokeClient(name string) error {
+	result := database.GetDB().Model(&models.ScimClient{}).Where("name = ?", name).Update("is_active", false)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("scim client not found")
+	}
+	return nil
+}
+
+// AuthenticateClient valida el token bearer de un cliente de aprovisionamiento
+func (s *ScimService) AuthenticateClient(token string) (*models.ScimClient, error) {
+	db := database.GetDB()
+
+	var client models.ScimClient
+	if err := db.Where("token_hash = ? AND is_active = ?", utils.HashToken(token), true).First(&client).Error; err != nil {
+		return nil, errors.New("invalid scim token")
+	}
+
+	now := time.Now()
+	db.Model(&client).Update("last_used_at", now)
+	return &client, nil
+}
+
+// ---------------------------------------------------------------------------
+// Users
+// ---------------------------------------------------------------------------
+
+// ListUsers lista usuarios con filtro y paginación SCIM
+func (s *ScimService) ListUsers(query *dto.ScimListQuery) (*dto.ScimListResponse, error) {
+	db := database.GetDB()
+	base := db.Model(&models.User{})
+	base, err := s.applyFilter(base, query.Filter, scimUserAttributes)
+	if err != nil {
+		return nil, err
+	}
+
+	var total int64
+	if err := base.Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	response := s.newListResponse(query, total)
+	if query.Count == 0 {
+		return response, nil
+	}
+
+	var users []models.User
+	if err := base.Preload("Role").Order("id").Offset(query.StartIndex - 1).Limit(query.Count).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	for i := range users {
+		response.Resources = append(response.Resources, s.toScimUser(&users[i]))
+	}
+	response.ItemsPerPage = len(users)
+	return response, nil
+}
+
+// GetUser obtiene un usuario SCIM por ID
+func (s *ScimService) GetUser(id uint) (*dto.ScimUser, error) {
+	user, err := s.findUser(database.GetDB(), id)
+	if err != nil {
+		return nil, err
+	}
+	return s.toScimUser(user), nil
+}
+
+// CreateUser aprovisiona un usuario nuevo con el rol por defecto
+func (s *ScimService) CreateUser(input *dto.ScimUser) (*dto.ScimUser, error) {
+	db := database.GetDB()
+
+	role, err := s.findDefaultRole(db)
+	if err != nil {
+		return nil, err
+	}
+
+	user := models.User{RoleID: role.ID, IsActive: true}
+	if err := s.applyScimUser(db, &user, input); err != nil {
+		return nil, err
+	}
+
+	// Los usuarios aprovisionados sin contraseña reciben una aleatoria inutilizable
+	if user.Password == "" {
+		user.HasPassword = false
+		randomPassword, err := utils.GenerateRandomToken(32)
+		if err != nil {
+			return nil, err
+		}
+		if user.Password, err = s.hasher.HashPassword(randomPassword); err != nil {
+			return nil, errors.New("failed to hash password")
+		}
+	}
+
+	if err := db.Create(&user).Error; err != nil {
+		return nil, err
+	}
+	// GORM omite el false frente a default:true, así que se persiste aparte
+	if !user.IsActive || !user.HasPassword {
+		if err := db.Model(&user).Updates(map[string]interface{}{
+			"is_active":    user.IsActive,
+			"has_password": user.HasPassword,
+		}).Error; err != nil {
+			return nil, err
+		}
+	}
+	if err := joinDefaultOrganization(db, user.ID, user.RoleID); err != nil {
+		return nil, err
+	}
+	return s.GetUser(user.ID)
+}
+
+// ReplaceUser reemplaza todos los atributos modificables de un usuario (PUT)
+func (s *ScimService) ReplaceUser(id uint, input *dto.ScimUser) (*dto.ScimUser, error) {
+	db := database.GetDB()
+
+	user, err := s.findUser(db, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyScimUser(db, user, input); err != nil {
+		return nil, err
+	}
+	if err := db.Omit("Role").Save(user).Error; err != nil {
+		return nil, err
+	}
+	return s.GetUser(id)
+}
+
+// PatchUser aplica operaciones PATCH sobre un usuario
+func (s *ScimService) PatchUser(id uint, req *dto.ScimPatchRequest) (*dto.ScimUser, error) {
+	current, err := s.GetUser(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var patched dto.ScimUser
+	if err := patchScimResource(current, req, &patched); err != nil {
+		return nil, err
+	}
+	return s.ReplaceUser(id, &patched)
+}
+
+// DeleteUser elimina un usuario (soft delete)
+func (s *ScimService) DeleteUser(id uint) error {
+	if err := s.userService.DeleteUser(id); err != nil {
+		if err.Error() == "user not found" {
+			return scimNotFound("User", id)
+		}
+		return err
+	}
+	return nil
+}
+
+// ---------------------------------------------------------------------------
+// Groups (mapeados sobre roles)
+// ---------------------------------------------------------------------------
+
+// ListGroups lista grupos con filtro y paginación SCIM
+func (s *ScimService) ListGroups(query *dto.ScimListQuery) (*dto.ScimListResponse, error) {
+	db := database.GetDB()
+	base := db.Model(&models.Role{}).Scopes(systemRoles)
+	base, err := s.applyFilter(base, query.Filter, scimGroupAttributes)
+	if err != nil {
+		return nil, err
+	}
+
+	var total int64
+	if err := base.Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	response := s.newListResponse(query, total)
+	if query.Count == 0 {
+		return response, nil
+	}
+
+	var roles []models.Role
+	if err := base.Preload("Users").Order("id").Offset(query.StartIndex - 1).Limit(query.Count).Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	for i := range roles {
+		response.Resources = append(response.Resources, s.toScimGroup(&roles[i]))
+	}
+	response.ItemsPerPage = len(roles)
+	return response, nil
+}
+
+// GetGroup obtiene un grupo SCIM por ID
+func (s *ScimService) GetGroup(id uint) (*dto.ScimGroup, error) {
+	role, err := s.findRole(database.GetDB(), id)
+	if err != nil {
+		return nil, err
+	}
+	return s.toScimGroup(role), nil
+}
+
+// CreateGroup crea un rol a partir de un grupo SCIM y asigna sus miembros
+func (s *ScimService) CreateGroup(input *dto.ScimGroup) (*dto.ScimGroup, error) {
+	if strings.TrimSpace(input.DisplayName) == "" {
+		return nil, scimInvalidValue("displayName is required")
+	}
+
+	var roleID uint
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		name, err := s.uniqueRoleName(tx, input.DisplayName)
+		if err != nil {
+			return err
+		}
+
+		role := models.Role{
+			Name:        name,
+			DisplayName: input.DisplayName,
+			ExternalID:  input.ExternalID,
+			IsActive:    true,
+		}
+		if err := tx.Create(&role).Error; err != nil {
+			return err
+		}
+		roleID = role.ID
+		return s.syncMembers(tx, &role, input.Members)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetGroup(roleID)
+}
+
+// ReplaceGroup reemplaza nombre, externalId y miembros de un grupo (PUT)
+func (s *ScimService) ReplaceGroup(id uint, input *dto.ScimGroup) (*dto.ScimGroup, error) {
+	if strings.TrimSpace(input.DisplayName) == "" {
+		return nil, scimInvalidValue("displayName is required")
+	}
+
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		role, err := s.findRole(tx, id)
+		if err != nil {
+			return err
+		}
+
+		role.DisplayName = input.DisplayName
+		role.ExternalID = input.ExternalID
+		if err := tx.Omit("Users").Save(role).Error; err != nil {
+			return err
+		}
+		return s.syncMembers(tx, role, input.Members)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetGroup(id)
+}
+
+// PatchGroup aplica operaciones PATCH sobre un grupo
+func (s *ScimService) PatchGroup(id uint, req *dto.ScimPatchRequest) (*dto.ScimGroup, error) {
+	current, err := s.GetGroup(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var patched dto.ScimGroup
+	if err := patchScimResource(current, req, &patched); err != nil {
+		return nil, err
+	}
+	return s.ReplaceGroup(id, &patched)
+}
+
+// DeleteGroup elimina un rol; sus miembros pasan al rol por defecto
+func (s *ScimService) DeleteGroup(id uint) error {
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		role, err := s.findRole(tx, id)
+		if err != nil {
+			return err
+		}
+		if err := s.syncMembers(tx, role, nil); err != nil {
+			return err
+		}
+		return tx.Delete(&models.Role{}, id).Error
+	})
+}
+
+// ---------------------------------------------------------------------------
+// Helpers
+// ---------------------------------------------------------------------------
+
+func (s *ScimService) applyFilter(query *gorm.DB, filter string, attributes map[string]scimAttribute) (*gorm.DB, error) {
+	if strings.TrimSpace(filter) == "" {
+		return query, nil
+	}
+	compiled, err := compileScimFilter(filter, attributes)
+	if err != nil {
+		return nil, &ScimError{Status: http.StatusBadRequest, ScimType: "invalidFilter", Detail: err.Error()}
+	}
+	return query.Where(compiled.SQL, compiled.Args...), nil
+}
+
+func (s *ScimService) newListResponse(query *dto.ScimListQuery, total int64) *dto.ScimListResponse {
+	return &dto.ScimListResponse{
+		Schemas:      []string{dto.ScimListResponseSchema},
+		TotalResults: total,
+		StartIndex:   query.StartIndex,
+		ItemsPerPage: 0,
+		Resources:    []interface{}{},
+	}
+}
+
+func (s *ScimService) findUser(db *gorm.DB, id uint) (*models.User, error) {
+	var user models.User
+	if err := db.Preload("Role").First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, scimNotFound("User", id)
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (s *ScimService) findRole(db *gorm.DB, id uint) (*models.Role, error) {
+	var role models.Role
+	if err := db.Scopes(systemRoles).Preload("Users").First(&role, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, scimNotFound("Group", id)
+		}
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (s *ScimService) findDefaultRole(db *gorm.DB) (*models.Role, error) {
+	var role models.Role
+	if err := db.Scopes(systemRoles).Where("name = ?", s.defaultRole).First(&role).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, &ScimError{
+				Status: http.StatusInternalServerError,
+				Detail: fmt.Sprintf("default role %q for provisioned users does not exist (SCIM_DEFAULT_ROLE)", s.defaultRole),
+			}
+		}
+		return nil, err
+	}
+	return &role, nil
+}
+
+// applyScimUser copia los atributos SCIM al modelo con semántica de reemplazo
+func (s *ScimService) applyScimUser(db *gorm.DB, user *models.User, input *dto.ScimUser) error {
+	userName := utils.NormalizeUserName(input.UserName)
+	if err := validateUserName(userName); err != nil {
+		return scimInvalidValue(err.Error())
+	}
+
+	email := ""
+	for _, candidate := range input.Emails {
+		if email == "" || candidate.Primary {
+			email = candidate.Value
+		}
+	}
+	email = utils.NormalizeEmail(email)
+	if email == "" {
+		return scimInvalidValue("at least one email is required")
+	}
+
+	var existing models.User
+	if err := db.Scopes(byUserName(userName)).Where("id <> ?", user.ID).First(&existing).Error; err == nil {
+		return &ScimError{Status: http.StatusConflict, ScimType: "uniqueness", Detail: "userName already exists"}
+	}
+	if err := db.Scopes(byEmail(email)).Where("id <> ?", user.ID).First(&existing).Error; err == nil {
+		return &ScimError{Status: http.StatusConflict, ScimType: "uniqueness", Detail: "email already exists"}
+	}
+
+	name := input.DisplayName
+	if input.Name != nil {
+		if input.Name.Formatted != "" {
+			name = input.Name.Formatted
+		} else if full := strings.TrimSpace(input.Name.GivenName + " " + input.Name.FamilyName); full != "" {
+			name = full
+		}
+	}
+	if strings.TrimSpace(name) == "" {
+		name = userName
+	}
+
+	user.UserName = userName
+	user.Email = email
+	user.Name = name
+	user.ExternalID = input.ExternalID
+	user.IsActive = input.Active == nil || *input.Active
+
+	if input.Password != "" {
+		hashedPassword, err := s.hasher.HashPassword(input.Password)
+		if err != nil {
+			return errors.New("failed to hash password")
+		}
+		user.Password = hashedPassword
+		user.HasPassword = true
+	}
+	return nil
+}
+
+// syncMembers deja en el rol exactamente a los miembros indicados. Como cada usuario
+// tiene un único rol, quien sale del grupo pasa al rol por defecto.
+func (s *ScimService) syncMembers(tx *gorm.DB, role *models.Role, members []dto.ScimMultiValued) error {
+	wanted := map[uint]bool{}
+	for _, member := range members {
+		id, err := strconv.ParseUint(member.Value, 10, 32)
+		if err != nil {
+			return scimInvalidValue(fmt.Sprintf("invalid member value %q", member.Value))
+		}
+		wanted[uint(id)] = true
+	}
+
+	var removed []uint
+	for _, user := range role.Users {
+		if !wanted[user.ID] {
+			removed = append(removed, user.ID)
+		}
+	}
+
+	if len(removed) > 0 {
+		defaultRole, err := s.findDefaultRole(tx)
+		if err != nil {
+			return err
+		}
+		if defaultRole.ID == role.ID {
+			return &ScimError{Status: http.StatusBadRequest, ScimType: "mutability", Detail: "members cannot be removed from the default role"}
+		}
+		if err := tx.Model(&models.User{}).Where("id IN ?", removed).Update("role_id", defaultRole.ID).Error; err != nil {
+			return err
+		}
+	}
+
+	if len(wanted) > 0 {
+		ids := make([]uint, 0, len(wanted))
+		for id := range wanted {
+			ids = append(ids, id)
+		}
+		var found int64
+		if err := tx.Model(&models.User{}).Where("id IN ?", ids).Count(&found).Error; err != nil {
+			return err
+		}
+		if int(found) != len(ids) {
+			return scimInvalidValue("one or more members do not exist")
+		}
+		if err := tx.Model(&models.User{}).Where("id IN ?", ids).Update("role_id", role.ID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *ScimService) uniqueRoleName(tx *gorm.DB, displayName string) (string, error) {
+	base := strings.Trim(roleNameSanitizer.ReplaceAllString(strings.ToLower(displayName), "-"), "-")
+	if base == "" {
+		base = "group"
+	}
+	name := base
+	for i := 2; ; i++ {
+		var count int64
+		if err := tx.Model(&models.Role{}).Scopes(systemRoles).Where("name = ?", name).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return name, nil
+		}
+		name = fmt.Sprintf("%s-%d", base, i)
+	}
+}
+
+func (s *ScimService) toScimUser(user *models.User) *dto.ScimUser {
+	id := strconv.Itoa(int(user.ID))
+	active := user.IsActive
+	scimUser := &dto.ScimUser{
+		Schemas:     []string{dto.ScimUserSchema},
+		ID:          id,
+		ExternalID:  user.ExternalID,
+		UserName:    user.UserName,
+		Name:        &dto.ScimName{Formatted: user.Name},
+		DisplayName: user.Name,
+		Emails:      []dto.ScimMultiValued{{Value: user.Email, Type: "work", Primary: true}},
+		Active:      &active,
+		Meta: &dto.ScimMeta{
+			ResourceType: "User",
+			Created:      user.CreatedAt,
+			LastModified: user.UpdatedAt,
+			Location:     s.baseURL + "/Users/" + id,
+		},
+	}
+	if user.Role.ID != 0 {
+		roleID := strconv.Itoa(int(user.Role.ID))
+		scimUser.Groups = []dto.ScimMultiValued{{
+			Value:   roleID,
+			Display: user.Role.DisplayName,
+			Ref:     s.baseURL + "/Groups/" + roleID,
+		}}
+	}
+	return scimUser
+}
+
+func (s *ScimService) toScimGroup(role *models.Role) *dto.ScimGroup {
+	id := strconv.Itoa(int(role.ID))
+	group := &dto.ScimGroup{
+		Schemas:     []string{dto.ScimGroupSchema},
+		ID:          id,
+		ExternalID:  role.ExternalID,
+		DisplayName: role.DisplayName,
+		Members:     []dto.ScimMultiValued{},
+		Meta: &dto.ScimMeta{
+			ResourceType: "Group",
+			Created:      role.CreatedAt,
+			LastModified: role.UpdatedAt,
+			Location:     s.baseURL + "/Groups/" + id,
+		},
+	}
+	for _, user := range role.Users {
+		userID := strconv.Itoa(int(user.ID))
+		group.Members = append(group.Members, dto.ScimMultiValued{
+			Value:   userID,
+			Display: user.UserName,
+			Ref:     s.baseURL + "/Users/" + userID,
+		})
+	}
+	return group
+}
+
+// patchScimResource aplica un PATCH sobre la representación JSON del recurso y
+// decodifica el resultado en target, que luego se guarda con semántica de PUT
+func patchScimResource(current interface{}, req *dto.ScimPatchRequest, target interface{}) error {
+	raw, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+	var resource map[string]interface{}
+	if err := json.Unmarshal(raw, &resource); err != nil {
+		return err
+	}
+
+	if err := applyScimPatch(resource, req.Operations); err != nil {
+		return err
+	}
+
+	// Algunos IdP envían "active" como cadena ("True"/"False")
+	if key := resourceKey(resource, "active"); resource[key] != nil {
+		if str, ok := resource[key].(string); ok {
+			resource[key] = strings.EqualFold(str, "true")
+		}
+	}
+
+	raw, err = json.Marshal(resource)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(raw, target); err != nil {
+		return scimInvalidValue("patched resource is invalid: " + err.Error())
+	}
+	return nil
+}
+
+func scimNotFound(resource string, id uint) *ScimError {
+	return &ScimError{Status: http.StatusNotFound, Detail: fmt.Sprintf("%s %d not found", resource, id)}
+}
+
+func scimInvalidValue(detail string) *ScimError {
+	return &ScimError{Status: http.StatusBadRequest, ScimType: "invalidValue", Detail: detail}
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"strings"
+
+	"megabaseGo/internal/database/encryption"
+	"megabaseGo/internal/models"
+	"megabaseGo/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// byUserName filtra usuarios por username sin distinguir mayúsculas (usa idx_users_user_name_lower)
+func byUserName(userName string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("LOWER(user_name) = LOWER(?)", utils.NormalizeUserName(userName))
+	}
+}
+
+// byEmail filtra usuarios por email sin distinguir mayúsculas (usa idx_users_email_lower).
+// Con el cifrado activo compara el índice ciego; las filas aún sin índice (guardadas antes de
+// activarlo y sin re-cifrar) siguen en claro y se comparan por LOWER(email).
+func byEmail(email string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		condition, args := emailCondition("", email)
+		return db.Where(condition, args...)
+	}
+}
+
+// emailCondition condición SQL de byEmail, con las columnas cualificadas con table si se indica
+func emailCondition(table, email string) (string, []any) {
+	prefix := ""
+	if table != "" {
+		prefix = table + "."
+	}
+	normalized := utils.NormalizeEmail(email)
+	if !encryption.Enabled() {
+		return "LOWER(" + prefix + "email) = ?", []any{normalized}
+	}
+	return "(" + prefix + "email_bidx = ? OR (" + prefix + "email_bidx = '' AND LOWER(" + prefix + "email) = ?))",
+		[]any{models.EmailBlindIndex(normalized), normalized}
+}
+
+// byLoginIdentifier filtra por email si el identificador lo parece y por username en otro caso
+func byLoginIdentifier(identifier string) func(db *gorm.DB) *gorm.DB {
+	if utils.IsEmailIdentifier(identifier) {
+		return byEmail(identifier)
+	}
+	return byUserName(identifier)
+}
+
+// systemRoles limita a los roles de sistema, que no pertenecen a ninguna organización.
+// Las búsquedas de roles por nombre para SSO/SCIM y permisos de plataforma deben usarlo:
+// una organización puede tener un rol propio con el mismo nombre.
+func systemRoles(db *gorm.DB) *gorm.DB {
+	return db.Where("roles.organization_id IS NULL")
+}
+
+// validateUserName impide usernames que se confundirían con un email al iniciar sesión
+func validateUserName(userName string) error {
+	if userName == "" {
+		return utils.NewBadRequestError("username is required")
+	}
+	if strings.Contains(userName, "@") {
+		return utils.NewBadRequestError("username cannot contain '@'")
+	}
+	return nil
+}
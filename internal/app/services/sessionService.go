@@ -0,0 +1,350 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"megabaseGo/internal/app/dto"
+	"megabaseGo/internal/database"
+	"megabaseGo/internal/models"
+	"megabaseGo/internal/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// sessionProfile duraciones en minutos aplicadas a una sesión; 0 en inactividad o
+// duración máxima significa sin límite
+type sessionProfile struct {
+	PolicyID        *uint
+	AccessMinutes   int
+	RefreshMinutes  int
+	IdleMinutes     int
+	AbsoluteMinutes int
+}
+
+// sessionOptions datos del login que determinan el perfil de la sesión
+type sessionOptions struct {
+	ClientID   string
+	RememberMe bool
+	// Organization slug o ID de la organización elegida; vacío si el usuario no eligió ninguna
+	Organization string
+	// PasswordAuthAt momento en que se comprobó la contraseña; nil en los logins sin contraseña.
+	// Solo con él los tokens de la sesión sirven para operaciones que exigen re-autenticación.
+	PasswordAuthAt *time.Time
+	Meta           *dto.RequestMeta
+}
+
+type SessionService struct {
+	organizations   *OrganizationService
+	defaultProfile  sessionProfile
+	rememberProfile sessionProfile
+}
+
+// NewSessionService crea una nueva instancia del servicio de sesiones.
+// Los perfiles por defecto salen del entorno; JWT_DURATION_HOURS se respeta si no hay JWT_ACCESS_MINUTES.
+func NewSessionService() *SessionService {
+	accessMinutes := utils.GetEnvInt("JWT_ACCESS_MINUTES", utils.GetEnvInt("JWT_DURATION_HOURS", 24)*60)
+	refreshMinutes := utils.GetEnvInt("JWT_REFRESH_MINUTES", 7*24*60)
+
+	return &SessionService{
+		organizations: NewOrganizationService(),
+		defaultProfile: sessionProfile{
+			AccessMinutes:   accessMinutes,
+			RefreshMinutes:  refreshMinutes,
+			IdleMinutes:     utils.GetEnvInt("SESSION_IDLE_TIMEOUT_MINUTES", 0),
+			AbsoluteMinutes: utils.GetEnvInt("SESSION_ABSOLUTE_TIMEOUT_MINUTES", 0),
+		},
+		rememberProfile: sessionProfile{
+			AccessMinutes:   utils.GetEnvInt("REMEMBER_ME_ACCESS_MINUTES", accessMinutes),
+			RefreshMinutes:  utils.GetEnvInt("REMEMBER_ME_REFRESH_MINUTES", 30*24*60),
+			IdleMinutes:     utils.GetEnvInt("REMEMBER_ME_IDLE_TIMEOUT_MINUTES", 0),
+			AbsoluteMinutes: utils.GetEnvInt("REMEMBER_ME_ABSOLUTE_TIMEOUT_MINUTES", 0),
+		},
+	}
+}
+
+// Open abre una sesión para el usuario con el perfil que corresponde a su rol, al cliente
+// y a la opción "recordarme". La duración máxima se cuenta desde authTime.
+// La organización de la sesión es la elegida o, si el usuario solo pertenece a una, esa.
+func (s *SessionService) Open(user *models.User, authTime time.Time, opts sessionOptions) (*models.Session, error) {
+	db := database.GetDB()
+
+	profile, err := s.resolveProfile(db, user.RoleID, opts.ClientID, opts.RememberMe)
+	if err != nil {
+		return nil, err
+	}
+
+	tenant, err := s.organizations.ResolveTenant(user.ID, opts.Organization, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session := models.Session{
+		ID:                  id,
+		UserID:              user.ID,
+		PolicyID:            profile.PolicyID,
+		ClientID:            opts.ClientID,
+		RememberMe:          opts.RememberMe,
+		AccessTokenMinutes:  profile.AccessMinutes,
+		RefreshTokenMinutes: profile.RefreshMinutes,
+		IdleTimeoutMinutes:  profile.IdleMinutes,
+		AuthTime:            authTime,
+		LastActivityAt:      now,
+		PasswordAuthAt:      opts.PasswordAuthAt,
+	}
+	if tenant != nil {
+		session.OrganizationID = &tenant.OrganizationID
+	}
+	if profile.AbsoluteMinutes > 0 {
+		expiresAt := authTime.Add(time.Duration(profile.AbsoluteMinutes) * time.Minute)
+		session.ExpiresAt = &expiresAt
+	}
+	if opts.Meta != nil {
+		session.IPAddress = opts.Meta.IPAddress
+		session.UserAgent = truncate(opts.Meta.UserAgent, 255)
+	}
+
+	if err := db.Create(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// Touch registra actividad en una sesión al renovar sus tokens. Falla si la sesión no existe,
+// fue revocada o superó su tiempo de inactividad o su duración máxima.
+func (s *SessionService) Touch(sessionID string, userID uint) (*models.Session, error) {
+	var session models.Session
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		// El bloqueo evita que dos renovaciones simultáneas lean la misma última actividad
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND user_id = ?", sessionID, userID).First(&session).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("invalid refresh token")
+			}
+			return err
+		}
+		if session.RevokedAt != nil {
+			return errors.New("invalid refresh token")
+		}
+
+		now := time.Now()
+		if sessionExpired(&session, now) {
+			return errors.New("session expired")
+		}
+
+		session.LastActivityAt = now
+		return tx.Model(&session).Update("last_activity_at", now).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// Revoke cierra una sesión; sus refresh tokens dejan de ser válidos
+func (s *SessionService) Revoke(sessionID string) error {
+	return database.GetDB().Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", sessionID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// TokenExpiries calcula la expiración del access y del refresh token emitidos ahora.
+// Ninguno supera el límite absoluto de la sesión y el refresh token tampoco su tiempo de inactividad.
+func (s *SessionService) TokenExpiries(session *models.Session, now time.Time) (time.Time, time.Time) {
+	accessExpiresAt := now.Add(time.Duration(session.AccessTokenMinutes) * time.Minute)
+	refreshExpiresAt := now.Add(time.Duration(session.RefreshTokenMinutes) * time.Minute)
+
+	if session.IdleTimeoutMinutes > 0 {
+		idleDeadline := now.Add(time.Duration(session.IdleTimeoutMinutes) * time.Minute)
+		if idleDeadline.Before(refreshExpiresAt) {
+			refreshExpiresAt = idleDeadline
+		}
+	}
+	if session.ExpiresAt != nil {
+		if session.ExpiresAt.Before(accessExpiresAt) {
+			accessExpiresAt = *session.ExpiresAt
+		}
+		if session.ExpiresAt.Before(refreshExpiresAt) {
+			refreshExpiresAt = *session.ExpiresAt
+		}
+	}
+	return accessExpiresAt, refreshExpiresAt
+}
+
+// resolveProfile elige el perfil más específico: cliente y rol, solo cliente, solo rol;
+// sin perfil configurado se usan los valores por defecto del entorno
+func (s *SessionService) resolveProfile(db *gorm.DB, roleID uint, clientID string, rememberMe bool) (sessionProfile, error) {
+	var policy models.SessionPolicy
+	err := db.Where("remember_me = ?", rememberMe).
+		Where("client_id = ? OR client_id = ''", clientID).
+		Where("role_id = ? OR role_id IS NULL", roleID).
+		Where("client_id <> '' OR role_id IS NOT NULL").
+		Order("client_id <> '' DESC, role_id IS NOT NULL DESC, id").
+		First(&policy).Error
+	if err == nil {
+		return sessionProfile{
+			PolicyID:        &policy.ID,
+			AccessMinutes:   policy.AccessTokenMinutes,
+			RefreshMinutes:  policy.RefreshTokenMinutes,
+			IdleMinutes:     policy.IdleTimeoutMinutes,
+			AbsoluteMinutes: policy.AbsoluteTimeoutMinutes,
+		}, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return sessionProfile{}, err
+	}
+
+	if rememberMe {
+		return s.rememberProfile, nil
+	}
+	return s.defaultProfile, nil
+}
+
+// CreatePolicy crea un perfil de sesión
+func (s *SessionService) CreatePolicy(req *dto.SessionPolicyRequest) (*dto.SessionPolicyResponse, error) {
+	db := database.GetDB()
+
+	var policy models.SessionPolicy
+	if err := s.applyPolicyRequest(db, &policy, req); err != nil {
+		return nil, err
+	}
+
+	var existing models.SessionPolicy
+	if err := db.Where("name = ?", policy.Name).First(&existing).Error; err == nil {
+		return nil, utils.NewConflictError("session policy with this name already exists")
+	}
+
+	if err := db.Create(&policy).Error; err != nil {
+		return nil, err
+	}
+	return s.toPolicyResponse(&policy), nil
+}
+
+// GetPolicies lista los perfiles de sesión configurados
+func (s *SessionService) GetPolicies() ([]dto.SessionPolicyResponse, error) {
+	var policies []models.SessionPolicy
+	if err := database.GetDB().Order("name").Find(&policies).Error; err != nil {
+		return nil, err
+	}
+
+	responses := make([]dto.SessionPolicyResponse, 0, len(policies))
+	for i := range policies {
+		responses = append(responses, *s.toPolicyResponse(&policies[i]))
+	}
+	return responses, nil
+}
+
+// GetPolicy obtiene un perfil de sesión por ID
+func (s *SessionService) GetPolicy(id uint) (*dto.SessionPolicyResponse, error) {
+	var policy models.SessionPolicy
+	if err := database.GetDB().First(&policy, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("Session policy")
+		}
+		return nil, err
+	}
+	return s.toPolicyResponse(&policy), nil
+}
+
+// UpdatePolicy reemplaza un perfil de sesión; las sesiones ya abiertas conservan sus duraciones
+func (s *SessionService) UpdatePolicy(id uint, req *dto.SessionPolicyRequest) (*dto.SessionPolicyResponse, error) {
+	db := database.GetDB()
+
+	var policy models.SessionPolicy
+	if err := db.First(&policy, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("Session policy")
+		}
+		return nil, err
+	}
+
+	if err := s.applyPolicyRequest(db, &policy, req); err != nil {
+		return nil, err
+	}
+
+	var existing models.SessionPolicy
+	if err := db.Where("name = ? AND id <> ?", policy.Name, id).First(&existing).Error; err == nil {
+		return nil, utils.NewConflictError("session policy with this name already exists")
+	}
+
+	// Select("*") para guardar también los false y los 0
+	if err := db.Model(&policy).Select("*").Updates(&policy).Error; err != nil {
+		return nil, err
+	}
+	return s.toPolicyResponse(&policy), nil
+}
+
+// DeletePolicy elimina un perfil de sesión
+func (s *SessionService) DeletePolicy(id uint) error {
+	result := database.GetDB().Delete(&models.SessionPolicy{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return utils.NewNotFoundError("Session policy")
+	}
+	return nil
+}
+
+func (s *SessionService) applyPolicyRequest(db *gorm.DB, policy *models.SessionPolicy, req *dto.SessionPolicyRequest) error {
+	if req.RoleID == nil && req.ClientID == "" {
+		return utils.NewValidationError("a session policy must target a role_id, a client_id or both")
+	}
+	if req.RoleID != nil {
+		var role models.Role
+		if err := db.First(&role, *req.RoleID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return utils.NewBadRequestError("role not found")
+			}
+			return err
+		}
+	}
+
+	policy.Name = req.Name
+	policy.RoleID = req.RoleID
+	policy.ClientID = req.ClientID
+	policy.RememberMe = req.RememberMe
+	policy.AccessTokenMinutes = req.AccessTokenMinutes
+	policy.RefreshTokenMinutes = req.RefreshTokenMinutes
+	policy.IdleTimeoutMinutes = req.IdleTimeoutMinutes
+	policy.AbsoluteTimeoutMinutes = req.AbsoluteTimeoutMinutes
+	return nil
+}
+
+func (s *SessionService) toPolicyResponse(policy *models.SessionPolicy) *dto.SessionPolicyResponse {
+	return &dto.SessionPolicyResponse{
+		ID:                     policy.ID,
+		Name:                   policy.Name,
+		RoleID:                 policy.RoleID,
+		ClientID:               policy.ClientID,
+		RememberMe:             policy.RememberMe,
+		AccessTokenMinutes:     policy.AccessTokenMinutes,
+		RefreshTokenMinutes:    policy.RefreshTokenMinutes,
+		IdleTimeoutMinutes:     policy.IdleTimeoutMinutes,
+		AbsoluteTimeoutMinutes: policy.AbsoluteTimeoutMinutes,
+		CreatedAt:              policy.CreatedAt,
+		UpdatedAt:              policy.UpdatedAt,
+	}
+}
+
+// sessionExpired indica si la sesión superó su tiempo de inactividad o su duración máxima
+func sessionExpired(session *models.Session, now time.Time) bool {
+	if session.ExpiresAt != nil && !now.Before(*session.ExpiresAt) {
+		return true
+	}
+	if session.IdleTimeoutMinutes > 0 {
+		idleDeadline := session.LastActivityAt.Add(time.Duration(session.IdleTimeoutMinutes) * time.Minute)
+		if !now.Before(idleDeadline) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"errors"
+	"strconv"
+
+	"megabaseGo/internal/models"
+	"megabaseGo/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// Tenant organización activa de una petición y rol del usuario actual en ella.
+// Los servicios obtenidos con ForTenant aplican los scopes de este archivo a todas sus
+// consultas, así que no pueden leer ni modificar datos de otra organización.
+type Tenant struct {
+	OrganizationID uint
+	RoleID         uint
+}
+
+// tenantUsers limita a los usuarios miembros de la organización; sin tenant no filtra (contexto de plataforma)
+func tenantUsers(tenant *Tenant) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if tenant == nil {
+			return db
+		}
+		return db.Where("users.id IN (SELECT user_id FROM organization_memberships WHERE organization_id = ?)", tenant.OrganizationID)
+	}
+}
+
+// tenantRoles limita a los roles visibles en la organización: los suyos y los de sistema.
+// Sin tenant solo quedan los roles de sistema.
+func tenantRoles(tenant *Tenant) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if tenant == nil {
+			return systemRoles(db)
+		}
+		return db.Where("roles.organization_id = ? OR roles.organization_id IS NULL", tenant.OrganizationID)
+	}
+}
+
+// tenantOwnedRoles limita a los roles que la organización puede modificar: solo los suyos.
+// Sin tenant solo quedan los roles de sistema.
+func tenantOwnedRoles(tenant *Tenant) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if tenant == nil {
+			return systemRoles(db)
+		}
+		return db.Where("roles.organization_id = ?", tenant.OrganizationID)
+	}
+}
+
+// findOrganization busca una organización activa por slug o por ID
+func findOrganization(db *gorm.DB, ref string) (*models.Organization, error) {
+	var organization models.Organization
+	query := db.Where("is_active = ?", true)
+	if id, err := strconv.ParseUint(ref, 10, 32); err == nil {
+		query = query.Where("id = ?", id)
+	} else {
+		query = query.Where("slug = ?", ref)
+	}
+	if err := query.First(&organization).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("Organization")
+		}
+		return nil, err
+	}
+	return &organization, nil
+}
+
+// findMembership devuelve la membresía del usuario en una organización activa; nil si no es miembro
+func findMembership(db *gorm.DB, organizationID, userID uint) (*models.OrganizationMembership, error) {
+	var membership models.OrganizationMembership
+	err := db.Joins("JOIN organizations ON organizations.id = organization_memberships.organization_id").
+		Where("organizations.is_active AND organizations.deleted_at IS NULL").
+		Where("organization_memberships.organization_id = ? AND organization_memberships.user_id = ?", organizationID, userID).
+		First(&membership).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &membership, nil
+}
+
+// singleMembership devuelve la única organización activa del usuario; nil si tiene ninguna o varias
+func singleMembership(db *gorm.DB, userID uint) (*models.OrganizationMembership, error) {
+	var memberships []models.OrganizationMembership
+	err := db.Joins("JOIN organizations ON organizations.id = organization_memberships.organization_id").
+		Where("organizations.is_active AND organizations.deleted_at IS NULL").
+		Where("organization_memberships.user_id = ?", userID).
+		Limit(2).Find(&memberships).Error
+	if err != nil || len(memberships) != 1 {
+		return nil, err
+	}
+	return &memberships[0], nil
+}
+
+// joinDefaultOrganization hace miembro de la organización por defecto (DEFAULT_ORGANIZATION)
+// a un usuario creado fuera de un tenant (registro, SCIM, SAML). Sin organización por defecto no hace nada.
+func joinDefaultOrganization(db *gorm.DB, userID, roleID uint) error {
+	slug := utils.GetEnv("DEFAULT_ORGANIZATION", "default")
+	if slug == "" {
+		return nil
+	}
+
+	var organization models.Organization
+	if err := db.Where("slug = ?", slug).First(&organization).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	membership := models.OrganizationMembership{OrganizationID: organization.ID, UserID: userID, RoleID: roleID}
+	return db.Where(models.OrganizationMembership{OrganizationID: organization.ID, UserID: userID}).
+		FirstOrCreate(&membership).Error
+}
+
+// defaultUserRole rol de plataforma de los usuarios creados dentro de una organización (DEFAULT_USER_ROLE).
+// El rol que eligen los administradores de la organización solo se aplica a la membresía.
+func defaultUserRole(db *gorm.DB) (*models.Role, error) {
+	name := utils.GetEnv("DEFAULT_USER_ROLE", "user")
+
+	var role models.Role
+	if err := db.Scopes(systemRoles).Where("name = ?", name).First(&role).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewInternalServerError("default role for organization users does not exist (DEFAULT_USER_ROLE)")
+		}
+		return nil, err
+	}
+	return &role, nil
+}
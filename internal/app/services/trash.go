@@ -0,0 +1,121 @@
+package services
+
+import (
+	"log"
+	"time"
+
+	"megabaseGo/internal/database"
+	"megabaseGo/internal/models"
+	"megabaseGo/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// Valores del filtro ?trashed= de los listados
+const (
+	// TrashedWith incluye los registros en la papelera junto a los activos
+	TrashedWith = "with"
+	// TrashedOnly devuelve solo los registros en la papelera
+	TrashedOnly = "only"
+)
+
+// trashedScope aplica el filtro de papelera sobre table; vacío deja solo los registros no eliminados
+func trashedScope(table, trashed string) (func(db *gorm.DB) *gorm.DB, error) {
+	switch trashed {
+	case "":
+		return func(db *gorm.DB) *gorm.DB { return db }, nil
+	case TrashedWith:
+		return func(db *gorm.DB) *gorm.DB { return db.Unscoped() }, nil
+	case TrashedOnly:
+		return func(db *gorm.DB) *gorm.DB {
+			return db.Unscoped().Where(table + ".deleted_at IS NOT NULL")
+		}, nil
+	default:
+		return nil, utils.NewBadRequestError("trashed must be 'with' or 'only'")
+	}
+}
+
+// deletedAt momento del borrado lógico; nil si el registro no está en la papelera
+func deletedAt(value gorm.DeletedAt) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+	return &value.Time
+}
+
+// TrashService purga los usuarios y roles que llevan en la papelera más de TRASH_RETENTION_DAYS
+type TrashService struct {
+	retention time.Duration
+}
+
+// NewTrashService crea una nueva instancia del servicio de papelera
+func NewTrashService() *TrashService {
+	return &TrashService{
+		retention: time.Duration(utils.GetEnvInt("TRASH_RETENTION_DAYS", 30)) * 24 * time.Hour,
+	}
+}
+
+// PurgeExpired elimina definitivamente los usuarios y roles caducados de todas las
+// organizaciones; primero los usuarios, que pueden ser lo único que aún referencia a un rol
+// eliminado. Devuelve cuántos usuarios y roles purgó.
+// Con TRASH_RETENTION_DAYS=0 no purga nada.
+func (s *TrashService) PurgeExpired() (users, roles int64, err error) {
+	if s.retention <= 0 {
+		return 0, 0, nil
+	}
+
+	userService := NewUserService()
+	roleService := NewRoleService()
+	cutoff := time.Now().Add(-s.retention)
+
+	var userIDs []uint
+	if err := database.GetDB().Unscoped().Model(&models.User{}).
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).Pluck("id", &userIDs).Error; err != nil {
+		return 0, 0, err
+	}
+	for _, id := range userIDs {
+		if err := userService.PurgeUser(id); err != nil {
+			return users, roles, err
+		}
+		users++
+	}
+
+	var expiredRoles []models.Role
+	if err := database.GetDB().Unscoped().Select("id", "organization_id").
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).Find(&expiredRoles).Error; err != nil {
+		return users, 0, err
+	}
+	for _, role := range expiredRoles {
+		// Los roles propios de una organización solo los ve el servicio con su tenant
+		owner := roleService
+		if role.OrganizationID != nil {
+			owner = roleService.ForTenant(&Tenant{OrganizationID: *role.OrganizationID})
+		}
+		if err := owner.PurgeRole(role.ID); err != nil {
+			// Un rol aún asignado a usuarios de otra organización se queda en la papelera
+			if _, ok := utils.IsAPIError(err); ok {
+				continue
+			}
+			return users, roles, err
+		}
+		roles++
+	}
+	return users, roles, nil
+}
+
+// RunPurging ejecuta PurgeExpired cada interval mientras el proceso esté vivo
+func (s *TrashService) RunPurging(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		users, roles, err := s.PurgeExpired()
+		if err != nil {
+			log.Printf("Error purgando la papelera: %v", err)
+			continue
+		}
+		if users > 0 || roles > 0 {
+			log.Printf("Papelera purgada: %d usuarios, %d roles", users, roles)
+		}
+	}
+}
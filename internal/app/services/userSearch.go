@@ -0,0 +1,208 @@
+package services
+
+import (
+	"html"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"megabaseGo/internal/app/dto"
+	"megabaseGo/internal/database"
+	"megabaseGo/internal/database/encryption"
+	"megabaseGo/internal/models"
+	"megabaseGo/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// Longitud máxima del texto buscado
+const maxSearchQueryLength = 100
+
+// Umbral de word_similarity por defecto: más bajo que el de pg_trgm (0.6) para tolerar erratas
+const defaultSearchSimilarity = 0.3
+
+// userSearchHit usuario con la puntuación calculada por la consulta de búsqueda
+type userSearchHit struct {
+	models.User
+	Rank float64 `gorm:"column:rank;->"`
+}
+
+// SearchUsers busca usuarios por nombre, username o email con coincidencias parciales y tolerancia
+// a erratas (pg_trgm) y por palabras (tsvector), ordenados por relevancia y paginados como los
+// listados. Con el cifrado de campos activo el nombre y el email no se pueden buscar en la BD:
+// solo se busca por username y por email exacto (índice ciego).
+func (s *UserService) SearchUsers(q string, params *dto.ListQuery, includeInactive bool) ([]dto.UserSearchResult, *dto.PageMeta, error) {
+	q = strings.ToLower(strings.TrimSpace(q))
+	if q == "" {
+		return nil, nil, utils.NewBadRequestError("q is required")
+	}
+	if utf8.RuneCountInString(q) > maxSearchQueryLength {
+		return nil, nil, utils.NewBadRequestError("q must not exceed " + strconv.Itoa(maxSearchQueryLength) + " characters")
+	}
+	terms := searchTerms(q)
+
+	var hits []userSearchHit
+	var meta *dto.PageMeta
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		// SET LOCAL: el umbral solo afecta a esta transacción
+		if err := tx.Exec("SELECT set_config('pg_trgm.word_similarity_threshold', ?, true)", searchSimilarity()).Error; err != nil {
+			return err
+		}
+
+		matches := tx.Model(&models.User{}).Scopes(tenantUsers(s.tenant), userSearchScope(q, terms))
+		query := tx.Table("(?) AS users", matches)
+		if !includeInactive && params.Filters["is_active"] == "" {
+			query = query.Where("users.is_active = ?", true)
+		}
+
+		var err error
+		hits, meta, err = paginate(query, s.userSearchSpec(), params, "Role")
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	users := make([]models.User, len(hits))
+	for i := range hits {
+		users[i] = hits[i].User
+	}
+	if err := s.applyMembershipRoles(users); err != nil {
+		return nil, nil, err
+	}
+
+	highlight := searchHighlighter(terms)
+	results := make([]dto.UserSearchResult, 0, len(users))
+	for i := range users {
+		result := dto.UserSearchResult{UserResponse: *s.toUserResponse(&users[i]), Score: hits[i].Rank}
+		for field, value := range map[string]string{"name": users[i].Name, "user_name": users[i].UserName, "email": users[i].Email} {
+			if marked, ok := highlight(value); ok {
+				if result.Highlights == nil {
+					result.Highlights = make(map[string]string)
+				}
+				result.Highlights[field] = marked
+			}
+		}
+		results = append(results, result)
+	}
+
+	return results, meta, nil
+}
+
+// userSearchScope selecciona los usuarios que coinciden con q y calcula su puntuación (rank):
+// la mayor word_similarity entre q y cada campo más el ts_rank de las palabras buscadas como prefijos
+func userSearchScope(q string, terms []string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if encryption.Enabled() {
+			email, emailArgs := emailCondition("users", q)
+			rank := "public.word_similarity(?, LOWER(users.user_name)) + CASE WHEN " + email + " THEN 1 ELSE 0 END"
+			return db.Select("users.*, ("+rank+")::float8 AS rank", append([]any{q}, emailArgs...)...).
+				Where("(? OPERATOR(public.<%) LOWER(users.user_name) OR "+email+")", append([]any{q}, emailArgs...)...)
+		}
+
+		columns := []string{"LOWER(users.user_name)", "LOWER(users.name)", "LOWER(users.email)"}
+		similarities := make([]string, len(columns))
+		conditions := make([]string, len(columns))
+		var args []any
+		for i, column := range columns {
+			similarities[i] = "public.word_similarity(?, " + column + ")"
+			conditions[i] = "? OPERATOR(public.<%) " + column
+			args = append(args, q)
+		}
+		rank := "GREATEST(" + strings.Join(similarities, ", ") + ")"
+		rankArgs := append([]any(nil), args...)
+
+		if tsquery := prefixTSQuery(terms); tsquery != "" {
+			rank += " + ts_rank(users.search_vector, to_tsquery('simple', ?))"
+			rankArgs = append(rankArgs, tsquery)
+			conditions = append(conditions, "users.search_vector @@ to_tsquery('simple', ?)")
+			args = append(args, tsquery)
+		}
+
+		return db.Select("users.*, ("+rank+")::float8 AS rank", rankArgs...).
+			Where("("+strings.Join(conditions, " OR ")+")", args...)
+	}
+}
+
+// userSearchSpec campos de orden y filtros de la búsqueda: los del listado más rank, que es el orden por defecto
+func (s *UserService) userSearchSpec() listSpec[userSearchHit] {
+	listing := s.userListSpec()
+	spec := listSpec[userSearchHit]{
+		table:       "users",
+		sorts:       map[string]func(*userSearchHit) any{"rank": func(h *userSearchHit) any { return h.Rank }},
+		defaultSort: "-rank",
+		filters:     listing.filters,
+	}
+	for field, read := range listing.sorts {
+		spec.sorts[field] = func(h *userSearchHit) any { return read(&h.User) }
+	}
+	return spec
+}
+
+// searchSimilarity umbral de word_similarity (USER_SEARCH_SIMILARITY, entre 0 y 1)
+func searchSimilarity() string {
+	threshold, err := strconv.ParseFloat(utils.GetEnv("USER_SEARCH_SIMILARITY", ""), 64)
+	if err != nil || threshold <= 0 || threshold > 1 {
+		threshold = defaultSearchSimilarity
+	}
+	return strconv.FormatFloat(threshold, 'f', -1, 64)
+}
+
+// searchTerms palabras de la búsqueda (letras y dígitos, al menos dos caracteres) sin repetir
+func searchTerms(q string) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, term := range strings.FieldsFunc(q, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		if utf8.RuneCountInString(term) < 2 || seen[term] {
+			continue
+		}
+		seen[term] = true
+		terms = append(terms, term)
+	}
+	return terms
+}
+
+// prefixTSQuery tsquery que exige todas las palabras como prefijo ("ana:* & garc:*").
+// Los términos solo contienen letras y dígitos, así que no pueden alterar la sintaxis.
+func prefixTSQuery(terms []string) string {
+	parts := make([]string, len(terms))
+	for i, term := range terms {
+		parts[i] = term + ":*"
+	}
+	return strings.Join(parts, " & ")
+}
+
+// searchHighlighter devuelve una función que marca con <mark> las apariciones de los términos
+// (sin distinguir mayúsculas) y escapa el resto como HTML; ok=false si no hay ninguna
+func searchHighlighter(terms []string) func(value string) (string, bool) {
+	if len(terms) == 0 {
+		return func(string) (string, bool) { return "", false }
+	}
+
+	// Los términos más largos primero para que la alternativa marque la coincidencia completa
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = regexp.QuoteMeta(term)
+	}
+	sort.Slice(quoted, func(i, j int) bool { return len(quoted[i]) > len(quoted[j]) })
+	pattern := regexp.MustCompile("(?i)" + strings.Join(quoted, "|"))
+
+	return func(value string) (string, bool) {
+		matches := pattern.FindAllStringIndex(value, -1)
+		if len(matches) == 0 {
+			return "", false
+		}
+		var marked strings.Builder
+		last := 0
+		for _, match := range matches {
+			marked.WriteString(html.EscapeString(value[last:match[0]]))
+			marked.WriteString("<mark>" + html.EscapeString(value[match[0]:match[1]]) + "</mark>")
+			last = match[1]
+		}
+		marked.WriteString(html.EscapeString(value[last:]))
+		return marked.String(), true
+	}
+}
@@ -2,47 +2,96 @@ package services
 
 import (
 	"errors"
+	"io"
+	"net/mail"
 	"megabaseGo/internal/app/dto"
 	"megabaseGo/internal/database"
+	"megabaseGo/internal/database/encryption"
 	"megabaseGo/internal/models"
 	"megabaseGo/internal/utils"
+	"strconv"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
 )
 
 type UserService struct {
-	hasher utils.PasswordHasher
+	hasher    utils.PasswordHasher
+	audit     *AuditService
+	revisions *RevisionService
+	tenant    *Tenant
+	actor     *Actor
+	// precondition If-Match de la petición: las modificaciones exigen que la versión coincida
+	precondition *Precondition
 }
 
 // NewUserService crea una nueva instancia del servicio de usuarios
 func NewUserService() *UserService {
 	return &UserService{
-		hasher: utils.NewBcryptHasher(),
+		hasher:    utils.NewBcryptHasher(),
+		audit:     NewAuditService(),
+		revisions: NewRevisionService(),
 	}
 }
 
-// CreateUser crea un nuevo usuario
+// ForTenant devuelve una copia del servicio limitada a la organización del tenant:
+// solo ve a sus miembros y los roles se asignan en la membresía
+func (s *UserService) ForTenant(tenant *Tenant) *UserService {
+	scoped := *s
+	scoped.tenant = tenant
+	return &scoped
+}
+
+// ForActor devuelve una copia del servicio que atribuye sus cambios al actor en la auditoría
+func (s *UserService) ForActor(actor *Actor) *UserService {
+	scoped := *s
+	scoped.actor = actor
+	return &scoped
+}
+
+// IfMatch devuelve una copia del servicio que solo modifica o elimina usuarios en una de las
+// versiones indicadas; nil no impone ninguna
+func (s *UserService) IfMatch(precondition *Precondition) *UserService {
+	scoped := *s
+	scoped.precondition = precondition
+	return &scoped
+}
+
+// users consulta usuarios aplicando siempre el scope del tenant
+func (s *UserService) users() *gorm.DB {
+	return database.GetDB().Scopes(tenantUsers(s.tenant))
+}
+
+// CreateUser crea un nuevo usuario. Con tenant lo hace miembro de la organización con el rol indicado.
 func (s *UserService) CreateUser(req *dto.CreateUserRequest) (*dto.UserResponse, error) {
 	db := database.GetDB()
 
-	// Verificar que el rol existe
+	// Verificar que el rol existe (y es visible en la organización)
 	var role models.Role
-	if err := db.First(&role, req.RoleID).Error; err != nil {
+	if err := db.Scopes(tenantRoles(s.tenant)).First(&role, req.RoleID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("role not found")
 		}
 		return nil, err
 	}
 
-	// Verificar username único
+	// Normalizar identificadores
+	req.UserName = utils.NormalizeUserName(req.UserName)
+	req.Email = utils.NormalizeEmail(req.Email)
+	if err := validateUserName(req.UserName); err != nil {
+		return nil, err
+	}
+
+	// Verificar username único (sin distinguir mayúsculas)
 	var existingUser models.User
-	if err := db.Where("user_name = ?", req.UserName).First(&existingUser).Error; err == nil {
-		return nil, errors.New("username already exists")
+	if err := db.Scopes(byUserName(req.UserName)).First(&existingUser).Error; err == nil {
+		return nil, utils.NewConflictError("username already exists")
 	}
 
-	// Verificar email único
-	if err := db.Where("email = ?", req.Email).First(&existingUser).Error; err == nil {
-		return nil, errors.New("email already exists")
+	// Verificar email único (sin distinguir mayúsculas)
+	if err := db.Scopes(byEmail(req.Email)).First(&existingUser).Error; err == nil {
+		return nil, utils.NewConflictError("email already exists")
 	}
 
 	// Hash de la contraseña
@@ -57,88 +106,290 @@ func (s *UserService) CreateUser(req *dto.CreateUserRequest) (*dto.UserResponse,
 		isActive = *req.IsActive
 	}
 
+	// Dentro de una organización el rol elegido es el de la membresía; el de plataforma es el por defecto
+	platformRoleID := req.RoleID
+	if s.tenant != nil {
+		platformRole, err := defaultUserRole(db)
+		if err != nil {
+			return nil, err
+		}
+		platformRoleID = platformRole.ID
+	}
+
 	// Crear usuario
 	user := models.User{
 		Name:     req.Name,
 		UserName: req.UserName,
 		Email:    req.Email,
 		Password: hashedPassword,
-		RoleID:   req.RoleID,
+		RoleID:   platformRoleID,
 		IsActive: isActive,
 	}
 
-	// Guardar en BD
-	if err := db.Create(&user).Error; err != nil {
+	// Guardar en BD junto con su membresía
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		if s.tenant == nil {
+			if err := joinDefaultOrganization(tx, user.ID, req.RoleID); err != nil {
+				return err
+			}
+		} else if err := tx.Create(&models.OrganizationMembership{
+			OrganizationID: s.tenant.OrganizationID,
+			UserID:         user.ID,
+			RoleID:         req.RoleID,
+		}).Error; err != nil {
+			return err
+		}
+		return s.audit.Record(tx, s.actor, auditEvent{
+			Action:         models.AuditUserCreate,
+			TargetType:     models.AuditTargetUser,
+			TargetID:       user.ID,
+			OrganizationID: s.auditOrganization(),
+			Changes:        auditDiff(nil, userAuditState(&user, req.RoleID)),
+		})
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	// Cargar relación y devolver
-	if err := db.Preload("Role").First(&user, user.ID).Error; err != nil {
-		return nil, err
-	}
-
-	return s.toUserResponse(&user), nil
+	return s.GetUserByID(user.ID)
 }
 
-// GetUsers obtiene todos los usuarios con filtros opcionales
-func (s *UserService) GetUsers(includeInactive bool, roleID *uint) ([]dto.UserResponse, error) {
-	db := database.GetDB()
-	var users []models.User
-
-	query := db.Preload("Role")
+// GetUsers obtiene una página de usuarios con los filtros y el orden de params (ver userListSpec).
+// Sin include_inactive ni filtro is_active solo devuelve los activos.
+// trashed ("with" u "only") incluye los usuarios en la papelera.
+func (s *UserService) GetUsers(params *dto.ListQuery, includeInactive bool, trashed string) ([]dto.UserResponse, *dto.PageMeta, error) {
+	trashedFilter, err := trashedScope("users", trashed)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	if !includeInactive {
-		query = query.Where("is_active = ?", true)
+	query := s.users().Scopes(trashedFilter)
+	if !includeInactive && params.Filters["is_active"] == "" {
+		query = query.Where("users.is_active = ?", true)
 	}
 
-	if roleID != nil {
-		query = query.Where("role_id = ?", *roleID)
+	users, meta, err := paginate(query, s.userListSpec(), params, "Role")
+	if err != nil {
+		return nil, nil, err
 	}
 
-	if err := query.Find(&users).Error; err != nil {
-		return nil, err
+	if err := s.applyMembershipRoles(users); err != nil {
+		return nil, nil, err
 	}
 
-	var responses []dto.UserResponse
+	responses := make([]dto.UserResponse, 0, len(users))
 	for _, user := range users {
 		responses = append(responses, *s.toUserResponse(&user))
 	}
 
-	return responses, nil
+	return responses, meta, nil
+}
+
+// userListSpec campos de orden y filtros del listado de usuarios. Con tenant el filtro de rol se
+// aplica al rol de la membresía. Con el cifrado de campos activo el nombre y el email no se pueden
+// ordenar ni buscar por subcadena en la BD: el email solo admite la coincidencia exacta.
+func (s *UserService) userListSpec() listSpec[models.User] {
+	spec := listSpec[models.User]{
+		table: "users",
+		sorts: map[string]func(*models.User) any{
+			"id":            func(u *models.User) any { return u.ID },
+			"user_name":     func(u *models.User) any { return u.UserName },
+			"name":          func(u *models.User) any { return u.Name },
+			"email":         func(u *models.User) any { return u.Email },
+			"is_active":     func(u *models.User) any { return u.IsActive },
+			"created_at":    func(u *models.User) any { return u.CreatedAt },
+			"updated_at":    func(u *models.User) any { return u.UpdatedAt },
+			"last_login_at": func(u *models.User) any { return u.LastLoginAt },
+		},
+		defaultSort: "id",
+		filters: map[string]listFilter{
+			"name":         containsFilter("users.name"),
+			"email":        containsFilter("users.email"),
+			"user_name":    containsFilter("users.user_name"),
+			"is_active":    boolFilter("users.is_active", "is_active"),
+			"created_from": timeFilter("users.created_at", ">=", "created_from"),
+			"created_to":   timeFilter("users.created_at", "<=", "created_to"),
+			"filter":       rsqlFilter(s.userFilterFields()),
+			"role_id": func(db *gorm.DB, value string) (*gorm.DB, error) {
+				roleID, err := strconv.ParseUint(value, 10, 32)
+				if err != nil {
+					return nil, utils.NewBadRequestError("Invalid role_id")
+				}
+				if s.tenant != nil {
+					return db.Where("users.id IN (SELECT user_id FROM organization_memberships WHERE organization_id = ? AND role_id = ?)",
+						s.tenant.OrganizationID, roleID), nil
+				}
+				return db.Where("users.role_id = ?", roleID), nil
+			},
+		},
+	}
+
+	if encryption.Enabled() {
+		delete(spec.sorts, "name")
+		delete(spec.sorts, "email")
+		spec.filters["name"] = func(db *gorm.DB, value string) (*gorm.DB, error) {
+			return nil, utils.NewBadRequestError("name filter is not available while field encryption is enabled")
+		}
+		spec.filters["email"] = func(db *gorm.DB, value string) (*gorm.DB, error) {
+			return db.Scopes(byEmail(value)), nil
+		}
+	}
+	return spec
+}
+
+// userFilterFields campos admitidos en ?filter= para usuarios. role.* se refiere al rol del
+// usuario o, con tenant, al de su membresía en la organización.
+func (s *UserService) userFilterFields() map[string]rsqlField {
+	roleSubquery := "users.role_id IN (SELECT roles.id FROM roles WHERE {cond})"
+	var roleArgs []any
+	if s.tenant != nil {
+		roleSubquery = "users.id IN (SELECT organization_memberships.user_id FROM organization_memberships " +
+			"JOIN roles ON roles.id = organization_memberships.role_id WHERE organization_memberships.organization_id = ? AND {cond})"
+		roleArgs = []any{s.tenant.OrganizationID}
+	}
+	roleField := func(column, kind string) rsqlField {
+		return rsqlField{Column: column, Kind: kind, Subquery: roleSubquery, SubqueryArgs: roleArgs}
+	}
+
+	return map[string]rsqlField{
+		"id":                {Column: "users.id", Kind: "id"},
+		"name":              {Column: "users.name", Kind: "string", Encrypted: true},
+		"user_name":         {Column: "users.user_name", Kind: "string"},
+		"email":             {Column: "users.email", Kind: "string", Encrypted: true, Match: func(value string) (string, []any) { return emailCondition("users", value) }},
+		"is_active":         {Column: "users.is_active", Kind: "bool"},
+		"created_at":        {Column: "users.created_at", Kind: "date"},
+		"updated_at":        {Column: "users.updated_at", Kind: "date"},
+		"last_login_at":     {Column: "users.last_login_at", Kind: "date"},
+		"role_id":           roleField("roles.id", "id"),
+		"role.id":           roleField("roles.id", "id"),
+		"role.name":         roleField("roles.name", "string"),
+		"role.display_name": roleField("roles.display_name", "string"),
+	}
 }
 
 // GetUserByID obtiene un usuario por ID
 func (s *UserService) GetUserByID(id uint) (*dto.UserResponse, error) {
-	db := database.GetDB()
 	var user models.User
 
-	if err := db.Preload("Role").First(&user, id).Error; err != nil {
+	if err := s.users().Preload("Role").First(&user, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("user not found")
 		}
 		return nil, err
 	}
 
-	return s.toUserResponse(&user), nil
+	users := []models.User{user}
+	if err := s.applyMembershipRoles(users); err != nil {
+		return nil, err
+	}
+
+	return s.toUserResponse(&users[0]), nil
 }
 
-// UpdateUser actualiza un usuario existente
+// UpdateUser actualiza un usuario existente.
+// Con tenant un cambio de rol modifica la membresía, no el rol de plataforma, y los datos de
+// la cuenta solo se cambian si el usuario no pertenece a otras organizaciones.
 func (s *UserService) UpdateUser(id uint, req *dto.UpdateUserRequest) (*dto.UserResponse, error) {
+	return s.updateUser(id, req, nil)
+}
+
+// PatchUser aplica un merge patch o JSON Patch a la representación editable del usuario y guarda
+// el resultado como una actualización completa, validándolo antes igual que al crear
+func (s *UserService) PatchUser(id uint, patch *dto.Patch) (*dto.UserResponse, error) {
+	current, err := s.GetUserByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.precondition.check(current.Version); err != nil {
+		return nil, err
+	}
+
+	isActive := current.IsActive
+	document := dto.UserDocument{
+		Name:     current.Name,
+		UserName: current.UserName,
+		Email:    current.Email,
+		RoleID:   current.RoleID,
+		IsActive: &isActive,
+	}
+	var patched dto.UserDocument
+	if err := applyPatch(document, patch, &patched); err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSpace(patched.Name) == "" {
+		return nil, utils.NewBadRequestError("name is required")
+	}
+	if err := validateUserName(utils.NormalizeUserName(patched.UserName)); err != nil {
+		return nil, err
+	}
+	if address, err := mail.ParseAddress(patched.Email); err != nil || address.Address != patched.Email {
+		return nil, utils.NewBadRequestError("email must be a valid email address")
+	}
+	if patched.RoleID == 0 {
+		return nil, utils.NewBadRequestError("role_id is required")
+	}
+	if patched.IsActive == nil {
+		return nil, utils.NewBadRequestError("is_active is required")
+	}
+
+	req := &dto.UpdateUserRequest{
+		Name:     strings.TrimSpace(patched.Name),
+		UserName: patched.UserName,
+		Email:    patched.Email,
+		RoleID:   patched.RoleID,
+		IsActive: patched.IsActive,
+	}
+	if patched.Password != nil {
+		if len(*patched.Password) < 6 {
+			return nil, utils.NewBadRequestError("password must be at least 6 characters")
+		}
+		req.Password = *patched.Password
+	}
+	// El parche se calculó sobre la versión leída: solo se guarda si sigue siendo la actual
+	return s.IfMatch(&Precondition{Versions: []uint{current.Version}}).updateUser(id, req, nil)
+}
+
+// changesAccount indica si la actualización toca datos de la cuenta global (identidad,
+// credenciales o estado) y no solo el rol
+func changesAccount(user *models.User, req *dto.UpdateUserRequest) bool {
+	return (req.Name != "" && req.Name != user.Name) ||
+		(req.UserName != "" && req.UserName != user.UserName) ||
+		(req.Email != "" && req.Email != user.Email) ||
+		req.Password != "" ||
+		(req.IsActive != nil && *req.IsActive != user.IsActive)
+}
+
+// updateUser aplica la actualización; auditMetadata se añade al evento de auditoría
+func (s *UserService) updateUser(id uint, req *dto.UpdateUserRequest, auditMetadata map[string]interface{}) (*dto.UserResponse, error) {
 	db := database.GetDB()
 	var user models.User
 
 	// Obtener usuario existente
-	if err := db.First(&user, id).Error; err != nil {
+	if err := s.users().First(&user, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("user not found")
 		}
 		return nil, err
 	}
 
-	// Verificar rol si se está cambiando
-	if req.RoleID != 0 && req.RoleID != user.RoleID {
+	if err := s.precondition.check(user.Version); err != nil {
+		return nil, err
+	}
+
+	before, err := s.auditState(db, &user)
+	if err != nil {
+		return nil, err
+	}
+
+	// Verificar rol si se está cambiando (con tenant siempre: se compara con el rol de plataforma)
+	if req.RoleID != 0 && (s.tenant != nil || req.RoleID != user.RoleID) {
 		var role models.Role
-		if err := db.First(&role, req.RoleID).Error; err != nil {
+		if err := db.Scopes(tenantRoles(s.tenant)).First(&role, req.RoleID).Error; err != nil {
 			if errors.Is(err, gorm.ErrRecordNotFound) {
 				return nil, errors.New("role not found")
 			}
@@ -146,19 +397,39 @@ func (s *UserService) UpdateUser(id uint, req *dto.UpdateUserRequest) (*dto.User
 		}
 	}
 
+	// Normalizar identificadores
+	req.UserName = utils.NormalizeUserName(req.UserName)
+	req.Email = utils.NormalizeEmail(req.Email)
+
+	// La cuenta es global: con tenant solo se edita si la organización es la única del usuario;
+	// si pertenece a otras, aquí solo se cambia su rol en la membresía
+	if s.tenant != nil && changesAccount(&user, req) {
+		var others int64
+		if err := db.Model(&models.OrganizationMembership{}).
+			Where("user_id = ? AND organization_id <> ?", user.ID, s.tenant.OrganizationID).Count(&others).Error; err != nil {
+			return nil, err
+		}
+		if others > 0 {
+			return nil, utils.NewForbiddenError("user belongs to other organizations; only the organization role can be changed here")
+		}
+	}
+
 	// Verificar username único si se está cambiando
 	if req.UserName != "" && req.UserName != user.UserName {
+		if err := validateUserName(req.UserName); err != nil {
+			return nil, err
+		}
 		var existing models.User
-		if err := db.Where("user_name = ? AND id != ?", req.UserName, id).First(&existing).Error; err == nil {
-			return nil, errors.New("username already exists")
+		if err := db.Scopes(byUserName(req.UserName)).Where("id != ?", id).First(&existing).Error; err == nil {
+			return nil, utils.NewConflictError("username already exists")
 		}
 	}
 
 	// Verificar email único si se está cambiando
 	if req.Email != "" && req.Email != user.Email {
 		var existing models.User
-		if err := db.Where("email = ? AND id != ?", req.Email, id).First(&existing).Error; err == nil {
-			return nil, errors.New("email already exists")
+		if err := db.Scopes(byEmail(req.Email)).Where("id != ?", id).First(&existing).Error; err == nil {
+			return nil, utils.NewConflictError("email already exists")
 		}
 	}
 
@@ -172,7 +443,7 @@ func (s *UserService) UpdateUser(id uint, req *dto.UpdateUserRequest) (*dto.User
 	if req.Email != "" {
 		user.Email = req.Email
 	}
-	if req.RoleID != 0 {
+	if req.RoleID != 0 && s.tenant == nil {
 		user.RoleID = req.RoleID
 	}
 	if req.IsActive != nil {
@@ -188,39 +459,421 @@ func (s *UserService) UpdateUser(id uint, req *dto.UpdateUserRequest) (*dto.User
 		user.Password = hashedPassword
 	}
 
-	// Guardar cambios
-	if err := db.Save(&user).Error; err != nil {
-		return nil, err
+	after := userAuditState(&user, before["role_id"].(uint))
+	if req.RoleID != 0 {
+		after["role_id"] = req.RoleID
+	}
+	changes := auditDiff(before, after)
+	if req.Password != "" {
+		changes["password"] = auditChange{Old: auditRedacted, New: auditRedacted}
 	}
 
-	// Cargar relación actualizada
-	if err := db.Preload("Role").First(&user, user.ID).Error; err != nil {
+	// Guardar cambios
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := updateVersioned(tx, &user, &user.Version); err != nil {
+			return err
+		}
+		if req.RoleID != 0 && s.tenant != nil {
+			if err := tx.Model(&models.OrganizationMembership{}).
+				Where("organization_id = ? AND user_id = ?", s.tenant.OrganizationID, user.ID).
+				Update("role_id", req.RoleID).Error; err != nil {
+				return err
+			}
+		}
+		if len(changes) == 0 {
+			return nil
+		}
+		return s.audit.Record(tx, s.actor, auditEvent{
+			Action:         models.AuditUserUpdate,
+			TargetType:     models.AuditTargetUser,
+			TargetID:       user.ID,
+			OrganizationID: s.auditOrganization(),
+			Changes:        changes,
+			Metadata:       auditMetadata,
+		})
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	return s.toUserResponse(&user), nil
+	// Cargar relación actualizada
+	return s.GetUserByID(user.ID)
 }
 
-// DeleteUser elimina un usuario (soft delete)
+// DeleteUser elimina un usuario (soft delete).
+// Con tenant lo saca de la organización si pertenece a otras; si no, lo envía a la papelera
+// conservando la membresía para que la organización pueda restaurarlo.
 func (s *UserService) DeleteUser(id uint) error {
 	db := database.GetDB()
 
 	// Verificar que el usuario existe
 	var user models.User
-	if err := db.First(&user, id).Error; err != nil {
+	if err := s.users().First(&user, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return errors.New("user not found")
 		}
 		return err
 	}
 
-	// Soft delete
-	return db.Delete(&user).Error
+	if err := s.precondition.check(user.Version); err != nil {
+		return err
+	}
+
+	before, err := s.auditState(db, &user)
+	if err != nil {
+		return err
+	}
+	event := auditEvent{
+		Action:         models.AuditUserDelete,
+		TargetType:     models.AuditTargetUser,
+		TargetID:       user.ID,
+		OrganizationID: s.auditOrganization(),
+		Changes:        auditDiff(before, nil),
+	}
+
+	if s.tenant == nil {
+		return db.Transaction(func(tx *gorm.DB) error {
+			// Soft delete
+			if err := deleteVersioned(tx, &user, user.Version); err != nil {
+				return err
+			}
+			return s.audit.Record(tx, s.actor, event)
+		})
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := s.audit.Record(tx, s.actor, event); err != nil {
+			return err
+		}
+
+		var others int64
+		if err := tx.Model(&models.OrganizationMembership{}).
+			Where("user_id = ? AND organization_id <> ?", user.ID, s.tenant.OrganizationID).Count(&others).Error; err != nil {
+			return err
+		}
+		if others > 0 {
+			return tx.Where("organization_id = ? AND user_id = ?", s.tenant.OrganizationID, user.ID).
+				Delete(&models.OrganizationMembership{}).Error
+		}
+
+		// Soft delete
+		return deleteVersioned(tx, &user, user.Version)
+	})
+}
+
+// RestoreUser saca un usuario de la papelera. Falla si su username o email los usa ya
+// otro usuario o si su rol de plataforma también está eliminado.
+func (s *UserService) RestoreUser(id uint) (*dto.UserResponse, error) {
+	db := database.GetDB()
+
+	var user models.User
+	if err := s.users().Unscoped().Where("users.deleted_at IS NOT NULL").First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("Deleted user")
+		}
+		return nil, err
+	}
+
+	var existingUser models.User
+	if err := db.Scopes(byUserName(user.UserName)).First(&existingUser).Error; err == nil {
+		return nil, utils.NewConflictError("username already exists")
+	}
+	if err := db.Scopes(byEmail(user.Email)).First(&existingUser).Error; err == nil {
+		return nil, utils.NewConflictError("email already exists")
+	}
+
+	var role models.Role
+	if err := db.Select("id").First(&role, user.RoleID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewConflictError("cannot restore user: its role is deleted")
+		}
+		return nil, err
+	}
+
+	after, err := s.auditState(db, &user)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&user).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		return s.audit.Record(tx, s.actor, auditEvent{
+			Action:         models.AuditUserRestore,
+			TargetType:     models.AuditTargetUser,
+			TargetID:       user.ID,
+			OrganizationID: s.auditOrganization(),
+			Changes:        auditDiff(nil, after),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetUserByID(user.ID)
+}
+
+// PurgeUser elimina definitivamente un usuario, esté o no en la papelera, junto con sus
+// membresías, sesiones, identidades externas, tokens pendientes y revisiones. Los intentos
+// de login se conservan sin el usuario. Con tenant solo alcanza a los usuarios que no
+// pertenecen a ninguna otra organización.
+func (s *UserService) PurgeUser(id uint) error {
+	db := database.GetDB()
+
+	var user models.User
+	if err := s.users().Unscoped().First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.NewNotFoundError("User")
+		}
+		return err
+	}
+
+	if s.tenant != nil {
+		var others int64
+		if err := db.Model(&models.OrganizationMembership{}).
+			Where("user_id = ? AND organization_id <> ?", user.ID, s.tenant.OrganizationID).Count(&others).Error; err != nil {
+			return err
+		}
+		if others > 0 {
+			return utils.NewConflictError("cannot purge user: it belongs to other organizations")
+		}
+	}
+
+	before, err := s.auditState(db, &user)
+	if err != nil {
+		return err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for _, dependent := range []interface{}{
+			&models.OrganizationMembership{},
+			&models.Session{},
+			&models.UserIdentity{},
+			&models.MagicLinkToken{},
+			&models.DeviceAuthorization{},
+			&models.EmailChangeRequest{},
+			&models.AccountDeletion{},
+		} {
+			if err := tx.Unscoped().Where("user_id = ?", user.ID).Delete(dependent).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&models.LoginAttempt{}).Where("user_id = ?", user.ID).Update("user_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("resource_type = ? AND resource_id = ?", models.RevisionUser, user.ID).
+			Delete(&models.Revision{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Delete(&user).Error; err != nil {
+			return err
+		}
+		return s.audit.Record(tx, s.actor, auditEvent{
+			Action:         models.AuditUserPurge,
+			TargetType:     models.AuditTargetUser,
+			TargetID:       user.ID,
+			OrganizationID: s.auditOrganization(),
+			Changes:        auditDiff(before, nil),
+		})
+	})
+	if err != nil {
+		return err
+	}
+	deleteAvatar(user.AvatarKey)
+	return nil
+}
+
+// UpdateAvatar sustituye el avatar del usuario por la imagen subida, redimensionada a los
+// tamaños estándar. Las versiones anteriores se borran del almacenamiento tras guardar.
+func (s *UserService) UpdateAvatar(id uint, file io.Reader) (*dto.UserResponse, error) {
+	var user models.User
+	if err := s.users().First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("User")
+		}
+		return nil, err
+	}
+
+	versions, ext, err := processAvatar(file)
+	if err != nil {
+		return nil, err
+	}
+	avatarKey, err := storeAvatar(s.avatarPrefix(user.ID), versions, ext)
+	if err != nil {
+		return nil, err
+	}
+
+	previousKey := user.AvatarKey
+	if err := s.saveAvatar(&user, avatarKey, ""); err != nil {
+		deleteAvatar(avatarKey)
+		return nil, err
+	}
+	deleteAvatar(previousKey)
+	return s.GetUserByID(user.ID)
+}
+
+// RemoveAvatar quita el avatar del usuario, subido o externo
+func (s *UserService) RemoveAvatar(id uint) (*dto.UserResponse, error) {
+	var user models.User
+	if err := s.users().First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("User")
+		}
+		return nil, err
+	}
+
+	previousKey := user.AvatarKey
+	if err := s.saveAvatar(&user, "", ""); err != nil {
+		return nil, err
+	}
+	deleteAvatar(previousKey)
+	return s.GetUserByID(user.ID)
+}
+
+// saveAvatar guarda la imagen y la URL externa del avatar y audita el cambio
+func (s *UserService) saveAvatar(user *models.User, avatarKey, avatarURL string) error {
+	changes := auditDiff(
+		map[string]interface{}{"avatar_key": user.AvatarKey, "avatar_url": user.AvatarURL},
+		map[string]interface{}{"avatar_key": avatarKey, "avatar_url": avatarURL},
+	)
+	if len(changes) == 0 {
+		return nil
+	}
+	user.AvatarKey = avatarKey
+	user.AvatarURL = avatarURL
+
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Select("avatar_key", "avatar_url").Updates(user).Error; err != nil {
+			return err
+		}
+		return s.audit.Record(tx, s.actor, auditEvent{
+			Action:         models.AuditUserUpdate,
+			TargetType:     models.AuditTargetUser,
+			TargetID:       user.ID,
+			OrganizationID: s.auditOrganization(),
+			Changes:        changes,
+		})
+	})
+}
+
+// avatarPrefix prefijo de las claves de los avatares del usuario
+func (s *UserService) avatarPrefix(id uint) string {
+	return "avatars/" + strconv.FormatUint(uint64(id), 10)
+}
+
+// GetRevisions obtiene el historial de revisiones de un usuario; con at, solo la vigente en ese momento
+func (s *UserService) GetRevisions(id uint, at *time.Time) ([]dto.RevisionResponse, error) {
+	if err := s.ensureVisible(id); err != nil {
+		return nil, err
+	}
+	return s.revisions.List(database.GetDB(), models.RevisionUser, id, at)
+}
+
+// GetRevision obtiene una revisión de un usuario con sus diferencias
+func (s *UserService) GetRevision(id, number uint) (*dto.RevisionResponse, error) {
+	if err := s.ensureVisible(id); err != nil {
+		return nil, err
+	}
+	return s.revisions.Get(database.GetDB(), models.RevisionUser, id, number)
+}
+
+// RestoreRevision devuelve un usuario al estado de una revisión anterior con las mismas
+// validaciones que UpdateUser; el guardado crea a su vez una revisión nueva.
+// La contraseña no forma parte del historial y no cambia. Con tenant el rol no se restaura:
+// la revisión guarda el rol de plataforma, no el de la membresía.
+func (s *UserService) RestoreRevision(id, number uint) (*dto.UserResponse, error) {
+	if err := s.ensureVisible(id); err != nil {
+		return nil, err
+	}
+	snapshot, err := s.revisions.Snapshot(database.GetDB(), models.RevisionUser, id, number)
+	if err != nil {
+		return nil, err
+	}
+
+	isActive := snapshotBool(snapshot, "is_active")
+	req := &dto.UpdateUserRequest{
+		Name:     snapshotString(snapshot, "name"),
+		UserName: snapshotString(snapshot, "user_name"),
+		Email:    snapshotString(snapshot, "email"),
+		IsActive: &isActive,
+	}
+	if s.tenant == nil {
+		req.RoleID = snapshotUint(snapshot, "role_id")
+	}
+	return s.updateUser(id, req, map[string]interface{}{"restored_revision": number})
+}
+
+// ensureVisible comprueba que el usuario existe y es visible para el tenant
+func (s *UserService) ensureVisible(id uint) error {
+	var user models.User
+	if err := s.users().Select("users.id").First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.NewNotFoundError("User")
+		}
+		return err
+	}
+	return nil
+}
+
+// auditState estado auditado de un usuario con su rol efectivo en el tenant
+func (s *UserService) auditState(db *gorm.DB, user *models.User) (map[string]interface{}, error) {
+	roleID := user.RoleID
+	if s.tenant != nil {
+		var membership models.OrganizationMembership
+		err := db.Where("organization_id = ? AND user_id = ?", s.tenant.OrganizationID, user.ID).First(&membership).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		if err == nil {
+			roleID = membership.RoleID
+		}
+	}
+	return userAuditState(user, roleID), nil
+}
+
+// auditOrganization organización en la que se registran los eventos (nil en plataforma)
+func (s *UserService) auditOrganization() *uint {
+	if s.tenant == nil {
+		return nil
+	}
+	return &s.tenant.OrganizationID
+}
+
+// applyMembershipRoles sustituye, con tenant, el rol de plataforma por el rol de la membresía
+func (s *UserService) applyMembershipRoles(users []models.User) error {
+	if s.tenant == nil || len(users) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, 0, len(users))
+	for _, user := range users {
+		ids = append(ids, user.ID)
+	}
+
+	var memberships []models.OrganizationMembership
+	if err := database.GetDB().Preload("Role").
+		Where("organization_id = ? AND user_id IN ?", s.tenant.OrganizationID, ids).
+		Find(&memberships).Error; err != nil {
+		return err
+	}
+
+	byUser := make(map[uint]*models.OrganizationMembership, len(memberships))
+	for i := range memberships {
+		byUser[memberships[i].UserID] = &memberships[i]
+	}
+	for i := range users {
+		if membership, ok := byUser[users[i].ID]; ok {
+			users[i].RoleID = membership.RoleID
+			users[i].Role = membership.Role
+		}
+	}
+	return nil
 }
 
 // toUserResponse convierte un modelo User a UserResponse
 func (s *UserService) toUserResponse(user *models.User) *dto.UserResponse {
-	return &dto.UserResponse{
+	response := &dto.UserResponse{
 		ID:          user.ID,
 		Name:        user.Name,
 		UserName:    user.UserName,
@@ -228,8 +881,12 @@ func (s *UserService) toUserResponse(user *models.User) *dto.UserResponse {
 		RoleID:      user.RoleID,
 		Role:        user.Role,
 		IsActive:    user.IsActive,
+		Version:     user.Version,
 		LastLoginAt: user.LastLoginAt,
 		CreatedAt:   user.CreatedAt,
 		UpdatedAt:   user.UpdatedAt,
+		DeletedAt:   deletedAt(user.DeletedAt),
 	}
+	response.AvatarURL, response.Avatars = avatarURLs(user)
+	return response
 }
\ No newline at end of file
@@ -3,6 +3,7 @@ package database
 import (
 	"log"
 	"megabaseGo/internal/config"
+	"megabaseGo/internal/database/encryption"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -21,6 +22,14 @@ func InitDB(cfg *config.Config) (*gorm.DB, error) {
 
 	DB = db
 	log.Println("Conexión a PostgreSQL establecida exitosamente")
+
+	// Claves del cifrado de campos; un error de configuración impide arrancar
+	if err := encryption.Configure(db); err != nil {
+		return nil, err
+	}
+	if encryption.Enabled() {
+		log.Println("Cifrado de campos activado")
+	}
 	return db, nil
 }
 
@@ -0,0 +1,243 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"megabaseGo/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// Prefijo de los valores cifrados: enc:v1:<id de la clave de datos>:<base64(nonce || texto cifrado)>.
+// Un valor sin prefijo es texto plano anterior al cifrado y se devuelve tal cual.
+const ciphertextPrefix = "enc:v1:"
+
+var (
+	// ErrNotConfigured hay valores cifrados pero no FIELD_ENCRYPTION_KEKS para descifrarlos
+	ErrNotConfigured = errors.New("field encryption is not configured")
+	// ErrUnknownKey el valor está cifrado con una clave de datos que no existe o cuya KEK no está configurada
+	ErrUnknownKey = errors.New("unknown data encryption key")
+)
+
+// Estado global: las claves se configuran una vez al abrir la conexión (database.InitDB)
+var (
+	mu         sync.RWMutex
+	db         *gorm.DB
+	keks       map[string][]byte
+	activeKEK  string
+	blindKey   []byte
+	dataKeys   map[uint]*dataKey
+	activeDEK  *dataKey
+	keysLoaded bool
+)
+
+// dataKey clave de datos (DEK) ya desenvuelta en memoria
+type dataKey struct {
+	id   uint
+	aead cipher.AEAD
+}
+
+// Configure lee las claves del entorno y guarda la conexión con la que se cargan las claves de datos.
+//
+//	FIELD_ENCRYPTION_KEKS        id:base64,id:base64  claves de cifrado de claves (32 bytes)
+//	FIELD_ENCRYPTION_ACTIVE_KEK  id de la KEK con la que se envuelven las claves de datos nuevas
+//	FIELD_BLIND_INDEX_KEY        base64 (32 bytes) para los índices ciegos
+//
+// Sin FIELD_ENCRYPTION_KEKS el cifrado queda desactivado y los campos se guardan en claro.
+func Configure(conn *gorm.DB) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	db = conn
+	keks = map[string][]byte{}
+	activeKEK = ""
+	blindKey = nil
+	dataKeys = map[uint]*dataKey{}
+	activeDEK = nil
+	keysLoaded = false
+
+	raw := strings.TrimSpace(utils.GetEnv("FIELD_ENCRYPTION_KEKS", ""))
+	if raw == "" {
+		return nil
+	}
+	for _, entry := range strings.Split(raw, ",") {
+		id, encoded, found := strings.Cut(strings.TrimSpace(entry), ":")
+		if !found || id == "" {
+			return fmt.Errorf("invalid FIELD_ENCRYPTION_KEKS entry %q: expected id:base64", entry)
+		}
+		key, err := decodeKey(encoded)
+		if err != nil {
+			return fmt.Errorf("invalid key %q in FIELD_ENCRYPTION_KEKS: %w", id, err)
+		}
+		keks[id] = key
+	}
+
+	activeKEK = utils.GetEnv("FIELD_ENCRYPTION_ACTIVE_KEK", "")
+	if _, ok := keks[activeKEK]; !ok {
+		return fmt.Errorf("FIELD_ENCRYPTION_ACTIVE_KEK %q is not one of FIELD_ENCRYPTION_KEKS", activeKEK)
+	}
+
+	key, err := decodeKey(utils.GetEnv("FIELD_BLIND_INDEX_KEY", ""))
+	if err != nil {
+		return fmt.Errorf("invalid FIELD_BLIND_INDEX_KEY: %w", err)
+	}
+	blindKey = key
+	return nil
+}
+
+// Enabled indica si los campos marcados se cifran al guardarlos
+func Enabled() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return len(keks) > 0
+}
+
+// BlindIndex calcula el índice ciego de un valor ya normalizado: un HMAC determinista que
+// permite buscar por igualdad sin descifrar. purpose separa los índices de campos distintos.
+// Devuelve "" con el cifrado desactivado o con un valor vacío.
+func BlindIndex(purpose, value string) string {
+	mu.RLock()
+	key := blindKey
+	mu.RUnlock()
+	if key == nil || value == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(purpose))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Encrypt cifra un valor con la clave de datos activa. field se autentica junto al valor
+// (AAD), así que un valor copiado a otra columna no se descifra.
+func Encrypt(field, plaintext string) (string, error) {
+	if plaintext == "" || !Enabled() {
+		return plaintext, nil
+	}
+	key, err := activeDataKey()
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, key.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := key.aead.Seal(nonce, nonce, []byte(plaintext), []byte(field))
+	return ciphertextPrefix + strconv.FormatUint(uint64(key.id), 10) + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt descifra un valor de Encrypt; los valores en claro se devuelven sin cambios
+func Decrypt(field, value string) (string, error) {
+	keyID, payload, encrypted, err := parseCiphertext(value)
+	if err != nil || !encrypted {
+		return value, err
+	}
+	if !Enabled() {
+		return "", ErrNotConfigured
+	}
+	key, err := dataKeyByID(keyID)
+	if err != nil {
+		return "", err
+	}
+
+	if len(payload) < key.aead.NonceSize() {
+		return "", errors.New("encrypted value is truncated")
+	}
+	nonce, sealed := payload[:key.aead.NonceSize()], payload[key.aead.NonceSize():]
+	plaintext, err := key.aead.Open(nil, nonce, sealed, []byte(field))
+	if err != nil {
+		return "", fmt.Errorf("could not decrypt %s: %w", field, err)
+	}
+	return string(plaintext), nil
+}
+
+// SealFields cifra los valores de texto de fields en values: copias de columnas cifradas guardadas
+// en otra tabla (instantáneas, auditoría). scope entra en el AAD junto al campo, así que el valor
+// solo se descifra en ese mismo contexto. Con el cifrado desactivado no cambia nada.
+func SealFields(scope string, values map[string]interface{}, fields []string) error {
+	for _, field := range fields {
+		plaintext, ok := values[field].(string)
+		if !ok {
+			continue
+		}
+		sealed, err := Encrypt(scope+"."+field, plaintext)
+		if err != nil {
+			return err
+		}
+		values[field] = sealed
+	}
+	return nil
+}
+
+// OpenFields descifra los valores cifrados con SealFields; los que siguen en claro no cambian
+func OpenFields(scope string, values map[string]interface{}, fields []string) error {
+	for _, field := range fields {
+		value, ok := values[field].(string)
+		if !ok {
+			continue
+		}
+		plaintext, err := Decrypt(scope+"."+field, value)
+		if err != nil {
+			return err
+		}
+		values[field] = plaintext
+	}
+	return nil
+}
+
+// IsEncrypted indica si un valor almacenado está cifrado y con qué clave de datos
+func IsEncrypted(value string) (uint, bool) {
+	keyID, _, encrypted, err := parseCiphertext(value)
+	return keyID, encrypted && err == nil
+}
+
+func parseCiphertext(value string) (uint, []byte, bool, error) {
+	if !strings.HasPrefix(value, ciphertextPrefix) {
+		return 0, nil, false, nil
+	}
+	id, encoded, found := strings.Cut(strings.TrimPrefix(value, ciphertextPrefix), ":")
+	if !found {
+		return 0, nil, true, errors.New("malformed encrypted value")
+	}
+	keyID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return 0, nil, true, errors.New("malformed encrypted value")
+	}
+	payload, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return 0, nil, true, errors.New("malformed encrypted value")
+	}
+	return uint(keyID), payload, true, nil
+}
+
+func decodeKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, errors.New("not valid base64")
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("must be 32 bytes, got %d", len(key))
+	}
+	return key, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,299 @@
+package encryption
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Clave del advisory lock que serializa la creación y rotación de claves de datos
+const dataKeyLockKey = 7_301_043
+
+// DataKey clave de datos (DEK) envuelta con una KEK del entorno. Los valores se cifran con la
+// DEK activa; rotar la DEK solo afecta a los valores nuevos (o re-cifrados) y rotar la KEK
+// solo vuelve a envolver las DEK, sin tocar los datos.
+type DataKey struct {
+	ID         uint       `gorm:"primarykey" json:"id"`
+	KEKID      string     `gorm:"column:kek_id;size:100;not null" json:"kek_id"`
+	WrappedKey string     `gorm:"size:255;not null" json:"-"`
+	Active     bool       `gorm:"not null;default:false" json:"active"`
+	CreatedAt  time.Time  `gorm:"not null" json:"created_at"`
+	RetiredAt  *time.Time `json:"retired_at"`
+}
+
+// TableName las claves viven siempre en el esquema público, sea cual sea el search_path
+func (DataKey) TableName() string {
+	return "public.encryption_keys"
+}
+
+// Keys devuelve las claves de datos, de la más reciente a la más antigua
+func Keys() ([]DataKey, error) {
+	if !Enabled() {
+		return nil, ErrNotConfigured
+	}
+	var keys []DataKey
+	err := conn().Order("id DESC").Find(&keys).Error
+	return keys, err
+}
+
+// RotateDataKey crea una clave de datos nueva y la marca como activa; las anteriores se retiran
+// pero se conservan para descifrar los valores existentes hasta que se re-cifren
+func RotateDataKey() (*DataKey, error) {
+	if !Enabled() {
+		return nil, ErrNotConfigured
+	}
+	var created *DataKey
+	err := conn().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", dataKeyLockKey).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&DataKey{}).Where("active").Updates(map[string]interface{}{
+			"active":     false,
+			"retired_at": time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+		key, err := insertDataKey(tx)
+		created = key
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, reload()
+}
+
+// RewrapDataKeys vuelve a envolver con la KEK activa las claves de datos envueltas con otra.
+// Después, la KEK anterior se puede quitar de FIELD_ENCRYPTION_KEKS.
+func RewrapDataKeys() (int, error) {
+	if !Enabled() {
+		return 0, ErrNotConfigured
+	}
+	mu.RLock()
+	active := activeKEK
+	mu.RUnlock()
+
+	rewrapped := 0
+	err := conn().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", dataKeyLockKey).Error; err != nil {
+			return err
+		}
+		var keys []DataKey
+		if err := tx.Where("kek_id <> ?", active).Find(&keys).Error; err != nil {
+			return err
+		}
+		for i := range keys {
+			raw, err := unwrap(&keys[i])
+			if err != nil {
+				return err
+			}
+			wrapped, err := wrap(active, raw)
+			if err != nil {
+				return err
+			}
+			if err := tx.Model(&keys[i]).Updates(map[string]interface{}{
+				"kek_id":      active,
+				"wrapped_key": wrapped,
+			}).Error; err != nil {
+				return err
+			}
+			rewrapped++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return rewrapped, reload()
+}
+
+// activeDataKey devuelve la clave de datos activa; si todavía no hay ninguna la crea
+func activeDataKey() (*dataKey, error) {
+	mu.RLock()
+	key, loaded := activeDEK, keysLoaded
+	mu.RUnlock()
+	if key != nil {
+		return key, nil
+	}
+	if !loaded {
+		if err := reload(); err != nil {
+			return nil, err
+		}
+		mu.RLock()
+		key = activeDEK
+		mu.RUnlock()
+		if key != nil {
+			return key, nil
+		}
+	}
+
+	err := conn().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", dataKeyLockKey).Error; err != nil {
+			return err
+		}
+		// Otro proceso puede haberla creado mientras se esperaba el lock
+		var existing int64
+		if err := tx.Model(&DataKey{}).Where("active").Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return nil
+		}
+		_, err := insertDataKey(tx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := reload(); err != nil {
+		return nil, err
+	}
+
+	mu.RLock()
+	defer mu.RUnlock()
+	if activeDEK == nil {
+		return nil, errors.New("no active data encryption key")
+	}
+	return activeDEK, nil
+}
+
+// dataKeyByID devuelve una clave de datos; si no está en memoria (p. ej. la rotó otro proceso) recarga
+func dataKeyByID(id uint) (*dataKey, error) {
+	mu.RLock()
+	key, ok := dataKeys[id]
+	mu.RUnlock()
+	if ok {
+		return key, nil
+	}
+	if err := reload(); err != nil {
+		return nil, err
+	}
+
+	mu.RLock()
+	defer mu.RUnlock()
+	if key, ok := dataKeys[id]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w %d", ErrUnknownKey, id)
+}
+
+// reload carga y desenvuelve todas las claves de datos. Las envueltas con una KEK que ya no
+// está configurada se omiten: sus valores darán ErrUnknownKey.
+func reload() error {
+	var keys []DataKey
+	if err := conn().Order("id").Find(&keys).Error; err != nil {
+		return err
+	}
+
+	loaded := make(map[uint]*dataKey, len(keys))
+	var active *dataKey
+	for i := range keys {
+		raw, err := unwrap(&keys[i])
+		if err != nil {
+			if errors.Is(err, ErrUnknownKey) {
+				continue
+			}
+			return err
+		}
+		aead, err := newAEAD(raw)
+		if err != nil {
+			return err
+		}
+		loaded[keys[i].ID] = &dataKey{id: keys[i].ID, aead: aead}
+		if keys[i].Active {
+			active = loaded[keys[i].ID]
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	dataKeys = loaded
+	activeDEK = active
+	keysLoaded = true
+	return nil
+}
+
+func insertDataKey(tx *gorm.DB) (*DataKey, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+	mu.RLock()
+	kekID := activeKEK
+	mu.RUnlock()
+
+	wrapped, err := wrap(kekID, raw)
+	if err != nil {
+		return nil, err
+	}
+	key := &DataKey{KEKID: kekID, WrappedKey: wrapped, Active: true}
+	if err := tx.Create(key).Error; err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// wrap cifra una clave de datos con la KEK indicada; el id de la KEK se autentica como AAD
+func wrap(kekID string, raw []byte) (string, error) {
+	mu.RLock()
+	kek, ok := keks[kekID]
+	mu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("%w: KEK %q is not configured", ErrUnknownKey, kekID)
+	}
+	aead, err := newAEAD(kek)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, raw, []byte("dek:"+kekID))), nil
+}
+
+func unwrap(key *DataKey) ([]byte, error) {
+	mu.RLock()
+	kek, ok := keks[key.KEKID]
+	mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: KEK %q is not configured", ErrUnknownKey, key.KEKID)
+	}
+	aead, err := newAEAD(kek)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(key.WrappedKey)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("data key %d is malformed", key.ID)
+	}
+	raw, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte("dek:"+key.KEKID))
+	if err != nil {
+		return nil, fmt.Errorf("could not unwrap data key %d with KEK %q: %w", key.ID, key.KEKID, err)
+	}
+	return raw, nil
+}
+
+// conn conexión para las claves, en una sesión nueva para no heredar la transacción de la
+// consulta que está cifrando o descifrando
+func conn() *gorm.DB {
+	mu.RLock()
+	defer mu.RUnlock()
+	return db.Session(&gorm.Session{NewDB: true})
+}
+
+// ActiveKeyID id de la clave de datos con la que se cifran los valores nuevos (la crea si no existe)
+func ActiveKeyID() (uint, error) {
+	if !Enabled() {
+		return 0, ErrNotConfigured
+	}
+	key, err := activeDataKey()
+	if err != nil {
+		return 0, err
+	}
+	return key.id, nil
+}
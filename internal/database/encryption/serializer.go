@@ -0,0 +1,50 @@
+package encryption
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm/schema"
+)
+
+// SerializerName nombre del serializador de GORM para los campos cifrados: gorm:"serializer:encrypted"
+const SerializerName = "encrypted"
+
+func init() {
+	schema.RegisterSerializer(SerializerName, fieldSerializer{})
+}
+
+// fieldSerializer cifra campos string al escribirlos y los descifra al leerlos. La columna de la
+// tabla autentica el valor, así que el mismo texto cifrado no vale en otra columna.
+type fieldSerializer struct{}
+
+// Scan implementa schema.SerializerInterface
+func (fieldSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var stored string
+	switch value := dbValue.(type) {
+	case nil:
+	case string:
+		stored = value
+	case []byte:
+		stored = string(value)
+	default:
+		return fmt.Errorf("encrypted field %s: unsupported database value %T", field.Name, dbValue)
+	}
+
+	plaintext, err := Decrypt(field.DBName, stored)
+	if err != nil {
+		return err
+	}
+	field.ReflectValueOf(ctx, dst).SetString(plaintext)
+	return nil
+}
+
+// Value implementa schema.SerializerValuerInterface
+func (fieldSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	plaintext, ok := fieldValue.(string)
+	if !ok {
+		return nil, fmt.Errorf("encrypted field %s must be a string, got %T", field.Name, fieldValue)
+	}
+	return Encrypt(field.DBName, plaintext)
+}
@@ -0,0 +1,16 @@
+package migrations
+
+// AllMigrations contiene las migraciones SQL en orden de aplicación
+var AllMigrations = []Migration{
+	{ID: "20250101_case_insensitive_user_identifiers", Up: caseInsensitiveUserIdentifiers},
+	{ID: "20250301_drop_user_remember_token", Up: dropRememberToken},
+	{ID: "20250315_default_organization", Up: defaultOrganization},
+	{ID: "20250401_audit_logs_append_only", Up: auditLogsAppendOnly},
+	{ID: "20250415_initial_revisions", Up: initialRevisions},
+	{ID: "20250501_partial_unique_indexes", Up: partialUniqueIndexes},
+	{ID: "20250515_field_encryption", Up: fieldEncryption},
+	{ID: "20250601_user_search", Up: userSearch},
+	{ID: "20250615_row_versions", Up: rowVersions},
+	{ID: "20250701_device_user_code_pending", Up: deviceUserCodePending},
+	// Añade aquí tus nuevas migraciones
+}
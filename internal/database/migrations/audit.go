@@ -0,0 +1,27 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// auditLogsAppendOnly convierte audit_logs en una tabla de solo inserción:
+// cualquier UPDATE, DELETE o TRUNCATE falla, también desde fuera de la aplicación
+func auditLogsAppendOnly(tx *gorm.DB) error {
+	statements := []string{
+		`CREATE OR REPLACE FUNCTION audit_logs_append_only() RETURNS trigger AS $$
+		BEGIN
+			RAISE EXCEPTION 'audit_logs is append-only';
+		END;
+		$$ LANGUAGE plpgsql`,
+		"DROP TRIGGER IF EXISTS audit_logs_no_modify ON audit_logs",
+		`CREATE TRIGGER audit_logs_no_modify BEFORE UPDATE OR DELETE ON audit_logs
+		FOR EACH ROW EXECUTE FUNCTION audit_logs_append_only()`,
+		"DROP TRIGGER IF EXISTS audit_logs_no_truncate ON audit_logs",
+		`CREATE TRIGGER audit_logs_no_truncate BEFORE TRUNCATE ON audit_logs
+		FOR EACH STATEMENT EXECUTE FUNCTION audit_logs_append_only()`,
+	}
+	for _, statement := range statements {
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// deviceUserCodePending limita la unicidad del user_code a las autorizaciones pendientes: los
+// códigos de solicitudes aprobadas, rechazadas o consumidas vuelven a estar disponibles
+func deviceUserCodePending(tx *gorm.DB) error {
+	statements := []string{
+		"DROP INDEX IF EXISTS idx_device_authorizations_user_code",
+		"CREATE UNIQUE INDEX idx_device_authorizations_user_code ON device_authorizations (user_code) WHERE status = 'pending'",
+	}
+	for _, statement := range statements {
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
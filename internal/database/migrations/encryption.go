@@ -0,0 +1,17 @@
+package migrations
+
+import (
+	"megabaseGo/internal/database/encryption"
+
+	"gorm.io/gorm"
+)
+
+// fieldEncryption crea la tabla de claves de datos y la unicidad
+// del email sobre su índice ciego, que sustituye a LOWER(email) cuando el email está cifrado
+func fieldEncryption(tx *gorm.DB) error {
+	if err := tx.AutoMigrate(&encryption.DataKey{}); err != nil {
+		return err
+	}
+	return tx.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_bidx ON users (email_bidx) " +
+		"WHERE deleted_at IS NULL AND email_bidx <> ''").Error
+}
@@ -0,0 +1,118 @@
+package migrations
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"megabaseGo/internal/database/encryption"
+	"megabaseGo/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// IdentifierCollision agrupa usuarios cuyo username o email coinciden tras normalizar
+type IdentifierCollision struct {
+	Field   string
+	Value   string
+	UserIDs []uint
+}
+
+type identifierRow struct {
+	ID       uint
+	UserName string
+	Email    string
+}
+
+// FindIdentifierCollisions detecta usuarios (incluidos los eliminados) que colisionarían
+// con los índices únicos sin distinción de mayúsculas
+func FindIdentifierCollisions(db *gorm.DB) ([]IdentifierCollision, error) {
+	var rows []identifierRow
+	if err := db.Table("users").Select("id, user_name, email").Order("id").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	byUserName := map[string][]uint{}
+	byEmail := map[string][]uint{}
+	for _, row := range rows {
+		// La consulta no pasa por el modelo: los emails cifrados se descifran aquí
+		email, err := encryption.Decrypt("email", row.Email)
+		if err != nil {
+			return nil, err
+		}
+		userNameKey := strings.ToLower(utils.NormalizeUserName(row.UserName))
+		emailKey := utils.NormalizeEmail(email)
+		byUserName[userNameKey] = append(byUserName[userNameKey], row.ID)
+		byEmail[emailKey] = append(byEmail[emailKey], row.ID)
+	}
+
+	var collisions []IdentifierCollision
+	collisions = append(collisions, collect("user_name", byUserName)...)
+	collisions = append(collisions, collect("email", byEmail)...)
+	return collisions, nil
+}
+
+// ReportIdentifierCollisions escribe en el log las colisiones encontradas
+func ReportIdentifierCollisions(collisions []IdentifierCollision) {
+	for _, collision := range collisions {
+		log.Printf("⚠ Colisión de %s %q entre los usuarios %v", collision.Field, collision.Value, collision.UserIDs)
+	}
+}
+
+func collect(field string, groups map[string][]uint) []IdentifierCollision {
+	var collisions []IdentifierCollision
+	for value, ids := range groups {
+		if len(ids) > 1 {
+			collisions = append(collisions, IdentifierCollision{Field: field, Value: value, UserIDs: ids})
+		}
+	}
+	sort.Slice(collisions, func(i, j int) bool { return collisions[i].Value < collisions[j].Value })
+	return collisions
+}
+
+// caseInsensitiveUserIdentifiers normaliza los identificadores existentes y reemplaza los
+// índices únicos sensibles a mayúsculas por índices sobre LOWER(). Si hay colisiones las
+// reporta y falla sin modificar nada para que se resuelvan manualmente.
+func caseInsensitiveUserIdentifiers(tx *gorm.DB) error {
+	collisions, err := FindIdentifierCollisions(tx)
+	if err != nil {
+		return err
+	}
+	if len(collisions) > 0 {
+		ReportIdentifierCollisions(collisions)
+		return fmt.Errorf("found %d user identifier collisions; resolve them (see `console identifiers check`) and run the migration again", len(collisions))
+	}
+
+	var rows []identifierRow
+	if err := tx.Table("users").Select("id, user_name, email").Scan(&rows).Error; err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if _, encrypted := encryption.IsEncrypted(row.Email); encrypted {
+			continue
+		}
+		userName := utils.NormalizeUserName(row.UserName)
+		email := utils.NormalizeEmail(row.Email)
+		if userName == row.UserName && email == row.Email {
+			continue
+		}
+		if err := tx.Table("users").Where("id = ?", row.ID).
+			Updates(map[string]interface{}{"user_name": userName, "email": email}).Error; err != nil {
+			return err
+		}
+	}
+
+	statements := []string{
+		"DROP INDEX IF EXISTS idx_users_user_name",
+		"DROP INDEX IF EXISTS idx_users_email",
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_users_user_name_lower ON users (LOWER(user_name))",
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_lower ON users (LOWER(email))",
+	}
+	for _, statement := range statements {
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
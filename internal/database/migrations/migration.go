@@ -0,0 +1,52 @@
+package migrations
+
+import (
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration representa un cambio de esquema que AutoMigrate no puede expresar
+// (índices funcionales, extensiones, limpieza de datos...)
+type Migration struct {
+	ID string
+	Up func(tx *gorm.DB) error
+}
+
+// SchemaMigration registra las migraciones ya aplicadas
+type SchemaMigration struct {
+	ID        string    `gorm:"primaryKey;size:255"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+// Run aplica en orden las migraciones pendientes, cada una en su propia transacción
+func Run(db *gorm.DB) error {
+	if err := db.AutoMigrate(&SchemaMigration{}); err != nil {
+		return err
+	}
+
+	for _, migration := range AllMigrations {
+		var count int64
+		if err := db.Model(&SchemaMigration{}).Where("id = ?", migration.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := migration.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&SchemaMigration{ID: migration.ID, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			log.Printf("Error aplicando migración %s: %v", migration.ID, err)
+			return err
+		}
+		log.Printf("Migración aplicada: %s", migration.ID)
+	}
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	"megabaseGo/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// defaultOrganization prepara las instalaciones existentes para multi-tenancy: crea la
+// organización por defecto (DEFAULT_ORGANIZATION), hace miembros a todos los usuarios con su
+// rol actual y cambia la unicidad del nombre de rol de global a por organización.
+func defaultOrganization(tx *gorm.DB) error {
+	statements := []string{
+		"DROP INDEX IF EXISTS idx_roles_name",
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_roles_organization_name ON roles (COALESCE(organization_id, 0), name)",
+	}
+	for _, statement := range statements {
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+
+	slug := utils.GetEnv("DEFAULT_ORGANIZATION", "default")
+	if slug == "" {
+		return nil
+	}
+
+	if err := tx.Exec(`
+		INSERT INTO organizations (slug, name, is_active, created_at, updated_at)
+		SELECT ?, 'Default', true, NOW(), NOW()
+		WHERE NOT EXISTS (SELECT 1 FROM organizations WHERE slug = ?)`, slug, slug).Error; err != nil {
+		return err
+	}
+
+	return tx.Exec(`
+		INSERT INTO organization_memberships (organization_id, user_id, role_id, created_at, updated_at)
+		SELECT o.id, u.id, u.role_id, NOW(), NOW()
+		FROM users u
+		JOIN organizations o ON o.slug = ?
+		WHERE u.deleted_at IS NULL
+		ON CONFLICT (organization_id, user_id) DO NOTHING`, slug).Error
+}
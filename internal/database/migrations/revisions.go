@@ -0,0 +1,28 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// initialRevisions crea la revisión 1 de los usuarios y roles que existían antes del historial,
+// con los mismos campos que User.RevisionSnapshot y Role.RevisionSnapshot
+func initialRevisions(tx *gorm.DB) error {
+	statements := []string{
+		`INSERT INTO revisions (resource_type, resource_id, number, snapshot, created_at)
+		SELECT 'user', u.id, 1, jsonb_build_object(
+			'name', u.name, 'user_name', u.user_name, 'email', u.email, 'has_password', u.has_password,
+			'role_id', u.role_id, 'is_active', u.is_active, 'external_id', COALESCE(u.external_id, '')), NOW()
+		FROM users u
+		WHERE NOT EXISTS (SELECT 1 FROM revisions r WHERE r.resource_type = 'user' AND r.resource_id = u.id)`,
+		`INSERT INTO revisions (resource_type, resource_id, number, snapshot, created_at)
+		SELECT 'role', r.id, 1, jsonb_build_object(
+			'name', r.name, 'display_name', r.display_name, 'description', COALESCE(r.description, ''),
+			'is_active', r.is_active, 'organization_id', r.organization_id, 'external_id', COALESCE(r.external_id, '')), NOW()
+		FROM roles r
+		WHERE NOT EXISTS (SELECT 1 FROM revisions v WHERE v.resource_type = 'role' AND v.resource_id = r.id)`,
+	}
+	for _, statement := range statements {
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
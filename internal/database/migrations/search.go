@@ -0,0 +1,25 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// userSearch prepara la búsqueda de usuarios: índices de trigramas (pg_trgm) para coincidencias
+// parciales y con erratas, y una columna tsvector generada para la búsqueda por palabras.
+// La extensión se instala siempre en public y se referencia cualificada, para que las consultas
+// no dependan del search_path.
+func userSearch(tx *gorm.DB) error {
+	statements := []string{
+		"CREATE EXTENSION IF NOT EXISTS pg_trgm SCHEMA public",
+		"CREATE INDEX IF NOT EXISTS idx_users_user_name_trgm ON users USING gin (LOWER(user_name) public.gin_trgm_ops)",
+		"CREATE INDEX IF NOT EXISTS idx_users_name_trgm ON users USING gin (LOWER(name) public.gin_trgm_ops)",
+		"CREATE INDEX IF NOT EXISTS idx_users_email_trgm ON users USING gin (LOWER(email) public.gin_trgm_ops)",
+		"ALTER TABLE users ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (" +
+			"to_tsvector('simple', COALESCE(name, '') || ' ' || COALESCE(user_name, '') || ' ' || COALESCE(email, ''))) STORED",
+		"CREATE INDEX IF NOT EXISTS idx_users_search_vector ON users USING gin (search_vector)",
+	}
+	for _, statement := range statements {
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// dropRememberToken elimina la columna remember_token: nunca se usó y su índice único
+// sobre cadenas vacías impedía crear más de un usuario. "Recordarme" vive ahora en las sesiones.
+func dropRememberToken(tx *gorm.DB) error {
+	statements := []string{
+		"DROP INDEX IF EXISTS idx_users_remember_token",
+		"ALTER TABLE users DROP COLUMN IF EXISTS remember_token",
+	}
+	for _, statement := range statements {
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// partialUniqueIndexes limita la unicidad de username, email y nombre de rol a las filas no
+// eliminadas, para que un registro en la papelera no impida reutilizar sus valores
+func partialUniqueIndexes(tx *gorm.DB) error {
+	statements := []string{
+		"DROP INDEX IF EXISTS idx_users_user_name_lower",
+		"CREATE UNIQUE INDEX idx_users_user_name_lower ON users (LOWER(user_name)) WHERE deleted_at IS NULL",
+		"DROP INDEX IF EXISTS idx_users_email_lower",
+		"CREATE UNIQUE INDEX idx_users_email_lower ON users (LOWER(email)) WHERE deleted_at IS NULL",
+		"DROP INDEX IF EXISTS idx_roles_organization_name",
+		"CREATE UNIQUE INDEX idx_roles_organization_name ON roles (COALESCE(organization_id, 0), name) WHERE deleted_at IS NULL",
+	}
+	for _, statement := range statements {
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// rowVersions incrementa la columna version de usuarios y roles en cada UPDATE, venga de donde
+// venga (API, SCIM, inicio de sesión, restauraciones...), para que el ETag cambie siempre que
+// cambie la fila. Las actualizaciones condicionadas a la versión leída detectan así las
+// escrituras concurrentes.
+func rowVersions(tx *gorm.DB) error {
+	statements := []string{
+		`CREATE OR REPLACE FUNCTION bump_row_version() RETURNS trigger AS $$
+BEGIN
+	NEW.version := OLD.version + 1;
+	RETURN NEW;
+END;
+$$ LANGUAGE plpgsql`,
+		"DROP TRIGGER IF EXISTS users_bump_version ON users",
+		"CREATE TRIGGER users_bump_version BEFORE UPDATE ON users FOR EACH ROW EXECUTE FUNCTION bump_row_version()",
+		"DROP TRIGGER IF EXISTS roles_bump_version ON roles",
+		"CREATE TRIGGER roles_bump_version BEFORE UPDATE ON roles FOR EACH ROW EXECUTE FUNCTION bump_row_version()",
+	}
+	for _, statement := range statements {
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
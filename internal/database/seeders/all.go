@@ -15,5 +15,6 @@ type Seeder interface {
 var AllSeeders = []Seeder{
     &RoleSeeder{},
     NewUserSeeder(utils.NewBcryptHasher()),
+    &OrganizationSeeder{},
     // Añade aquí tus nuevos seeders, e.g.: &ProductSeeder{},
 }
\ No newline at end of file
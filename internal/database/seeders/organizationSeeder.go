@@ -0,0 +1,42 @@
+package seeders
+
+import (
+	"log"
+
+	"megabaseGo/internal/models"
+	"megabaseGo/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// OrganizationSeeder crea la organización por defecto y hace miembro al usuario admin
+type OrganizationSeeder struct{}
+
+func (s *OrganizationSeeder) Run(db *gorm.DB) error {
+	slug := utils.GetEnv("DEFAULT_ORGANIZATION", "default")
+	if slug == "" {
+		log.Println("Sin DEFAULT_ORGANIZATION, se omite la organización por defecto")
+		return nil
+	}
+
+	organization := models.Organization{Slug: slug, Name: "Default", IsActive: true}
+	if err := db.FirstOrCreate(&organization, models.Organization{Slug: slug}).Error; err != nil {
+		log.Printf("Error creando la organización por defecto: %v", err)
+		return err
+	}
+
+	var admin models.User
+	if err := db.Where("user_name = ?", "admin").First(&admin).Error; err != nil {
+		log.Printf("Error buscando el usuario admin: %v", err)
+		return err
+	}
+
+	membership := models.OrganizationMembership{OrganizationID: organization.ID, UserID: admin.ID, RoleID: admin.RoleID}
+	if err := db.FirstOrCreate(&membership, models.OrganizationMembership{OrganizationID: organization.ID, UserID: admin.ID}).Error; err != nil {
+		log.Printf("Error añadiendo el admin a la organización por defecto: %v", err)
+		return err
+	}
+
+	log.Println("Creacion de organización por defecto exitosa")
+	return nil
+}
@@ -17,12 +17,27 @@ func (s *RoleSeeder) Run(db *gorm.DB) error {
 		IsActive:    true,
 	}
 
-	// Create admin role with ID 1
-	if err := db.FirstOrCreate(&adminRole, models.Role{Name: "admin"}).Error; err != nil {
+	// Create admin role with ID 1 (rol de sistema: sin organización)
+	if err := db.Where("organization_id IS NULL").FirstOrCreate(&adminRole, models.Role{Name: "admin"}).Error; err != nil {
 		log.Printf("Error creando rol admin: %v", err)
 		return err
 	}
 
 	log.Println("Creacion de rol admin exitosa")
+
+	// Rol de plataforma por defecto para usuarios creados en una organización, SCIM y SAML
+	userRole := models.Role{
+		Name:        "user",
+		DisplayName: "User",
+		Description: "Usuario estándar",
+		IsActive:    true,
+	}
+
+	if err := db.Where("organization_id IS NULL").FirstOrCreate(&userRole, models.Role{Name: "user"}).Error; err != nil {
+		log.Printf("Error creando rol user: %v", err)
+		return err
+	}
+
+	log.Println("Creacion de rol user exitosa")
 	return nil
 }
@@ -2,7 +2,25 @@ package models
 
 // AllModels contiene todos los modelos para migración dinámica
 var AllModels = []interface{}{        
+    &Organization{},
     &Role{},
     &User{},
+    &OrganizationMembership{},
+    &MagicLinkToken{},
+    &DeviceAuthorization{},
+    &LoginAttempt{},
+    &ScimClient{},
+    &SamlProvider{},
+    &SamlRequest{},
+    &SamlAssertion{},
+    &UserIdentity{},
+    &SessionPolicy{},
+    &Session{},
+    &AuditLog{},
+    &Revision{},
+    &DataExport{},
+    &ErasureRequest{},
+    &EmailChangeRequest{},
+    &AccountDeletion{},
     // Añade aquí tus nuevos modelos, e.g.: &Product{},
 }
\ No newline at end of file
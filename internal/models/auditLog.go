@@ -0,0 +1,83 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Acciones registradas en el log de auditoría
+const (
+	AuditUserCreate  = "user.create"
+	AuditUserUpdate  = "user.update"
+	AuditUserDelete  = "user.delete"
+	AuditUserRestore = "user.restore"
+	AuditUserPurge   = "user.purge"
+	AuditRoleCreate  = "role.create"
+	AuditRoleUpdate  = "role.update"
+	AuditRoleDelete  = "role.delete"
+	AuditRoleRestore = "role.restore"
+	AuditRolePurge   = "role.purge"
+
+	AuditLogin                = "auth.login"
+	AuditLoginFailed          = "auth.login_failed"
+	AuditRegister             = "auth.register"
+	AuditTokenRefresh         = "auth.token_refresh"
+	AuditLogout               = "auth.logout"
+	AuditPasswordChange       = "auth.password_change"
+	AuditPasswordChangeFailed = "auth.password_change_failed"
+	AuditReauthenticate       = "auth.reauthenticate"
+	AuditReauthenticateFailed = "auth.reauthenticate_failed"
+
+	AuditDataExport      = "privacy.export"
+	AuditErasureRequest  = "privacy.erasure_request"
+	AuditErasureCancel   = "privacy.erasure_cancel"
+	AuditErasureComplete = "privacy.erasure_complete"
+
+	AuditIdentityUnlink = "identity.unlink"
+	AuditAccountMerge   = "identity.merge"
+
+	AuditSamlProviderCreate = "saml_provider.create"
+	AuditSamlProviderUpdate = "saml_provider.update"
+	AuditSamlProviderDelete = "saml_provider.delete"
+
+	AuditProfileUpdate           = "profile.update"
+	AuditEmailChangeRequest      = "profile.email_change_request"
+	AuditEmailChange             = "profile.email_change"
+	AuditAccountDeletionRequest  = "profile.deletion_request"
+	AuditAccountReactivate       = "profile.reactivate"
+	AuditAccountDeletionComplete = "profile.deletion_complete"
+)
+
+// Tipos de objetivo de una entrada de auditoría
+const (
+	AuditTargetUser         = "user"
+	AuditTargetRole         = "role"
+	AuditTargetSamlProvider = "saml_provider"
+)
+
+// AuditLog entrada del log de auditoría. La tabla es de solo inserción: un trigger
+// rechaza UPDATE, DELETE y TRUNCATE (migración 20250401_audit_logs_append_only).
+// Cada entrada guarda el hash de la anterior, así que alterar o borrar una rompe la cadena.
+type AuditLog struct {
+	ID             uint   `gorm:"primarykey" json:"id"`
+	ActorID        *uint  `gorm:"index" json:"actor_id"`
+	ActorName      string `gorm:"size:100" json:"actor_name,omitempty"`
+	Action         string `gorm:"size:50;not null;index" json:"action"`
+	TargetType     string `gorm:"size:30;index:idx_audit_logs_target" json:"target_type,omitempty"`
+	TargetID       string `gorm:"size:64;index:idx_audit_logs_target" json:"target_id,omitempty"`
+	OrganizationID *uint  `gorm:"index" json:"organization_id,omitempty"`
+	// Changes diferencia antes/después de los campos modificados: {"campo": {"old": ..., "new": ...}}
+	Changes json.RawMessage `gorm:"type:jsonb" json:"changes,omitempty"`
+	// Metadata datos propios del evento (método de login, motivo del fallo...)
+	Metadata  json.RawMessage `gorm:"type:jsonb" json:"metadata,omitempty"`
+	IPAddress string          `gorm:"size:45" json:"ip_address,omitempty"`
+	UserAgent string          `gorm:"size:512" json:"user_agent,omitempty"`
+	RequestID string          `gorm:"size:64;index" json:"request_id,omitempty"`
+	CreatedAt time.Time       `gorm:"not null;index" json:"created_at"`
+	// PrevHash hash de la entrada anterior; vacío en la primera de la cadena
+	PrevHash string `gorm:"size:64" json:"prev_hash"`
+	// Hash SHA-256 del contenido de la entrada y de PrevHash
+	Hash string `gorm:"size:64;index" json:"hash"`
+	// Signature HMAC-SHA256 de Hash con la clave del servidor (AUDIT_SIGNING_KEY); vacía sin clave
+	Signature string `gorm:"size:64" json:"signature,omitempty"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Estados de una autorización de dispositivo (RFC 8628)
+const (
+	DeviceAuthPending  = "pending"
+	DeviceAuthApproved = "approved"
+	DeviceAuthDenied   = "denied"
+	DeviceAuthConsumed = "consumed"
+)
+
+// DeviceAuthorization representa una solicitud de autorización de dispositivo (RFC 8628)
+type DeviceAuthorization struct {
+	ID             uint       `gorm:"primarykey" json:"id"`
+	DeviceCodeHash string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	UserCode       string     `gorm:"size:9;not null" json:"user_code"` // único entre las pendientes (migración device_user_code_pending)
+	ClientID       string     `gorm:"size:100;not null" json:"client_id"`
+	Scope          string     `gorm:"size:255" json:"scope"`
+	Status         string     `gorm:"size:20;not null;default:pending" json:"status"`
+	UserID         *uint      `gorm:"index" json:"user_id"`
+	AuthTime       *time.Time `json:"-"`
+	Interval       int        `gorm:"not null" json:"interval"`
+	ExpiresAt      time.Time  `gorm:"not null;index" json:"expires_at"`
+	LastPolledAt   *time.Time `json:"-"`
+	CreatedAt      time.Time  `gorm:"not null" json:"created_at"`
+	UpdatedAt      time.Time  `gorm:"not null" json:"updated_at"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Métodos de autenticación registrados en el historial de login
+const (
+	LoginMethodPassword   = "password"
+	LoginMethodMagicLink  = "magic_link"
+	LoginMethodDeviceCode = "device_code"
+	LoginMethodSAML       = "saml"
+)
+
+// LoginAttempt registra cada intento de inicio de sesión, exitoso o fallido
+type LoginAttempt struct {
+	ID            uint      `gorm:"primarykey" json:"id"`
+	UserID        *uint     `gorm:"index" json:"user_id"`
+	Identifier    string    `gorm:"size:255" json:"identifier"`
+	Success       bool      `gorm:"not null" json:"success"`
+	FailureReason string    `gorm:"size:100" json:"failure_reason,omitempty"`
+	Method        string    `gorm:"size:30;not null" json:"method"`
+	IPAddress     string    `gorm:"size:45" json:"ip_address"`
+	UserAgent     string    `gorm:"size:512" json:"user_agent"`
+	Fingerprint   string    `gorm:"size:64;index" json:"-"`
+	NewDevice     bool      `gorm:"not null;default:false" json:"new_device"`
+	CreatedAt     time.Time `gorm:"not null;index" json:"created_at"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// MagicLinkToken registra cada enlace mágico emitido para controlar su uso único y el rate limit por email
+type MagicLinkToken struct {
+	ID        uint       `gorm:"primarykey" json:"id"`
+	Email     string     `gorm:"size:100;not null;index" json:"email"`
+	UserID    *uint      `gorm:"index" json:"user_id"`
+	TokenHash string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	RequestIP string     `gorm:"size:45" json:"request_ip"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `gorm:"not null;index" json:"created_at"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Organization inquilino (cliente) de la plataforma. Los usuarios acceden a sus datos
+// a través de una membresía con un rol propio de la organización.
+type Organization struct {
+	ID          uint                     `gorm:"primarykey" json:"id"`
+	Slug        string                   `gorm:"size:100;not null;uniqueIndex" json:"slug"`
+	Name        string                   `gorm:"size:255;not null" json:"name"`
+	IsActive    bool                     `gorm:"not null;default:true" json:"is_active"`
+	CreatedAt   time.Time                `gorm:"not null" json:"created_at"`
+	UpdatedAt   time.Time                `gorm:"not null" json:"updated_at"`
+	DeletedAt   gorm.DeletedAt           `gorm:"index" json:"-"`
+	Memberships []OrganizationMembership `gorm:"foreignKey:OrganizationID" json:"-"`
+}
+
+// OrganizationMembership pertenencia de un usuario a una organización con el rol que tiene en ella
+type OrganizationMembership struct {
+	ID             uint         `gorm:"primarykey" json:"id"`
+	OrganizationID uint         `gorm:"not null;uniqueIndex:idx_membership_org_user" json:"organization_id"`
+	Organization   Organization `gorm:"foreignKey:OrganizationID" json:"organization"`
+	UserID         uint         `gorm:"not null;uniqueIndex:idx_membership_org_user;index" json:"user_id"`
+	RoleID         uint         `gorm:"not null;index" json:"role_id"`
+	Role           Role         `gorm:"foreignKey:RoleID" json:"role"`
+	CreatedAt      time.Time    `gorm:"not null" json:"created_at"`
+	UpdatedAt      time.Time    `gorm:"not null" json:"updated_at"`
+}
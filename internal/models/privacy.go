@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// Estados de una exportación de datos personales
+const (
+	DataExportPending = "pending"
+	DataExportReady   = "ready"
+	DataExportFailed  = "failed"
+)
+
+// Estados de una solicitud de supresión de cuenta
+const (
+	ErasurePending   = "pending"
+	ErasureCompleted = "completed"
+	ErasureCancelled = "cancelled"
+)
+
+// DataExport archivo con todos los datos de un usuario (derecho de acceso, RGPD art. 15).
+// Se genera en segundo plano y se puede descargar hasta ExpiresAt.
+type DataExport struct {
+	ID          uint       `gorm:"primarykey" json:"id"`
+	UserID      uint       `gorm:"not null;index" json:"user_id"`
+	Status      string     `gorm:"size:20;not null;default:pending" json:"status"`
+	FilePath    string     `gorm:"size:255" json:"-"`
+	Size        int64      `gorm:"not null;default:0" json:"size"`
+	Error       string     `gorm:"size:255" json:"error,omitempty"`
+	CompletedAt *time.Time `json:"completed_at"`
+	ExpiresAt   *time.Time `gorm:"index" json:"expires_at"`
+	CreatedAt   time.Time  `gorm:"not null" json:"created_at"`
+	UpdatedAt   time.Time  `gorm:"not null" json:"updated_at"`
+}
+
+// ErasureRequest solicitud de supresión de una cuenta (derecho al olvido, RGPD art. 17).
+// La pide el propio usuario con un plazo de gracia para cancelarla, o un admin con efecto inmediato.
+// Las solicitudes completadas también sirven para seudonimizar el log de auditoría al leerlo.
+type ErasureRequest struct {
+	ID     uint   `gorm:"primarykey" json:"id"`
+	UserID uint   `gorm:"not null;index" json:"user_id"`
+	Status string `gorm:"size:20;not null;default:pending;index" json:"status"`
+	// RequestedByID admin que ordenó la supresión; nil si la pidió el propio usuario
+	RequestedByID *uint      `json:"requested_by_id"`
+	ScheduledFor  time.Time  `gorm:"not null;index" json:"scheduled_for"`
+	CompletedAt   *time.Time `json:"completed_at"`
+	CancelledAt   *time.Time `json:"cancelled_at"`
+	CreatedAt     time.Time  `gorm:"not null" json:"created_at"`
+	UpdatedAt     time.Time  `gorm:"not null" json:"updated_at"`
+}
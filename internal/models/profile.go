@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Estados de una solicitud de baja de cuenta
+const (
+	AccountDeletionPending   = "pending"
+	AccountDeletionCompleted = "completed"
+	AccountDeletionCancelled = "cancelled"
+)
+
+// EmailChangeRequest cambio de email pedido desde el perfil. El nuevo email no se aplica hasta
+// que se confirma con el enlace enviado a esa dirección.
+type EmailChangeRequest struct {
+	ID          uint       `gorm:"primarykey" json:"id"`
+	UserID      uint       `gorm:"not null;index" json:"user_id"`
+	NewEmail    string     `gorm:"size:512;not null;serializer:encrypted" json:"new_email"`
+	TokenHash   string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	ExpiresAt   time.Time  `gorm:"not null" json:"expires_at"`
+	ConfirmedAt *time.Time `json:"confirmed_at"`
+	CreatedAt   time.Time  `gorm:"not null" json:"created_at"`
+}
+
+// AccountDeletion baja de su cuenta pedida por el propio usuario. La cuenta se desactiva al
+// pedirla y pasa a la papelera al vencer ScheduledFor; hasta entonces el enlace enviado por
+// email la reactiva.
+type AccountDeletion struct {
+	ID           uint       `gorm:"primarykey" json:"id"`
+	UserID       uint       `gorm:"not null;index" json:"user_id"`
+	Status       string     `gorm:"size:20;not null;default:pending;index" json:"status"`
+	TokenHash    string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	ScheduledFor time.Time  `gorm:"not null;index" json:"scheduled_for"`
+	CompletedAt  *time.Time `json:"completed_at"`
+	CancelledAt  *time.Time `json:"cancelled_at"`
+	CreatedAt    time.Time  `gorm:"not null" json:"created_at"`
+	UpdatedAt    time.Time  `gorm:"not null" json:"updated_at"`
+}
@@ -0,0 +1,153 @@
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"megabaseGo/internal/database/encryption"
+
+	"gorm.io/gorm"
+)
+
+// Tipos de recurso con historial de revisiones
+const (
+	RevisionUser = "user"
+	RevisionRole = "role"
+)
+
+// Contexto del AAD de los campos cifrados dentro de las instantáneas
+const revisionEncryptionScope = "revisions"
+
+// Revision instantánea numerada de un recurso tras cada guardado que cambia sus campos versionados.
+// Los hooks AfterSave de User y Role la crean en la misma transacción que el guardado.
+type Revision struct {
+	ID           uint            `gorm:"primarykey" json:"id"`
+	ResourceType string          `gorm:"size:30;not null;uniqueIndex:idx_revisions_resource" json:"resource_type"`
+	ResourceID   uint            `gorm:"not null;uniqueIndex:idx_revisions_resource" json:"resource_id"`
+	Number       uint            `gorm:"not null;uniqueIndex:idx_revisions_resource" json:"revision"`
+	Snapshot     json.RawMessage `gorm:"type:jsonb;not null" json:"snapshot"`
+	CreatedAt    time.Time       `gorm:"not null;index" json:"created_at"`
+}
+
+// RevisionSnapshot campos versionados de un usuario. La contraseña y las marcas de tiempo
+// (último login, actualización) no forman parte del historial.
+func (u *User) RevisionSnapshot() map[string]interface{} {
+	return map[string]interface{}{
+		"name":         u.Name,
+		"user_name":    u.UserName,
+		"email":        u.Email,
+		"has_password": u.HasPassword,
+		"role_id":      u.RoleID,
+		"is_active":    u.IsActive,
+		"external_id":  u.ExternalID,
+	}
+}
+
+// AfterSave registra una revisión del usuario si cambió algún campo versionado
+func (u *User) AfterSave(tx *gorm.DB) error {
+	return recordRevision(tx, RevisionUser, u.ID, &User{})
+}
+
+// RevisionSnapshot campos versionados de un rol
+func (r *Role) RevisionSnapshot() map[string]interface{} {
+	return map[string]interface{}{
+		"name":            r.Name,
+		"display_name":    r.DisplayName,
+		"description":     r.Description,
+		"is_active":       r.IsActive,
+		"organization_id": r.OrganizationID,
+		"external_id":     r.ExternalID,
+	}
+}
+
+// AfterSave registra una revisión del rol si cambió algún campo versionado
+func (r *Role) AfterSave(tx *gorm.DB) error {
+	return recordRevision(tx, RevisionRole, r.ID, &Role{})
+}
+
+type revisioned interface {
+	RevisionSnapshot() map[string]interface{}
+}
+
+// recordRevision relee la fila guardada (un Update parcial no trae el modelo completo) y crea
+// la siguiente revisión si su instantánea difiere de la última. Las actualizaciones masivas
+// sin clave primaria no tienen una fila concreta y no generan revisión.
+func recordRevision(tx *gorm.DB, resourceType string, id uint, fresh revisioned) error {
+	if id == 0 {
+		return nil
+	}
+	db := tx.Session(&gorm.Session{NewDB: true})
+	if err := db.Unscoped().Take(fresh, id).Error; err != nil {
+		return err
+	}
+	// Ida y vuelta por JSON para comparar con la última revisión con los mismos tipos
+	encoded, err := json.Marshal(fresh.RevisionSnapshot())
+	if err != nil {
+		return err
+	}
+	current := map[string]interface{}{}
+	if err := json.Unmarshal(encoded, &current); err != nil {
+		return err
+	}
+
+	var last Revision
+	if err := db.Where("resource_type = ? AND resource_id = ?", resourceType, id).
+		Order("number DESC").Limit(1).Find(&last).Error; err != nil {
+		return err
+	}
+	// Los campos cifrados usan un nonce aleatorio: se compara el contenido descifrado
+	if last.ID != 0 {
+		previous, err := OpenRevisionSnapshot(resourceType, last.Snapshot)
+		if err != nil {
+			return err
+		}
+		if reflect.DeepEqual(previous, current) {
+			return nil
+		}
+	}
+
+	snapshot, err := SealRevisionSnapshot(resourceType, current)
+	if err != nil {
+		return err
+	}
+	return db.Create(&Revision{
+		ResourceType: resourceType,
+		ResourceID:   id,
+		Number:       last.Number + 1,
+		Snapshot:     snapshot,
+	}).Error
+}
+
+// revisionEncryptedFields campos de la instantánea que están cifrados en la tabla del recurso
+func revisionEncryptedFields(resourceType string) []string {
+	if resourceType == RevisionUser {
+		return UserEncryptedFields
+	}
+	return nil
+}
+
+// SealRevisionSnapshot codifica una instantánea cifrando los campos que el recurso guarda cifrados
+func SealRevisionSnapshot(resourceType string, snapshot map[string]interface{}) (json.RawMessage, error) {
+	sealed := make(map[string]interface{}, len(snapshot))
+	for field, value := range snapshot {
+		sealed[field] = value
+	}
+	if err := encryption.SealFields(revisionEncryptionScope, sealed, revisionEncryptedFields(resourceType)); err != nil {
+		return nil, err
+	}
+	return json.Marshal(sealed)
+}
+
+// OpenRevisionSnapshot decodifica una instantánea y descifra sus campos cifrados. Las revisiones
+// anteriores al cifrado de campos los tienen en claro y se devuelven igual.
+func OpenRevisionSnapshot(resourceType string, raw json.RawMessage) (map[string]interface{}, error) {
+	snapshot := map[string]interface{}{}
+	if err := json.Unmarshal(raw, &snapshot); err != nil {
+		return nil, err
+	}
+	if err := encryption.OpenFields(revisionEncryptionScope, snapshot, revisionEncryptedFields(resourceType)); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
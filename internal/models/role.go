@@ -8,10 +8,16 @@ import (
 
 type Role struct {
 	gorm.Model
-	Name          string    `gorm:"size:100;not null;uniqueIndex" json:"name"`
+	// Nombre único por organización: índice sobre (organization_id, name) creado en database/migrations
+	Name          string    `gorm:"size:100;not null" json:"name"`
 	DisplayName   string    `gorm:"size:100;not null" json:"display_name"`
 	Description   string    `gorm:"type:text" json:"description"`
 	IsActive      bool      `gorm:"not null;default:true" json:"is_active"`
+	// OrganizationID organización dueña del rol; nil en los roles de sistema compartidos por todas
+	OrganizationID *uint    `gorm:"index" json:"organization_id"`
+	ExternalID    string    `gorm:"size:255;index" json:"external_id,omitempty"`
+	// Version se incrementa en cada UPDATE (trigger creado en database/migrations); base del ETag
+	Version       uint      `gorm:"not null;default:1" json:"version"`
 	CreatedAt     time.Time `gorm:"not null" json:"created_at"`
 	UpdatedAt     time.Time `gorm:"not null" json:"updated_at"`
 	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
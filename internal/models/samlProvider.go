@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// SamlProvider configuración de un proveedor de identidad SAML 2.0 (IdP) de un cliente empresarial
+type SamlProvider struct {
+	ID                uint              `gorm:"primarykey" json:"id"`
+	Slug              string            `gorm:"size:100;not null;uniqueIndex" json:"slug"`
+	Name              string            `gorm:"size:100;not null" json:"name"`
+	IdPEntityID       string            `gorm:"size:512" json:"idp_entity_id"`
+	SSOURL            string            `gorm:"size:512" json:"sso_url"`
+	Certificate       string            `gorm:"type:text" json:"certificate"`
+	MetadataXML       string            `gorm:"type:text" json:"metadata_xml,omitempty"`
+	AttributeMapping  map[string]string `gorm:"type:text;serializer:json" json:"attribute_mapping"`
+	GroupAttribute    string            `gorm:"size:255" json:"group_attribute"`
+	GroupRoleMapping  map[string]string `gorm:"type:text;serializer:json" json:"group_role_mapping"`
+	DefaultRole       string            `gorm:"size:100" json:"default_role"`
+	AutoProvision     bool              `gorm:"not null;default:false" json:"auto_provision"`
+	AllowedDomains    []string          `gorm:"type:text;serializer:json" json:"allowed_domains"` // dominios con los que se vincula por email una cuenta existente
+	AllowIdPInitiated bool              `gorm:"not null;default:false" json:"allow_idp_initiated"`
+	IsActive          bool              `gorm:"not null;default:true" json:"is_active"`
+	CreatedAt         time.Time         `gorm:"not null" json:"created_at"`
+	UpdatedAt         time.Time         `gorm:"not null" json:"updated_at"`
+}
+
+// SamlRequest AuthnRequest emitido en un login iniciado por el SP, pendiente de respuesta del IdP
+type SamlRequest struct {
+	ID             string    `gorm:"primarykey;size:100" json:"id"`
+	ProviderID     uint      `gorm:"not null;index" json:"provider_id"`
+	RelayStateHash string    `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	RedirectTo     string    `gorm:"size:1024" json:"redirect_to"`
+	LinkUserID     *uint     `gorm:"index" json:"link_user_id"` // vinculación de identidad en lugar de login
+	ReauthUserID   *uint     `gorm:"index" json:"reauth_user_id"` // re-autenticación (step-up) en lugar de login
+	ReauthSession  string    `gorm:"size:64" json:"-"`            // sesión a la que se asocia el token elevado
+	ExpiresAt      time.Time `gorm:"not null;index" json:"expires_at"`
+	CreatedAt      time.Time `gorm:"not null" json:"created_at"`
+}
+
+// SamlAssertion aserción ya consumida; impide reutilizarla mientras siga siendo válida
+type SamlAssertion struct {
+	ProviderID  uint      `gorm:"primarykey;autoIncrement:false" json:"provider_id"`
+	AssertionID string    `gorm:"primarykey;size:255" json:"assertion_id"`
+	ExpiresAt   time.Time `gorm:"not null;index" json:"expires_at"`
+	CreatedAt   time.Time `gorm:"not null" json:"created_at"`
+}
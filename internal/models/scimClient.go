@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// ScimClient cliente de aprovisionamiento SCIM (p. ej. el IdP de un cliente) con su token bearer
+type ScimClient struct {
+	ID         uint       `gorm:"primarykey" json:"id"`
+	Name       string     `gorm:"size:100;not null;uniqueIndex" json:"name"`
+	TokenHash  string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	IsActive   bool       `gorm:"not null;default:true" json:"is_active"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `gorm:"not null" json:"created_at"`
+	UpdatedAt  time.Time  `gorm:"not null" json:"updated_at"`
+}
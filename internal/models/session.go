@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Session sesión de servidor abierta en un login. Los refresh tokens la referencian (claim sid)
+// y cada renovación actualiza su última actividad para aplicar el tiempo de inactividad.
+// Las duraciones se copian del perfil al abrirla: cambiar el perfil solo afecta a sesiones nuevas.
+type Session struct {
+	ID       string `gorm:"size:64;primaryKey" json:"id"`
+	UserID   uint   `gorm:"not null;index" json:"user_id"`
+	PolicyID *uint  `gorm:"index" json:"policy_id"`
+	ClientID string `gorm:"size:100" json:"client_id"`
+	// OrganizationID organización (tenant) elegida al iniciar sesión
+	OrganizationID      *uint     `json:"organization_id"`
+	RememberMe          bool      `gorm:"not null;default:false" json:"remember_me"`
+	AccessTokenMinutes  int       `gorm:"not null" json:"access_token_minutes"`
+	RefreshTokenMinutes int       `gorm:"not null" json:"refresh_token_minutes"`
+	IdleTimeoutMinutes  int       `gorm:"not null" json:"idle_timeout_minutes"`
+	AuthTime            time.Time `gorm:"not null" json:"auth_time"`
+	LastActivityAt      time.Time `gorm:"not null" json:"last_activity_at"`
+	// PasswordAuthAt momento en que el login que abrió la sesión comprobó la contraseña; es el
+	// auth_time de sus tokens. nil si se abrió sin contraseña (enlace mágico, SSO, registro...)
+	PasswordAuthAt *time.Time `json:"password_auth_at"`
+	// ExpiresAt límite absoluto de la sesión, independiente de las renovaciones (nil = sin límite)
+	ExpiresAt *time.Time `gorm:"index" json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+	IPAddress string     `gorm:"size:45" json:"ip_address"`
+	UserAgent string     `gorm:"size:255" json:"user_agent"`
+	CreatedAt time.Time  `gorm:"not null" json:"created_at"`
+	UpdatedAt time.Time  `gorm:"not null" json:"updated_at"`
+}
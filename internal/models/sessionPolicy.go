@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// SessionPolicy perfil de duración de sesión para un rol o un cliente (client_id).
+// Los perfiles de cliente tienen prioridad sobre los de rol; sin perfil se usan los valores
+// por defecto del entorno. RememberMe distingue el perfil usado cuando el usuario marca "recordarme".
+// Un tiempo de inactividad o una duración máxima de 0 minutos significan sin límite.
+type SessionPolicy struct {
+	ID                     uint      `gorm:"primarykey" json:"id"`
+	Name                   string    `gorm:"size:100;not null;uniqueIndex" json:"name"`
+	RoleID                 *uint     `gorm:"index" json:"role_id"`
+	ClientID               string    `gorm:"size:100;index" json:"client_id"`
+	RememberMe             bool      `gorm:"not null;default:false" json:"remember_me"`
+	AccessTokenMinutes     int       `gorm:"not null" json:"access_token_minutes"`
+	RefreshTokenMinutes    int       `gorm:"not null" json:"refresh_token_minutes"`
+	IdleTimeoutMinutes     int       `gorm:"not null;default:0" json:"idle_timeout_minutes"`
+	AbsoluteTimeoutMinutes int       `gorm:"not null;default:0" json:"absolute_timeout_minutes"`
+	CreatedAt              time.Time `gorm:"not null" json:"created_at"`
+	UpdatedAt              time.Time `gorm:"not null" json:"updated_at"`
+}
@@ -3,21 +3,53 @@ package models
 import (
 	"time"
 
+	"megabaseGo/internal/database/encryption"
+	"megabaseGo/internal/utils"
+
 	"gorm.io/gorm"
 )
 
 type User struct {
 	gorm.Model
-	Name          string    `gorm:"size:100;not null" json:"name"`
-	UserName      string    `gorm:"size:100;not null;uniqueIndex" json:"user_name"`
-	Email         string    `gorm:"size:100;not null;uniqueIndex" json:"email"`
+	// Name y Email se cifran en la BD si FIELD_ENCRYPTION_KEKS está configurado (database/encryption)
+	Name          string    `gorm:"size:512;not null;serializer:encrypted" json:"name"`
+	// Unicidad sin distinguir mayúsculas: índices sobre LOWER() creados en database/migrations
+	UserName      string    `gorm:"size:100;not null" json:"user_name"`
+	Email         string    `gorm:"size:512;not null;serializer:encrypted" json:"email"`
+	// EmailIndex índice ciego del email normalizado para buscar y garantizar unicidad sin descifrar
+	EmailIndex    string    `gorm:"column:email_bidx;size:64;not null;default:''" json:"-"`
 	Password      string    `gorm:"size:255;not null" json:"-"`
+	// false en cuentas creadas por SSO/SCIM cuya contraseña es aleatoria e inutilizable
+	HasPassword   bool      `gorm:"not null;default:true" json:"has_password"`
 	RoleID        uint      `gorm:"not null" json:"role_id"`
 	Role          Role      `gorm:"foreignKey:RoleID" json:"role"`
-	RememberToken string    `gorm:"size:100;uniqueIndex" json:"-"`
 	IsActive      bool      `gorm:"not null;default:true" json:"is_active"`
+	ExternalID    string    `gorm:"size:255;index" json:"external_id,omitempty"`
+	// AvatarURL avatar externo indicado en el perfil; AvatarKey, la imagen subida (prevalece)
+	AvatarURL     string    `gorm:"size:500" json:"avatar_url,omitempty"`
+	AvatarKey     string    `gorm:"size:255" json:"-"`
 	LastLoginAt   time.Time `json:"last_login_at"`
+	// Version se incrementa en cada UPDATE (trigger creado en database/migrations); base del ETag
+	Version       uint      `gorm:"not null;default:1" json:"version"`
 	CreatedAt     time.Time `gorm:"not null" json:"created_at"`
 	UpdatedAt     time.Time `gorm:"not null" json:"updated_at"`
 	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
 }
+
+// UserEncryptedFields campos de User cifrados en la BD; sus copias en instantáneas de revisión y
+// en la auditoría también se guardan cifradas (encryption.SealFields)
+var UserEncryptedFields = []string{"name", "email"}
+
+// Propósito del índice ciego del email; separa su HMAC del de otros campos
+const emailBlindIndexPurpose = "users.email"
+
+// EmailBlindIndex índice ciego de un email; "" con el cifrado desactivado
+func EmailBlindIndex(email string) string {
+	return encryption.BlindIndex(emailBlindIndexPurpose, utils.NormalizeEmail(email))
+}
+
+// BeforeSave mantiene el índice ciego del email al día con el email
+func (u *User) BeforeSave(tx *gorm.DB) error {
+	u.EmailIndex = EmailBlindIndex(u.Email)
+	return nil
+}
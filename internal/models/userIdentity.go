@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Proveedores de identidades externas vinculables a una cuenta
+const (
+	IdentityProviderSAML = "saml"
+)
+
+// UserIdentity identidad externa (p. ej. un NameID de un IdP SAML) vinculada a una cuenta.
+// Un usuario puede tener varias; cada identidad pertenece a un único usuario.
+type UserIdentity struct {
+	ID            uint       `gorm:"primarykey" json:"id"`
+	UserID        uint       `gorm:"not null;index" json:"user_id"`
+	Provider      string     `gorm:"size:30;not null;uniqueIndex:idx_user_identities_subject" json:"provider"`
+	ProviderRef   string     `gorm:"size:100;not null;uniqueIndex:idx_user_identities_subject" json:"provider_ref"`
+	Subject       string     `gorm:"size:255;not null;uniqueIndex:idx_user_identities_subject" json:"subject"`
+	Email         string     `gorm:"size:255;index" json:"email"`
+	EmailVerified bool       `gorm:"not null;default:false" json:"email_verified"`
+	LastUsedAt    *time.Time `json:"last_used_at"`
+	CreatedAt     time.Time  `gorm:"not null" json:"created_at"`
+	UpdatedAt     time.Time  `gorm:"not null" json:"updated_at"`
+}
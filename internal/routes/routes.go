@@ -1,8 +1,13 @@
 package routes
 
 import (
+	"os"
+	"strings"
+
 	"megabaseGo/internal/app/handlers"
 	"megabaseGo/internal/app/middleware"
+	"megabaseGo/internal/storage"
+	"megabaseGo/internal/utils"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -15,15 +20,30 @@ func Setup() *gin.Engine {
 
 	// Configurar CORS
 	config := cors.DefaultConfig()
-	config.AllowAllOrigins = true
-	config.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
-	config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Requested-With"}
+	if origins := os.Getenv("CORS_ALLOWED_ORIGINS"); origins != "" {
+		// Las cookies de sesión requieren orígenes explícitos y credenciales
+		config.AllowOrigins = strings.Split(origins, ",")
+		config.AllowCredentials = true
+	} else {
+		config.AllowAllOrigins = true
+	}
+	config.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+	config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Requested-With", utils.CSRFHeader, utils.AuthModeHeader, middleware.OrganizationHeader, middleware.RequestIDHeader, "If-Match", "If-None-Match"}
+	config.ExposeHeaders = []string{middleware.RequestIDHeader, "Link", "ETag"}
 	router.Use(cors.New(config))
 
+	// Identificador de petición para correlacionar logs y auditoría
+	router.Use(middleware.RequestID())
+
 	// Middleware de logging
 	router.Use(gin.Logger())
 	router.Use(gin.Recovery())
 
+	// Ficheros subidos (avatares) con el almacenamiento local; con S3 los sirve el bucket o su CDN
+	if storage.ServesLocalFiles() {
+		router.Static(storage.LocalURLPath, storage.LocalDir())
+	}
+
 	// Ruta de health check
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{
@@ -37,10 +57,66 @@ func Setup() *gin.Engine {
 	userHandler := handlers.NewUserHandler()
 	roleHandler := handlers.NewRoleHandler()
 	authHandler := handlers.NewAuthHandler()
+	magicLinkHandler := handlers.NewMagicLinkHandler()
+	deviceAuthHandler := handlers.NewDeviceAuthHandler()
+	loginHistoryHandler := handlers.NewLoginHistoryHandler()
+	scimHandler := handlers.NewScimHandler()
+	samlHandler := handlers.NewSamlHandler()
+	identityHandler := handlers.NewIdentityHandler()
+	sessionPolicyHandler := handlers.NewSessionPolicyHandler()
+	organizationHandler := handlers.NewOrganizationHandler()
+	auditHandler := handlers.NewAuditHandler()
+	privacyHandler := handlers.NewPrivacyHandler()
+	profileHandler := handlers.NewProfileHandler()
 	authMiddleware := middleware.NewAuthMiddleware()
+	csrfMiddleware := middleware.NewCSRFMiddleware()
+	scimMiddleware := middleware.NewScimMiddleware()
+	tenantMiddleware := middleware.NewTenantMiddleware()
+
+	// Aprovisionamiento SCIM 2.0 para IdPs (token bearer por cliente, sin cookies ni CSRF)
+	scim := router.Group("/scim/v2")
+	{
+		// Descubrimiento (público)
+		scim.GET("/ServiceProviderConfig", scimHandler.ServiceProviderConfig) // GET /scim/v2/ServiceProviderConfig
+		scim.GET("/ResourceTypes", scimHandler.ResourceTypes)                 // GET /scim/v2/ResourceTypes
+		scim.GET("/ResourceTypes/:id", scimHandler.ResourceType)              // GET /scim/v2/ResourceTypes/:id
+		scim.GET("/Schemas", scimHandler.Schemas)                             // GET /scim/v2/Schemas
+		scim.GET("/Schemas/:id", scimHandler.Schema)                          // GET /scim/v2/Schemas/:id
+
+		provisioning := scim.Group("/")
+		provisioning.Use(scimMiddleware.RequireClient())
+		{
+			provisioning.GET("/Users", scimHandler.ListUsers)          // GET /scim/v2/Users
+			provisioning.POST("/Users", scimHandler.CreateUser)        // POST /scim/v2/Users
+			provisioning.GET("/Users/:id", scimHandler.GetUser)        // GET /scim/v2/Users/:id
+			provisioning.PUT("/Users/:id", scimHandler.ReplaceUser)    // PUT /scim/v2/Users/:id
+			provisioning.PATCH("/Users/:id", scimHandler.PatchUser)    // PATCH /scim/v2/Users/:id
+			provisioning.DELETE("/Users/:id", scimHandler.DeleteUser)  // DELETE /scim/v2/Users/:id
+
+			provisioning.GET("/Groups", scimHandler.ListGroups)         // GET /scim/v2/Groups
+			provisioning.POST("/Groups", scimHandler.CreateGroup)       // POST /scim/v2/Groups
+			provisioning.GET("/Groups/:id", scimHandler.GetGroup)       // GET /scim/v2/Groups/:id
+			provisioning.PUT("/Groups/:id", scimHandler.ReplaceGroup)   // PUT /scim/v2/Groups/:id
+			provisioning.PATCH("/Groups/:id", scimHandler.PatchGroup)   // PATCH /scim/v2/Groups/:id
+			provisioning.DELETE("/Groups/:id", scimHandler.DeleteGroup) // DELETE /scim/v2/Groups/:id
+		}
+	}
+
+	// SSO SAML 2.0: el IdP envía el POST al ACS desde otro origen, así que estas rutas
+	// quedan fuera del middleware CSRF (la respuesta va firmada por el IdP)
+	saml := router.Group("/api/v1/auth/saml/:slug")
+	{
+		saml.GET("/metadata", samlHandler.Metadata) // GET /api/v1/auth/saml/:slug/metadata
+		saml.GET("/login", samlHandler.Login)       // GET /api/v1/auth/saml/:slug/login?redirect_to=
+		saml.POST("/acs", samlHandler.ACS)          // POST /api/v1/auth/saml/:slug/acs
+	}
+
+	// Operaciones sensibles exigen haber introducido la contraseña recientemente
+	recentAuth := authMiddleware.RequireRecentAuth(middleware.StepUpMaxAge())
 
 	// Grupo de rutas API v1
 	v1 := router.Group("/api/v1")
+	v1.Use(csrfMiddleware.Protect())
 	{
 		// Rutas públicas de autenticación
 		auth := v1.Group("/auth")
@@ -49,6 +125,14 @@ func Setup() *gin.Engine {
 			auth.POST("/register", authHandler.Register)              // POST /api/v1/auth/register
 			auth.POST("/refresh", authHandler.RefreshToken)           // POST /api/v1/auth/refresh
 			auth.POST("/logout", authHandler.Logout)                  // POST /api/v1/auth/logout
+			auth.POST("/magic-link", magicLinkHandler.RequestLink)    // POST /api/v1/auth/magic-link
+			auth.POST("/magic-link/verify", magicLinkHandler.VerifyLink) // POST /api/v1/auth/magic-link/verify
+			auth.POST("/reauthenticate", authMiddleware.RequireAuth(), authHandler.Reauthenticate) // POST /api/v1/auth/reauthenticate
+			auth.POST("/reauthenticate/saml/:slug", authMiddleware.RequireAuth(), samlHandler.Reauthenticate) // POST /api/v1/auth/reauthenticate/saml/:slug (cuentas sin contraseña)
+			auth.POST("/device/code", deviceAuthHandler.RequestCode)  // POST /api/v1/auth/device/code
+			auth.POST("/device/token", deviceAuthHandler.Token)       // POST /api/v1/auth/device/token
+			auth.POST("/email-change/confirm", profileHandler.ConfirmEmailChange) // POST /api/v1/auth/email-change/confirm
+			auth.POST("/reactivate", profileHandler.ReactivateAccount)  // POST /api/v1/auth/reactivate
 		}
 
 		// Rutas protegidas (requieren autenticación)
@@ -56,28 +140,124 @@ func Setup() *gin.Engine {
 		protected.Use(authMiddleware.RequireAuth())
 		{
 			// Profile endpoints
-			protected.GET("/profile", authHandler.GetProfile)           // GET /api/v1/profile
-			protected.POST("/change-password", authHandler.ChangePassword) // POST /api/v1/change-password
+			protected.GET("/profile", profileHandler.GetProfile)         // GET /api/v1/profile
+			protected.PATCH("/profile", profileHandler.UpdateProfile) // PATCH /api/v1/profile (cambiar el email exige re-autenticación reciente)
+			protected.DELETE("/profile", profileHandler.DeleteProfile)   // DELETE /api/v1/profile
+			protected.PUT("/profile/avatar", profileHandler.UploadAvatar)    // PUT /api/v1/profile/avatar (multipart)
+			protected.DELETE("/profile/avatar", profileHandler.RemoveAvatar) // DELETE /api/v1/profile/avatar
+			protected.GET("/profile/logins", loginHistoryHandler.GetMyLogins) // GET /api/v1/profile/logins
+			protected.GET("/profile/organizations", organizationHandler.GetMyOrganizations)      // GET /api/v1/profile/organizations
+			protected.GET("/profile/identities", identityHandler.GetMyIdentities)                 // GET /api/v1/profile/identities
+			protected.POST("/profile/identities/saml/:slug", recentAuth, identityHandler.LinkSaml) // POST /api/v1/profile/identities/saml/:slug
+			protected.DELETE("/profile/identities/:id", recentAuth, identityHandler.Unlink)       // DELETE /api/v1/profile/identities/:id
+			protected.POST("/profile/export", recentAuth, privacyHandler.RequestExport)           // POST /api/v1/profile/export
+			protected.GET("/profile/exports", privacyHandler.GetExports)                          // GET /api/v1/profile/exports
+			protected.GET("/profile/exports/:id", privacyHandler.GetExport)                       // GET /api/v1/profile/exports/:id
+			protected.GET("/profile/exports/:id/download", privacyHandler.DownloadExport)         // GET /api/v1/profile/exports/:id/download
+			protected.POST("/profile/erasure", recentAuth, privacyHandler.RequestErasure)         // POST /api/v1/profile/erasure
+			protected.GET("/profile/erasure", privacyHandler.GetErasure)                          // GET /api/v1/profile/erasure
+			protected.DELETE("/profile/erasure", privacyHandler.CancelErasure)                    // DELETE /api/v1/profile/erasure
+			protected.POST("/change-password", recentAuth, authHandler.ChangePassword) // POST /api/v1/change-password
 			protected.GET("/check-auth", authHandler.CheckAuth)         // GET /api/v1/check-auth
 
-			// Rutas para roles (requiere autenticación)
+			// Aprobación de dispositivos (RFC 8628)
+			device := protected.Group("/device")
+			{
+				device.GET("", deviceAuthHandler.GetPending)         // GET /api/v1/device?user_code=
+				device.POST("/approve", deviceAuthHandler.Approve)   // POST /api/v1/device/approve
+				device.POST("/deny", deviceAuthHandler.Deny)         // POST /api/v1/device/deny
+			}
+
+			// Cuentas duplicadas por email verificado (admin)
+			accounts := protected.Group("/admin/accounts")
+			accounts.Use(authMiddleware.RequireRole("admin"))
+			{
+				accounts.GET("/duplicates", identityHandler.GetDuplicateAccounts)   // GET /api/v1/admin/accounts/duplicates
+				accounts.POST("/merge", recentAuth, identityHandler.MergeAccounts)  // POST /api/v1/admin/accounts/merge
+				accounts.POST("/:id/erase", recentAuth, privacyHandler.EraseUser)   // POST /api/v1/admin/accounts/:id/erase
+			}
+
+			// Configuración de IdPs SAML (admin)
+			samlProviders := protected.Group("/saml-providers")
+			samlProviders.Use(authMiddleware.RequireRole("admin"))
+			{
+				samlProviders.POST("", samlHandler.CreateProvider)       // POST /api/v1/saml-providers
+				samlProviders.GET("", samlHandler.GetProviders)          // GET /api/v1/saml-providers
+				samlProviders.GET("/:id", samlHandler.GetProvider)       // GET /api/v1/saml-providers/:id
+				samlProviders.PUT("/:id", samlHandler.UpdateProvider)    // PUT /api/v1/saml-providers/:id
+				samlProviders.DELETE("/:id", samlHandler.DeleteProvider) // DELETE /api/v1/saml-providers/:id
+			}
+
+			// Perfiles de duración de sesión por rol o cliente (admin)
+			sessionPolicies := protected.Group("/session-policies")
+			sessionPolicies.Use(authMiddleware.RequireRole("admin"))
+			{
+				sessionPolicies.POST("", sessionPolicyHandler.CreatePolicy)       // POST /api/v1/session-policies
+				sessionPolicies.GET("", sessionPolicyHandler.GetPolicies)         // GET /api/v1/session-policies
+				sessionPolicies.GET("/:id", sessionPolicyHandler.GetPolicy)       // GET /api/v1/session-policies/:id
+				sessionPolicies.PUT("/:id", sessionPolicyHandler.UpdatePolicy)    // PUT /api/v1/session-policies/:id
+				sessionPolicies.DELETE("/:id", sessionPolicyHandler.DeletePolicy) // DELETE /api/v1/session-policies/:id
+			}
+
+			// Log de auditoría (admin)
+			audit := protected.Group("/audit")
+			audit.Use(authMiddleware.RequireRole("admin"))
+			{
+				audit.GET("", auditHandler.GetAuditLogs)           // GET /api/v1/audit
+				audit.GET("/export", auditHandler.ExportAuditLogs) // GET /api/v1/audit/export (JSON Lines)
+			}
+
+			// Organizaciones y sus miembros (admin)
+			organizations := protected.Group("/organizations")
+			organizations.Use(authMiddleware.RequireRole("admin"))
+			{
+				organizations.POST("", organizationHandler.CreateOrganization)                  // POST /api/v1/organizations
+				organizations.GET("", organizationHandler.GetOrganizations)                     // GET /api/v1/organizations
+				organizations.GET("/:id", organizationHandler.GetOrganization)                  // GET /api/v1/organizations/:id
+				organizations.PUT("/:id", organizationHandler.UpdateOrganization)               // PUT /api/v1/organizations/:id
+				organizations.DELETE("/:id", recentAuth, organizationHandler.DeleteOrganization) // DELETE /api/v1/organizations/:id
+				organizations.GET("/:id/members", organizationHandler.GetMembers)               // GET /api/v1/organizations/:id/members
+				organizations.POST("/:id/members", organizationHandler.AddMember)               // POST /api/v1/organizations/:id/members
+				organizations.PUT("/:id/members/:userId", organizationHandler.UpdateMember)     // PUT /api/v1/organizations/:id/members/:userId
+				organizations.DELETE("/:id/members/:userId", organizationHandler.RemoveMember)  // DELETE /api/v1/organizations/:id/members/:userId
+			}
+
+			// Rutas para roles (requiere autenticación y organización activa)
 			roles := protected.Group("/roles")
+			roles.Use(tenantMiddleware.RequireTenant())
 			{
 				roles.POST("", roleHandler.CreateRole)           // POST /api/v1/roles
-				roles.GET("", roleHandler.GetRoles)              // GET /api/v1/roles
+				roles.GET("", roleHandler.GetRoles)              // GET /api/v1/roles?page=&per_page=&cursor=&sort=&<filtros>
 				roles.GET("/:id", roleHandler.GetRole)           // GET /api/v1/roles/:id
 				roles.PUT("/:id", roleHandler.UpdateRole)        // PUT /api/v1/roles/:id
+				roles.PATCH("/:id", roleHandler.PatchRole)       // PATCH /api/v1/roles/:id (merge patch o JSON Patch)
 				roles.DELETE("/:id", roleHandler.DeleteRole)     // DELETE /api/v1/roles/:id
+				roles.GET("/:id/revisions", roleHandler.GetRoleRevisions)     // GET /api/v1/roles/:id/revisions?at=
+				roles.GET("/:id/revisions/:rev", roleHandler.GetRoleRevision) // GET /api/v1/roles/:id/revisions/:rev
+				roles.POST("/:id/revisions/:rev/restore", authMiddleware.RequireRole("admin"), recentAuth, roleHandler.RestoreRoleRevision) // POST /api/v1/roles/:id/revisions/:rev/restore (admin)
+				roles.POST("/:id/restore", authMiddleware.RequireRole("admin"), recentAuth, roleHandler.RestoreRole) // POST /api/v1/roles/:id/restore (admin)
+				roles.DELETE("/:id/purge", authMiddleware.RequireRole("admin"), recentAuth, roleHandler.PurgeRole)   // DELETE /api/v1/roles/:id/purge (admin)
 			}
 
-			// Rutas para usuarios (requiere autenticación)
+			// Rutas para usuarios (requiere autenticación y organización activa)
 			users := protected.Group("/users")
+			users.Use(tenantMiddleware.RequireTenant())
 			{
 				users.POST("", userHandler.CreateUser)           // POST /api/v1/users
-				users.GET("", userHandler.GetUsers)              // GET /api/v1/users
+				users.GET("", userHandler.GetUsers)              // GET /api/v1/users?page=&per_page=&cursor=&sort=&<filtros>
+				users.GET("/search", authMiddleware.RequireRole("admin"), userHandler.SearchUsers) // GET /api/v1/users/search?q= (admin)
 				users.GET("/:id", userHandler.GetUser)           // GET /api/v1/users/:id
-				users.PUT("/:id", userHandler.UpdateUser)        // PUT /api/v1/users/:id
-				users.DELETE("/:id", userHandler.DeleteUser)     // DELETE /api/v1/users/:id
+				users.PUT("/:id", recentAuth, userHandler.UpdateUser)    // PUT /api/v1/users/:id
+				users.PATCH("/:id", recentAuth, userHandler.PatchUser)   // PATCH /api/v1/users/:id (merge patch o JSON Patch)
+				users.DELETE("/:id", recentAuth, userHandler.DeleteUser) // DELETE /api/v1/users/:id
+				users.GET("/:id/logins", authMiddleware.RequireRole("admin"), loginHistoryHandler.GetUserLogins) // GET /api/v1/users/:id/logins (admin)
+				users.GET("/:id/revisions", userHandler.GetUserRevisions)     // GET /api/v1/users/:id/revisions?at=
+				users.GET("/:id/revisions/:rev", userHandler.GetUserRevision) // GET /api/v1/users/:id/revisions/:rev
+				users.POST("/:id/revisions/:rev/restore", authMiddleware.RequireRole("admin"), recentAuth, userHandler.RestoreUserRevision) // POST /api/v1/users/:id/revisions/:rev/restore (admin)
+				users.POST("/:id/restore", authMiddleware.RequireRole("admin"), recentAuth, userHandler.RestoreUser) // POST /api/v1/users/:id/restore (admin)
+				users.DELETE("/:id/purge", authMiddleware.RequireRole("admin"), recentAuth, userHandler.PurgeUser)   // DELETE /api/v1/users/:id/purge (admin)
+				users.PUT("/:id/avatar", authMiddleware.RequireRole("admin"), userHandler.UploadAvatar)     // PUT /api/v1/users/:id/avatar (admin, multipart)
+				users.DELETE("/:id/avatar", authMiddleware.RequireRole("admin"), userHandler.RemoveAvatar)  // DELETE /api/v1/users/:id/avatar (admin)
 			}
 		}
 
@@ -92,41 +272,129 @@ func Setup() *gin.Engine {
 				},
 				"endpoints": gin.H{
 					"auth": gin.H{
-						"login":     "POST /api/v1/auth/login",
+						"login":     "POST /api/v1/auth/login (username or email, optional remember_me and client_id)",
 						"register":  "POST /api/v1/auth/register",
 						"refresh":   "POST /api/v1/auth/refresh",
-						"logout":    "POST /api/v1/auth/logout",
+						"logout":    "POST /api/v1/auth/logout (revokes the refresh token session)",
+						"magic_link": "POST /api/v1/auth/magic-link",
+						"magic_link_verify": "POST /api/v1/auth/magic-link/verify",
+						"reauthenticate": "POST /api/v1/auth/reauthenticate (protected)",
+						"reauthenticate_saml": "POST /api/v1/auth/reauthenticate/saml/:slug (protected, accounts without password)",
+						"device_code": "POST /api/v1/auth/device/code",
+						"device_token": "POST /api/v1/auth/device/token",
 						"profile":   "GET /api/v1/profile (protected)",
+						"update_profile": "PATCH /api/v1/profile (protected) - name, user_name, avatar_url; a new email requires recent auth and is applied once confirmed",
+						"confirm_email": "POST /api/v1/auth/email-change/confirm",
+						"upload_avatar": "PUT /api/v1/profile/avatar (protected, multipart field 'avatar': JPEG, PNG or GIF)",
+						"remove_avatar": "DELETE /api/v1/profile/avatar (protected)",
+						"delete_account": "DELETE /api/v1/profile (protected, password) - deactivates now, deleted after the grace period",
+						"reactivate": "POST /api/v1/auth/reactivate",
+						"logins":    "GET /api/v1/profile/logins (protected)",
+						"organizations": "GET /api/v1/profile/organizations (protected)",
+						"identities": "GET /api/v1/profile/identities (protected)",
+						"link_saml":  "POST /api/v1/profile/identities/saml/:slug (protected, recent auth)",
+						"unlink":     "DELETE /api/v1/profile/identities/:id (protected, recent auth)",
 						"check":     "GET /api/v1/check-auth (protected)",
-						"password":  "POST /api/v1/change-password (protected)",
+						"password":  "POST /api/v1/change-password (protected, recent auth)",
+					},
+					"privacy": gin.H{
+						"export":         "POST /api/v1/profile/export (protected, recent auth) - generated asynchronously",
+						"exports":        "GET /api/v1/profile/exports, GET /api/v1/profile/exports/:id (protected)",
+						"download":       "GET /api/v1/profile/exports/:id/download (protected)",
+						"request_erasure": "POST /api/v1/profile/erasure (protected, recent auth)",
+						"erasure_status": "GET /api/v1/profile/erasure (protected)",
+						"cancel_erasure": "DELETE /api/v1/profile/erasure (protected)",
+					},
+					"device": gin.H{
+						"lookup":  "GET /api/v1/device?user_code= (protected)",
+						"approve": "POST /api/v1/device/approve (protected)",
+						"deny":    "POST /api/v1/device/deny (protected)",
 					},
 					"roles": gin.H{
 						"create": "POST /api/v1/roles (protected)",
-						"list":   "GET /api/v1/roles (protected)",
-						"get":    "GET /api/v1/roles/:id (protected)",
+						"list":   "GET /api/v1/roles?page=&per_page=&cursor=&sort=&filter=&name=&is_active=&created_from=&created_to= (protected)",
+						"get":    "GET /api/v1/roles/:id (protected, ETag / If-None-Match; PUT, PATCH and DELETE accept If-Match)",
 						"update": "PUT /api/v1/roles/:id (protected)",
+						"patch":  "PATCH /api/v1/roles/:id (protected, application/merge-patch+json or application/json-patch+json)",
 						"delete": "DELETE /api/v1/roles/:id (protected)",
+						"revisions": "GET /api/v1/roles/:id/revisions?at=, GET /api/v1/roles/:id/revisions/:rev (protected)",
+						"restore":   "POST /api/v1/roles/:id/revisions/:rev/restore (admin, recent auth)",
+						"untrash":   "POST /api/v1/roles/:id/restore (admin, recent auth)",
+						"purge":     "DELETE /api/v1/roles/:id/purge (admin, recent auth)",
 					},
 					"users": gin.H{
 						"create": "POST /api/v1/users (protected)",
-						"list":   "GET /api/v1/users (protected)",
-						"get":    "GET /api/v1/users/:id (protected)",
-						"update": "PUT /api/v1/users/:id (protected)",
-						"delete": "DELETE /api/v1/users/:id (protected)",
+						"list":   "GET /api/v1/users?page=&per_page=&cursor=&sort=&filter=&name=&email=&user_name=&role_id=&is_active=&created_from=&created_to= (protected)",
+						"search": "GET /api/v1/users/search?q=&page=&per_page=&cursor=&sort= (admin)",
+						"get":    "GET /api/v1/users/:id (protected, ETag / If-None-Match; PUT, PATCH and DELETE accept If-Match)",
+						"update": "PUT /api/v1/users/:id (protected, recent auth; in an organization, account fields of users in other organizations are read-only)",
+						"patch":  "PATCH /api/v1/users/:id (protected, recent auth, application/merge-patch+json or application/json-patch+json)",
+						"delete": "DELETE /api/v1/users/:id (protected, recent auth)",
+						"logins": "GET /api/v1/users/:id/logins (admin, members of the current organization)",
+						"revisions": "GET /api/v1/users/:id/revisions?at=, GET /api/v1/users/:id/revisions/:rev (protected)",
+						"restore":   "POST /api/v1/users/:id/revisions/:rev/restore (admin, recent auth)",
+						"untrash":   "POST /api/v1/users/:id/restore (admin, recent auth)",
+						"purge":     "DELETE /api/v1/users/:id/purge (admin, recent auth)",
+						"avatar":    "PUT|DELETE /api/v1/users/:id/avatar (admin) - multipart field 'avatar', resized to 32, 64, 128 and 256 px",
+					},
+					"accounts": gin.H{
+						"duplicates": "GET /api/v1/admin/accounts/duplicates (admin)",
+						"merge":      "POST /api/v1/admin/accounts/merge (admin, recent auth)",
+						"erase":      "POST /api/v1/admin/accounts/:id/erase (admin, recent auth)",
+					},
+					"organizations": gin.H{
+						"organizations": "GET|POST /api/v1/organizations, GET|PUT|DELETE /api/v1/organizations/:id (admin)",
+						"members":       "GET|POST /api/v1/organizations/:id/members, PUT|DELETE /api/v1/organizations/:id/members/:userId (admin)",
+						"tenant":        "users and roles are scoped to the organization from the X-Organization header, the token or the user's only membership",
+					},
+					"audit": gin.H{
+						"search": "GET /api/v1/audit (admin)",
+						"export": "GET /api/v1/audit/export (admin, JSON Lines)",
+					},
+					"session_policies": gin.H{
+						"policies": "GET|POST /api/v1/session-policies, GET|PUT|DELETE /api/v1/session-policies/:id (admin)",
+					},
+					"saml": gin.H{
+						"metadata":  "GET /api/v1/auth/saml/:slug/metadata",
+						"login":     "GET /api/v1/auth/saml/:slug/login?redirect_to=",
+						"acs":       "POST /api/v1/auth/saml/:slug/acs",
+						"providers": "GET|POST /api/v1/saml-providers, GET|PUT|DELETE /api/v1/saml-providers/:id (admin)",
+					},
+					"scim": gin.H{
+						"users":     "GET|POST /scim/v2/Users, GET|PUT|PATCH|DELETE /scim/v2/Users/:id (SCIM token)",
+						"groups":    "GET|POST /scim/v2/Groups, GET|PUT|PATCH|DELETE /scim/v2/Groups/:id (SCIM token)",
+						"discovery": "GET /scim/v2/ServiceProviderConfig, /scim/v2/Schemas, /scim/v2/ResourceTypes",
 					},
 				},
 				"authentication": gin.H{
 					"type":   "JWT Bearer Token",
 					"header": "Authorization: Bearer <token>",
 					"note":   "Include access token in Authorization header for protected routes",
+					"cookie_mode": gin.H{
+						"enable": "Send X-Auth-Mode: cookie on login (requires AUTH_COOKIE_MODE=true)",
+						"csrf":   "Echo the csrf_token cookie in the X-CSRF-Token header on POST/PUT/DELETE",
+					},
 				},
 				"query_params": gin.H{
 					"roles": gin.H{
 						"include_inactive": "bool - Include inactive roles",
+						"trashed":          "string - 'with' to include deleted roles, 'only' for the trash",
 					},
 					"users": gin.H{
 						"include_inactive": "bool - Include inactive users",
 						"role_id":          "int - Filter by role ID",
+						"trashed":          "string - 'with' to include deleted users, 'only' for the trash",
+					},
+					"logins": gin.H{
+						"limit":  "int - Page size (default 20, max 100)",
+						"offset": "int - Number of entries to skip",
+					},
+					"audit": gin.H{
+						"filters": "actor_id, action, target_type, target_id, organization_id, request_id, ip_address",
+						"from":    "RFC 3339 timestamp - Entries at or after this time",
+						"to":      "RFC 3339 timestamp - Entries before this time",
+						"limit":   "int - Page size (default 50, max 500, search only)",
+						"offset":  "int - Number of entries to skip (search only)",
 					},
 				},
 			})
@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"megabaseGo/internal/utils"
+)
+
+// LocalURLPath ruta en la que la API sirve los ficheros del driver local
+const LocalURLPath = "/uploads"
+
+// LocalDir directorio del driver local (STORAGE_LOCAL_DIR)
+func LocalDir() string {
+	return utils.GetEnv("STORAGE_LOCAL_DIR", "storage/uploads")
+}
+
+// ServesLocalFiles indica si la API debe servir LocalDir en LocalURLPath
+func ServesLocalFiles() bool {
+	return utils.GetEnv("STORAGE_DRIVER", "local") == "local"
+}
+
+// Local guarda los objetos como ficheros bajo un directorio
+type Local struct {
+	dir       string
+	publicURL string
+}
+
+// NewLocal crea un backend local en dir cuyos ficheros se sirven bajo publicURL
+func NewLocal(dir, publicURL string) *Local {
+	return &Local{dir: dir, publicURL: strings.TrimRight(publicURL, "/")}
+}
+
+// Put implementa Storage. Escribe en un temporal y lo renombra para no servir nunca un fichero a medias.
+func (s *Local) Put(key string, body io.Reader, size int64, contentType string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Get implementa Storage
+func (s *Local) Get(key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return file, err
+}
+
+// Delete implementa Storage
+func (s *Local) Delete(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// URL implementa Storage
+func (s *Local) URL(key string) string {
+	return s.publicURL + "/" + key
+}
+
+func (s *Local) path(key string) (string, error) {
+	if err := validateKey(key); err != nil {
+		return "", err
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
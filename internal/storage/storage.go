@@ -0,0 +1,67 @@
+// Package storage guarda ficheros subidos por los usuarios (avatares) en un backend intercambiable:
+// el sistema de ficheros local o un almacenamiento de objetos compatible con S3 (AWS S3, MinIO...).
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"megabaseGo/internal/utils"
+)
+
+// ErrNotFound el objeto no existe
+var ErrNotFound = errors.New("storage object not found")
+
+// Storage backend de almacenamiento de objetos. Las claves usan "/" como separador y no
+// empiezan por "/" (p. ej. avatars/12/abc-128.jpg).
+type Storage interface {
+	// Put guarda el objeto, sustituyéndolo si ya existía
+	Put(key string, body io.Reader, size int64, contentType string) error
+	// Get abre el objeto; devuelve ErrNotFound si no existe
+	Get(key string) (io.ReadCloser, error)
+	// Delete borra el objeto; no falla si no existe
+	Delete(key string) error
+	// URL pública con la que los clientes descargan el objeto
+	URL(key string) string
+}
+
+// New construye el backend configurado en STORAGE_DRIVER ("local" por defecto o "s3")
+//
+//	STORAGE_LOCAL_DIR      directorio de los ficheros con el driver local (storage/uploads)
+//	STORAGE_PUBLIC_URL     URL base con la que se sirven; con el driver local la sirve la API en /uploads
+//	S3_ENDPOINT            https://s3.<region>.amazonaws.com o la URL del servicio compatible
+//	S3_REGION, S3_BUCKET, S3_ACCESS_KEY_ID, S3_SECRET_ACCESS_KEY
+//	S3_FORCE_PATH_STYLE    true para servicios que no admiten buckets como subdominio (MinIO)
+func New() (Storage, error) {
+	switch driver := utils.GetEnv("STORAGE_DRIVER", "local"); driver {
+	case "local":
+		return NewLocal(LocalDir(), utils.GetEnv("STORAGE_PUBLIC_URL", "http://localhost:8080"+LocalURLPath)), nil
+	case "s3":
+		return NewS3(S3Config{
+			Endpoint:        utils.GetEnv("S3_ENDPOINT", ""),
+			Region:          utils.GetEnv("S3_REGION", "us-east-1"),
+			Bucket:          utils.GetEnv("S3_BUCKET", ""),
+			AccessKeyID:     utils.GetEnv("S3_ACCESS_KEY_ID", ""),
+			SecretAccessKey: utils.GetEnv("S3_SECRET_ACCESS_KEY", ""),
+			ForcePathStyle:  utils.GetEnv("S3_FORCE_PATH_STYLE", "false") == "true",
+			PublicURL:       utils.GetEnv("STORAGE_PUBLIC_URL", ""),
+		})
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_DRIVER %q: expected local or s3", driver)
+	}
+}
+
+// validateKey rechaza claves vacías, absolutas o que salgan del prefijo con ".."
+func validateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return fmt.Errorf("invalid storage key %q", key)
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return fmt.Errorf("invalid storage key %q", key)
+		}
+	}
+	return nil
+}
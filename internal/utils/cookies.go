@@ -0,0 +1,137 @@
+package utils
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// AccessTokenCookie nombre de la cookie con el access token
+	AccessTokenCookie = "access_token"
+	// RefreshTokenCookie nombre de la cookie con el refresh token
+	RefreshTokenCookie = "refresh_token"
+	// CSRFCookie nombre de la cookie con el token CSRF (legible por JavaScript)
+	CSRFCookie = "csrf_token"
+	// CSRFHeader cabecera en la que el cliente devuelve el token CSRF
+	CSRFHeader = "X-CSRF-Token"
+	// AuthModeHeader cabecera con la que un cliente pide sesión por cookies
+	AuthModeHeader = "X-Auth-Mode"
+
+	refreshCookiePath = "/api/v1/auth"
+)
+
+// CookieManager maneja las cookies de sesión para clientes de navegador
+type CookieManager struct {
+	enabled  bool
+	domain   string
+	secure   bool
+	sameSite http.SameSite
+}
+
+// NewCookieManager crea el manager de cookies a partir de las variables de entorno
+func NewCookieManager() *CookieManager {
+	sameSite := http.SameSiteLaxMode
+	switch strings.ToLower(os.Getenv("AUTH_COOKIE_SAMESITE")) {
+	case "strict":
+		sameSite = http.SameSiteStrictMode
+	case "none":
+		sameSite = http.SameSiteNoneMode
+	}
+
+	return &CookieManager{
+		enabled:  os.Getenv("AUTH_COOKIE_MODE") == "true",
+		domain:   os.Getenv("AUTH_COOKIE_DOMAIN"),
+		secure:   os.Getenv("AUTH_COOKIE_SECURE") != "false",
+		sameSite: sameSite,
+	}
+}
+
+// Enabled indica si el modo de sesión por cookies está habilitado
+func (m *CookieManager) Enabled() bool {
+	return m.enabled
+}
+
+// WantsCookies indica si el cliente pidió sesión por cookies (X-Auth-Mode: cookie)
+func (m *CookieManager) WantsCookies(c *gin.Context) bool {
+	return m.enabled && strings.EqualFold(c.GetHeader(AuthModeHeader), "cookie")
+}
+
+// SetAuthCookies guarda los tokens en cookies HttpOnly y emite un nuevo token CSRF
+func (m *CookieManager) SetAuthCookies(c *gin.Context, accessToken, refreshToken string, accessMaxAge, refreshMaxAge int) error {
+	csrfToken, err := GenerateRandomToken(32)
+	if err != nil {
+		return err
+	}
+
+	m.setCookie(c, AccessTokenCookie, accessToken, "/", accessMaxAge, true)
+	m.setCookie(c, RefreshTokenCookie, refreshToken, refreshCookiePath, refreshMaxAge, true)
+	m.setCookie(c, CSRFCookie, csrfToken, "/", refreshMaxAge, false)
+	return nil
+}
+
+// SetAccessCookie reemplaza solo la cookie del access token (p. ej. tras una re-autenticación)
+func (m *CookieManager) SetAccessCookie(c *gin.Context, accessToken string, maxAge int) {
+	m.setCookie(c, AccessTokenCookie, accessToken, "/", maxAge, true)
+}
+
+// ClearAuthCookies elimina las cookies de sesión
+func (m *CookieManager) ClearAuthCookies(c *gin.Context) {
+	m.setCookie(c, AccessTokenCookie, "", "/", -1, true)
+	m.setCookie(c, RefreshTokenCookie, "", refreshCookiePath, -1, true)
+	m.setCookie(c, CSRFCookie, "", "/", -1, false)
+}
+
+// GetAccessToken obtiene el access token de la cookie si el modo está habilitado
+func (m *CookieManager) GetAccessToken(c *gin.Context) (string, bool) {
+	return m.getCookie(c, AccessTokenCookie)
+}
+
+// GetRefreshToken obtiene el refresh token de la cookie si el modo está habilitado
+func (m *CookieManager) GetRefreshToken(c *gin.Context) (string, bool) {
+	return m.getCookie(c, RefreshTokenCookie)
+}
+
+// HasSessionCookies indica si la petición viaja con cookies de sesión
+func (m *CookieManager) HasSessionCookies(c *gin.Context) bool {
+	_, hasAccess := m.GetAccessToken(c)
+	_, hasRefresh := m.GetRefreshToken(c)
+	return hasAccess || hasRefresh
+}
+
+// ValidCSRF comprueba el double-submit: la cabecera debe coincidir con la cookie CSRF
+func (m *CookieManager) ValidCSRF(c *gin.Context) bool {
+	cookieToken, err := c.Cookie(CSRFCookie)
+	if err != nil || cookieToken == "" {
+		return false
+	}
+	headerToken := c.GetHeader(CSRFHeader)
+	return subtle.ConstantTimeCompare([]byte(cookieToken), []byte(headerToken)) == 1
+}
+
+func (m *CookieManager) getCookie(c *gin.Context, name string) (string, bool) {
+	if !m.enabled {
+		return "", false
+	}
+	value, err := c.Cookie(name)
+	if err != nil || value == "" {
+		return "", false
+	}
+	return value, true
+}
+
+func (m *CookieManager) setCookie(c *gin.Context, name, value, path string, maxAge int, httpOnly bool) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   m.domain,
+		MaxAge:   maxAge,
+		Secure:   m.secure,
+		HttpOnly: httpOnly,
+		SameSite: m.sameSite,
+	})
+}
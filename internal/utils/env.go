@@ -0,0 +1,24 @@
+package utils
+
+import (
+	"os"
+	"strconv"
+)
+
+// GetEnv obtiene una variable de entorno o el valor por defecto
+func GetEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// GetEnvInt obtiene una variable de entorno entera o el valor por defecto
+func GetEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
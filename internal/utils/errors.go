@@ -64,6 +64,46 @@ func NewConflictError(message string) *APIError {
 	}
 }
 
+// NewTooManyRequestsError crea un error 429
+func NewTooManyRequestsError(message string) *APIError {
+	return &APIError{
+		Message:    message,
+		StatusCode: http.StatusTooManyRequests,
+	}
+}
+
+// NewPayloadTooLargeError crea un error 413
+func NewPayloadTooLargeError(message string) *APIError {
+	return &APIError{
+		Message:    message,
+		StatusCode: http.StatusRequestEntityTooLarge,
+	}
+}
+
+// NewUnsupportedMediaTypeError crea un error 415
+func NewUnsupportedMediaTypeError(message string) *APIError {
+	return &APIError{
+		Message:    message,
+		StatusCode: http.StatusUnsupportedMediaType,
+	}
+}
+
+// NewPreconditionFailedError crea un error 412
+func NewPreconditionFailedError(message string) *APIError {
+	return &APIError{
+		Message:    message,
+		StatusCode: http.StatusPreconditionFailed,
+	}
+}
+
+// NewPreconditionRequiredError crea un error 428
+func NewPreconditionRequiredError(message string) *APIError {
+	return &APIError{
+		Message:    message,
+		StatusCode: http.StatusPreconditionRequired,
+	}
+}
+
 // NewInternalServerError crea un error 500
 func NewInternalServerError(message string) *APIError {
 	return &APIError{
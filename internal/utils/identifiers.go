@@ -0,0 +1,24 @@
+package utils
+
+import (
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormalizeEmail recorta espacios y pasa el email a minúsculas
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// NormalizeUserName recorta espacios y aplica normalización Unicode NFKC,
+// de modo que variantes visualmente idénticas (p. ej. "ｕｓｅｒ" y "user") coincidan.
+// Las mayúsculas se conservan; la unicidad se comprueba sin distinguirlas.
+func NormalizeUserName(userName string) string {
+	return norm.NFKC.String(strings.TrimSpace(userName))
+}
+
+// IsEmailIdentifier indica si un identificador de login corresponde a un email
+func IsEmailIdentifier(identifier string) bool {
+	return strings.Contains(identifier, "@")
+}
@@ -16,13 +16,34 @@ type JWTClaims struct {
 	Email    string `json:"email"`
 	RoleID   uint   `json:"role_id"`
 	RoleName string `json:"role_name"`
+	// AuthTime momento en que el usuario demostró su contraseña (o se re-autenticó con su IdP)
+	// por última vez; no lo llevan las sesiones abiertas sin contraseña (enlace mágico, SSO...)
+	AuthTime *jwt.NumericDate `json:"auth_time,omitempty"`
+	// SessionID sesión de servidor a la que pertenece el token
+	SessionID string `json:"sid,omitempty"`
+	// OrganizationID organización (tenant) elegida al iniciar sesión
+	OrganizationID uint `json:"org_id,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// JWTManager maneja la generación y validación de tokens JWT
+// RefreshClaims claims del refresh token; conserva el auth_time del login original
+type RefreshClaims struct {
+	AuthTime  *jwt.NumericDate `json:"auth_time,omitempty"`
+	SessionID string           `json:"sid,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// MagicLinkClaims claims del token firmado incluido en los enlaces mágicos
+type MagicLinkClaims struct {
+	Email string `json:"email"`
+	jwt.RegisteredClaims
+}
+
+// JWTManager maneja la generación y validación de tokens JWT.
+// La duración de los tokens de sesión la decide el perfil de sesión (ver SessionService).
 type JWTManager struct {
-	secretKey     string
-	tokenDuration time.Duration
+	secretKey        string
+	elevatedDuration time.Duration
 }
 
 // NewJWTManager crea una nueva instancia del manager JWT
@@ -32,29 +53,42 @@ func NewJWTManager() *JWTManager {
 		secret = "megabase-default-secret-key-change-in-production"
 	}
 
-	duration := time.Hour * 24 // 24 horas por defecto
-	if durationStr := os.Getenv("JWT_DURATION_HOURS"); durationStr != "" {
-		if hours, err := strconv.Atoi(durationStr); err == nil {
-			duration = time.Hour * time.Duration(hours)
+	elevatedDuration := time.Minute * 5 // 5 minutos por defecto
+	if minutesStr := os.Getenv("JWT_ELEVATED_MINUTES"); minutesStr != "" {
+		if minutes, err := strconv.Atoi(minutesStr); err == nil {
+			elevatedDuration = time.Minute * time.Duration(minutes)
 		}
 	}
 
 	return &JWTManager{
-		secretKey:     secret,
-		tokenDuration: duration,
+		secretKey:        secret,
+		elevatedDuration: elevatedDuration,
 	}
 }
 
-// GenerateToken genera un nuevo token JWT
-func (manager *JWTManager) GenerateToken(userID uint, userName, email string, roleID uint, roleName string) (string, error) {
+// GenerateToken genera un nuevo access token de la sesión indicada que expira en expiresAt.
+// authTime nil omite el claim auth_time: el token no sirve para operaciones que exigen re-autenticación.
+func (manager *JWTManager) GenerateToken(userID uint, userName, email string, roleID uint, roleName string, authTime *time.Time, sessionID string, organizationID uint, expiresAt time.Time) (string, error) {
+	return manager.generateAccessToken(userID, userName, email, roleID, roleName, authTime, sessionID, organizationID, expiresAt)
+}
+
+// GenerateElevatedToken genera un access token de vida corta tras una re-autenticación
+func (manager *JWTManager) GenerateElevatedToken(userID uint, userName, email string, roleID uint, roleName, sessionID string, organizationID uint) (string, error) {
+	now := time.Now()
+	return manager.generateAccessToken(userID, userName, email, roleID, roleName, &now, sessionID, organizationID, now.Add(manager.elevatedDuration))
+}
+
+func (manager *JWTManager) generateAccessToken(userID uint, userName, email string, roleID uint, roleName string, authTime *time.Time, sessionID string, organizationID uint, expiresAt time.Time) (string, error) {
 	claims := JWTClaims{
-		UserID:   userID,
-		UserName: userName,
-		Email:    email,
-		RoleID:   roleID,
-		RoleName: roleName,
+		UserID:         userID,
+		UserName:       userName,
+		Email:          email,
+		RoleID:         roleID,
+		RoleName:       roleName,
+		SessionID:      sessionID,
+		OrganizationID: organizationID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(manager.tokenDuration)),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 			Issuer:    "megabase-go",
@@ -62,18 +96,26 @@ func (manager *JWTManager) GenerateToken(userID uint, userName, email string, ro
 		},
 	}
 
+	if authTime != nil {
+		claims.AuthTime = jwt.NewNumericDate(*authTime)
+	}
+
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(manager.secretKey))
 }
 
-// GenerateRefreshToken genera un refresh token (válido por más tiempo)
-func (manager *JWTManager) GenerateRefreshToken(userID uint) (string, error) {
-	claims := jwt.RegisteredClaims{
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 24 * 7)), // 7 días
-		IssuedAt:  jwt.NewNumericDate(time.Now()),
-		NotBefore: jwt.NewNumericDate(time.Now()),
-		Issuer:    "megabase-go-refresh",
-		Subject:   strconv.Itoa(int(userID)),
+// GenerateRefreshToken genera un refresh token de la sesión indicada que expira en expiresAt
+func (manager *JWTManager) GenerateRefreshToken(userID uint, authTime time.Time, sessionID string, expiresAt time.Time) (string, error) {
+	claims := RefreshClaims{
+		AuthTime:  jwt.NewNumericDate(authTime),
+		SessionID: sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+			Issuer:    "megabase-go-refresh",
+			Subject:   strconv.Itoa(int(userID)),
+		},
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -94,15 +136,23 @@ func (manager *JWTManager) ValidateToken(tokenString string) (*JWTClaims, error)
 	}
 
 	if claims, ok := token.Claims.(*JWTClaims); ok && token.Valid {
+		// Los refresh tokens y los enlaces mágicos se firman con la misma clave: solo vale el emisor de acceso
+		if claims.Issuer != "megabase-go" {
+			return nil, errors.New("invalid token issuer")
+		}
+		if claims.UserID == 0 {
+			return nil, errors.New("invalid token subject")
+		}
 		return claims, nil
 	}
 
 	return nil, errors.New("invalid token")
 }
 
-// ValidateRefreshToken valida un refresh token
-func (manager *JWTManager) ValidateRefreshToken(tokenString string) (uint, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &jwt.RegisteredClaims{}, func(token *jwt.Token) (interface{}, error) {
+// ValidateRefreshToken valida un refresh token y retorna el ID de usuario, la sesión y el auth_time original.
+// Los refresh tokens emitidos antes de existir las sesiones de servidor no llevan sid.
+func (manager *JWTManager) ValidateRefreshToken(tokenString string) (uint, string, time.Time, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &RefreshClaims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, errors.New("unexpected signing method")
 		}
@@ -110,24 +160,73 @@ func (manager *JWTManager) ValidateRefreshToken(tokenString string) (uint, error
 	})
 
 	if err != nil {
-		return 0, err
+		return 0, "", time.Time{}, err
 	}
 
-	if claims, ok := token.Claims.(*jwt.RegisteredClaims); ok && token.Valid {
+	if claims, ok := token.Claims.(*RefreshClaims); ok && token.Valid {
 		if claims.Issuer != "megabase-go-refresh" {
-			return 0, errors.New("invalid refresh token issuer")
+			return 0, "", time.Time{}, errors.New("invalid refresh token issuer")
 		}
 		userID, err := strconv.Atoi(claims.Subject)
 		if err != nil {
-			return 0, err
+			return 0, "", time.Time{}, err
+		}
+		// Los refresh tokens emitidos antes de existir auth_time usan su fecha de emisión
+		var authTime time.Time
+		if claims.AuthTime != nil {
+			authTime = claims.AuthTime.Time
+		} else if claims.IssuedAt != nil {
+			authTime = claims.IssuedAt.Time
+		}
+		return uint(userID), claims.SessionID, authTime, nil
+	}
+
+	return 0, "", time.Time{}, errors.New("invalid refresh token")
+}
+
+// GenerateMagicLinkToken genera el token firmado de un enlace mágico.
+// El jti identifica el registro de un solo uso guardado en la base de datos.
+func (manager *JWTManager) GenerateMagicLinkToken(userID uint, email, jti string, ttl time.Duration) (string, error) {
+	claims := MagicLinkClaims{
+		Email: email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+			Issuer:    "megabase-go-magic-link",
+			Subject:   strconv.Itoa(int(userID)),
+			ID:        jti,
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(manager.secretKey))
+}
+
+// ValidateMagicLinkToken valida la firma y expiración de un token de enlace mágico
+func (manager *JWTManager) ValidateMagicLinkToken(tokenString string) (*MagicLinkClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &MagicLinkClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return []byte(manager.secretKey), nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if claims, ok := token.Claims.(*MagicLinkClaims); ok && token.Valid {
+		if claims.Issuer != "megabase-go-magic-link" {
+			return nil, errors.New("invalid magic link issuer")
 		}
-		return uint(userID), nil
+		return claims, nil
 	}
 
-	return 0, errors.New("invalid refresh token")
+	return nil, errors.New("invalid magic link token")
 }
 
-// GetTokenDuration retorna la duración del token en segundos
-func (manager *JWTManager) GetTokenDuration() int64 {
-	return int64(manager.tokenDuration.Seconds())
-}
\ No newline at end of file
+// GetElevatedTokenDuration retorna la duración del token elevado en segundos
+func (manager *JWTManager) GetElevatedTokenDuration() int64 {
+	return int64(manager.elevatedDuration.Seconds())
+}
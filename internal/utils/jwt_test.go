@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidateTokenRejectsOtherTokenTypes(t *testing.T) {
+	manager := &JWTManager{secretKey: "test-secret", elevatedDuration: time.Minute}
+	expiresAt := time.Now().Add(time.Hour)
+	authTime := time.Now()
+
+	access, err := manager.GenerateToken(7, "ana", "ana@example.com", 1, "admin", &authTime, "sid", 0, expiresAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	refresh, err := manager.GenerateRefreshToken(7, time.Now(), "sid", expiresAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	magicLink, err := manager.GenerateMagicLinkToken(7, "ana@example.com", "jti", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	anonymous, err := manager.GenerateToken(0, "", "", 0, "admin", &authTime, "sid", 0, expiresAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{"access token", access, false},
+		{"refresh token", refresh, true},
+		{"magic link token", magicLink, true},
+		{"user id 0", anonymous, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := manager.ValidateToken(tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && claims.UserID != 7 {
+				t.Errorf("UserID = %d, want 7", claims.UserID)
+			}
+		})
+	}
+}
+
+func TestGenerateTokenWithoutAuthTime(t *testing.T) {
+	manager := &JWTManager{secretKey: "test-secret", elevatedDuration: time.Minute}
+
+	token, err := manager.GenerateToken(7, "ana", "ana@example.com", 1, "admin", nil, "sid", 0, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := manager.ValidateToken(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.AuthTime != nil {
+		t.Errorf("AuthTime = %v, want nil for a session opened without a password", claims.AuthTime)
+	}
+
+	elevated, err := manager.GenerateElevatedToken(7, "ana", "ana@example.com", 1, "admin", "sid", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims, err = manager.ValidateToken(elevated); err != nil {
+		t.Fatal(err)
+	}
+	if claims.AuthTime == nil || time.Since(claims.AuthTime.Time) > time.Minute {
+		t.Errorf("AuthTime = %v, want the time of the re-authentication", claims.AuthTime)
+	}
+}
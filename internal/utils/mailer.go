@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"fmt"
+	"log"
+	"net/smtp"
+	"os"
+	"strings"
+)
+
+// Mailer abstrae el envío de correos de notificación
+type Mailer interface {
+	Send(to, subject, body string) error
+}
+
+// SMTPMailer implementa Mailer usando un servidor SMTP
+type SMTPMailer struct {
+	host     string
+	port     string
+	username string
+	password string
+	from     string
+}
+
+// LogMailer implementa Mailer escribiendo los correos en el log (desarrollo)
+type LogMailer struct{}
+
+// NewMailer construye el Mailer según la configuración del entorno.
+// Si SMTP_HOST no está definido se usa LogMailer.
+func NewMailer() Mailer {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		return &LogMailer{}
+	}
+
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "587"
+	}
+
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "no-reply@megabase.local"
+	}
+
+	return &SMTPMailer{
+		host:     host,
+		port:     port,
+		username: os.Getenv("SMTP_USERNAME"),
+		password: os.Getenv("SMTP_PASSWORD"),
+		from:     from,
+	}
+}
+
+// Send envía un correo de texto plano
+func (m *SMTPMailer) Send(to, subject, body string) error {
+	var auth smtp.Auth
+	if m.username != "" {
+		auth = smtp.PlainAuth("", m.username, m.password, m.host)
+	}
+
+	msg := strings.Join([]string{
+		"From: " + m.from,
+		"To: " + to,
+		"Subject: " + subject,
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		body,
+	}, "\r\n")
+
+	if err := smtp.SendMail(m.host+":"+m.port, auth, m.from, []string{to}, []byte(msg)); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+// Send registra el correo en el log en lugar de enviarlo
+func (m *LogMailer) Send(to, subject, body string) error {
+	log.Printf("📧 [mail] to=%s subject=%q\n%s", to, subject, body)
+	return nil
+}